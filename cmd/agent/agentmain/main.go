@@ -86,7 +86,19 @@ func doStatus() {
 
 // Print out agent self-description of config/metadata
 func doSelfDescribe() {
+	set := flag.NewFlagSet("selfdescribe", flag.ExitOnError)
+	jsonSchema := set.Bool("json-schema", false, "output a JSON Schema of the agent config file instead of the documentation metadata")
+
+	if err := set.Parse(os.Args[2:]); err != nil {
+		set.Usage()
+		os.Exit(1)
+	}
+
 	log.SetOutput(os.Stderr)
+	if *jsonSchema {
+		selfdescribe.JSONSchema(os.Stdout)
+		return
+	}
 	selfdescribe.JSON(os.Stdout)
 }
 
@@ -130,6 +142,48 @@ func doDatapointTap() {
 	}
 }
 
+// Write a support bundle with diagnostic info from an existing instance of the
+// agent.
+func doSupportBundle() {
+	set := flag.NewFlagSet("support-bundle", flag.ExitOnError)
+	configPath := set.String("config", getDefaultConfigPath(), "agent config path")
+	outPath := set.String("out", fmt.Sprintf("signalfx-agent-support-%s.tar.gz", time.Now().Format("20060102-150405")),
+		"path to write the bundle tarball to, or '-' for stdout")
+
+	if err := set.Parse(os.Args[2:]); err != nil {
+		set.Usage()
+		os.Exit(1)
+	}
+
+	log.SetLevel(log.ErrorLevel)
+
+	bundle, err := core.SupportBundle(*configPath)
+	if err != nil {
+		fmt.Printf("Could not get support bundle: %s\nAre you sure the agent is currently running?\n", err)
+		os.Exit(1)
+	}
+	defer bundle.Close()
+
+	out := os.Stdout
+	if *outPath != "-" {
+		out, err = os.Create(*outPath)
+		if err != nil {
+			fmt.Printf("Could not create %s: %v\n", *outPath, err)
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+
+	if _, err := io.Copy(out, bundle); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing support bundle: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *outPath != "-" {
+		fmt.Printf("Wrote support bundle to %s\n", *outPath)
+	}
+}
+
 // glog is a transitive dependency of the agent and puts a bunch of flags in
 // the flag package.  We don't really ever need to have users override these,
 // but we would like ERROR messages going to stderr of the agent instead of to
@@ -256,6 +310,8 @@ func Run() {
 		doSelfDescribe()
 	case "tap-dps":
 		doDatapointTap()
+	case "support-bundle":
+		doSupportBundle()
 	default:
 		if firstArg != "" && !strings.HasPrefix(firstArg, "-") {
 			log.Errorf("Unknown subcommand '%s'", firstArg)
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"reflect"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -17,6 +18,7 @@ import (
 	"github.com/signalfx/signalfx-agent/pkg/core/config"
 	"github.com/signalfx/signalfx-agent/pkg/core/hostid"
 	"github.com/signalfx/signalfx-agent/pkg/core/meta"
+	"github.com/signalfx/signalfx-agent/pkg/core/recording"
 	"github.com/signalfx/signalfx-agent/pkg/core/services"
 	"github.com/signalfx/signalfx-agent/pkg/core/writer"
 	"github.com/signalfx/signalfx-agent/pkg/core/writer/tracetracker"
@@ -48,6 +50,8 @@ type Agent struct {
 	dimensionChan       chan *types.Dimension
 	spanChan            chan []*trace.Span
 	endpointHostTracker *services.EndpointHostTracker
+	inputRecording      *recording.Bundle
+	hostIDDims          map[string]string
 
 	diagnosticServer     *http.Server
 	profileServerRunning bool
@@ -82,16 +86,14 @@ func NewAgent() *Agent {
 }
 
 func (a *Agent) configure(conf *config.Config) {
-	log.SetFormatter(conf.Logging.LogrusFormatter())
-
-	level := conf.Logging.LogrusLevel()
-	if level != nil {
-		log.SetLevel(*level)
+	// Don't reset levels that were changed at runtime unless the logging
+	// config itself changed
+	if a.lastConfig == nil || !reflect.DeepEqual(a.lastConfig.Logging, conf.Logging) {
+		configureLogging(&conf.Logging)
 	}
 
-	log.Infof("Using log level %s", log.GetLevel().String())
-
 	hostDims := hostid.Dimensions(conf.SendMachineID, conf.Hostname, conf.UseFullyQualifiedHost)
+	a.hostIDDims = hostDims
 	if !conf.DisableHostDimensions {
 		log.Infof("Using host id dimensions %v", hostDims)
 		conf.Writer.HostIDDims = hostDims
@@ -102,20 +104,22 @@ func (a *Agent) configure(conf *config.Config) {
 	}
 
 	if a.lastConfig == nil || a.lastConfig.Writer.Hash() != conf.Writer.Hash() || a.lastConfig.Cluster != conf.Cluster {
-		if a.writer != nil {
-			a.writer.Shutdown()
-		}
-
 		spanSourceTracker := tracetracker.NewSpanSourceTracker(a.endpointHostTracker, a.dimensionChan, conf.Cluster)
 
 		var err error
-		a.writer, err = writer.New(
-			&conf.Writer,
-			a.dpChan,
-			a.eventChan,
-			a.dimensionChan,
-			a.spanChan,
-			spanSourceTracker)
+		if a.writer == nil {
+			a.writer, err = writer.New(
+				&conf.Writer,
+				a.dpChan,
+				a.eventChan,
+				a.dimensionChan,
+				a.spanChan,
+				spanSourceTracker)
+		} else {
+			// The old writer sends what it has buffered with the new config
+			// before the new one starts taking input
+			a.writer, err = a.writer.Replace(&conf.Writer, spanSourceTracker)
+		}
 		if err != nil {
 			// This is a catastrophic error if we can't write datapoints.
 			log.WithError(err).Error("Could not configure SignalFx datapoint writer, unable to start up")
@@ -129,6 +133,11 @@ func (a *Agent) configure(conf *config.Config) {
 
 	a.meta.InternalStatusHost = conf.InternalStatusHost
 	a.meta.InternalStatusPort = conf.InternalStatusPort
+	a.meta.InternalStatusServer = conf.InternalStatusServer
+
+	if a.lastConfig == nil || a.lastConfig.InputRecording != conf.InputRecording {
+		a.configureInputRecording(&conf.InputRecording)
+	}
 
 	// The order of Configure calls is very important!
 	a.monitors.Configure(conf.Monitors, &conf.Collectd, conf.IntervalSeconds, conf.EnableBuiltInFiltering)
@@ -136,6 +145,44 @@ func (a *Agent) configure(conf *config.Config) {
 	a.lastConfig = conf
 }
 
+// Only monitors that are created after this will use the new recording
+// config
+func (a *Agent) configureInputRecording(conf *config.InputRecordingConfig) {
+	a.closeInputRecording()
+
+	var bundle *recording.Bundle
+	var err error
+	switch conf.Mode {
+	case recording.ModeRecord:
+		bundle, err = recording.Create(conf.Path)
+		if err == nil {
+			log.Infof("Recording monitor inputs to %s", conf.Path)
+		}
+	case recording.ModeReplay:
+		bundle, err = recording.Open(conf.Path)
+		if err == nil {
+			log.Infof("Replaying monitor inputs from %s", conf.Path)
+		}
+	}
+	if err != nil {
+		log.WithError(err).WithField("path", conf.Path).Error("Could not open monitor input recording bundle")
+		bundle = nil
+	}
+
+	a.inputRecording = bundle
+	a.monitors.SetInputRecording(bundle)
+}
+
+func (a *Agent) closeInputRecording() {
+	if a.inputRecording == nil {
+		return
+	}
+	if err := a.inputRecording.Close(); err != nil {
+		log.WithError(err).Error("Could not close monitor input recording bundle")
+	}
+	a.inputRecording = nil
+}
+
 func (a *Agent) endpointAdded(service services.Endpoint) {
 	a.endpointHostTracker.EndpointAdded(service)
 	a.monitors.EndpointAdded(service)
@@ -149,6 +196,7 @@ func (a *Agent) endpointRemoved(service services.Endpoint) {
 func (a *Agent) shutdown() {
 	a.observers.Shutdown()
 	a.monitors.Shutdown()
+	a.closeInputRecording()
 	//neopy.Instance().Shutdown()
 	a.writer.Shutdown()
 	if a.diagnosticServer != nil {
@@ -162,6 +210,8 @@ func (a *Agent) shutdown() {
 func Startup(configPath string) (context.CancelFunc, <-chan struct{}) {
 	cwc, cancel := context.WithCancel(context.Background())
 
+	captureRecentLogs()
+
 	configLoads, err := config.LoadConfig(cwc, configPath)
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -192,7 +242,7 @@ func Startup(configPath string) (context.CancelFunc, <-chan struct{}) {
 				log.Info("Done configuring agent")
 
 				if config.InternalStatusHost != "" {
-					agent.serveDiagnosticInfo(config.InternalStatusHost, config.InternalStatusPort)
+					agent.serveDiagnosticInfo(config.InternalStatusHost, config.InternalStatusPort, &config.InternalStatusServer)
 				}
 
 			case <-ctx.Done():
@@ -214,7 +264,18 @@ func Status(configPath string, section string) ([]byte, error) {
 	}
 
 	conf := <-configLoads
-	return readStatusInfo(conf.InternalStatusHost, conf.InternalStatusPort, section)
+	return readStatusInfo(conf, section)
+}
+
+// SupportBundle fetches a support bundle tarball from the running agent
+func SupportBundle(configPath string) (io.ReadCloser, error) {
+	configLoads, err := config.LoadConfig(context.Background(), configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := <-configLoads
+	return readSupportBundle(conf)
 }
 
 // StreamDatapoints reads the text from the diagnostic socket and returns it if available.
@@ -225,7 +286,7 @@ func StreamDatapoints(configPath string, metric string, dims string) (io.ReadClo
 	}
 
 	conf := <-configLoads
-	return streamDatapoints(conf.InternalStatusHost, conf.InternalStatusPort, metric, dims)
+	return streamDatapoints(conf, metric, dims)
 }
 
 func startSyncClusterProperty(dimChan chan *types.Dimension, cluster string, hostDims map[string]string, setOnHost bool) {
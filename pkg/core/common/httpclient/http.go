@@ -34,6 +34,17 @@ type HTTPConfig struct {
 	ClientCertPath string `yaml:"clientCertPath"`
 	// Path to the client TLS key to use for TLS required connections
 	ClientKeyPath string `yaml:"clientKeyPath"`
+
+	// Set by the agent when monitor inputs are being recorded or replayed
+	InputRecorder RoundTripperWrapper `yaml:"-" json:"-"`
+}
+
+// RoundTripperWrapper can intercept the requests of clients built from an
+// HTTPConfig
+type RoundTripperWrapper interface {
+	WrapRoundTripper(http.RoundTripper) http.RoundTripper
+	// Replaying is true if requests should never reach the network
+	Replaying() bool
 }
 
 // Scheme returns https if enabled, otherwise http
@@ -46,6 +57,15 @@ func (h *HTTPConfig) Scheme() string {
 
 // Build returns a configured http.Client
 func (h *HTTPConfig) Build() (*http.Client, error) {
+	if h.InputRecorder != nil && h.InputRecorder.Replaying() {
+		// Don't bother setting up TLS since the cert files might not exist
+		// where the inputs are replayed.
+		return &http.Client{
+			Timeout:   h.HTTPTimeout.AsDuration(),
+			Transport: h.InputRecorder.WrapRoundTripper(nil),
+		}, nil
+	}
+
 	roundTripper, err := func() (http.RoundTripper, error) {
 		transport := http.DefaultTransport.(*http.Transport).Clone()
 
@@ -73,6 +93,10 @@ func (h *HTTPConfig) Build() (*http.Client, error) {
 		}
 	}
 
+	if h.InputRecorder != nil {
+		roundTripper = h.InputRecorder.WrapRoundTripper(roundTripper)
+	}
+
 	return &http.Client{
 		Timeout:   h.HTTPTimeout.AsDuration(),
 		Transport: roundTripper,
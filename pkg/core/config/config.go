@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	set "gopkg.in/fatih/set.v0"
 
@@ -19,6 +20,7 @@ import (
 	"github.com/signalfx/signalfx-agent/pkg/core/config/validation"
 	"github.com/signalfx/signalfx-agent/pkg/utils"
 	"github.com/signalfx/signalfx-agent/pkg/utils/hostfs"
+	"github.com/signalfx/signalfx-agent/pkg/utils/timeutil"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -99,12 +101,25 @@ type Config struct {
 	Observers []ObserverConfig `yaml:"observers" default:"[]"`
 	// A list of monitors to use (see monitor config)
 	Monitors []MonitorConfig `yaml:"monitors" default:"[]"`
+
+	// A dir of YAML files (ending in `.yaml` or `.yml`) that each contain a
+	// list of monitor configs, which are added to `monitors`.  Relative paths
+	// are relative to the dir of the main config file.  The dir is watched
+	// for changes if `configSources.watch` is true.
+	MonitorsDir string `yaml:"monitorsDir"`
+	// A dir of YAML files that each contain a list of observer configs,
+	// which are added to `observers`, like `monitorsDir`
+	ObserversDir string `yaml:"observersDir"`
+
 	// Configuration of the datapoint/event writer
 	Writer WriterConfig `yaml:"writer"`
 	// Log configuration
 	Logging LogConfig `yaml:"logging" default:"{}"`
 	// Configuration of the managed collectd subprocess
 	Collectd CollectdConfig `yaml:"collectd" default:"{}"`
+	// Record the raw inputs that monitors receive to a bundle file, or
+	// replay them from one, to reproduce monitor issues offline
+	InputRecording InputRecordingConfig `yaml:"inputRecording" default:"{}"`
 	// If true, the agent will filter out [custom
 	// metrics](https://docs.signalfx.com/en/latest/admin-guide/usage.html#about-custom-bundled-and-high-resolution-metrics)
 	// without having to rely on the `whitelist.json` filter that was
@@ -138,6 +153,13 @@ type Config struct {
 	// The port on which the internal status server will listen.  See
 	// `internalStatusHost`.
 	InternalStatusPort uint16 `yaml:"internalStatusPort" default:"8095"`
+	// TLS and authentication for the internal status server.  Set these if
+	// `internalStatusHost` is anything other than localhost since the server
+	// exposes config-derived diagnostics and a live tap of datapoints.
+	InternalStatusServer InternalStatusServerConfig `yaml:"internalStatusServer" default:"{}"`
+	// Thresholds for the `/healthz` and `/readyz` paths of the internal
+	// status server
+	HealthChecks HealthCheckConfig `yaml:"healthChecks" default:"{}"`
 
 	// Enables Go pprof endpoint on port 6060 that serves profiling data for
 	// development
@@ -250,6 +272,44 @@ func (c *Config) validate() error {
 		}
 	}
 
+	if err := c.Writer.SpanMetrics.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Writer.TraceSampling.Validate(); err != nil {
+		return err
+	}
+
+	for i := range c.Writer.SpanProcessors {
+		if err := c.Writer.SpanProcessors[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.Writer.DatapointAggregations {
+		if err := c.Writer.DatapointAggregations[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.Writer.CounterConversions {
+		if err := c.Writer.CounterConversions[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Logging.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.InputRecording.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.InternalStatusServer.Validate(); err != nil {
+		return err
+	}
+
 	return c.Collectd.Validate()
 }
 
@@ -257,14 +317,8 @@ func (c *Config) validate() error {
 // need them
 func (c *Config) propagateValuesDown() error {
 	for i := range c.Monitors {
-		if err := c.Monitors[i].Validate(); err != nil {
-			return fmt.Errorf("monitor config for type '%s' is invalid: %v", c.Monitors[i].Type, err)
-		}
-		if c.Monitors[i].ValidateDiscoveryRule == nil {
-			c.Monitors[i].ValidateDiscoveryRule = c.ValidateDiscoveryRules
-		}
-		if c.Monitors[i].ProcPath == "" {
-			c.Monitors[i].ProcPath = c.ProcPath
+		if err := c.PrepareMonitorConfig(&c.Monitors[i]); err != nil {
+			return err
 		}
 	}
 
@@ -279,7 +333,27 @@ func (c *Config) propagateValuesDown() error {
 	c.Writer.TraceEndpointURL = c.TraceEndpointURL
 	c.Writer.SignalFxAccessToken = c.SignalFxAccessToken
 	c.Writer.GlobalDimensions = c.GlobalDimensions
+	c.Writer.Replaying = c.InputRecording.Mode == "replay"
+	c.Writer.ReplayOutputPath = c.InputRecording.OutputPath
+
+	return nil
+}
 
+// PrepareMonitorConfig validates a monitor config and fills in the values
+// that it inherits from the main config
+func (c *Config) PrepareMonitorConfig(mc *MonitorConfig) error {
+	if err := mc.Validate(); err != nil {
+		if mc.ConfigFile != "" {
+			return fmt.Errorf("monitor config for type '%s' in %s is invalid: %v", mc.Type, mc.ConfigFile, err)
+		}
+		return fmt.Errorf("monitor config for type '%s' is invalid: %v", mc.Type, err)
+	}
+	if mc.ValidateDiscoveryRule == nil {
+		mc.ValidateDiscoveryRule = c.ValidateDiscoveryRules
+	}
+	if mc.ProcPath == "" {
+		mc.ProcPath = c.ProcPath
+	}
 	return nil
 }
 
@@ -298,9 +372,88 @@ type LogConfig struct {
 	Level string `yaml:"level" default:"info"`
 	// The log output format to use.  Valid values are: `text`, `json`.
 	Format string `yaml:"format" validate:"oneof=text json" default:"text"`
+	// Log levels that override `level` for particular components of the
+	// agent, keyed by component name (e.g. `writer: debug`).  The component
+	// of a log message is in its `component` field.
+	ComponentLevels map[string]string `yaml:"componentLevels"`
+	// Log levels that override `level` and `componentLevels` for monitors of
+	// a particular type, keyed by monitor type (e.g. `kubernetes-cluster:
+	// debug`).
+	MonitorTypeLevels map[string]string `yaml:"monitorTypeLevels"`
+	// If set, identical warning and error messages from the same source are
+	// only logged once per this interval (e.g. `30s`).  The number of
+	// messages that were suppressed in between is included in the
+	// `suppressedCount` field of the next one that is logged.
+	DedupInterval timeutil.Duration `yaml:"dedupInterval"`
 	// TODO: Support log file output and other log targets
 }
 
+// Validate the level overrides of the logging config
+func (lc *LogConfig) Validate() error {
+	for _, levels := range []map[string]string{lc.ComponentLevels, lc.MonitorTypeLevels} {
+		for k, v := range levels {
+			if _, err := log.ParseLevel(v); err != nil {
+				return fmt.Errorf("logging level for %s is invalid: %v", k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// InputRecordingConfig controls the recording and replay of monitor inputs.
+// The HTTP responses received by monitors that use the common HTTP client
+// options (e.g. `httpTimeout`, `useHTTPS`) and the messages received from
+// subprocess monitors (e.g. the Python based ones) are supported.
+type InputRecordingConfig struct {
+	// Set to `record` to write the inputs of all monitors to the bundle file
+	// at `path` as they are received.  Set to `replay` to feed the monitors
+	// the inputs from that bundle instead of connecting to the actual
+	// services.  Monitor configs must be the same when replaying as they were
+	// when recording for their inputs to be found.  Nothing is sent to
+	// ingest or the API while replaying.
+	Mode string `yaml:"mode" validate:"omitempty,oneof=record replay"`
+	// The path to the bundle file.  It is overwritten when recording.
+	Path string `yaml:"path"`
+	// When replaying, the datapoints, events, trace spans and dimension
+	// updates that the agent would have sent are written to this file as
+	// JSON, one per line.  They can also be watched with the `tap-dps`
+	// command, whether or not this is set.  The file is overwritten.
+	OutputPath string `yaml:"outputPath"`
+}
+
+// Validate the input recording config
+func (irc *InputRecordingConfig) Validate() error {
+	if irc.Mode != "" && irc.Path == "" {
+		return errors.New("inputRecording.path must be set when inputRecording.mode is set")
+	}
+	return nil
+}
+
+// HealthCheckConfig holds the thresholds used by the liveness (`/healthz`)
+// and readiness (`/readyz`) checks.  The liveness check fails if the config
+// isn't loaded or if collectd is needed but hasn't been running for
+// `collectdStartTimeout`.  The readiness check also fails if datapoints have
+// failed to be sent for `writerFailureTimeout`, if any observer could not be
+// configured, or if any monitor hasn't sent anything for
+// `stuckMonitorTimeout`.
+type HealthCheckConfig struct {
+	// How long sending datapoints to ingest can fail, without any
+	// successful sends, before the agent isn't ready.  This should be a
+	// duration string that is accepted by
+	// https://golang.org/pkg/time/#ParseDuration.
+	WriterFailureTimeout timeutil.Duration `yaml:"writerFailureTimeout" default:"5m"`
+	// How long collectd can be not running, while there are monitors that
+	// use it, before the agent isn't healthy.
+	CollectdStartTimeout timeutil.Duration `yaml:"collectdStartTimeout" default:"1m"`
+	// How long a monitor that is failing to collect can go without sending
+	// anything before the agent isn't ready.  Monitors are only considered
+	// once they are stale (see the `/status/monitors` path) and their last
+	// collection failed, so monitors that only receive data aren't stuck
+	// just because nothing was sent to them.  Set to `0s` to not check
+	// monitors.
+	StuckMonitorTimeout timeutil.Duration `yaml:"stuckMonitorTimeout" default:"10m"`
+}
+
 // LogrusLevel returns a logrus log level based on the configured level in
 // LogConfig.
 func (lc *LogConfig) LogrusLevel() *log.Level {
@@ -321,7 +474,9 @@ func (lc *LogConfig) LogrusLevel() *log.Level {
 func (lc *LogConfig) LogrusFormatter() log.Formatter {
 	switch lc.Format {
 	case "json":
-		return &log.JSONFormatter{}
+		return &log.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+		}
 	default:
 		return &log.TextFormatter{}
 	}
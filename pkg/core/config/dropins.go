@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/config/sources/file"
+	"github.com/signalfx/signalfx-agent/pkg/core/config/types"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Reads the monitors and observers from the files in the `monitorsDir` and
+// `observersDir` drop-in dirs, and keeps the versions of the files that were
+// read so that they can be watched for changes.
+type dropInLoader struct {
+	// Relative drop-in dirs are relative to the dir of the main config file
+	configDir string
+	// The versions of the globs of files that were last read
+	versions map[string]uint64
+}
+
+func newDropInLoader(configPath string) *dropInLoader {
+	return &dropInLoader{
+		configDir: filepath.Dir(configPath),
+		versions:  make(map[string]uint64),
+	}
+}
+
+func (d *dropInLoader) globs(dir string) []string {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(d.configDir, dir)
+	}
+	return []string{filepath.Join(dir, "*.yaml"), filepath.Join(dir, "*.yml")}
+}
+
+// Reads all of the files in the dir, keyed by path.  An empty or missing dir
+// isn't an error since files might be added later.
+func (d *dropInLoader) readDir(source types.ConfigSource, dir string) (map[string][]byte, error) {
+	out := make(map[string][]byte)
+	for _, glob := range d.globs(dir) {
+		content, version, err := source.Get(glob)
+		if err != nil {
+			if _, ok := err.(types.ErrNotFound); !ok {
+				return nil, err
+			}
+		}
+		d.versions[glob] = version
+		for path, c := range content {
+			out[path] = c
+		}
+	}
+	return out, nil
+}
+
+func sortedPaths(content map[string][]byte) []string {
+	paths := make([]string, 0, len(content))
+	for path := range content {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Adds the monitors and observers in the drop-in dirs to the config.  Each
+// file must contain a list of monitor or observer configs.  The monitors are
+// marked with the file they came from.  Both dirs are read before anything is
+// parsed so that all of the files can be watched even if one is invalid.
+func (d *dropInLoader) load(conf *Config) error {
+	d.versions = make(map[string]uint64)
+	source := file.New(time.Duration(conf.Sources.File.PollRateSeconds) * time.Second)
+
+	var monitorFiles, observerFiles map[string][]byte
+	var err error
+	if conf.MonitorsDir != "" {
+		if monitorFiles, err = d.readDir(source, conf.MonitorsDir); err != nil {
+			return fmt.Errorf("could not read monitorsDir %s: %v", conf.MonitorsDir, err)
+		}
+	}
+	if conf.ObserversDir != "" {
+		if observerFiles, err = d.readDir(source, conf.ObserversDir); err != nil {
+			return fmt.Errorf("could not read observersDir %s: %v", conf.ObserversDir, err)
+		}
+	}
+
+	for _, path := range sortedPaths(monitorFiles) {
+		var monitors []MonitorConfig
+		if err := unmarshalDropIn(monitorFiles[path], conf.Sources.Templating, &monitors); err != nil {
+			return fmt.Errorf("could not parse monitors in %s: %v", path, err)
+		}
+		for i := range monitors {
+			monitors[i].ConfigFile = path
+		}
+		conf.Monitors = append(conf.Monitors, monitors...)
+	}
+
+	for _, path := range sortedPaths(observerFiles) {
+		var observers []ObserverConfig
+		if err := unmarshalDropIn(observerFiles[path], conf.Sources.Templating, &observers); err != nil {
+			return fmt.Errorf("could not parse observers in %s: %v", path, err)
+		}
+		conf.Observers = append(conf.Observers, observers...)
+	}
+
+	return nil
+}
+
+func unmarshalDropIn(content []byte, templating bool, out interface{}) error {
+	if templating {
+		var err error
+		if content, err = renderConfigTemplates(content); err != nil {
+			return err
+		}
+	}
+
+	content = preprocessConfig(content)
+	if err := yaml.UnmarshalStrict(content, out); err != nil {
+		return utils.YAMLErrorWithContext(content, err)
+	}
+	return nil
+}
+
+// Watches the files that were last read and sends on changes once any of
+// them change, are removed, or if files are added.  Returns once it has
+// notified or stop is closed.
+func (d *dropInLoader) watch(pollInterval time.Duration, changes chan<- struct{}, stop <-chan struct{}) {
+	source := file.New(pollInterval)
+
+	// Only notify once, even if multiple globs change
+	changed := make(chan struct{})
+	for glob, version := range d.versions {
+		go func(glob string, version uint64) {
+			for {
+				err := source.WaitForChange(glob, version, stop)
+				if utils.IsSignalChanClosed(stop) {
+					return
+				}
+				if err != nil {
+					if _, ok := err.(types.ErrNotFound); ok && version == 0 {
+						// There still aren't any files
+						continue
+					} else if !ok {
+						log.WithError(err).WithField("path", glob).Error("Could not watch drop-in config files")
+						time.Sleep(5 * time.Second)
+						continue
+					}
+				}
+
+				select {
+				case changed <- struct{}{}:
+				case <-stop:
+				}
+				return
+			}
+		}(glob, version)
+	}
+
+	go func() {
+		select {
+		case <-changed:
+			log.Info("Drop-in config files changed")
+			select {
+			case changes <- struct{}{}:
+			case <-stop:
+			}
+		case <-stop:
+		}
+	}()
+}
@@ -37,7 +37,9 @@ func LoadConfig(ctx context.Context, configPath string) (<-chan *Config, error)
 		return nil, err
 	}
 
-	config, err := loadYAML(finalYAML)
+	dropIns := newDropInLoader(configPath)
+
+	config, err := loadYAML(finalYAML, dropIns)
 	if err != nil {
 		cancelDynamic()
 		return nil, err
@@ -49,6 +51,31 @@ func LoadConfig(ctx context.Context, configPath string) (<-chan *Config, error)
 	loads <- config
 
 	if configFileChanges != nil {
+		dropInChanges := make(chan struct{})
+		dropInPollInterval := time.Duration(config.Sources.File.PollRateSeconds) * time.Second
+		dropInCtx, cancelDropIns := context.WithCancel(ctx)
+		dropIns.watch(dropInPollInterval, dropInChanges, dropInCtx.Done())
+
+		// Loads the config again from the last rendered YAML and rewatches
+		// the drop-in files that were read, even if the config is invalid so
+		// that it is loaded once the invalid files are fixed.
+		reload := func() {
+			config, err := loadYAML(finalYAML, dropIns)
+
+			cancelDropIns()
+			if config != nil {
+				dropInPollInterval = time.Duration(config.Sources.File.PollRateSeconds) * time.Second
+			}
+			dropInCtx, cancelDropIns = context.WithCancel(ctx)
+			dropIns.watch(dropInPollInterval, dropInChanges, dropInCtx.Done())
+
+			if err != nil {
+				log.WithError(err).Error("Could not parse config after change")
+				return
+			}
+			loads <- config
+		}
+
 		go func() {
 			for {
 				// We can have changes either in the dynamic values or the
@@ -68,22 +95,14 @@ func LoadConfig(ctx context.Context, configPath string) (<-chan *Config, error)
 						continue
 					}
 
-					config, err := loadYAML(finalYAML)
-					if err != nil {
-						log.WithError(err).Error("Could not parse config after change")
-						continue
-					}
-
-					loads <- config
+					reload()
 				case finalYAML = <-dynamicChanges:
-					config, err := loadYAML(finalYAML)
-					if err != nil {
-						log.WithError(err).Error("Could not parse config after change")
-						continue
-					}
-					loads <- config
+					reload()
+				case <-dropInChanges:
+					reload()
 				case <-ctx.Done():
 					cancelDynamic()
+					cancelDropIns()
 					return
 				}
 			}
@@ -94,16 +113,27 @@ func LoadConfig(ctx context.Context, configPath string) (<-chan *Config, error)
 	return loads, nil
 }
 
-func loadYAML(fileContent []byte) (*Config, error) {
+func loadYAML(fileContent []byte, dropIns *dropInLoader) (*Config, error) {
 	config := &Config{}
 
+	fileContent, err := renderConfigFileTemplates(fileContent)
+	if err != nil {
+		return nil, err
+	}
+
 	preprocessedContent := preprocessConfig(fileContent)
 
-	err := yaml.UnmarshalStrict(preprocessedContent, config)
+	err = yaml.UnmarshalStrict(preprocessedContent, config)
 	if err != nil {
 		return nil, utils.YAMLErrorWithContext(preprocessedContent, err)
 	}
 
+	// Drop-in monitors and observers are added before defaults are set so
+	// that they get them too
+	if err := dropIns.load(config); err != nil {
+		return nil, err
+	}
+
 	if err := defaults.Set(config); err != nil {
 		panic(fmt.Sprintf("Config defaults are wrong types: %s", err))
 	}
@@ -129,23 +159,27 @@ var envVarWhitelist = map[string]bool{
 func preprocessConfig(content []byte) []byte {
 	return envVarRE.ReplaceAllFunc(content, func(bs []byte) []byte {
 		parts := envVarRE.FindSubmatch(bs)
-		envvar := string(parts[1])
+		return []byte(getEnvVar(string(parts[1])))
+	})
+}
 
-		val, ok := envVarCache[envvar]
+// Gets the envvar from the cache, or from the environment the first time,
+// after which it is removed from the environment
+func getEnvVar(envvar string) string {
+	val, ok := envVarCache[envvar]
 
-		if !ok {
-			val = os.Getenv(envvar)
-			envVarCache[envvar] = val
+	if !ok {
+		val = os.Getenv(envvar)
+		envVarCache[envvar] = val
 
-			log.WithFields(log.Fields{
-				"envvar": envvar,
-			}).Debug("Sanitizing envvar from agent")
+		log.WithFields(log.Fields{
+			"envvar": envvar,
+		}).Debug("Sanitizing envvar from agent")
 
-			if !envVarWhitelist[envvar] {
-				os.Unsetenv(envvar)
-			}
+		if !envVarWhitelist[envvar] {
+			os.Unsetenv(envvar)
 		}
+	}
 
-		return []byte(val)
-	})
+	return val
 }
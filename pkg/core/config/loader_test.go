@@ -480,6 +480,177 @@ var _ = Describe("Config Loader", func() {
 		Expect(config.Monitors[0].OtherConfig["templates"]).Should(ConsistOf(`LoadPlugin "cpufreq"`))
 	})
 
+	It("Adds monitors and observers from drop-in dirs", func() {
+		monPath := mkFile("agent/monitors.d/cpu.yaml", outdent(`
+			- type: cpu
+			- type: memory
+		`))
+		mkFile("agent/monitors.d/load.yml", outdent(`
+			- type: load
+		`))
+		mkFile("agent/monitors.d/README.md", "not config")
+		mkFile("agent/observers.d/k8s.yaml", outdent(`
+			- type: k8s-api
+		`))
+
+		path := mkFile("agent/agent.yaml", outdent(`
+			signalFxAccessToken: abcd
+			monitorsDir: monitors.d
+			observersDir: observers.d
+			monitors:
+			- type: vmem
+		`))
+
+		loads, err := LoadConfig(ctx, path)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var config *Config
+		Eventually(loads).Should(Receive(&config))
+
+		Expect(config.Monitors).To(HaveLen(4))
+		Expect(config.Monitors[0].Type).To(Equal("vmem"))
+		Expect(config.Monitors[0].ConfigFile).To(Equal(""))
+		Expect(config.Monitors[1].Type).To(Equal("cpu"))
+		Expect(config.Monitors[1].ConfigFile).To(Equal(monPath))
+		Expect(config.Monitors[2].Type).To(Equal("memory"))
+		Expect(config.Monitors[3].Type).To(Equal("load"))
+		Expect(config.Monitors[3].ConfigFile).To(Equal(filepath.Join(dir, "agent/monitors.d/load.yml")))
+		// Defaults are set on drop-in monitors too
+		Expect(config.Monitors[3].ValidateDiscoveryRule).ToNot(BeNil())
+
+		Expect(config.Observers).To(HaveLen(1))
+		Expect(config.Observers[0].Type).To(Equal("k8s-api"))
+	})
+
+	It("Allows an empty or missing drop-in dir", func() {
+		path := mkFile("agent/agent.yaml", outdent(`
+			signalFxAccessToken: abcd
+			monitorsDir: monitors.d
+		`))
+
+		loads, err := LoadConfig(ctx, path)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var config *Config
+		Eventually(loads).Should(Receive(&config))
+
+		Expect(config.Monitors).To(HaveLen(0))
+	})
+
+	It("Names the drop-in file in errors", func() {
+		badPath := mkFile("agent/monitors.d/bad.yaml", outdent(`
+			- type: cpu
+			  intervalSeconds: [1]
+		`))
+		path := mkFile("agent/agent.yaml", outdent(`
+			signalFxAccessToken: abcd
+			monitorsDir: monitors.d
+		`))
+
+		_, err := LoadConfig(ctx, path)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(badPath))
+	})
+
+	It("Renders templates if enabled", func() {
+		tokenPath := mkFile("agent/token", "abcd")
+		path := mkFile("agent/agent.yaml", outdent(fmt.Sprintf(`
+			signalFxAccessToken: {"#from": '%s'}
+			configSources:
+			  templating: true
+			monitors:
+			- type: collectd/redis
+			  host: '{{ "" | default "redis.local" }}'
+			  port: '{{ "" | default 6379 }}'
+			  auth: '{{ readFile "%s" | upper }}'
+		`, tokenPath, tokenPath)))
+
+		loads, err := LoadConfig(ctx, path)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var config *Config
+		Eventually(loads).Should(Receive(&config))
+
+		Expect(config.SignalFxAccessToken).To(Equal("abcd"))
+		Expect(config.Monitors[0].OtherConfig["host"]).To(Equal("redis.local"))
+		Expect(config.Monitors[0].OtherConfig["port"]).To(Equal(6379))
+		Expect(config.Monitors[0].OtherConfig["auth"]).To(Equal("ABCD"))
+	})
+
+	It("Only renders templates in config source values if asked to", func() {
+		secretPath := mkFile("agent/secret", `{{ readFile "/etc/passwd" }}`)
+		hostPath := mkFile("agent/host", `'{{ "REDIS.LOCAL" | lower }}'`)
+		path := mkFile("agent/agent.yaml", outdent(fmt.Sprintf(`
+			signalFxAccessToken: abcd
+			configSources:
+			  templating: true
+			monitors:
+			- type: collectd/redis
+			  host: {"#from": '%s', template: true}
+			  auth: {"#from": '%s', raw: true}
+			  database: '{{ "0123" }}'
+		`, hostPath, secretPath)))
+
+		loads, err := LoadConfig(ctx, path)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var config *Config
+		Eventually(loads).Should(Receive(&config))
+
+		Expect(config.Monitors[0].OtherConfig["host"]).To(Equal("redis.local"))
+		Expect(config.Monitors[0].OtherConfig["auth"]).To(Equal(`{{ readFile "/etc/passwd" }}`))
+		Expect(config.Monitors[0].OtherConfig["database"]).To(Equal("0123"))
+	})
+
+	It("Does not render templates unless enabled", func() {
+		path := mkFile("agent/agent.yaml", outdent(`
+			signalFxAccessToken: abcd
+			monitors:
+			- type: collectd/custom
+			  template: '{{ "not rendered" }}'
+		`))
+
+		loads, err := LoadConfig(ctx, path)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var config *Config
+		Eventually(loads).Should(Receive(&config))
+
+		Expect(config.Monitors[0].OtherConfig["template"]).To(Equal(`{{ "not rendered" }}`))
+	})
+
+	It("Watches drop-in dirs for changes", func() {
+		mkFile("agent/monitors.d/cpu.yaml", outdent(`
+			- type: cpu
+		`))
+		path := mkFile("agent/agent.yaml", outdent(`
+			signalFxAccessToken: abcd
+			monitorsDir: monitors.d
+			configSources:
+			  file:
+			    pollRateSeconds: 1
+		`))
+
+		loads, err := LoadConfig(ctx, path)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		var config *Config
+		Eventually(loads).Should(Receive(&config))
+		Expect(config.Monitors).To(HaveLen(1))
+
+		mkFile("agent/monitors.d/memory.yaml", outdent(`
+			- type: memory
+		`))
+		Eventually(loads, 3).Should(Receive(&config))
+		Expect(config.Monitors).To(HaveLen(2))
+		Expect(config.Monitors[1].Type).To(Equal("memory"))
+
+		os.Remove(filepath.Join(dir, "agent/monitors.d/cpu.yaml"))
+		Eventually(loads, 3).Should(Receive(&config))
+		Expect(config.Monitors).To(HaveLen(1))
+		Expect(config.Monitors[0].Type).To(Equal("memory"))
+	})
+
 })
 
 func TestLoader(t *testing.T) {
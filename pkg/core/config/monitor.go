@@ -99,6 +99,8 @@ type MonitorConfig struct {
 	// so that diagnostics can output it.
 	ValidationError string          `yaml:"-" json:"-" hash:"ignore"`
 	MonitorID       types.MonitorID `yaml:"-" hash:"ignore"`
+	// The drop-in file in `monitorsDir` that this config came from, if any
+	ConfigFile string `yaml:"-" json:"-"`
 }
 
 var _ CustomConfigurable = &MonitorConfig{}
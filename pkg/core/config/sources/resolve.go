@@ -2,6 +2,7 @@ package sources
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -15,11 +16,16 @@ type resolveFunc func(v RawDynamicValueSpec) ([]interface{}, string, *dynamicVal
 // value.
 type resolver struct {
 	sources map[string]*configSourceCacher
+	// Whether the config is rendered as templates once the values are filled
+	// in, in which case values are escaped unless their spec has `template:
+	// true`
+	templating bool
 }
 
-func newResolver(sources map[string]*configSourceCacher) *resolver {
+func newResolver(sources map[string]*configSourceCacher, templating bool) *resolver {
 	return &resolver{
-		sources: sources,
+		sources:    sources,
+		templating: templating,
 	}
 }
 
@@ -48,11 +54,38 @@ func (r *resolver) Resolve(raw RawDynamicValueSpec) ([]interface{}, string, *dyn
 		}
 	} else {
 		value, err = convertFileBytesToValues(contentMap, spec.Raw)
+		if err == nil && r.templating && !spec.Template {
+			for i := range value {
+				value[i] = escapeTemplates(value[i])
+			}
+		}
 	}
 
 	return value, spec.From.Path(), spec, err
 }
 
+// Escapes the template actions in all of the strings in the value, so that
+// e.g. secrets that contain `{{` render to themselves.  Nested dynamic value
+// specs are left alone since they are resolved, and escaped, separately.
+func escapeTemplates(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		if val["#from"] != nil {
+			return val
+		}
+		for k := range val {
+			val[k] = escapeTemplates(val[k])
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = escapeTemplates(val[i])
+		}
+	case string:
+		return strings.Replace(val, "{{", `{{"{{"}}`, -1)
+	}
+	return v
+}
+
 func convertFileBytesToValues(content map[string][]byte, raw bool) ([]interface{}, error) {
 	var out []interface{}
 	for path := range content {
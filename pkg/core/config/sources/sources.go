@@ -38,6 +38,14 @@ type SourceConfig struct {
 	// resource usage. This option is not itself watched for changes. If you
 	// change the value of this option, you must restart the agent.
 	Watch bool `yaml:"watch" default:"true"`
+	// If true, string values in the config that contain `{{` are rendered as
+	// [Go templates](https://golang.org/pkg/text/template/) after the config
+	// source values (`#from`) are filled in, and before envvars (`${VAR}`)
+	// are replaced.  Values from config sources are only rendered if their
+	// `#from` has `template: true`.  Only a limited set of functions that
+	// read values is available, see the remote config docs for the list.
+	// Drop-in files in `monitorsDir` and `observersDir` are rendered too.
+	Templating bool `yaml:"templating"`
 	// Configuration for other file sources
 	File file.Config `yaml:"file" default:"{}"`
 	// Configuration for a Zookeeper remote config source
@@ -225,7 +233,7 @@ func (dvp *DynamicValueProvider) ReadDynamicValues(configContent []byte, stop <-
 		cachers[name] = cacher
 	}
 
-	resolver := newResolver(cachers)
+	resolver := newResolver(cachers, sourceConfig.Templating)
 
 	renderedContent, err := renderDynamicValues(configContent, resolver.Resolve)
 	if err != nil {
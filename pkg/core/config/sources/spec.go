@@ -14,6 +14,7 @@ type dynamicValueSpec struct {
 	Flatten  bool        `yaml:"flatten"`
 	Optional bool        `yaml:"optional"`
 	Raw      bool        `yaml:"raw"`
+	Template bool        `yaml:"template"`
 	Default  interface{} `yaml:"default"`
 }
 
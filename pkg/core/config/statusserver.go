@@ -0,0 +1,128 @@
+package config
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/signalfx/signalfx-agent/pkg/core/common/auth"
+)
+
+// InternalStatusServerConfig holds the TLS and authentication options of
+// the internal status server, along with the options that the agent's own
+// clients of that server (the `status`, `tap-dps` and `support-bundle`
+// commands and the `internal-metrics` monitor) use to connect to it.  The
+// `/healthz` and `/readyz` paths never require authentication or a client
+// cert so that they can be used as probes.
+type InternalStatusServerConfig struct {
+	// Path to the TLS cert that the status server will use.  If this and
+	// `tlsKeyPath` are set, the server will only serve HTTPS.
+	TLSCertPath string `yaml:"tlsCertPath"`
+	// Path to the key of the TLS cert in `tlsCertPath`
+	TLSKeyPath string `yaml:"tlsKeyPath"`
+	// Path to the CA cert that client certs must be signed by.  If set,
+	// requests must present a valid client cert (mTLS).  Requires TLS to be
+	// configured.
+	ClientCACertPath string `yaml:"clientCACertPath"`
+
+	// If set, requests must have an `Authorization: Bearer <token>` header
+	// with this token.
+	BearerToken string `yaml:"bearerToken" neverLog:"true"`
+	// If set, requests must use basic auth with this username and
+	// `password`.  Requests that have the bearer token are also accepted if
+	// both are configured.
+	Username string `yaml:"username"`
+	// The basic auth password, see `username`
+	Password string `yaml:"password" neverLog:"true"`
+
+	// If true, the `/control/monitors` API is served, which can disable,
+	// enable and restart monitors, and add monitors that aren't in the
+	// config until the agent restarts.  Requires `bearerToken`, `username`
+	// or `clientCACertPath` to be set.
+	EnableControlAPI bool `yaml:"enableControlAPI"`
+
+	// Path to the CA cert that signed the server's TLS cert, for the agent's
+	// own clients of the server.  The system CA certs are used if not set.
+	CACertPath string `yaml:"caCertPath"`
+	// If true, the agent's own clients will not verify the server's TLS cert
+	SkipVerify bool `yaml:"skipVerify"`
+	// The name to verify the server's TLS cert against, if it doesn't match
+	// `internalStatusHost` (e.g. if that is `0.0.0.0`)
+	TLSServerName string `yaml:"tlsServerName"`
+	// Path to the client cert that the agent's own clients will present if
+	// `clientCACertPath` is set
+	ClientCertPath string `yaml:"clientCertPath"`
+	// Path to the key of the client cert in `clientCertPath`
+	ClientKeyPath string `yaml:"clientKeyPath"`
+}
+
+// Validate the status server config
+func (c *InternalStatusServerConfig) Validate() error {
+	if (c.TLSCertPath == "") != (c.TLSKeyPath == "") {
+		return errors.New("internalStatusServer.tlsCertPath and tlsKeyPath must be set together")
+	}
+	if c.ClientCACertPath != "" && !c.TLSEnabled() {
+		return errors.New("internalStatusServer.clientCACertPath requires tlsCertPath and tlsKeyPath")
+	}
+	if (c.ClientCertPath == "") != (c.ClientKeyPath == "") {
+		return errors.New("internalStatusServer.clientCertPath and clientKeyPath must be set together")
+	}
+	if c.Password != "" && c.Username == "" {
+		return errors.New("internalStatusServer.password requires username")
+	}
+	if c.EnableControlAPI && c.BearerToken == "" && c.Username == "" && c.ClientCACertPath == "" {
+		return errors.New("internalStatusServer.enableControlAPI requires bearerToken, username or clientCACertPath to be set")
+	}
+	return nil
+}
+
+// TLSEnabled is true if the server should serve HTTPS
+func (c *InternalStatusServerConfig) TLSEnabled() bool {
+	return c.TLSCertPath != "" && c.TLSKeyPath != ""
+}
+
+// Scheme returns https if TLS is enabled, otherwise http
+func (c *InternalStatusServerConfig) Scheme() string {
+	if c.TLSEnabled() {
+		return "https"
+	}
+	return "http"
+}
+
+// NewClient returns an http.Client that connects to the status server with
+// the configured TLS options and credentials
+func (c *InternalStatusServerConfig) NewClient(timeout time.Duration) (*http.Client, error) {
+	var roundTripper http.RoundTripper
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	roundTripper = transport
+
+	if c.TLSEnabled() {
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: c.SkipVerify,
+			ServerName:         c.TLSServerName,
+		}
+		if _, err := auth.TLSConfig(transport.TLSClientConfig, c.CACertPath, c.ClientCertPath, c.ClientKeyPath); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case c.BearerToken != "":
+		roundTripper = &auth.TransportWithToken{
+			RoundTripper: roundTripper,
+			Token:        c.BearerToken,
+		}
+	case c.Username != "":
+		roundTripper = &auth.TransportWithBasicAuth{
+			RoundTripper: roundTripper,
+			Username:     c.Username,
+			Password:     c.Password,
+		}
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: roundTripper,
+	}, nil
+}
@@ -0,0 +1,265 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The functions available to config templates.  They are limited to ones
+// that can only read values, so that templates can't change anything on the
+// host.
+//nolint: gochecknoglobals
+var templateFuncs = template.FuncMap{
+	"env":       templateEnv,
+	"default":   templateDefault,
+	"required":  templateRequired,
+	"b64dec":    templateB64Dec,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"trim":      strings.TrimSpace,
+	"split":     templateSplit,
+	"join":      templateJoin,
+	"hostname":  os.Hostname,
+	"hostIP":    templateHostIP,
+	"lookupIP":  templateLookupIP,
+	"lookupIPs": templateLookupIPs,
+	"readFile":  templateReadFile,
+}
+
+// Partial config that is parsed to see if templating is enabled before the
+// rest of the config is rendered
+type templatingConfig struct {
+	Sources struct {
+		Templating bool `yaml:"templating"`
+	} `yaml:"configSources"`
+}
+
+// Renders the templates in the main config, after the config source values
+// are filled in, if templating is enabled in it.  The config source values
+// are already escaped unless their `#from` has `template: true`.
+func renderConfigFileTemplates(content []byte) ([]byte, error) {
+	var templating templatingConfig
+	if err := yaml.Unmarshal(content, &templating); err != nil {
+		return nil, utils.YAMLErrorWithContext(content, err)
+	}
+	if !templating.Sources.Templating {
+		return content, nil
+	}
+	return renderConfigTemplates(content)
+}
+
+// Renders every string value in the YAML content that contains a template
+// action as a Go template with templateFuncs.  Rendered values stay strings
+// unless they are exactly how an int, float or bool is written in YAML, see
+// scalarFromString.
+func renderConfigTemplates(content []byte) ([]byte, error) {
+	var tree interface{}
+	if err := yaml.Unmarshal(content, &tree); err != nil {
+		return nil, utils.YAMLErrorWithContext(content, err)
+	}
+
+	rendered, err := renderTemplateValues(tree, "")
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(rendered)
+}
+
+func renderTemplateValues(v interface{}, path string) (interface{}, error) {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		for k := range val {
+			out, err := renderTemplateValues(val[k], strings.TrimPrefix(fmt.Sprintf("%s.%v", path, k), "."))
+			if err != nil {
+				return nil, err
+			}
+			val[k] = out
+		}
+	case []interface{}:
+		for i := range val {
+			out, err := renderTemplateValues(val[i], fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			val[i] = out
+		}
+	case string:
+		if !strings.Contains(val, "{{") {
+			return val, nil
+		}
+		out, err := renderTemplate(path, val)
+		if err != nil {
+			return nil, err
+		}
+		return scalarFromString(out), nil
+	}
+	return v, nil
+}
+
+func renderTemplate(path string, tmplText string) (string, error) {
+	tmpl, err := template.New(path).Option("missingkey=error").Funcs(templateFuncs).Parse(tmplText)
+	if err != nil {
+		return "", fmt.Errorf("could not parse config template: %v", err)
+	}
+
+	out := bytes.Buffer{}
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return "", fmt.Errorf("could not render config template: %v", err)
+	}
+	return out.String(), nil
+}
+
+// Converts the rendered value to an int, float or bool only if that is
+// written exactly the same in YAML, e.g. `8080` or `true`, so that it
+// decodes the same whether the field it ends up in is a string or that type.
+// Everything else, e.g. `0123`, `0x10`, `yes` or `1e3`, is left as a string
+// so that it isn't changed, and so that rendered values can't add any
+// structure to the config.
+func scalarFromString(s string) interface{} {
+	var out interface{}
+	if err := yaml.Unmarshal([]byte(s), &out); err != nil {
+		return s
+	}
+	switch out.(type) {
+	case int, int64, uint64, float64, bool:
+		canonical, err := yaml.Marshal(out)
+		if err == nil && strings.TrimSuffix(string(canonical), "\n") == s {
+			return out
+		}
+	}
+	return s
+}
+
+// Gets an envvar the same way as `${VAR}` references so that it still works
+// after it has been removed from the agent's environment
+func templateEnv(name string) string {
+	return getEnvVar(name)
+}
+
+func isEmptyValue(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// Returns val if it isn't empty, otherwise def, e.g. `{{ env "PORT" | default 8080 }}`
+func templateDefault(def interface{}, val interface{}) interface{} {
+	if isEmptyValue(val) {
+		return def
+	}
+	return val
+}
+
+// Fails rendering with the message if val is empty, e.g.
+// `{{ env "HOST" | required "HOST must be set" }}`
+func templateRequired(msg string, val interface{}) (interface{}, error) {
+	if isEmptyValue(val) {
+		return nil, errors.New(msg)
+	}
+	return val, nil
+}
+
+func templateB64Dec(s string) (string, error) {
+	out, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// The separator comes first so that it works in pipelines, e.g.
+// `{{ env "HOSTS" | split "," }}`
+func templateSplit(sep string, s string) []string {
+	return strings.Split(s, sep)
+}
+
+func templateJoin(sep string, parts []string) string {
+	return strings.Join(parts, sep)
+}
+
+// Returns the first non-loopback IPv4 address of the host, or the first
+// IPv6 one if it has none
+func templateHostIP() (string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+
+	var ipv6 string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+		if ipv6 == "" {
+			ipv6 = ipNet.IP.String()
+		}
+	}
+
+	if ipv6 == "" {
+		return "", fmt.Errorf("host has no non-loopback IP address")
+	}
+	return ipv6, nil
+}
+
+// Returns the first IP address of the host, preferring IPv4
+func templateLookupIP(host string) (string, error) {
+	ips, err := templateLookupIPs(host)
+	if err != nil {
+		return "", err
+	}
+	for _, ip := range ips {
+		if net.ParseIP(ip).To4() != nil {
+			return ip, nil
+		}
+	}
+	return ips[0], nil
+}
+
+func templateLookupIPs(host string) ([]string, error) {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no IP addresses found for %s", host)
+	}
+
+	out := make([]string, len(ips))
+	for i := range ips {
+		out[i] = ips[i].String()
+	}
+	return out, nil
+}
+
+// Returns the content of the file without trailing whitespace, so that e.g.
+// tokens in files ending in a newline can be used directly
+func templateReadFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), " \t\r\n"), nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestRenderConfigTemplates(t *testing.T) {
+	render := func(t *testing.T, content string) map[interface{}]interface{} {
+		out, err := renderConfigTemplates([]byte(content))
+		require.Nil(t, err)
+
+		var tree map[interface{}]interface{}
+		require.Nil(t, yaml.Unmarshal(out, &tree))
+		return tree
+	}
+
+	t.Run("Leaves values without templates alone", func(t *testing.T) {
+		tree := render(t, `{a: "b", c: 1, d: ["${E}"]}`)
+		require.Equal(t, "b", tree["a"])
+		require.Equal(t, 1, tree["c"])
+		require.Equal(t, []interface{}{"${E}"}, tree["d"])
+	})
+
+	t.Run("Renders nested values", func(t *testing.T) {
+		tree := render(t, `{monitors: [{type: cpu, host: '{{ "MyHost" | lower }}'}]}`)
+		require.Equal(t, "myhost", tree["monitors"].([]interface{})[0].(map[interface{}]interface{})["host"])
+	})
+
+	t.Run("Converts rendered scalars that are written the same in YAML", func(t *testing.T) {
+		tree := render(t, `{port: '{{ "" | default 8080 }}', enabled: '{{ "TRUE" | lower }}', ratio: '{{ "0.5" }}', name: '{{ "a: b" }}'}`)
+		require.Equal(t, 8080, tree["port"])
+		require.Equal(t, true, tree["enabled"])
+		require.Equal(t, 0.5, tree["ratio"])
+		require.Equal(t, "a: b", tree["name"])
+	})
+
+	t.Run("Leaves other rendered scalars as strings", func(t *testing.T) {
+		for _, val := range []string{"0123", "0x10", "yes", "off", "1e3", "+5", "1.50", "~", "null"} {
+			tree := render(t, `{password: '{{ "`+val+`" }}'}`)
+			require.Equal(t, val, tree["password"])
+		}
+	})
+
+	t.Run("Uses cached envvars", func(t *testing.T) {
+		os.Setenv("TEMPLATING_TEST_HOSTS", "a,b")
+		tree := render(t, `{first: '{{ index (env "TEMPLATING_TEST_HOSTS" | split ",") 0 }}', all: '{{ env "TEMPLATING_TEST_HOSTS" | upper }}'}`)
+		require.Equal(t, "a", tree["first"])
+		require.Equal(t, "A,B", tree["all"])
+	})
+
+	t.Run("Decodes base64", func(t *testing.T) {
+		tree := render(t, `{password: '{{ b64dec "czNjcjN0" }}'}`)
+		require.Equal(t, "s3cr3t", tree["password"])
+	})
+
+	t.Run("Reads files", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "templating-test")
+		require.Nil(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "token")
+		require.Nil(t, ioutil.WriteFile(path, []byte("abcd\n"), 0600))
+
+		tree := render(t, `{token: '{{ readFile "`+path+`" }}'}`)
+		require.Equal(t, "abcd", tree["token"])
+	})
+
+	t.Run("Looks up IPs", func(t *testing.T) {
+		tree := render(t, `{host: '{{ lookupIP "localhost" }}'}`)
+		require.Contains(t, []string{"127.0.0.1", "::1"}, tree["host"])
+	})
+
+	t.Run("Fails on missing required values with the path", func(t *testing.T) {
+		_, err := renderConfigTemplates([]byte(`{monitors: [{host: '{{ env "TEMPLATING_TEST_MISSING" | required "host is required" }}'}]}`))
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "monitors[0].host")
+		require.Contains(t, err.Error(), "host is required")
+	})
+
+	t.Run("Fails on unknown functions", func(t *testing.T) {
+		_, err := renderConfigTemplates([]byte(`{a: '{{ exec "ls" }}'}`))
+		require.NotNil(t, err)
+	})
+}
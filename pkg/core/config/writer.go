@@ -1,7 +1,11 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/signalfx/signalfx-agent/pkg/utils/timeutil"
@@ -101,6 +105,32 @@ type WriterConfig struct {
 	// handle the volume of trace spans and should be upgraded to more powerful
 	// hardware/networking.
 	MaxTraceSpansInFlight uint `yaml:"maxTraceSpansInFlight" default:"100000"`
+	// Configuration for generating request rate, error and duration metrics
+	// from the trace spans that are sent through the agent.
+	SpanMetrics SpanMetricsConfig `yaml:"spanMetrics" default:"{}"`
+	// Configuration for tail-based sampling of trace spans before they are
+	// sent.
+	TraceSampling TraceSamplingConfig `yaml:"traceSampling" default:"{}"`
+	// A list of processors that transform or drop trace spans.  They are
+	// applied in order to every span sent by the agent, before span metrics
+	// are generated and before sampling.
+	SpanProcessors []SpanProcessorConfig `yaml:"spanProcessors" default:"[]"`
+	// Rules that roll up datapoints into aggregates grouped by a subset of
+	// their dimensions, e.g. to send per-deployment instead of per-pod
+	// series.  They apply to datapoints from all monitors, after the
+	// `metricsToExclude` filtering.
+	DatapointAggregations []DatapointAggregationConfig `yaml:"datapointAggregations" default:"[]"`
+	// Cumulative counters to convert to per-second rates or deltas before
+	// they are sent.  Conversions happen after the `metricsToExclude`
+	// filtering and before `datapointAggregations`.
+	CounterConversions []CounterConversionConfig `yaml:"counterConversions" default:"[]"`
+	// How long the writer keeps trying to send the datapoints, events and
+	// trace spans that it has buffered when it is shut down, or replaced
+	// because its config changed.  When replaced, what is buffered is sent
+	// with the new token and URLs, and the new writer starts accepting data
+	// once that is done or this times out.  This should be a duration string
+	// that is accepted by https://golang.org/pkg/time/#ParseDuration.
+	ShutdownFlushTimeout timeutil.Duration `yaml:"shutdownFlushTimeout" default:"5s"`
 	// The following are propagated from elsewhere
 	HostIDDims          map[string]string      `yaml:"-"`
 	IngestURL           string                 `yaml:"-"`
@@ -112,6 +142,340 @@ type WriterConfig struct {
 	MetricsToInclude    []MetricFilter         `yaml:"-"`
 	MetricsToExclude    []MetricFilter         `yaml:"-"`
 	PropertiesToExclude []PropertyFilterConfig `yaml:"-"`
+	// Set when monitor inputs are being replayed, in which case nothing is
+	// sent and what would have been is written to ReplayOutputPath, if set
+	Replaying        bool   `yaml:"-"`
+	ReplayOutputPath string `yaml:"-"`
+}
+
+// SpanMetricsConfig holds configuration for generating metrics from trace
+// spans.  The metrics are generated from every span that is sent by the
+// writer, so they are accurate even if the spans are sampled downstream.
+type SpanMetricsConfig struct {
+	// If true, the `spans.count`, `spans.errors`, `spans.duration_ms.sum`
+	// and `spans.duration_ms.bucket` cumulative counters will be generated
+	// for each service and operation (span name) seen in trace spans.  A
+	// span is considered an error if it has the tag `error` set to `true` or
+	// `otel.status_code` set to `ERROR`.
+	Enabled bool `yaml:"enabled"`
+	// How frequently to send the span metrics.  This should be a duration
+	// string that is accepted by https://golang.org/pkg/time/#ParseDuration.
+	Interval timeutil.Duration `yaml:"interval" default:"10s"`
+	// Span tags whose values will be added as dimensions to the span
+	// metrics (e.g. `http.status_code`).  Any `.` in the tag name is replaced
+	// with `_` to get the dimension name.  Spans without the tag will not
+	// have the dimension.
+	DimensionTags []string `yaml:"dimensionTags"`
+	// The upper bounds, in milliseconds, of the span duration histogram
+	// buckets.  Each bucket is sent as the `spans.duration_ms.bucket`
+	// counter with an `upper_bound` dimension and counts the spans with a
+	// duration less than or equal to that bound.
+	HistogramBuckets []float64 `yaml:"histogramBuckets" default:"[5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000]"`
+	// The maximum number of distinct sets of dimensions to track.  Once
+	// this is reached, spans that would create a new set of dimensions are
+	// counted against the `operation` value `_overflow` for their service,
+	// without any of the `dimensionTags` dimensions.
+	MaxTimeSeries int `yaml:"maxTimeSeries" default:"5000"`
+	// How long to continue sending span metrics for a set of dimensions
+	// after a matching span was last seen.  This should be a duration string
+	// that is accepted by https://golang.org/pkg/time/#ParseDuration.
+	StaleTimeout timeutil.Duration `yaml:"staleTimeout" default:"5m"`
+}
+
+// TraceSamplingConfig holds configuration for tail-based trace sampling.
+// Spans are buffered by trace ID until the decision wait has passed since the
+// first span of the trace was received, and then the whole trace is either
+// kept or dropped.  A trace is kept if any of the following is true, checked
+// in order:
+//
+//  1. `keepErrors` is true and any span has the tag `error` set to `true` or
+//     `otel.status_code` set to `ERROR`
+//  2. `latencyThreshold` is set and any span is at least that long
+//  3. Any span matches one of the `alwaysKeep` items
+//  4. The trace ID falls within `samplingPercentage`, and the root service
+//     of the trace has not had more than `maxTracesPerSecondPerService`
+//     traces kept by this rule in the current second
+type TraceSamplingConfig struct {
+	// If true, trace spans will be sampled before being sent
+	Enabled bool `yaml:"enabled"`
+	// How long to wait after the first span of a trace is received before
+	// deciding whether to keep the trace.  Spans that arrive after the
+	// decision is made follow the same decision.  This should be a duration
+	// string that is accepted by https://golang.org/pkg/time/#ParseDuration.
+	DecisionWait timeutil.Duration `yaml:"decisionWait" default:"10s"`
+	// The maximum number of spans to buffer while waiting for decisions.
+	// If exceeded, decisions are made early for the oldest traces.
+	MaxSpansBuffered int `yaml:"maxSpansBuffered" default:"100000"`
+	// How many trace decisions to remember so that late spans can follow
+	// the decision for their trace.
+	DecisionCacheSize int `yaml:"decisionCacheSize" default:"50000"`
+	// Whether to keep all traces with an error span
+	KeepErrors *bool `yaml:"keepErrors" default:"true"`
+	// Traces with any span at least this long are kept.  This should be a
+	// duration string that is accepted by
+	// https://golang.org/pkg/time/#ParseDuration.  If not set, traces are
+	// not kept based on latency.
+	LatencyThreshold timeutil.Duration `yaml:"latencyThreshold"`
+	// Traces with a span that matches any of these items are always kept
+	AlwaysKeep []TraceSamplingMatch `yaml:"alwaysKeep"`
+	// The percentage (0-100) of the remaining traces to keep
+	SamplingPercentage float64 `yaml:"samplingPercentage" default:"10"`
+	// If greater than 0, the maximum number of traces per second to keep for
+	// each root service because of `samplingPercentage`
+	MaxTracesPerSecondPerService int `yaml:"maxTracesPerSecondPerService"`
+}
+
+// TraceSamplingMatch matches spans by service and operation.  Both fields can
+// be globs or regexes surrounded by `/`, and an empty field matches anything.
+type TraceSamplingMatch struct {
+	// The service name of the span
+	Service string `yaml:"service"`
+	// The operation name of the span
+	Operation string `yaml:"operation"`
+}
+
+// The aggregations that can be done on datapoints, besides percentiles
+const (
+	AggregationSum   = "sum"
+	AggregationAvg   = "avg"
+	AggregationMin   = "min"
+	AggregationMax   = "max"
+	AggregationCount = "count"
+)
+
+// DatapointAggregationConfig describes how to roll up a set of datapoints.
+// Over each interval, the last value of every series (or the total of the
+// values for delta counts) that matches is taken, and those values are
+// aggregated across the series that have the same values for the `groupBy`
+// dimensions.
+type DatapointAggregationConfig struct {
+	// Only aggregate datapoints from monitors of this type.  If not set,
+	// datapoints from all monitors are aggregated.
+	MonitorType string `yaml:"monitorType"`
+	// The metrics to aggregate.  These can be globs or regexes surrounded
+	// by `/`, and can be negated with `!`.
+	MetricNames []string `yaml:"metricNames" validate:"required"`
+	// The dimensions that the aggregated datapoints keep.  Series that have
+	// the same values for all of them are aggregated together.  If empty,
+	// all of the series of a metric are aggregated together.
+	GroupBy []string `yaml:"groupBy"`
+	// The aggregations to send for each group, as the metric
+	// `<metric>.<aggregation>`.  Can be `sum`, `avg`, `min`, `max`, `count`
+	// (the number of series) and percentiles such as `p90` or `p99.9`.
+	// Percentiles are only sent for gauges.  The `sum` has the same metric
+	// type as the source metric and all others are gauges.  The `sum` of
+	// cumulative counters adds up the increase of each series since it was
+	// first seen, so it doesn't go down when a series misses an interval or
+	// goes away.  Defaults to `sum`.
+	Aggregations []string `yaml:"aggregations"`
+	// How frequently to send the aggregated datapoints.  This should be a
+	// duration string that is accepted by
+	// https://golang.org/pkg/time/#ParseDuration.  Defaults to `10s`.
+	Interval timeutil.Duration `yaml:"interval"`
+	// If true, the datapoints that are aggregated are not sent on their own.
+	DropSourceSeries bool `yaml:"dropSourceSeries"`
+	// How long to remember the last value of a cumulative counter series in
+	// the `sum` after it was last seen.  The `sum` is sent until all of its
+	// series are stale.  This should be a duration string that is accepted
+	// by https://golang.org/pkg/time/#ParseDuration.  Defaults to `5m`.
+	StaleTimeout timeutil.Duration `yaml:"staleTimeout"`
+}
+
+// Validate the datapoint aggregation config
+func (dac *DatapointAggregationConfig) Validate() error {
+	if len(dac.MetricNames) == 0 {
+		return errors.New("writer.datapointAggregations requires metricNames")
+	}
+	if dac.Interval.AsDuration() < 0 {
+		return errors.New("writer.datapointAggregations interval must not be negative")
+	}
+	if dac.StaleTimeout.AsDuration() < 0 {
+		return errors.New("writer.datapointAggregations staleTimeout must not be negative")
+	}
+	for _, agg := range dac.Aggregations {
+		switch agg {
+		case AggregationSum, AggregationAvg, AggregationMin, AggregationMax, AggregationCount:
+		default:
+			if _, err := ParsePercentile(agg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ParsePercentile parses a percentile aggregation such as `p95` into the
+// percentile (95)
+func ParsePercentile(agg string) (float64, error) {
+	if !strings.HasPrefix(agg, "p") {
+		return 0, fmt.Errorf("datapoint aggregation %q is not supported", agg)
+	}
+	p, err := strconv.ParseFloat(agg[1:], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("datapoint aggregation percentile %q must be between p0 and p100", agg)
+	}
+	return p, nil
+}
+
+// The conversions that can be done on cumulative counters
+const (
+	CounterConversionRate  = "rate"
+	CounterConversionDelta = "delta"
+)
+
+// CounterConversionConfig describes a set of cumulative counters to convert.
+// Each series is tracked separately by its metric name and dimensions.  The
+// first datapoint of a series is not sent since there is nothing to compare
+// it to.  If the counter goes down, it is assumed to have been reset to zero
+// (e.g. by a restart of the process) and its new value is taken as the
+// increase.  Only datapoints with the cumulative counter metric type are
+// converted.
+type CounterConversionConfig struct {
+	// The counters to convert, matched by `monitorType`, `metricNames` (or
+	// `metricName`) and `dimensions` like the `metricsToExclude` filters.
+	MetricFilter `yaml:",inline"`
+	// What to convert the counters to, either `rate` to send the per-second
+	// increase as a gauge, or `delta` to send the increase since the last
+	// datapoint as a delta counter.  Defaults to `rate`.
+	Convert string `yaml:"convert"`
+	// A suffix to add to the metric name of the converted datapoints, e.g.
+	// `.rate`.  If not set, the metric name is not changed.
+	MetricNameSuffix string `yaml:"metricNameSuffix"`
+	// How long to remember the last value of a series after it was last
+	// seen.  This should be a duration string that is accepted by
+	// https://golang.org/pkg/time/#ParseDuration.  Defaults to `5m`.
+	StaleTimeout timeutil.Duration `yaml:"staleTimeout"`
+}
+
+// Validate the counter conversion config
+func (ccc *CounterConversionConfig) Validate() error {
+	switch ccc.Convert {
+	case "", CounterConversionRate, CounterConversionDelta:
+	default:
+		return fmt.Errorf("writer.counterConversions convert value %q is not supported", ccc.Convert)
+	}
+	if ccc.MetricName == "" && len(ccc.MetricNames) == 0 {
+		return errors.New("writer.counterConversions requires metricName or metricNames")
+	}
+	if ccc.StaleTimeout.AsDuration() < 0 {
+		return errors.New("writer.counterConversions staleTimeout must not be negative")
+	}
+	return nil
+}
+
+// The actions that a span processor can take
+const (
+	SpanActionDelete       = "delete"
+	SpanActionHash         = "hash"
+	SpanActionMask         = "mask"
+	SpanActionRename       = "rename"
+	SpanActionDrop         = "drop"
+	SpanActionNormalizeSQL = "normalizeSQL"
+)
+
+// SpanProcessorConfig describes a single transformation of trace spans.  The
+// processor only applies to spans that match all of `service`, `operation`
+// and `matchTags`, if specified.
+type SpanProcessorConfig struct {
+	// What to do to matching spans.  One of:
+	//
+	//  - `delete`: Remove the `tags`
+	//  - `hash`: Replace the values of `tags` with their SHA-256 hash (hex
+	//    encoded)
+	//  - `mask`: Replace the parts of the values of `tags` that match
+	//    `pattern` with `replacement`
+	//  - `rename`: Rename tags according to `renames`
+	//  - `drop`: Drop the span entirely
+	//  - `normalizeSQL`: Replace literal values in the SQL statements in
+	//    `tags` with `?` so that they don't leak data
+	Action string `yaml:"action" validate:"required"`
+	// The tags to act on for the `delete`, `hash`, `mask` and
+	// `normalizeSQL` actions
+	Tags []string `yaml:"tags"`
+	// A regex that matches the parts of tag values to replace for the
+	// `mask` action
+	Pattern string `yaml:"pattern"`
+	// What to replace the parts of tag values that match `pattern` with for
+	// the `mask` action.  Can refer to capture groups in `pattern` with the
+	// syntax `${1}`.  If not set, `****` is used.
+	Replacement *string `yaml:"replacement"`
+	// A map of the old to the new tag names for the `rename` action.  If the
+	// new tag already exists on the span it is overwritten.
+	Renames map[string]string `yaml:"renames"`
+	// Only apply to spans whose service matches this.  Can be a glob or a
+	// regex surrounded by `/`.
+	Service string `yaml:"service"`
+	// Only apply to spans whose operation (span name) matches this.  Can be
+	// a glob or a regex surrounded by `/`.
+	Operation string `yaml:"operation"`
+	// Only apply to spans that have all of these tags with values that match
+	// the given values.  The values can be globs or regexes surrounded by
+	// `/`.
+	MatchTags map[string]string `yaml:"matchTags"`
+}
+
+// Validate the span processor config
+func (spc *SpanProcessorConfig) Validate() error {
+	switch spc.Action {
+	case SpanActionDelete, SpanActionHash, SpanActionNormalizeSQL:
+		if len(spc.Tags) == 0 {
+			return fmt.Errorf("span processor action %s requires tags", spc.Action)
+		}
+	case SpanActionMask:
+		if len(spc.Tags) == 0 || spc.Pattern == "" {
+			return fmt.Errorf("span processor action %s requires tags and pattern", spc.Action)
+		}
+		if _, err := regexp.Compile(spc.Pattern); err != nil {
+			return fmt.Errorf("span processor pattern %q is invalid: %v", spc.Pattern, err)
+		}
+	case SpanActionRename:
+		if len(spc.Renames) == 0 {
+			return fmt.Errorf("span processor action %s requires renames", spc.Action)
+		}
+	case SpanActionDrop:
+		if spc.Service == "" && spc.Operation == "" && len(spc.MatchTags) == 0 {
+			return fmt.Errorf("span processor action %s requires service, operation or matchTags", spc.Action)
+		}
+	default:
+		return fmt.Errorf("span processor action %q is not supported", spc.Action)
+	}
+	return nil
+}
+
+// Validate the trace sampling config
+func (tsc *TraceSamplingConfig) Validate() error {
+	if !tsc.Enabled {
+		return nil
+	}
+	if tsc.DecisionWait.AsDuration() <= 0 {
+		return errors.New("writer.traceSampling.decisionWait must be greater than 0")
+	}
+	if tsc.MaxSpansBuffered <= 0 || tsc.DecisionCacheSize <= 0 {
+		return errors.New("writer.traceSampling.maxSpansBuffered and decisionCacheSize must be greater than 0")
+	}
+	if tsc.SamplingPercentage < 0 || tsc.SamplingPercentage > 100 {
+		return errors.New("writer.traceSampling.samplingPercentage must be between 0 and 100")
+	}
+	return nil
+}
+
+// Validate the span metrics config
+func (smc *SpanMetricsConfig) Validate() error {
+	if !smc.Enabled {
+		return nil
+	}
+	if smc.Interval.AsDuration() <= 0 {
+		return errors.New("writer.spanMetrics.interval must be greater than 0")
+	}
+	if smc.MaxTimeSeries <= 0 {
+		return errors.New("writer.spanMetrics.maxTimeSeries must be greater than 0")
+	}
+	for i := 1; i < len(smc.HistogramBuckets); i++ {
+		if smc.HistogramBuckets[i] <= smc.HistogramBuckets[i-1] {
+			return errors.New("writer.spanMetrics.histogramBuckets must be in increasing order")
+		}
+	}
+	return nil
 }
 
 func (wc *WriterConfig) initialize() {
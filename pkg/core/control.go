@@ -0,0 +1,156 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// The largest monitor config that can be posted to the control API
+const maxControlBodySize = 1 << 20
+
+// Serves the monitor control API, which is only enabled if the status server
+// requires authentication:
+//
+//	GET    /control/monitors               - status of all monitors
+//	POST   /control/monitors               - add an ephemeral monitor from a YAML or JSON body
+//	DELETE /control/monitors/<id>          - remove an ephemeral monitor
+//	POST   /control/monitors/<id>/disable  - disable a monitor, for the optional `duration` param
+//	POST   /control/monitors/<id>/enable   - enable a disabled monitor
+//	POST   /control/monitors/<id>/collect  - collect from a monitor once right away
+func (a *Agent) controlMonitorsHandler(rw http.ResponseWriter, req *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, "/control/monitors"), "/")
+	var parts []string
+	if path != "" {
+		parts = strings.Split(path, "/")
+	}
+
+	switch {
+	case len(parts) == 0 && req.Method == http.MethodGet:
+		writeControlResponse(rw, 200, a.monitors.MonitorStatuses())
+	case len(parts) == 0 && req.Method == http.MethodPost:
+		a.addEphemeralMonitor(rw, req)
+	case len(parts) == 1 && req.Method == http.MethodDelete:
+		err := a.monitors.RemoveEphemeralMonitor(types.MonitorID(parts[0]))
+		a.writeMonitorControlResult(rw, types.MonitorID(parts[0]), err)
+	case len(parts) == 2 && req.Method == http.MethodPost:
+		a.monitorAction(rw, req, types.MonitorID(parts[0]), parts[1])
+	case len(parts) <= 2:
+		writeControlError(rw, 405, "method not allowed")
+	default:
+		writeControlError(rw, 404, "not found")
+	}
+}
+
+func (a *Agent) monitorAction(rw http.ResponseWriter, req *http.Request, id types.MonitorID, action string) {
+	var err error
+	switch action {
+	case "disable":
+		var duration time.Duration
+		if d := req.URL.Query().Get("duration"); d != "" {
+			duration, err = time.ParseDuration(d)
+			if err != nil {
+				writeControlError(rw, 400, "invalid duration: "+err.Error())
+				return
+			}
+		}
+		err = a.monitors.DisableMonitor(id, duration)
+	case "enable":
+		err = a.monitors.EnableMonitor(id)
+	case "collect":
+		err = a.monitors.CollectNow(id)
+	default:
+		writeControlError(rw, 404, "unknown action "+action)
+		return
+	}
+	a.writeMonitorControlResult(rw, id, err)
+}
+
+func (a *Agent) addEphemeralMonitor(rw http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxControlBodySize))
+	if err != nil {
+		writeControlError(rw, 400, err.Error())
+		return
+	}
+
+	var conf config.MonitorConfig
+	if err := yaml.UnmarshalStrict(body, &conf); err != nil {
+		writeControlError(rw, 400, "invalid monitor config: "+err.Error())
+		return
+	}
+	if err := a.lastConfig.PrepareMonitorConfig(&conf); err != nil {
+		writeControlError(rw, 400, err.Error())
+		return
+	}
+
+	ids, err := a.monitors.AddEphemeralMonitor(conf)
+	if err != nil {
+		writeControlError(rw, 400, err.Error())
+		return
+	}
+
+	if ids == nil {
+		ids = []types.MonitorID{}
+	}
+	writeControlResponse(rw, 201, map[string]interface{}{"monitorIDs": ids})
+}
+
+// Writes the current status of the monitor if the action succeeded
+func (a *Agent) writeMonitorControlResult(rw http.ResponseWriter, id types.MonitorID, err error) {
+	switch {
+	case err == monitors.ErrMonitorNotFound:
+		writeControlError(rw, 404, err.Error())
+		return
+	case err == monitors.ErrCollectNowNotSupported:
+		writeControlError(rw, 501, err.Error())
+		return
+	case isCollectionError(err):
+		writeControlError(rw, 500, err.Error())
+		return
+	case err != nil:
+		writeControlError(rw, 400, err.Error())
+		return
+	}
+
+	for _, status := range a.monitors.MonitorStatuses() {
+		if status.MonitorID == id {
+			writeControlResponse(rw, 200, status)
+			return
+		}
+	}
+	// The monitor was removed
+	writeControlResponse(rw, 200, map[string]interface{}{"removed": id})
+}
+
+func isCollectionError(err error) bool {
+	_, ok := err.(*monitors.CollectionError)
+	return ok
+}
+
+func writeControlError(rw http.ResponseWriter, code int, msg string) {
+	writeControlResponse(rw, code, map[string]string{"error": msg})
+}
+
+func writeControlResponse(rw http.ResponseWriter, code int, resp interface{}) {
+	jsonOut, err := json.Marshal(resp)
+	if err != nil {
+		log.WithError(err).Error("Could not serialize control API response to JSON")
+		rw.WriteHeader(500)
+		_, _ = rw.Write([]byte(err.Error()))
+		return
+	}
+
+	rw.Header().Add("Content-Type", "application/json")
+	rw.WriteHeader(code)
+
+	_, _ = rw.Write(jsonOut)
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/meta"
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlMonitorsHandler(t *testing.T) {
+	a := &Agent{
+		monitors:   monitors.NewMonitorManager(&meta.AgentMeta{}),
+		lastConfig: &config.Config{},
+	}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		a.controlMonitorsHandler(rw, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rw
+	}
+
+	rw := do("GET", "/control/monitors", "")
+	require.Equal(t, 200, rw.Code)
+	require.Equal(t, "[]", rw.Body.String())
+
+	rw = do("POST", "/control/monitors/5/disable", "")
+	require.Equal(t, 404, rw.Code)
+	require.Contains(t, rw.Body.String(), "monitor not found")
+
+	require.Equal(t, 400, do("POST", "/control/monitors/5/disable?duration=soon", "").Code)
+	require.Equal(t, 404, do("POST", "/control/monitors/5/pause", "").Code)
+	require.Equal(t, 405, do("PUT", "/control/monitors/5", "").Code)
+	require.Equal(t, 404, do("GET", "/control/monitors/5/a/b", "").Code)
+
+	rw = do("POST", "/control/monitors", "type: [")
+	require.Equal(t, 400, rw.Code)
+	require.Contains(t, rw.Body.String(), "invalid monitor config")
+
+	rw = do("POST", "/control/monitors", `{"type": "not-a-monitor"}`)
+	require.Equal(t, 400, rw.Code)
+	require.Contains(t, rw.Body.String(), "unknown monitor type")
+}
+
+func TestControlMonitorsHandlerCollectFailure(t *testing.T) {
+	a := &Agent{
+		monitors:   monitors.NewMonitorManager(&meta.AgentMeta{}),
+		lastConfig: &config.Config{},
+	}
+	defer a.monitors.Shutdown()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		a.controlMonitorsHandler(rw, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rw
+	}
+
+	// Nothing listens on the port, so collecting fails
+	rw := do("POST", "/control/monitors", `{"type": "internal-metrics", "host": "127.0.0.1", "port": 1, "intervalSeconds": 3600}`)
+	require.Equal(t, 201, rw.Code)
+
+	var resp struct {
+		MonitorIDs []string `json:"monitorIDs"`
+	}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+	require.Len(t, resp.MonitorIDs, 1)
+
+	rw = do("POST", "/control/monitors/"+resp.MonitorIDs[0]+"/collect", "")
+	require.Equal(t, 500, rw.Code)
+	require.Contains(t, rw.Body.String(), "monitor failed to collect")
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/signalfx/golib/v3/datapoint"
 	"github.com/signalfx/golib/v3/sfxclient"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
 	"github.com/signalfx/signalfx-agent/pkg/core/dpfilters"
 	"github.com/signalfx/signalfx-agent/pkg/core/writer/tap"
 	"github.com/signalfx/signalfx-agent/pkg/utils"
@@ -30,19 +31,37 @@ import (
 var VersionLine string
 
 // Serves the diagnostic status on the specified path
-func (a *Agent) serveDiagnosticInfo(host string, port uint16) {
+func (a *Agent) serveDiagnosticInfo(host string, port uint16, serverConf *config.InternalStatusServerConfig) {
 	if a.diagnosticServer != nil {
 		a.diagnosticServer.Close()
 	}
 
+	tlsConf, err := statusServerTLSConfig(serverConf)
+	if err != nil {
+		log.WithError(err).Error("Could not set up TLS for the internal status server, not starting it")
+		a.diagnosticServer = nil
+		return
+	}
+
 	mux := http.NewServeMux()
 	mux.Handle("/", http.HandlerFunc(a.diagnosticTextHandler))
 	mux.Handle("/metrics", http.HandlerFunc(a.internalMetricsHandler))
+	mux.Handle("/metrics/prometheus", http.HandlerFunc(a.prometheusMetricsHandler))
+	mux.Handle("/status/monitors", http.HandlerFunc(a.monitorStatusHandler))
 	mux.Handle("/tap-dps", http.HandlerFunc(a.datapointTapHandler))
+	mux.Handle("/support-bundle", http.HandlerFunc(a.supportBundleHandler))
+	mux.Handle("/log-levels", http.HandlerFunc(a.logLevelsHandler))
+	mux.Handle("/healthz", http.HandlerFunc(a.healthzHandler))
+	mux.Handle("/readyz", http.HandlerFunc(a.readyzHandler))
+	if serverConf.EnableControlAPI {
+		mux.Handle("/control/monitors", http.HandlerFunc(a.controlMonitorsHandler))
+		mux.Handle("/control/monitors/", http.HandlerFunc(a.controlMonitorsHandler))
+	}
 
 	a.diagnosticServer = &http.Server{
 		Addr:        fmt.Sprintf("%s:%d", host, port),
-		Handler:     mux,
+		Handler:     requireStatusAuth(mux, serverConf),
+		TLSConfig:   tlsConf,
 		ReadTimeout: 5 * time.Second,
 		// Set this to 0 so that streaming works.
 		WriteTimeout: 0, //5 * time.Second,
@@ -51,7 +70,12 @@ func (a *Agent) serveDiagnosticInfo(host string, port uint16) {
 	go func() {
 		log.Infof("Serving internal metrics at %s:%d", host, port)
 		for {
-			err := a.diagnosticServer.ListenAndServe()
+			var err error
+			if serverConf.TLSEnabled() {
+				err = a.diagnosticServer.ListenAndServeTLS(serverConf.TLSCertPath, serverConf.TLSKeyPath)
+			} else {
+				err = a.diagnosticServer.ListenAndServe()
+			}
 			if err != nil {
 				if err == http.ErrServerClosed {
 					return
@@ -69,8 +93,8 @@ func (a *Agent) serveDiagnosticInfo(host string, port uint16) {
 	}()
 }
 
-func readStatusInfo(host string, port uint16, section string) ([]byte, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%s:%d/?section=%s", host, port, section))
+func readStatusInfo(conf *config.Config, section string) ([]byte, error) {
+	resp, err := getFromStatusServer(conf, "/?section="+url.QueryEscape(section), 0)
 	if err != nil {
 		return nil, err
 	}
@@ -94,6 +118,21 @@ func (a *Agent) internalMetricsHandler(rw http.ResponseWriter, req *http.Request
 	_, _ = rw.Write(jsonOut)
 }
 
+func (a *Agent) monitorStatusHandler(rw http.ResponseWriter, req *http.Request) {
+	jsonOut, err := json.Marshal(a.monitors.MonitorStatuses())
+	if err != nil {
+		log.WithError(err).Error("Could not serialize monitor status to JSON")
+		rw.WriteHeader(500)
+		_, _ = rw.Write([]byte(err.Error()))
+		return
+	}
+
+	rw.Header().Add("Content-Type", "application/json")
+	rw.WriteHeader(200)
+
+	_, _ = rw.Write(jsonOut)
+}
+
 // InternalMetrics aggregates internal metrics from subcomponents and returns a
 // list of datapoints that represent the instaneous state of the agent
 func (a *Agent) InternalMetrics() []*datapoint.Datapoint {
@@ -220,14 +259,11 @@ func (a *Agent) datapointTapHandler(rw http.ResponseWriter, req *http.Request) {
 	logrus.Infof("Datapoint tap cleared")
 }
 
-func streamDatapoints(host string, port uint16, metric string, dims string) (io.ReadCloser, error) {
-	c := http.Client{
-		Timeout: 0,
-	}
+func streamDatapoints(conf *config.Config, metric string, dims string) (io.ReadCloser, error) {
 	qs := url.Values{}
 	qs.Set("metric", metric)
 	qs.Set("dims", dims)
-	resp, err := c.Get(fmt.Sprintf("http://%s:%d/tap-dps?%s", host, port, qs.Encode())) // nolint:bodyclose
+	resp, err := getFromStatusServer(conf, "/tap-dps?"+qs.Encode(), 0) // nolint:bodyclose
 	if err != nil {
 		return nil, err
 	}
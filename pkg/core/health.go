@@ -0,0 +1,196 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/collectd"
+	log "github.com/sirupsen/logrus"
+)
+
+// The result of a single health check
+type healthCheck struct {
+	Name string `json:"name"`
+	OK   bool   `json:"ok"`
+	// Why the check failed, or some detail on why it passed
+	Message string `json:"message,omitempty"`
+}
+
+type healthResponse struct {
+	OK bool `json:"ok"`
+	// The names of the checks that failed
+	Failing []string      `json:"failing"`
+	Checks  []healthCheck `json:"checks"`
+}
+
+func newHealthResponse(checks []healthCheck) *healthResponse {
+	resp := &healthResponse{
+		OK:      true,
+		Failing: []string{},
+		Checks:  checks,
+	}
+	for _, c := range checks {
+		if !c.OK {
+			resp.OK = false
+			resp.Failing = append(resp.Failing, c.Name)
+		}
+	}
+	return resp
+}
+
+func checkConfigLoaded(conf *config.Config) healthCheck {
+	if conf == nil {
+		return healthCheck{Name: "config", Message: "config has not been loaded"}
+	}
+	return healthCheck{Name: "config", OK: true}
+}
+
+func checkCollectd(state string, since time.Time, inUse bool, timeout time.Duration, now time.Time) healthCheck {
+	check := healthCheck{Name: "collectd", OK: true}
+	switch {
+	case !inUse:
+		check.Message = "not used by any monitors"
+	case state == collectd.Running:
+		check.Message = "running"
+	case state == collectd.Errored:
+		check.OK = false
+		check.Message = "collectd could not be started"
+	case now.Sub(since) > timeout:
+		check.OK = false
+		check.Message = fmt.Sprintf("collectd has been %s for %s", state, now.Sub(since).Round(time.Second))
+	default:
+		check.Message = state
+	}
+	return check
+}
+
+func checkWriter(lastSuccess, lastFailure time.Time, lastErr string, startTime time.Time, timeout time.Duration, now time.Time) healthCheck {
+	check := healthCheck{Name: "writer", OK: true}
+	if lastFailure.IsZero() || lastSuccess.After(lastFailure) {
+		return check
+	}
+
+	// Count from when the writer started if nothing has been sent yet
+	since := lastSuccess
+	if since.IsZero() {
+		since = startTime
+	}
+	if now.Sub(since) > timeout {
+		check.OK = false
+		check.Message = fmt.Sprintf("datapoints have failed to send for %s: %s", now.Sub(since).Round(time.Second), lastErr)
+	}
+	return check
+}
+
+func checkObservers(configureErrors map[string]string) healthCheck {
+	check := healthCheck{Name: "observers", OK: true}
+	if len(configureErrors) == 0 {
+		return check
+	}
+
+	var problems []string
+	for observerType, err := range configureErrors {
+		problems = append(problems, fmt.Sprintf("%s: %s", observerType, err))
+	}
+	sort.Strings(problems)
+
+	check.OK = false
+	check.Message = "could not configure observers: " + strings.Join(problems, "; ")
+	return check
+}
+
+func checkMonitors(statuses []monitors.MonitorStatus, timeout time.Duration, now time.Time) healthCheck {
+	check := healthCheck{Name: "monitors", OK: true}
+	if timeout <= 0 {
+		return check
+	}
+
+	var stuck []string
+	for _, s := range statuses {
+		// Monitors that only receive data, or whose output is all filtered,
+		// can legitimately send nothing for a long time, so only the ones
+		// that are also failing to collect are stuck
+		if !s.Stale || s.ConfigureError != "" || s.ConsecutiveErrors == 0 {
+			continue
+		}
+		var last time.Time
+		switch {
+		case s.LastEmission != nil:
+			last = *s.LastEmission
+		case s.CreatedAt != nil:
+			last = *s.CreatedAt
+		}
+		if now.Sub(last) > timeout {
+			stuck = append(stuck, string(s.MonitorID)+" ("+s.MonitorType+")")
+		}
+	}
+
+	if len(stuck) > 0 {
+		sort.Strings(stuck)
+		check.OK = false
+		check.Message = fmt.Sprintf("monitors have failed to collect and not sent anything for over %s: %s", timeout, strings.Join(stuck, ", "))
+	}
+	return check
+}
+
+// The checks that determine whether the agent should be restarted
+func (a *Agent) livenessChecks() []healthCheck {
+	conf := a.lastConfig
+	checks := []healthCheck{checkConfigLoaded(conf)}
+	if conf == nil {
+		return checks
+	}
+
+	state, since, inUse := collectd.MainState()
+	return append(checks,
+		checkCollectd(state, since, inUse, conf.HealthChecks.CollectdStartTimeout.AsDuration(), time.Now()))
+}
+
+// The checks that determine whether the agent is fully working
+func (a *Agent) readinessChecks() []healthCheck {
+	checks := a.livenessChecks()
+	conf := a.lastConfig
+	if conf == nil {
+		return checks
+	}
+
+	now := time.Now()
+	lastSuccess, lastFailure, lastErr := a.writer.SendStatus()
+	return append(checks,
+		checkWriter(lastSuccess, lastFailure, lastErr, a.writer.StartTime(), conf.HealthChecks.WriterFailureTimeout.AsDuration(), now),
+		checkObservers(a.observers.ConfigureErrors()),
+		checkMonitors(a.monitors.MonitorStatuses(), conf.HealthChecks.StuckMonitorTimeout.AsDuration(), now))
+}
+
+func writeHealthResponse(rw http.ResponseWriter, checks []healthCheck) {
+	resp := newHealthResponse(checks)
+	jsonOut, err := json.Marshal(resp)
+	if err != nil {
+		log.WithError(err).Error("Could not serialize health checks to JSON")
+		rw.WriteHeader(500)
+		_, _ = rw.Write([]byte(err.Error()))
+		return
+	}
+
+	rw.Header().Add("Content-Type", "application/json")
+	if resp.OK {
+		rw.WriteHeader(200)
+	} else {
+		rw.WriteHeader(503)
+	}
+	_, _ = rw.Write(jsonOut)
+}
+
+func (a *Agent) healthzHandler(rw http.ResponseWriter, req *http.Request) {
+	writeHealthResponse(rw, a.livenessChecks())
+}
+
+func (a *Agent) readyzHandler(rw http.ResponseWriter, req *http.Request) {
+	writeHealthResponse(rw, a.readinessChecks())
+}
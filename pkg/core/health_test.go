@@ -0,0 +1,83 @@
+package core
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/collectd"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Unix(100000, 0)
+
+func TestCheckWriter(t *testing.T) {
+	start := testNow.Add(-time.Hour)
+
+	// Nothing has been sent yet
+	require.True(t, checkWriter(time.Time{}, time.Time{}, "", start, time.Minute, testNow).OK)
+	// The last send worked
+	require.True(t, checkWriter(testNow.Add(-time.Second), testNow.Add(-time.Minute), "", start, time.Minute, testNow).OK)
+	// Sends have only been failing for a little while
+	require.True(t, checkWriter(testNow.Add(-30*time.Second), testNow.Add(-time.Second), "", start, time.Minute, testNow).OK)
+
+	check := checkWriter(testNow.Add(-2*time.Minute), testNow.Add(-time.Second), "401 Unauthorized", start, time.Minute, testNow)
+	require.False(t, check.OK)
+	require.Contains(t, check.Message, "401 Unauthorized")
+
+	// Nothing has ever been sent successfully
+	require.False(t, checkWriter(time.Time{}, testNow.Add(-time.Second), "timeout", start, time.Minute, testNow).OK)
+}
+
+func TestCheckCollectd(t *testing.T) {
+	require.True(t, checkCollectd("", time.Time{}, false, time.Minute, testNow).OK)
+	require.True(t, checkCollectd(collectd.Running, testNow.Add(-time.Hour), true, time.Minute, testNow).OK)
+	require.True(t, checkCollectd(collectd.Starting, testNow.Add(-time.Second), true, time.Minute, testNow).OK)
+	require.False(t, checkCollectd(collectd.Starting, testNow.Add(-2*time.Minute), true, time.Minute, testNow).OK)
+	require.False(t, checkCollectd(collectd.Errored, testNow, true, time.Minute, testNow).OK)
+}
+
+func TestCheckMonitors(t *testing.T) {
+	old := testNow.Add(-time.Hour)
+	recent := testNow.Add(-time.Minute)
+	statuses := []monitors.MonitorStatus{
+		{MonitorID: "1", MonitorType: "cpu", LastEmission: &recent},
+		{MonitorID: "2", MonitorType: "redis", Stale: true, CreatedAt: &old, ConsecutiveErrors: 60},
+		{MonitorID: "3", MonitorType: "mysql", Stale: true, CreatedAt: &old, LastEmission: &recent, ConsecutiveErrors: 1},
+		// Receivers that nothing was sent to are idle, not stuck
+		{MonitorID: "5", MonitorType: "otlp", Stale: true, CreatedAt: &old},
+		{MonitorID: "6", MonitorType: "signalfx-forwarder", Stale: true, CreatedAt: &old, LastEmission: &old},
+		// Monitors that failed to configure aren't stuck, they are broken
+		{MonitorID: "4", MonitorType: "postgresql", Stale: true, ConfigureError: "bad config"},
+	}
+
+	check := checkMonitors(statuses, 10*time.Minute, testNow)
+	require.False(t, check.OK)
+	require.Contains(t, check.Message, "2 (redis)")
+	require.NotContains(t, check.Message, "mysql")
+	require.NotContains(t, check.Message, "postgresql")
+	require.NotContains(t, check.Message, "otlp")
+	require.NotContains(t, check.Message, "signalfx-forwarder")
+
+	require.True(t, checkMonitors(statuses[3:], 10*time.Minute, testNow).OK)
+	require.True(t, checkMonitors(statuses, 0, testNow).OK)
+}
+
+func TestHealthResponse(t *testing.T) {
+	rw := httptest.NewRecorder()
+	writeHealthResponse(rw, []healthCheck{
+		checkConfigLoaded(nil),
+		checkObservers(map[string]string{"k8s-api": "observer type not recognized"}),
+		checkObservers(nil),
+	})
+
+	require.Equal(t, 503, rw.Code)
+	require.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+	require.Contains(t, rw.Body.String(), `"failing":["config","observers"]`)
+
+	rw = httptest.NewRecorder()
+	writeHealthResponse(rw, []healthCheck{checkObservers(nil)})
+	require.Equal(t, 200, rw.Code)
+	require.Contains(t, rw.Body.String(), `"ok":true`)
+}
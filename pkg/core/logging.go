@@ -0,0 +1,104 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/logging"
+	log "github.com/sirupsen/logrus"
+)
+
+// All agent log output goes through this so that levels can be overridden
+// per component and monitor type
+var logFilter = logging.NewFilter(&log.TextFormatter{}) //nolint: gochecknoglobals
+
+func configureLogging(conf *config.LogConfig) {
+	logFilter.SetFormatter(conf.LogrusFormatter())
+
+	if level := conf.LogrusLevel(); level != nil {
+		logFilter.SetLevel(*level)
+	}
+
+	// These were validated when the config was loaded
+	componentLevels, _ := logging.ParseLevels(conf.ComponentLevels)
+	monitorTypeLevels, _ := logging.ParseLevels(conf.MonitorTypeLevels)
+	logFilter.SetOverrides(componentLevels, monitorTypeLevels)
+	logFilter.SetDedupInterval(conf.DedupInterval.AsDuration())
+
+	log.SetFormatter(logFilter)
+	applyLogLevels()
+}
+
+// The logger has to be at the most verbose of the levels, otherwise logrus
+// won't even pass entries to the filter.
+func applyLogLevels() {
+	log.SetLevel(logFilter.MostVerboseLevel())
+
+	levels := logFilter.Levels()
+	log.WithFields(log.Fields{
+		"componentLevels":   levels.ComponentLevels,
+		"monitorTypeLevels": levels.MonitorTypeLevels,
+	}).Infof("Using log level %s", levels.Level)
+}
+
+// Shows the current log levels on GET, and changes them on POST.  The form
+// value `level` sets the global level, unless `component` or `monitorType` is
+// also given, in which case it overrides the level of only that component or
+// monitor type.  An empty level removes the override.  Changes last until
+// the logging config changes.
+func (a *Agent) logLevelsHandler(rw http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut:
+		if err := changeLogLevel(req); err != nil {
+			rw.WriteHeader(400)
+			_, _ = rw.Write([]byte(err.Error()))
+			return
+		}
+	default:
+		rw.WriteHeader(405)
+		return
+	}
+
+	jsonOut, err := json.Marshal(logFilter.Levels())
+	if err != nil {
+		rw.WriteHeader(500)
+		_, _ = rw.Write([]byte(err.Error()))
+		return
+	}
+
+	rw.Header().Add("Content-Type", "application/json")
+	rw.WriteHeader(200)
+	_, _ = rw.Write(jsonOut)
+}
+
+func changeLogLevel(req *http.Request) error {
+	levelStr := req.FormValue("level")
+	component := req.FormValue("component")
+	monitorType := req.FormValue("monitorType")
+
+	var level *log.Level
+	if levelStr != "" {
+		l, err := log.ParseLevel(levelStr)
+		if err != nil {
+			return err
+		}
+		level = &l
+	}
+
+	switch {
+	case monitorType != "":
+		logFilter.SetMonitorTypeLevel(monitorType, level)
+	case component != "":
+		logFilter.SetComponentLevel(component, level)
+	case level != nil:
+		logFilter.SetLevel(*level)
+	default:
+		return errors.New("level must be provided to change the global log level")
+	}
+
+	applyLogLevels()
+	return nil
+}
@@ -0,0 +1,294 @@
+// Package logging contains the agent's log output handling that goes beyond
+// what logrus provides on its own, namely log levels that can be overridden
+// per component and monitor type, and deduplication of repeated messages.
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	log "github.com/sirupsen/logrus"
+)
+
+// The log entry fields that identify the source of a log message
+const (
+	ComponentField   = "component"
+	MonitorTypeField = "monitorType"
+)
+
+// The field added to a deduplicated message with the number of identical
+// messages that were suppressed since it was last logged
+const suppressedCountField = "suppressedCount"
+
+// How many distinct messages are tracked for deduplication
+const dedupCacheSize = 200
+
+// Levels is a snapshot of the log levels used by a Filter
+type Levels struct {
+	Level             string            `json:"level"`
+	ComponentLevels   map[string]string `json:"componentLevels"`
+	MonitorTypeLevels map[string]string `json:"monitorTypeLevels"`
+}
+
+type seenMessage struct {
+	lastLogged time.Time
+	suppressed int
+}
+
+// Filter is a logrus formatter that wraps another formatter and drops log
+// entries that are below the level configured for their component or monitor
+// type, and that suppresses warnings and errors that repeat more frequently
+// than the dedup interval.  Since logrus won't even create entries below the
+// level of the logger, the logger's level must be kept at the most verbose
+// level returned by MostVerboseLevel.
+type Filter struct {
+	lock              sync.RWMutex
+	next              log.Formatter
+	level             log.Level
+	componentLevels   map[string]log.Level
+	monitorTypeLevels map[string]log.Level
+
+	dedupInterval time.Duration
+	seen          *lru.Cache
+
+	// For unit testing
+	now func() time.Time
+}
+
+var _ log.Formatter = &Filter{}
+
+// NewFilter creates a filter that passes all entries at the info level or
+// above to next
+func NewFilter(next log.Formatter) *Filter {
+	seen, err := lru.New(dedupCacheSize)
+	if err != nil {
+		panic("could not create log dedup LRU cache")
+	}
+
+	return &Filter{
+		next:              next,
+		level:             log.InfoLevel,
+		componentLevels:   map[string]log.Level{},
+		monitorTypeLevels: map[string]log.Level{},
+		seen:              seen,
+		now:               time.Now,
+	}
+}
+
+// ParseLevels converts a map of log level names to logrus levels
+func ParseLevels(levels map[string]string) (map[string]log.Level, error) {
+	out := make(map[string]log.Level, len(levels))
+	for k, v := range levels {
+		level, err := log.ParseLevel(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level for %s: %v", k, err)
+		}
+		out[k] = level
+	}
+	return out, nil
+}
+
+// SetFormatter changes the formatter that entries are passed to
+func (f *Filter) SetFormatter(next log.Formatter) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.next = next
+}
+
+// SetLevel sets the level used for entries without an overridden level
+func (f *Filter) SetLevel(level log.Level) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.level = level
+}
+
+// SetComponentLevel overrides the level of entries from the given component.
+// A nil level removes the override.
+func (f *Filter) SetComponentLevel(component string, level *log.Level) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	setOrDelete(f.componentLevels, component, level)
+}
+
+// SetMonitorTypeLevel overrides the level of entries from monitors of the
+// given type.  A nil level removes the override.
+func (f *Filter) SetMonitorTypeLevel(monitorType string, level *log.Level) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	setOrDelete(f.monitorTypeLevels, monitorType, level)
+}
+
+func setOrDelete(levels map[string]log.Level, key string, level *log.Level) {
+	if level == nil {
+		delete(levels, key)
+		return
+	}
+	levels[key] = *level
+}
+
+// SetOverrides replaces all of the component and monitor type overrides
+func (f *Filter) SetOverrides(componentLevels, monitorTypeLevels map[string]log.Level) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.componentLevels = copyLevels(componentLevels)
+	f.monitorTypeLevels = copyLevels(monitorTypeLevels)
+}
+
+func copyLevels(levels map[string]log.Level) map[string]log.Level {
+	out := make(map[string]log.Level, len(levels))
+	for k, v := range levels {
+		out[k] = v
+	}
+	return out
+}
+
+// SetDedupInterval sets how long identical warning and error messages are
+// suppressed for after they are logged.  Zero disables deduplication.
+func (f *Filter) SetDedupInterval(interval time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.dedupInterval = interval
+	f.seen.Purge()
+}
+
+// MostVerboseLevel returns the most verbose of all of the configured levels
+func (f *Filter) MostVerboseLevel() log.Level {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	level := f.level
+	for _, l := range f.componentLevels {
+		if l > level {
+			level = l
+		}
+	}
+	for _, l := range f.monitorTypeLevels {
+		if l > level {
+			level = l
+		}
+	}
+	return level
+}
+
+// Levels returns the current levels of the filter
+func (f *Filter) Levels() Levels {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	out := Levels{
+		Level:             f.level.String(),
+		ComponentLevels:   map[string]string{},
+		MonitorTypeLevels: map[string]string{},
+	}
+	for k, v := range f.componentLevels {
+		out.ComponentLevels[k] = v.String()
+	}
+	for k, v := range f.monitorTypeLevels {
+		out.MonitorTypeLevels[k] = v.String()
+	}
+	return out
+}
+
+// The level that applies to the entry, with monitor type overrides taking
+// precedence over component overrides.  Must be called with the lock held.
+func (f *Filter) levelFor(entry *log.Entry) log.Level {
+	if monitorType, ok := entry.Data[MonitorTypeField].(string); ok {
+		if level, ok := f.monitorTypeLevels[monitorType]; ok {
+			return level
+		}
+	}
+	if component, ok := entry.Data[ComponentField].(string); ok {
+		if level, ok := f.componentLevels[component]; ok {
+			return level
+		}
+	}
+	return f.level
+}
+
+// LevelEnabled returns whether the entry is at or above the level that
+// applies to it
+func (f *Filter) LevelEnabled(entry *log.Entry) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return entry.Level <= f.levelFor(entry)
+}
+
+// Format the entry with the wrapped formatter, or return nothing if the entry
+// should not be logged.
+func (f *Filter) Format(entry *log.Entry) ([]byte, error) {
+	f.lock.RLock()
+	next := f.next
+	level := f.levelFor(entry)
+	dedupInterval := f.dedupInterval
+	f.lock.RUnlock()
+
+	if entry.Level > level {
+		return nil, nil
+	}
+
+	if dedupInterval > 0 && entry.Level <= log.WarnLevel {
+		suppressed, ok := f.dedup(entry, dedupInterval)
+		if !ok {
+			return nil, nil
+		}
+		if suppressed > 0 {
+			data := make(log.Fields, len(entry.Data)+1)
+			for k, v := range entry.Data {
+				data[k] = v
+			}
+			data[suppressedCountField] = suppressed
+
+			withCount := *entry
+			withCount.Data = data
+			entry = &withCount
+		}
+	}
+
+	return next.Format(entry)
+}
+
+// Returns whether the entry should be logged and, if so, how many identical
+// entries were suppressed before it.
+func (f *Filter) dedup(entry *log.Entry, interval time.Duration) (int, bool) {
+	key := dedupKey(entry)
+	now := f.now()
+
+	// The cache is safe for concurrent use but the read-modify-write of an
+	// entry isn't.
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if v, ok := f.seen.Get(key); ok {
+		sm := v.(*seenMessage)
+		if now.Sub(sm.lastLogged) < interval {
+			sm.suppressed++
+			return 0, false
+		}
+		suppressed := sm.suppressed
+		sm.lastLogged = now
+		sm.suppressed = 0
+		return suppressed, true
+	}
+
+	f.seen.Add(key, &seenMessage{lastLogged: now})
+	return 0, true
+}
+
+// Identical messages have the same level, message, and source.  Other fields,
+// such as the error, are left out since they often vary slightly (e.g. with
+// timestamps or addresses) for what is fundamentally the same problem.
+func dedupKey(entry *log.Entry) string {
+	var sources []string
+	for _, field := range []string{ComponentField, MonitorTypeField, "monitorID"} {
+		if v, ok := entry.Data[field]; ok {
+			sources = append(sources, fmt.Sprintf("%s=%v", field, v))
+		}
+	}
+	sort.Strings(sources)
+	return entry.Level.String() + "|" + strings.Join(sources, ",") + "|" + entry.Message
+}
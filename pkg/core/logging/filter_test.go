@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger() (*log.Logger, *Filter, *bytes.Buffer) {
+	var out bytes.Buffer
+	filter := NewFilter(&log.TextFormatter{DisableColors: true, DisableTimestamp: true})
+
+	logger := log.New()
+	logger.Out = &out
+	logger.Formatter = filter
+	return logger, filter, &out
+}
+
+func TestLevelOverrides(t *testing.T) {
+	logger, filter, out := newTestLogger()
+
+	filter.SetLevel(log.WarnLevel)
+	filter.SetOverrides(map[string]log.Level{"writer": log.DebugLevel},
+		map[string]log.Level{"kubernetes-cluster": log.DebugLevel, "cpu": log.ErrorLevel})
+	require.Equal(t, log.DebugLevel, filter.MostVerboseLevel())
+	logger.SetLevel(filter.MostVerboseLevel())
+
+	logger.Info("global info")
+	logger.Warn("global warn")
+	logger.WithField("component", "writer").Debug("writer debug")
+	logger.WithField("monitorType", "kubernetes-cluster").Debug("k8s debug")
+	logger.WithField("monitorType", "cpu").Warn("cpu warn")
+	// Monitor type overrides take precedence over the component
+	logger.WithFields(log.Fields{"component": "writer", "monitorType": "cpu"}).Info("cpu info")
+
+	logged := out.String()
+	require.NotContains(t, logged, "global info")
+	require.Contains(t, logged, "global warn")
+	require.Contains(t, logged, "writer debug")
+	require.Contains(t, logged, "k8s debug")
+	require.NotContains(t, logged, "cpu warn")
+	require.NotContains(t, logged, "cpu info")
+
+	filter.SetMonitorTypeLevel("cpu", nil)
+	out.Reset()
+	logger.WithField("monitorType", "cpu").Warn("cpu warn")
+	require.Contains(t, out.String(), "cpu warn")
+
+	require.Equal(t, Levels{
+		Level:             "warning",
+		ComponentLevels:   map[string]string{"writer": "debug"},
+		MonitorTypeLevels: map[string]string{"kubernetes-cluster": "debug"},
+	}, filter.Levels())
+}
+
+func TestDedup(t *testing.T) {
+	logger, filter, out := newTestLogger()
+
+	now := time.Unix(1000, 0)
+	filter.now = func() time.Time { return now }
+	filter.SetDedupInterval(30 * time.Second)
+
+	for i := 0; i < 5; i++ {
+		logger.WithField("error", i).Error("Error shipping datapoints")
+	}
+	// Different messages and info messages are not suppressed
+	logger.Error("Something else")
+	logger.Info("Starting")
+	logger.Info("Starting")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4)
+	require.Contains(t, lines[0], "Error shipping datapoints")
+	require.NotContains(t, lines[0], "suppressedCount")
+
+	now = now.Add(31 * time.Second)
+	out.Reset()
+	logger.WithField("error", 5).Error("Error shipping datapoints")
+
+	require.Contains(t, out.String(), "Error shipping datapoints")
+	require.Contains(t, out.String(), "suppressedCount=4")
+	require.Contains(t, out.String(), "level=error")
+}
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels(map[string]string{"writer": "debug"})
+	require.Nil(t, err)
+	require.Equal(t, map[string]log.Level{"writer": log.DebugLevel}, levels)
+
+	_, err = ParseLevels(map[string]string{"writer": "loud"})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// How many of the most recent log lines are kept for support bundles
+const logHistorySize = 2000
+
+// logHistory is a logrus hook that keeps the most recent log lines in memory
+// so that they can be included in support bundles, even if the agent's
+// output isn't being captured anywhere.
+type logHistory struct {
+	formatter log.Formatter
+
+	lock  sync.Mutex
+	lines [][]byte
+	// The index in lines where the next line goes
+	next int
+	full bool
+}
+
+func newLogHistory(size int) *logHistory {
+	return &logHistory{
+		formatter: &log.TextFormatter{
+			DisableColors: true,
+			FullTimestamp: true,
+		},
+		lines: make([][]byte, size),
+	}
+}
+
+// Levels that the hook receives, which is all of them
+func (lh *logHistory) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire records a single log entry
+func (lh *logHistory) Fire(entry *log.Entry) error {
+	// The logger itself may be more verbose than the configured levels
+	if !logFilter.LevelEnabled(entry) {
+		return nil
+	}
+
+	line, err := lh.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	lh.lock.Lock()
+	defer lh.lock.Unlock()
+
+	lh.lines[lh.next] = line
+	lh.next = (lh.next + 1) % len(lh.lines)
+	if lh.next == 0 {
+		lh.full = true
+	}
+	return nil
+}
+
+// Bytes returns the recorded log lines, oldest first
+func (lh *logHistory) Bytes() []byte {
+	lh.lock.Lock()
+	defer lh.lock.Unlock()
+
+	var out []byte
+	if lh.full {
+		for _, line := range lh.lines[lh.next:] {
+			out = append(out, line...)
+		}
+	}
+	for _, line := range lh.lines[:lh.next] {
+		out = append(out, line...)
+	}
+	return out
+}
@@ -1,5 +1,7 @@
 package meta
 
+import "github.com/signalfx/signalfx-agent/pkg/core/config"
+
 // AgentMeta provides monitors access to global agent metadata.  Putting this
 // into a single interface allows easy expansion of metadata without breaking
 // backwards-compatibility and without exposing global variables that monitors
@@ -8,4 +10,6 @@ package meta
 type AgentMeta struct {
 	InternalStatusHost string
 	InternalStatusPort uint16
+	// The TLS and auth config of the internal status server
+	InternalStatusServer config.InternalStatusServerConfig
 }
@@ -43,6 +43,7 @@ import (
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/heroku"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/internalmetrics"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/jaegergrpc"
+	_ "github.com/signalfx/signalfx-agent/pkg/monitors/jaegerthrift"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/jmx"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/load"
@@ -50,7 +51,9 @@ import (
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/logstash/tcp"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/memory"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/metadata/hostmetadata"
+	_ "github.com/signalfx/signalfx-agent/pkg/monitors/mysql"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/netio"
+	_ "github.com/signalfx/signalfx-agent/pkg/monitors/otlp"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/postgresql"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/processlist"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/prometheus/go"
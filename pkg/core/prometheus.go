@@ -0,0 +1,202 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/signalfx/golib/v3/datapoint"
+	log "github.com/sirupsen/logrus"
+)
+
+var invalidMetricNameChars = regexp.MustCompile(`[^a-zA-Z0-9_:]`) //nolint: gochecknoglobals
+var invalidLabelNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)   //nolint: gochecknoglobals
+
+// Serves the same internal metrics as the /metrics path but in the
+// Prometheus exposition format so that they can be scraped directly.  The
+// text format is used unless the scraper asks for the protobuf or OpenMetrics
+// format.
+func (a *Agent) prometheusMetricsHandler(rw http.ResponseWriter, req *http.Request) {
+	servePrometheusMetrics(rw, req, a.InternalMetrics())
+}
+
+func servePrometheusMetrics(rw http.ResponseWriter, req *http.Request, dps []*datapoint.Datapoint) {
+	format := expfmt.NegotiateIncludingOpenMetrics(req.Header)
+
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, format)
+	for _, mf := range datapointsToMetricFamilies(dps) {
+		if err := enc.Encode(mf); err != nil {
+			log.WithError(err).Error("Could not serialize internal metrics to Prometheus format")
+			rw.WriteHeader(500)
+			_, _ = rw.Write([]byte(err.Error()))
+			return
+		}
+	}
+	// This writes the trailing "# EOF" line required by OpenMetrics
+	if closer, ok := enc.(expfmt.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.WithError(err).Error("Could not serialize internal metrics to Prometheus format")
+			rw.WriteHeader(500)
+			_, _ = rw.Write([]byte(err.Error()))
+			return
+		}
+	}
+
+	rw.Header().Add("Content-Type", string(format))
+	rw.WriteHeader(200)
+
+	_, _ = rw.Write(buf.Bytes())
+}
+
+// Groups the datapoints into metric families by their sanitized metric name,
+// sorted by name.  The type of each family comes from the first datapoint
+// with that name and counter families get the "_total" suffix.  Datapoints
+// with non-numeric values are skipped, as are datapoints whose labels are the
+// same as an earlier one in the family, which can happen when different names
+// are the same once sanitized, since Prometheus rejects duplicate series.
+func datapointsToMetricFamilies(dps []*datapoint.Datapoint) []*dto.MetricFamily {
+	families := map[string]*dto.MetricFamily{}
+	seenLabels := map[string]map[string]bool{}
+	var names []string
+
+	for _, dp := range dps {
+		value, ok := prometheusValue(dp.Value)
+		if !ok {
+			continue
+		}
+
+		metricType, help := prometheusType(dp)
+		name := sanitizeMetricName(dp.Metric)
+		if metricType == dto.MetricType_COUNTER && !strings.HasSuffix(name, "_total") {
+			name += "_total"
+		}
+
+		mf, ok := families[name]
+		if !ok {
+			mf = &dto.MetricFamily{
+				Name: &name,
+				Help: &help,
+				Type: &metricType,
+			}
+			families[name] = mf
+			seenLabels[name] = map[string]bool{}
+			names = append(names, name)
+		}
+
+		labels := prometheusLabels(dp.Dimensions)
+		labelsKey := labelSetKey(labels)
+		if seenLabels[name][labelsKey] {
+			continue
+		}
+		seenLabels[name][labelsKey] = true
+
+		m := &dto.Metric{
+			Label: labels,
+		}
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			m.Counter = &dto.Counter{Value: &value}
+		case dto.MetricType_GAUGE:
+			m.Gauge = &dto.Gauge{Value: &value}
+		default:
+			m.Untyped = &dto.Untyped{Value: &value}
+		}
+		mf.Metric = append(mf.Metric, m)
+	}
+
+	sort.Strings(names)
+	out := make([]*dto.MetricFamily, 0, len(names))
+	for _, name := range names {
+		out = append(out, families[name])
+	}
+	return out
+}
+
+// Returns the Prometheus metric type of the datapoint along with help text
+// that has its original name.  Delta counters have no equivalent in
+// Prometheus so they are untyped.
+func prometheusType(dp *datapoint.Datapoint) (dto.MetricType, string) {
+	switch dp.MetricType {
+	case datapoint.Counter:
+		return dto.MetricType_COUNTER, fmt.Sprintf("Agent internal metric %s (cumulative counter)", dp.Metric)
+	case datapoint.Gauge:
+		return dto.MetricType_GAUGE, fmt.Sprintf("Agent internal metric %s (gauge)", dp.Metric)
+	case datapoint.Count:
+		return dto.MetricType_UNTYPED, fmt.Sprintf("Agent internal metric %s (delta counter)", dp.Metric)
+	default:
+		return dto.MetricType_UNTYPED, fmt.Sprintf("Agent internal metric %s", dp.Metric)
+	}
+}
+
+func prometheusValue(v datapoint.Value) (float64, bool) {
+	switch val := v.(type) {
+	case datapoint.IntValue:
+		return float64(val.Int()), true
+	case datapoint.FloatValue:
+		return val.Float(), true
+	}
+	return 0, false
+}
+
+// Converts the dimensions to labels sorted by name.  If several dimensions
+// have the same name once sanitized, the one whose original name sorts first
+// is used.
+func prometheusLabels(dims map[string]string) []*dto.LabelPair {
+	keys := make([]string, 0, len(dims))
+	for k := range dims {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	byName := map[string]string{}
+	var names []string
+	for _, k := range keys {
+		name := sanitizeLabelName(k)
+		if _, ok := byName[name]; ok {
+			continue
+		}
+		byName[name] = dims[k]
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	labels := make([]*dto.LabelPair, 0, len(names))
+	for i := range names {
+		value := byName[names[i]]
+		labels = append(labels, &dto.LabelPair{Name: &names[i], Value: &value})
+	}
+	return labels
+}
+
+func labelSetKey(labels []*dto.LabelPair) string {
+	var sb strings.Builder
+	for _, l := range labels {
+		sb.WriteString(l.GetName())
+		sb.WriteByte(0)
+		sb.WriteString(l.GetValue())
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+func sanitizeMetricName(name string) string {
+	name = invalidMetricNameChars.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+func sanitizeLabelName(name string) string {
+	name = invalidLabelNameChars.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
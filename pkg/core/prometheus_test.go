@@ -0,0 +1,91 @@
+package core
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/golib/v3/sfxclient"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatapointsToMetricFamilies(t *testing.T) {
+	dps := []*datapoint.Datapoint{
+		sfxclient.Gauge("sfxagent.go_num_goroutine", map[string]string{"host": "a"}, 20),
+		sfxclient.Cumulative("sfxagent.datapoints_sent", map[string]string{"host": "a"}, 100),
+		sfxclient.GaugeF("sfxagent.monitor_health", map[string]string{"monitor-id": "1", "host": "a"}, 0.5),
+		sfxclient.GaugeF("sfxagent.monitor_health", map[string]string{"monitor-id": "2", "host": "a"}, 1),
+		datapoint.New("sfxagent.requests", nil, datapoint.NewIntValue(3), datapoint.Count, testNow),
+		datapoint.New("sfxagent.version", nil, datapoint.NewStringValue("5.0"), datapoint.Gauge, testNow),
+	}
+
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.FmtText)
+	mfs := datapointsToMetricFamilies(dps)
+	require.Len(t, mfs, 4)
+	for _, mf := range mfs {
+		require.NoError(t, enc.Encode(mf))
+	}
+
+	require.Equal(t, `# HELP sfxagent_datapoints_sent_total Agent internal metric sfxagent.datapoints_sent (cumulative counter)
+# TYPE sfxagent_datapoints_sent_total counter
+sfxagent_datapoints_sent_total{host="a"} 100
+# HELP sfxagent_go_num_goroutine Agent internal metric sfxagent.go_num_goroutine (gauge)
+# TYPE sfxagent_go_num_goroutine gauge
+sfxagent_go_num_goroutine{host="a"} 20
+# HELP sfxagent_monitor_health Agent internal metric sfxagent.monitor_health (gauge)
+# TYPE sfxagent_monitor_health gauge
+sfxagent_monitor_health{host="a",monitor_id="1"} 0.5
+sfxagent_monitor_health{host="a",monitor_id="2"} 1
+# HELP sfxagent_requests Agent internal metric sfxagent.requests (delta counter)
+# TYPE sfxagent_requests untyped
+sfxagent_requests 3
+`, buf.String())
+}
+
+func TestDatapointsToMetricFamiliesDropsDuplicates(t *testing.T) {
+	dps := []*datapoint.Datapoint{
+		sfxclient.Gauge("sfxagent.queue", map[string]string{"monitor-id": "1", "monitor.id": "2"}, 1),
+		sfxclient.Gauge("sfxagent-queue", map[string]string{"monitor_id": "1"}, 2),
+		sfxclient.Gauge("sfxagent.queue", map[string]string{"monitor_id": "2"}, 3),
+	}
+
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.FmtText)
+	for _, mf := range datapointsToMetricFamilies(dps) {
+		require.NoError(t, enc.Encode(mf))
+	}
+
+	require.Equal(t, `# HELP sfxagent_queue Agent internal metric sfxagent.queue (gauge)
+# TYPE sfxagent_queue gauge
+sfxagent_queue{monitor_id="1"} 1
+sfxagent_queue{monitor_id="2"} 3
+`, buf.String())
+}
+
+func TestServePrometheusMetricsOpenMetrics(t *testing.T) {
+	dps := []*datapoint.Datapoint{
+		sfxclient.Cumulative("sfxagent.datapoints_sent", nil, 100),
+	}
+	req := httptest.NewRequest("GET", "/metrics/prometheus", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+	rw := httptest.NewRecorder()
+
+	servePrometheusMetrics(rw, req, dps)
+
+	require.Equal(t, 200, rw.Code)
+	require.Contains(t, rw.Header().Get("Content-Type"), "application/openmetrics-text")
+	require.Equal(t, `# HELP sfxagent_datapoints_sent Agent internal metric sfxagent.datapoints_sent (cumulative counter)
+# TYPE sfxagent_datapoints_sent counter
+sfxagent_datapoints_sent_total 100.0
+# EOF
+`, rw.Body.String())
+}
+
+func TestSanitizeMetricName(t *testing.T) {
+	require.Equal(t, "sfxagent_go_sys", sanitizeMetricName("sfxagent.go_sys"))
+	require.Equal(t, "_5xx_errors", sanitizeMetricName("5xx-errors"))
+	require.Equal(t, "kubernetes_pod_name", sanitizeLabelName("kubernetes.pod-name"))
+}
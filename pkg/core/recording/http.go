@@ -0,0 +1,101 @@
+package recording
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// The scheme and host are left out of the key since the address of
+// discovered endpoints usually changes between recording and replay
+func requestKey(req *http.Request) string {
+	return req.Method + " " + req.URL.RequestURI()
+}
+
+// WrapRoundTripper returns a round tripper that records the responses from
+// rt, or that replays recorded responses without using rt at all.
+func (mr *MonitorRecorder) WrapRoundTripper(rt http.RoundTripper) http.RoundTripper {
+	if mr.Replaying() {
+		return &replayingRoundTripper{recorder: mr}
+	}
+	return &recordingRoundTripper{recorder: mr, next: rt}
+}
+
+type recordingRoundTripper struct {
+	recorder *MonitorRecorder
+	next     http.RoundTripper
+}
+
+func (rrt *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	e := &Entry{
+		Kind:    KindHTTP,
+		Request: requestKey(req),
+	}
+
+	resp, err := rrt.next.RoundTrip(req)
+	if err != nil {
+		e.Error = err.Error()
+		_ = rrt.recorder.record(e)
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	e.StatusCode = resp.StatusCode
+	e.Header = resp.Header
+	e.Body = body
+	_ = rrt.recorder.record(e)
+
+	return resp, nil
+}
+
+type replayingRoundTripper struct {
+	recorder *MonitorRecorder
+}
+
+func (rrt *replayingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	key := requestKey(req)
+
+	mr := rrt.recorder
+	mr.lock.Lock()
+	responses := mr.httpResponses[key]
+	if len(responses) == 0 {
+		mr.lock.Unlock()
+		return nil, fmt.Errorf("no more recorded responses for %s %s", req.Method, req.URL)
+	}
+	e := responses[0]
+	mr.httpResponses[key] = responses[1:]
+	mr.lock.Unlock()
+
+	if e.Error != "" {
+		return nil, errors.New(e.Error)
+	}
+
+	header := e.Header
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,249 @@
+// Package recording captures the raw inputs that monitors receive, such as
+// HTTP responses and subprocess messages, into a bundle file and can replay
+// them later.  This makes it possible to run a monitor config offline against
+// exactly what a remote service returned, which is useful for reproducing
+// metric bugs without access to that service.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Modes of recording that can be configured
+const (
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+// Kinds of entries in a bundle
+const (
+	KindHTTP    = "http"
+	KindMessage = "message"
+)
+
+// Entry is a single recorded input of a monitor.  A bundle file consists of
+// one JSON encoded entry per line.
+type Entry struct {
+	// Identifies the monitor instance that received the input
+	Monitor string `json:"monitor"`
+	Kind    string `json:"kind"`
+	// How long after the previous input of the same monitor this one was
+	// received.  Used to pace subprocess messages when replaying.
+	Delay time.Duration `json:"delay"`
+
+	// The method and URL of the HTTP request
+	Request    string      `json:"request,omitempty"`
+	StatusCode int         `json:"statusCode,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	// The error that the request failed with, if any
+	Error string `json:"error,omitempty"`
+
+	// The type of the subprocess message
+	MessageType uint32 `json:"messageType,omitempty"`
+
+	// The response body or message payload
+	Body []byte `json:"body,omitempty"`
+}
+
+// Bundle is a file of recorded monitor inputs that is either being written
+// to or replayed from.  It is safe for concurrent use.
+type Bundle struct {
+	path      string
+	replaying bool
+
+	lock    sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	// Entries from a bundle being replayed, by monitor key
+	entries map[string][]*Entry
+}
+
+// Create a new bundle file at path that monitor inputs will be recorded to.
+// Any existing file at the path will be truncated.
+func Create(path string) (*Bundle, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := bufio.NewWriter(f)
+	return &Bundle{
+		path:    path,
+		file:    f,
+		writer:  w,
+		encoder: json.NewEncoder(w),
+	}, nil
+}
+
+// Open an existing bundle file for replay
+func Open(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b := &Bundle{
+		path:      path,
+		replaying: true,
+		entries:   map[string][]*Entry{},
+	}
+
+	dec := json.NewDecoder(f)
+	for {
+		var e Entry
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("bundle %s is corrupt: %v", path, err)
+		}
+		b.entries[e.Monitor] = append(b.entries[e.Monitor], &e)
+	}
+
+	return b, nil
+}
+
+// Path of the bundle file
+func (b *Bundle) Path() string {
+	return b.path
+}
+
+// Replaying returns true if the bundle is being replayed instead of recorded
+func (b *Bundle) Replaying() bool {
+	return b.replaying
+}
+
+// Monitors returns the keys of the monitor instances that have inputs in a
+// bundle being replayed, sorted
+func (b *Bundle) Monitors() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	keys := make([]string, 0, len(b.entries))
+	for key := range b.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Monitor returns the recorder for a single monitor instance.  The key must
+// be the same between recording and replay for the inputs to be found.
+func (b *Bundle) Monitor(key string) *MonitorRecorder {
+	mr := &MonitorRecorder{
+		bundle: b,
+		key:    key,
+		last:   time.Now(),
+	}
+
+	if b.replaying {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		mr.httpResponses = map[string][]*Entry{}
+		for _, e := range b.entries[key] {
+			switch e.Kind {
+			case KindHTTP:
+				mr.httpResponses[e.Request] = append(mr.httpResponses[e.Request], e)
+			case KindMessage:
+				mr.messages = append(mr.messages, e)
+			}
+		}
+	}
+	return mr
+}
+
+func (b *Bundle) write(e *Entry) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.encoder == nil {
+		return fmt.Errorf("bundle %s is closed", b.path)
+	}
+	if err := b.encoder.Encode(e); err != nil {
+		return err
+	}
+	// Flush every entry so that the bundle is usable even if the agent is
+	// killed without shutting down.
+	return b.writer.Flush()
+}
+
+// Close the bundle, after which nothing else will be recorded
+func (b *Bundle) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.file == nil {
+		return nil
+	}
+
+	err := b.writer.Flush()
+	if closeErr := b.file.Close(); err == nil {
+		err = closeErr
+	}
+	b.file = nil
+	b.encoder = nil
+	return err
+}
+
+// MonitorRecorder records or replays the inputs of a single monitor instance
+type MonitorRecorder struct {
+	bundle *Bundle
+	key    string
+
+	lock sync.Mutex
+	// When the previous input was recorded
+	last time.Time
+	// Recorded responses that haven't been replayed yet, by request
+	httpResponses map[string][]*Entry
+	messages      []*Entry
+}
+
+// Replaying returns true if inputs should come from the bundle instead of the
+// actual service
+func (mr *MonitorRecorder) Replaying() bool {
+	return mr.bundle.replaying
+}
+
+func (mr *MonitorRecorder) record(e *Entry) error {
+	mr.lock.Lock()
+	now := time.Now()
+	e.Monitor = mr.key
+	e.Delay = now.Sub(mr.last)
+	mr.last = now
+	mr.lock.Unlock()
+
+	return mr.bundle.write(e)
+}
+
+// RecordMessage records a message that was received from a subprocess
+func (mr *MonitorRecorder) RecordMessage(msgType uint32, payload []byte) error {
+	return mr.record(&Entry{
+		Kind:        KindMessage,
+		MessageType: msgType,
+		Body:        payload,
+	})
+}
+
+// NextMessage returns the next recorded subprocess message, or nil if there
+// are no more.
+func (mr *MonitorRecorder) NextMessage() *Entry {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	if len(mr.messages) == 0 {
+		return nil
+	}
+	e := mr.messages[0]
+	mr.messages = mr.messages[1:]
+	return e
+}
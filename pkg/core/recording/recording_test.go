@@ -0,0 +1,127 @@
+package recording
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func tempBundlePath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "recording")
+	require.Nil(t, err)
+	return filepath.Join(dir, "bundle.json"), func() { os.RemoveAll(dir) }
+}
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	resp, err := client.Get(url)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path, cleanup := tempBundlePath(t)
+	defer cleanup()
+
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		count++
+		if r.URL.Path == "/missing" {
+			rw.WriteHeader(404)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(rw, "response %d", count)
+	}))
+
+	bundle, err := Create(path)
+	require.Nil(t, err)
+
+	mr := bundle.Monitor("collectd/nginx/abc")
+	client := &http.Client{Transport: mr.WrapRoundTripper(http.DefaultTransport)}
+
+	for _, p := range []string{"/status", "/status", "/missing"} {
+		_, _ = get(t, client, server.URL+p)
+	}
+	require.Nil(t, mr.RecordMessage(1, []byte("first")))
+	require.Nil(t, mr.RecordMessage(2, []byte("second")))
+	// Inputs of other monitors aren't mixed in
+	require.Nil(t, bundle.Monitor("other").RecordMessage(1, []byte("other")))
+	require.Nil(t, bundle.Close())
+
+	server.Close()
+
+	bundle, err = Open(path)
+	require.Nil(t, err)
+	require.True(t, bundle.Replaying())
+	require.Equal(t, []string{"collectd/nginx/abc", "other"}, bundle.Monitors())
+
+	mr = bundle.Monitor("collectd/nginx/abc")
+	require.True(t, mr.Replaying())
+	client = &http.Client{Transport: mr.WrapRoundTripper(nil)}
+
+	status, body := get(t, client, server.URL+"/status")
+	require.Equal(t, 200, status)
+	require.Equal(t, "response 1", body)
+
+	status, body = get(t, client, server.URL+"/status")
+	require.Equal(t, 200, status)
+	require.Equal(t, "response 2", body)
+
+	// Responses are found even if the endpoint has a different address
+	status, _ = get(t, client, "http://192.0.2.1:8080/missing")
+	require.Equal(t, 404, status)
+
+	_, err = client.Get(server.URL + "/status")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "no more recorded responses for GET "+server.URL+"/status")
+
+	msg := mr.NextMessage()
+	require.Equal(t, uint32(1), msg.MessageType)
+	require.Equal(t, []byte("first"), msg.Body)
+	msg = mr.NextMessage()
+	require.Equal(t, uint32(2), msg.MessageType)
+	require.Equal(t, []byte("second"), msg.Body)
+	require.Nil(t, mr.NextMessage())
+}
+
+func TestRecordRequestError(t *testing.T) {
+	path, cleanup := tempBundlePath(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	bundle, err := Create(path)
+	require.Nil(t, err)
+	client := &http.Client{Transport: bundle.Monitor("m").WrapRoundTripper(http.DefaultTransport)}
+	_, recordErr := client.Get(url)
+	require.NotNil(t, recordErr)
+	require.Nil(t, bundle.Close())
+
+	bundle, err = Open(path)
+	require.Nil(t, err)
+	client = &http.Client{Transport: bundle.Monitor("m").WrapRoundTripper(nil)}
+	_, replayErr := client.Get(url)
+	require.NotNil(t, replayErr)
+	require.Equal(t, recordErr.Error(), replayErr.Error())
+}
+
+func TestOpenCorruptBundle(t *testing.T) {
+	path, cleanup := tempBundlePath(t)
+	defer cleanup()
+
+	require.Nil(t, ioutil.WriteFile(path, []byte("{not json"), 0600))
+	_, err := Open(path)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/common/auth"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+)
+
+// Makes the TLS config for the status server if TLS is enabled
+func statusServerTLSConfig(conf *config.InternalStatusServerConfig) (*tls.Config, error) {
+	if !conf.TLSEnabled() {
+		return nil, nil
+	}
+
+	tlsConf := &tls.Config{}
+	if conf.ClientCACertPath != "" {
+		pool := x509.NewCertPool()
+		if err := auth.AugmentCertPoolFromCAFile(pool, conf.ClientCACertPath); err != nil {
+			return nil, err
+		}
+		tlsConf.ClientCAs = pool
+		// Client certs are enforced by requireStatusAuth so that the health
+		// paths can still be used by probes that can't present one.
+		tlsConf.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConf, nil
+}
+
+// Wraps the status server handler so that all requests, other than to the
+// health paths, must be authenticated as configured
+func requireStatusAuth(handler http.Handler, conf *config.InternalStatusServerConfig) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/healthz" || req.URL.Path == "/readyz" {
+			handler.ServeHTTP(rw, req)
+			return
+		}
+
+		if conf.ClientCACertPath != "" && (req.TLS == nil || len(req.TLS.VerifiedChains) == 0) {
+			rw.WriteHeader(401)
+			_, _ = rw.Write([]byte("a valid client certificate is required"))
+			return
+		}
+
+		if !statusRequestAuthorized(req, conf) {
+			if conf.Username != "" {
+				rw.Header().Set("WWW-Authenticate", `Basic realm="signalfx-agent"`)
+			}
+			rw.WriteHeader(401)
+			_, _ = rw.Write([]byte("unauthorized"))
+			return
+		}
+
+		handler.ServeHTTP(rw, req)
+	})
+}
+
+func statusRequestAuthorized(req *http.Request, conf *config.InternalStatusServerConfig) bool {
+	if conf.BearerToken == "" && conf.Username == "" {
+		return true
+	}
+
+	if conf.BearerToken != "" {
+		const prefix = "bearer "
+		header := req.Header.Get("Authorization")
+		if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) &&
+			subtle.ConstantTimeCompare([]byte(header[len(prefix):]), []byte(conf.BearerToken)) == 1 {
+			return true
+		}
+	}
+
+	if conf.Username != "" {
+		username, password, ok := req.BasicAuth()
+		// Check both so that the time taken doesn't reveal which was wrong
+		userOK := subtle.ConstantTimeCompare([]byte(username), []byte(conf.Username)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(password), []byte(conf.Password)) == 1
+		if ok && userOK && passOK {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Makes a GET request to the given path (which can include a query string)
+// of the status server of a running agent with the given config.  A timeout
+// of 0 means no timeout.  The caller must close the response body.
+func getFromStatusServer(conf *config.Config, path string, timeout time.Duration) (*http.Response, error) {
+	client, err := conf.InternalStatusServer.NewClient(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s://%s:%d%s", conf.InternalStatusServer.Scheme(), conf.InternalStatusHost, conf.InternalStatusPort, path)
+	resp, err := client.Get(url) // nolint:bodyclose
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("request to %s failed with status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireStatusAuth(t *testing.T) {
+	serverConf := &config.InternalStatusServerConfig{
+		BearerToken: "s3cret",
+		Username:    "admin",
+		Password:    "hunter2",
+	}
+	handler := requireStatusAuth(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(200)
+	}), serverConf)
+
+	do := func(path string, setAuth func(req *http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if setAuth != nil {
+			setAuth(req)
+		}
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req)
+		return rw
+	}
+
+	rw := do("/tap-dps", nil)
+	require.Equal(t, 401, rw.Code)
+	require.Equal(t, `Basic realm="signalfx-agent"`, rw.Header().Get("WWW-Authenticate"))
+
+	require.Equal(t, 200, do("/tap-dps", func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer s3cret")
+	}).Code)
+	require.Equal(t, 401, do("/tap-dps", func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer wrong")
+	}).Code)
+	require.Equal(t, 200, do("/", func(req *http.Request) {
+		req.SetBasicAuth("admin", "hunter2")
+	}).Code)
+	require.Equal(t, 401, do("/", func(req *http.Request) {
+		req.SetBasicAuth("admin", "hunter3")
+	}).Code)
+
+	// Probes don't need credentials
+	require.Equal(t, 200, do("/healthz", nil).Code)
+	require.Equal(t, 200, do("/readyz", nil).Code)
+
+	// A client cert is required if a client CA is configured
+	serverConf.ClientCACertPath = "/etc/ca.pem"
+	require.Equal(t, 401, do("/", func(req *http.Request) {
+		req.SetBasicAuth("admin", "hunter2")
+	}).Code)
+}
+
+func TestGetFromStatusServer(t *testing.T) {
+	server := httptest.NewServer(requireStatusAuth(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(req.URL.RawQuery))
+	}), &config.InternalStatusServerConfig{BearerToken: "s3cret"}))
+	defer server.Close()
+
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	conf := &config.Config{
+		InternalStatusHost: host,
+		InternalStatusPort: uint16(port),
+	}
+
+	_, err = getFromStatusServer(conf, "/", 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "401")
+
+	conf.InternalStatusServer.BearerToken = "s3cret"
+	resp, err := getFromStatusServer(conf, "/?section=monitors", 0)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "section=monitors", string(body))
+}
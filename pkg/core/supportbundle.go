@@ -0,0 +1,210 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/collectd"
+	log "github.com/sirupsen/logrus"
+)
+
+// The most recent log lines of the agent process
+var (
+	recentLogs     = newLogHistory(logHistorySize) //nolint: gochecknoglobals
+	recentLogsOnce sync.Once                       //nolint: gochecknoglobals
+)
+
+// Start keeping the most recent log lines in memory for support bundles
+func captureRecentLogs() {
+	recentLogsOnce.Do(func() {
+		log.AddHook(recentLogs)
+	})
+}
+
+// The status sections that are included in the bundle, by the file name they
+// are written to
+var supportBundleStatusSections = map[string]string{
+	"summary":   "",
+	"monitors":  "monitors",
+	"endpoints": "endpoints",
+}
+
+// Matches the values of options in rendered collectd config that are likely
+// to be secrets
+var collectdSecretOptionRegexp = regexp.MustCompile(
+	`(?im)^(\s*"?\w*(?:password|passwd|secret|token|apikey|credential)\w*"?\s+).+$`)
+
+func redactCollectdConfig(content []byte) []byte {
+	return collectdSecretOptionRegexp.ReplaceAll(content, []byte(`${1}"***************"`))
+}
+
+// supportBundle writes files into a gzipped tarball.  Files that can't be
+// gathered are noted in an errors.txt file at the end instead of failing the
+// whole bundle.
+type supportBundle struct {
+	tw      *tar.Writer
+	modTime time.Time
+	err     error
+	// Problems gathering individual files
+	problems []string
+}
+
+func (sb *supportBundle) addFile(name string, content []byte) {
+	if sb.err != nil {
+		return
+	}
+
+	sb.err = sb.tw.WriteHeader(&tar.Header{
+		Name:    filepath.ToSlash(filepath.Join("signalfx-agent-support", name)),
+		Mode:    0600,
+		Size:    int64(len(content)),
+		ModTime: sb.modTime,
+	})
+	if sb.err != nil {
+		return
+	}
+	_, sb.err = sb.tw.Write(content)
+}
+
+func (sb *supportBundle) addJSON(name string, v interface{}) {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		sb.addProblem(name, err)
+		return
+	}
+	sb.addFile(name, content)
+}
+
+func (sb *supportBundle) addProfile(name string, profile string, debug int) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup(profile).WriteTo(&buf, debug); err != nil {
+		sb.addProblem(name, err)
+		return
+	}
+	sb.addFile(name, buf.Bytes())
+}
+
+// Add all of the files in dir underneath the bundle dir prefix, passing their
+// content through transform
+func (sb *supportBundle) addDir(prefix string, dir string, transform func([]byte) []byte) {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			sb.addProblem(path, err)
+			return nil
+		}
+		sb.addFile(filepath.Join(prefix, rel), transform(content))
+		return sb.err
+	})
+	if err != nil && sb.err == nil {
+		sb.addProblem(dir, err)
+	}
+}
+
+func (sb *supportBundle) addProblem(name string, err error) {
+	sb.problems = append(sb.problems, fmt.Sprintf("%s: %v", name, err))
+}
+
+// WriteSupportBundle writes a gzipped tarball with everything that is useful
+// for troubleshooting the agent to w.  Any sensitive config values are
+// redacted.
+func (a *Agent) WriteSupportBundle(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	sb := &supportBundle{
+		tw:      tar.NewWriter(gz),
+		modTime: time.Now(),
+	}
+
+	sb.addFile("version.txt", []byte(VersionLine))
+	// This respects the neverLog tags on config fields
+	sb.addFile("config.txt", []byte(config.ToString(a.lastConfig)))
+
+	for name, section := range supportBundleStatusSections {
+		sb.addFile(filepath.Join("status", name+".txt"), []byte(a.DiagnosticText(section)))
+	}
+
+	sb.addJSON(filepath.Join("status", "monitors.json"), a.monitors.MonitorStatuses())
+	sb.addJSON("internal-metrics.json", a.InternalMetrics())
+	sb.addJSON("host-id-dims.json", a.hostIDDims)
+	sb.addFile("logs.txt", recentLogs.Bytes())
+
+	sb.addProfile(filepath.Join("profiles", "goroutine.txt"), "goroutine", 2)
+	sb.addProfile(filepath.Join("profiles", "heap.pprof"), "heap", 0)
+
+	if dir := collectd.MainManagedConfigDir(); dir != "" {
+		sb.addDir(filepath.Join("collectd", "managed_config"), dir, redactCollectdConfig)
+	}
+
+	if len(sb.problems) > 0 {
+		var buf bytes.Buffer
+		for _, p := range sb.problems {
+			fmt.Fprintln(&buf, p)
+		}
+		sb.addFile("errors.txt", buf.Bytes())
+	}
+
+	if sb.err != nil {
+		return sb.err
+	}
+	if err := sb.tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (a *Agent) supportBundleHandler(rw http.ResponseWriter, req *http.Request) {
+	serveSupportBundle(rw, a.WriteSupportBundle)
+}
+
+// Builds the whole bundle first so that a failure partway through is reported
+// with a 500 status instead of sending a truncated tarball with a 200 status
+func serveSupportBundle(rw http.ResponseWriter, writeBundle func(io.Writer) error) {
+	var buf bytes.Buffer
+	if err := writeBundle(&buf); err != nil {
+		log.WithError(err).Error("Could not write support bundle")
+		rw.WriteHeader(500)
+		_, _ = rw.Write([]byte(err.Error()))
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/gzip")
+	rw.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	rw.WriteHeader(200)
+
+	_, _ = buf.WriteTo(rw)
+}
+
+func readSupportBundle(conf *config.Config) (io.ReadCloser, error) {
+	resp, err := getFromStatusServer(conf, "/support-bundle", 0) // nolint:bodyclose
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
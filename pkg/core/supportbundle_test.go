@@ -0,0 +1,101 @@
+package core
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogHistoryKeepsMostRecent(t *testing.T) {
+	lh := newLogHistory(3)
+	logger := log.New()
+	logger.AddHook(lh)
+	logger.Out = &strings.Builder{}
+
+	logger.Info("one")
+	require.Contains(t, string(lh.Bytes()), "msg=one")
+
+	for _, msg := range []string{"two", "three", "four", "five"} {
+		logger.Info(msg)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(lh.Bytes())), "\n")
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[0], "msg=three")
+	require.Contains(t, lines[1], "msg=four")
+	require.Contains(t, lines[2], "msg=five")
+}
+
+func TestRedactCollectdConfig(t *testing.T) {
+	conf := `
+<Plugin mysql>
+  <Database "db">
+    Host "localhost"
+    User "admin"
+    Password "hunter2"
+  </Database>
+</Plugin>
+<Plugin python>
+  <Module redis_info>
+    Auth_Token "abc"
+  </Module>
+</Plugin>
+`
+	out := string(redactCollectdConfig([]byte(conf)))
+
+	require.NotContains(t, out, "hunter2")
+	require.NotContains(t, out, "abc")
+	require.Contains(t, out, `    Password "***************"`)
+	require.Contains(t, out, `    User "admin"`)
+}
+
+func TestServeSupportBundleSendsCompleteTarball(t *testing.T) {
+	rw := httptest.NewRecorder()
+	serveSupportBundle(rw, func(w io.Writer) error {
+		gz := gzip.NewWriter(w)
+		sb := &supportBundle{tw: tar.NewWriter(gz)}
+		sb.addFile("version.txt", []byte("1.0"))
+		sb.addFile("logs.txt", []byte("msg=one"))
+		require.NoError(t, sb.err)
+		require.NoError(t, sb.tw.Close())
+		return gz.Close()
+	})
+	require.Equal(t, 200, rw.Code)
+	require.Equal(t, "application/gzip", rw.Header().Get("Content-Type"))
+	require.Equal(t, strconv.Itoa(rw.Body.Len()), rw.Header().Get("Content-Length"))
+
+	gz, err := gzip.NewReader(rw.Body)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+	}
+	require.NoError(t, gz.Close())
+	require.Equal(t, []string{"signalfx-agent-support/version.txt", "signalfx-agent-support/logs.txt"}, names)
+}
+
+func TestServeSupportBundleFailsWithoutPartialTarball(t *testing.T) {
+	rw := httptest.NewRecorder()
+	serveSupportBundle(rw, func(w io.Writer) error {
+		_, _ = w.Write([]byte("partial"))
+		return errors.New("disk on fire")
+	})
+	require.Equal(t, 500, rw.Code)
+	require.Equal(t, "disk on fire", rw.Body.String())
+	require.Empty(t, rw.Header().Get("Content-Type"))
+}
@@ -0,0 +1,444 @@
+// Package aggregation rolls up datapoints into aggregates that are grouped by
+// a subset of their dimensions, so that high cardinality series can be sent
+// at a coarser granularity.
+package aggregation
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/golib/v3/sfxclient"
+	"github.com/signalfx/signalfx-agent/pkg/core/common/dpmeta"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+	"github.com/signalfx/signalfx-agent/pkg/utils/filter"
+)
+
+// Marks datapoints generated by the aggregator so that they aren't aggregated
+// again when they come back through the writer
+const aggregatedMeta = "sfx-aggregated"
+
+const (
+	defaultInterval     = 10 * time.Second
+	defaultStaleTimeout = 5 * time.Minute
+)
+
+// The values of a single source series within an interval
+type seriesValue struct {
+	last  float64
+	total float64
+}
+
+type group struct {
+	metric          string
+	dims            map[string]string
+	metricType      datapoint.MetricType
+	monitorType     string
+	notHostSpecific bool
+	// Keyed by the full set of source dimensions
+	series map[string]*seriesValue
+}
+
+// The running sum of the cumulative counters in a group.  It is kept across
+// intervals and only ever goes up, so that series that miss an interval or go
+// away don't look like a counter reset.
+type counterSum struct {
+	group *group
+	total float64
+	// Keyed by the full set of source dimensions
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	last     float64
+	lastSeen time.Time
+}
+
+type rule struct {
+	conf         *config.DatapointAggregationConfig
+	metricFilter *filter.BasicStringFilter
+	aggregations []string
+	interval     time.Duration
+	staleTimeout time.Duration
+	sumCounters  bool
+
+	lock   sync.Mutex
+	groups map[string]*group
+	// By group key, only used if the sum is one of the aggregations
+	counterSums map[string]*counterSum
+}
+
+// Aggregator applies the aggregation rules to the datapoints it is given and
+// periodically sends the aggregates.  It is thread-safe.
+type Aggregator struct {
+	rules   []*rule
+	timeNow func() time.Time
+
+	// Internal metrics
+	datapointsAggregated int64
+	datapointsDropped    int64
+	aggregatesSent       int64
+}
+
+// New creates an Aggregator from the given rules
+func New(confs []config.DatapointAggregationConfig) (*Aggregator, error) {
+	a := &Aggregator{
+		timeNow: time.Now,
+	}
+
+	for i := range confs {
+		conf := &confs[i]
+		if err := conf.Validate(); err != nil {
+			return nil, err
+		}
+
+		metricFilter, err := filter.NewBasicStringFilter(conf.MetricNames)
+		if err != nil {
+			return nil, err
+		}
+
+		r := &rule{
+			conf:         conf,
+			metricFilter: metricFilter,
+			aggregations: conf.Aggregations,
+			interval:     conf.Interval.AsDuration(),
+			staleTimeout: conf.StaleTimeout.AsDuration(),
+			groups:       make(map[string]*group),
+			counterSums:  make(map[string]*counterSum),
+		}
+		if len(r.aggregations) == 0 {
+			r.aggregations = []string{config.AggregationSum}
+		}
+		if r.interval == 0 {
+			r.interval = defaultInterval
+		}
+		if r.staleTimeout == 0 {
+			r.staleTimeout = defaultStaleTimeout
+		}
+		for _, agg := range r.aggregations {
+			if agg == config.AggregationSum {
+				r.sumCounters = true
+			}
+		}
+		a.rules = append(a.rules, r)
+	}
+
+	return a, nil
+}
+
+// Start sending the aggregates of each rule on its interval until the context
+// is cancelled.  Nothing is sent right away so that an aggregator that is
+// started again, e.g. by a writer that takes it over, doesn't cut the current
+// interval short.
+func (a *Aggregator) Start(ctx context.Context, send func([]*datapoint.Datapoint)) {
+	for i := range a.rules {
+		r := a.rules[i]
+		go func() {
+			ticker := time.NewTicker(r.interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if dps := a.flush(r); len(dps) > 0 {
+						send(dps)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Add the datapoint to the aggregates of all of the rules that it matches.
+// Returns false if the datapoint should not be sent itself.
+func (a *Aggregator) Add(dp *datapoint.Datapoint) bool {
+	if _, ok := dp.Meta[aggregatedMeta]; ok {
+		return true
+	}
+
+	monitorType, _ := dp.Meta[dpmeta.MonitorTypeMeta].(string)
+	now := a.timeNow()
+
+	keep := true
+	for _, r := range a.rules {
+		if r.conf.MonitorType != "" && r.conf.MonitorType != monitorType {
+			continue
+		}
+		if !r.metricFilter.Matches(dp.Metric) {
+			continue
+		}
+
+		value, ok := floatValue(dp.Value)
+		if !ok {
+			continue
+		}
+
+		r.add(dp, monitorType, value, now)
+		atomic.AddInt64(&a.datapointsAggregated, 1)
+
+		if r.conf.DropSourceSeries {
+			keep = false
+		}
+	}
+
+	if !keep {
+		atomic.AddInt64(&a.datapointsDropped, 1)
+	}
+	return keep
+}
+
+func (r *rule) add(dp *datapoint.Datapoint, monitorType string, value float64, now time.Time) {
+	dims := make(map[string]string, len(r.conf.GroupBy))
+	for _, dim := range r.conf.GroupBy {
+		if v, ok := dp.Dimensions[dim]; ok {
+			dims[dim] = v
+		}
+	}
+	notHostSpecific, _ := dp.Meta[dpmeta.NotHostSpecificMeta].(bool)
+
+	groupKey := strings.Join([]string{
+		dp.Metric, monitorType, strconv.Itoa(int(dp.MetricType)),
+		strconv.FormatBool(notHostSpecific), dimsKey(dims)}, "|")
+	sourceKey := dimsKey(dp.Dimensions)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	g, ok := r.groups[groupKey]
+	if !ok {
+		g = &group{
+			metric:          dp.Metric,
+			dims:            dims,
+			metricType:      dp.MetricType,
+			monitorType:     monitorType,
+			notHostSpecific: notHostSpecific,
+			series:          make(map[string]*seriesValue),
+		}
+		r.groups[groupKey] = g
+	}
+
+	sv, ok := g.series[sourceKey]
+	if !ok {
+		sv = &seriesValue{}
+		g.series[sourceKey] = sv
+	}
+	sv.last = value
+	sv.total += value
+
+	if r.sumCounters && g.metricType == datapoint.Counter {
+		r.addToCounterSum(groupKey, g, sourceKey, value, now)
+	}
+}
+
+// Adds the increase of the series since its last value to the running sum of
+// its group.  A series that is new, or that went down because it was reset,
+// adds its whole value.  Must be called with the lock held.
+func (r *rule) addToCounterSum(groupKey string, g *group, sourceKey string, value float64, now time.Time) {
+	cs, ok := r.counterSums[groupKey]
+	if !ok {
+		cs = &counterSum{
+			group: &group{
+				metric:          g.metric,
+				dims:            g.dims,
+				metricType:      g.metricType,
+				monitorType:     g.monitorType,
+				notHostSpecific: g.notHostSpecific,
+			},
+			series: make(map[string]*counterSeries),
+		}
+		r.counterSums[groupKey] = cs
+	}
+
+	s, ok := cs.series[sourceKey]
+	switch {
+	case !ok:
+		s = &counterSeries{}
+		cs.series[sourceKey] = s
+		cs.total += value
+	case value >= s.last:
+		cs.total += value - s.last
+	default:
+		cs.total += value
+	}
+	s.last = value
+	s.lastSeen = now
+}
+
+// Forgets the series that haven't been seen within the stale timeout, and
+// the sums that have no series left.  Must be called with the lock held.
+func (r *rule) purgeStaleCounterSeries(now time.Time) {
+	for groupKey, cs := range r.counterSums {
+		for sourceKey, s := range cs.series {
+			if now.Sub(s.lastSeen) > r.staleTimeout {
+				delete(cs.series, sourceKey)
+			}
+		}
+		if len(cs.series) == 0 {
+			delete(r.counterSums, groupKey)
+		}
+	}
+}
+
+func dimsKey(dims map[string]string) string {
+	keys := make([]string, 0, len(dims))
+	for k := range dims {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(dims[k])
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+func floatValue(v datapoint.Value) (float64, bool) {
+	switch val := v.(type) {
+	case datapoint.IntValue:
+		return float64(val.Int()), true
+	case datapoint.FloatValue:
+		return val.Float(), true
+	}
+	return 0, false
+}
+
+// Generates the aggregates of all of the groups that received datapoints
+// since the last flush and starts a new interval.  The sums of cumulative
+// counters are sent for as long as any of their series aren't stale.
+func (a *Aggregator) flush(r *rule) []*datapoint.Datapoint {
+	now := a.timeNow()
+
+	r.lock.Lock()
+	groups := r.groups
+	r.groups = make(map[string]*group)
+
+	r.purgeStaleCounterSeries(now)
+	var out []*datapoint.Datapoint
+	for _, cs := range r.counterSums {
+		dp := datapoint.New(cs.group.metric+"."+config.AggregationSum, utils.CloneStringMap(cs.group.dims),
+			datapoint.NewFloatValue(cs.total), datapoint.Counter, now)
+		out = append(out, cs.group.finish(dp))
+	}
+	r.lock.Unlock()
+
+	for _, g := range groups {
+		values := make([]float64, 0, len(g.series))
+		for _, sv := range g.series {
+			if g.metricType == datapoint.Count {
+				// Delta counts have to be added up to not lose anything
+				values = append(values, sv.total)
+			} else {
+				values = append(values, sv.last)
+			}
+		}
+		sort.Float64s(values)
+
+		for _, agg := range r.aggregations {
+			if agg == config.AggregationSum && g.metricType == datapoint.Counter {
+				// Sent from the running sum above
+				continue
+			}
+			dp := g.aggregate(agg, values, now)
+			if dp == nil {
+				continue
+			}
+			out = append(out, g.finish(dp))
+		}
+	}
+
+	atomic.AddInt64(&a.aggregatesSent, int64(len(out)))
+	return out
+}
+
+// Sets the meta of an aggregate of the group
+func (g *group) finish(dp *datapoint.Datapoint) *datapoint.Datapoint {
+	dp.Meta[aggregatedMeta] = true
+	if g.monitorType != "" {
+		dp.Meta[dpmeta.MonitorTypeMeta] = g.monitorType
+	}
+	if g.notHostSpecific {
+		dp.Meta[dpmeta.NotHostSpecificMeta] = true
+	}
+	return dp
+}
+
+// Returns nil if the aggregation doesn't apply to the group.  values must be
+// sorted and not empty.
+func (g *group) aggregate(agg string, values []float64, now time.Time) *datapoint.Datapoint {
+	var value float64
+	metricType := datapoint.Gauge
+
+	switch agg {
+	case config.AggregationSum:
+		value = sum(values)
+		metricType = g.metricType
+	case config.AggregationAvg:
+		value = sum(values) / float64(len(values))
+	case config.AggregationMin:
+		value = values[0]
+	case config.AggregationMax:
+		value = values[len(values)-1]
+	case config.AggregationCount:
+		return datapoint.New(g.metric+"."+agg, utils.CloneStringMap(g.dims),
+			datapoint.NewIntValue(int64(len(values))), datapoint.Gauge, now)
+	default:
+		if g.metricType != datapoint.Gauge {
+			return nil
+		}
+		p, err := config.ParsePercentile(agg)
+		if err != nil {
+			return nil
+		}
+		value = percentile(values, p)
+	}
+
+	return datapoint.New(g.metric+"."+agg, utils.CloneStringMap(g.dims),
+		datapoint.NewFloatValue(value), metricType, now)
+}
+
+func sum(values []float64) float64 {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// The nearest-rank percentile of the sorted values
+func percentile(values []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(values))))
+	if rank < 1 {
+		rank = 1
+	}
+	return values[rank-1]
+}
+
+// InternalMetrics returns datapoints describing the state of the aggregator
+func (a *Aggregator) InternalMetrics() []*datapoint.Datapoint {
+	var activeGroups int64
+	for _, r := range a.rules {
+		r.lock.Lock()
+		activeGroups += int64(len(r.groups))
+		r.lock.Unlock()
+	}
+
+	return []*datapoint.Datapoint{
+		sfxclient.Gauge("sfxagent.datapoint_aggregation_active_groups", nil, activeGroups),
+		sfxclient.CumulativeP("sfxagent.datapoint_aggregation_datapoints_aggregated", nil, &a.datapointsAggregated),
+		sfxclient.CumulativeP("sfxagent.datapoint_aggregation_datapoints_dropped", nil, &a.datapointsDropped),
+		sfxclient.CumulativeP("sfxagent.datapoint_aggregation_aggregates_sent", nil, &a.aggregatesSent),
+	}
+}
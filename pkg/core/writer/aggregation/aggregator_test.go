@@ -0,0 +1,185 @@
+package aggregation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/core/common/dpmeta"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/utils/timeutil"
+	"github.com/stretchr/testify/require"
+)
+
+func makeDP(metric string, dims map[string]string, value float64, metricType datapoint.MetricType) *datapoint.Datapoint {
+	dp := datapoint.New(metric, dims, datapoint.NewFloatValue(value), metricType, time.Now())
+	dp.Meta[dpmeta.MonitorTypeMeta] = "kubelet-stats"
+	return dp
+}
+
+func valuesByMetric(dps []*datapoint.Datapoint, dims map[string]string) map[string]string {
+	out := map[string]string{}
+outer:
+	for _, dp := range dps {
+		if len(dp.Dimensions) != len(dims) {
+			continue
+		}
+		for k, v := range dims {
+			if dp.Dimensions[k] != v {
+				continue outer
+			}
+		}
+		out[dp.Metric] = dp.Value.String()
+	}
+	return out
+}
+
+func TestAggregator(t *testing.T) {
+	a, err := New([]config.DatapointAggregationConfig{
+		{
+			MonitorType:  "kubelet-stats",
+			MetricNames:  []string{"container_cpu_*"},
+			GroupBy:      []string{"kubernetes_namespace", "deployment"},
+			Aggregations: []string{"sum", "avg", "min", "max", "count", "p50", "p100"},
+		},
+	})
+	require.NoError(t, err)
+
+	pod := func(name, deployment string) map[string]string {
+		return map[string]string{"kubernetes_namespace": "default", "deployment": deployment, "kubernetes_pod_name": name}
+	}
+
+	require.True(t, a.Add(makeDP("container_cpu_utilization", pod("a", "web"), 10, datapoint.Gauge)))
+	// Only the last value of a series in the interval counts
+	require.True(t, a.Add(makeDP("container_cpu_utilization", pod("a", "web"), 20, datapoint.Gauge)))
+	require.True(t, a.Add(makeDP("container_cpu_utilization", pod("b", "web"), 40, datapoint.Gauge)))
+	require.True(t, a.Add(makeDP("container_cpu_utilization", pod("c", "web"), 30, datapoint.Gauge)))
+	require.True(t, a.Add(makeDP("container_cpu_utilization", pod("d", "db"), 5, datapoint.Gauge)))
+	// Other metrics and monitors aren't aggregated
+	require.True(t, a.Add(makeDP("container_memory_usage_bytes", pod("a", "web"), 100, datapoint.Gauge)))
+	other := makeDP("container_cpu_utilization", pod("a", "web"), 100, datapoint.Gauge)
+	other.Meta[dpmeta.MonitorTypeMeta] = "docker-container-stats"
+	require.True(t, a.Add(other))
+
+	dps := a.flush(a.rules[0])
+	require.Equal(t, map[string]string{
+		"container_cpu_utilization.sum":   "90",
+		"container_cpu_utilization.avg":   "30",
+		"container_cpu_utilization.min":   "20",
+		"container_cpu_utilization.max":   "40",
+		"container_cpu_utilization.count": "3",
+		"container_cpu_utilization.p50":   "30",
+		"container_cpu_utilization.p100":  "40",
+	}, valuesByMetric(dps, map[string]string{"kubernetes_namespace": "default", "deployment": "web"}))
+	require.Equal(t, "5", valuesByMetric(dps, map[string]string{"kubernetes_namespace": "default", "deployment": "db"})["container_cpu_utilization.sum"])
+	require.Len(t, dps, 14)
+
+	for _, dp := range dps {
+		require.Equal(t, "kubelet-stats", dp.Meta[dpmeta.MonitorTypeMeta])
+		// Aggregates aren't aggregated again
+		require.True(t, a.Add(dp))
+	}
+
+	// Nothing is sent for an interval without datapoints
+	require.Len(t, a.flush(a.rules[0]), 0)
+}
+
+func TestAggregatorCounters(t *testing.T) {
+	a, err := New([]config.DatapointAggregationConfig{
+		{
+			MetricNames:      []string{"requests", "bytes"},
+			Aggregations:     []string{"sum", "p90"},
+			DropSourceSeries: true,
+		},
+	})
+	require.NoError(t, err)
+
+	require.False(t, a.Add(makeDP("requests", map[string]string{"pod": "a"}, 2, datapoint.Count)))
+	require.False(t, a.Add(makeDP("requests", map[string]string{"pod": "a"}, 3, datapoint.Count)))
+	require.False(t, a.Add(makeDP("requests", map[string]string{"pod": "b"}, 1, datapoint.Count)))
+	require.False(t, a.Add(makeDP("bytes", map[string]string{"pod": "a"}, 100, datapoint.Counter)))
+	require.False(t, a.Add(makeDP("bytes", map[string]string{"pod": "a"}, 150, datapoint.Counter)))
+	require.False(t, a.Add(makeDP("bytes", map[string]string{"pod": "b"}, 50, datapoint.Counter)))
+
+	dps := a.flush(a.rules[0])
+	// Percentiles are only for gauges
+	require.Len(t, dps, 2)
+	for _, dp := range dps {
+		switch dp.Metric {
+		case "requests.sum":
+			// Delta counts are totaled within each series
+			require.Equal(t, "6", dp.Value.String())
+			require.Equal(t, datapoint.Count, dp.MetricType)
+		case "bytes.sum":
+			// Cumulative counters use the last value of each series
+			require.Equal(t, "200", dp.Value.String())
+			require.Equal(t, datapoint.Counter, dp.MetricType)
+		default:
+			t.Fatalf("unexpected metric %s", dp.Metric)
+		}
+		require.Len(t, dp.Dimensions, 0)
+	}
+}
+
+func TestAggregatorCounterSumAcrossIntervals(t *testing.T) {
+	a, err := New([]config.DatapointAggregationConfig{
+		{
+			MetricNames:  []string{"bytes"},
+			Aggregations: []string{"sum", "count"},
+			StaleTimeout: timeutil.Duration(time.Minute),
+		},
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	a.timeNow = func() time.Time { return now }
+
+	pod := func(name string) map[string]string {
+		return map[string]string{"pod": name}
+	}
+	sumOf := func(dps []*datapoint.Datapoint) string {
+		return valuesByMetric(dps, map[string]string{})["bytes.sum"]
+	}
+
+	a.Add(makeDP("bytes", pod("a"), 100, datapoint.Counter))
+	a.Add(makeDP("bytes", pod("b"), 50, datapoint.Counter))
+	require.Equal(t, "150", sumOf(a.flush(a.rules[0])))
+
+	// b misses an interval, which doesn't make the sum go down
+	now = now.Add(10 * time.Second)
+	a.Add(makeDP("bytes", pod("a"), 120, datapoint.Counter))
+	dps := a.flush(a.rules[0])
+	require.Equal(t, "170", sumOf(dps))
+	require.Equal(t, "1", valuesByMetric(dps, map[string]string{})["bytes.count"])
+
+	// a is reset, so its new value is all increase
+	now = now.Add(10 * time.Second)
+	a.Add(makeDP("bytes", pod("a"), 10, datapoint.Counter))
+	a.Add(makeDP("bytes", pod("b"), 60, datapoint.Counter))
+	require.Equal(t, "190", sumOf(a.flush(a.rules[0])))
+
+	// The sum is still sent without any datapoints in the interval
+	now = now.Add(10 * time.Second)
+	require.Equal(t, "190", sumOf(a.flush(a.rules[0])))
+
+	// b goes away and becomes stale, which doesn't make the sum go down
+	now = now.Add(time.Minute)
+	a.Add(makeDP("bytes", pod("a"), 15, datapoint.Counter))
+	require.Equal(t, "195", sumOf(a.flush(a.rules[0])))
+
+	// Nothing is sent once all of the series are stale
+	now = now.Add(2 * time.Minute)
+	require.Len(t, a.flush(a.rules[0]), 0)
+}
+
+func TestInvalidAggregation(t *testing.T) {
+	_, err := New([]config.DatapointAggregationConfig{
+		{MetricNames: []string{"cpu"}, Aggregations: []string{"median"}},
+	})
+	require.Error(t, err)
+
+	_, err = New([]config.DatapointAggregationConfig{
+		{MetricNames: []string{"cpu"}, Aggregations: []string{"p101"}},
+	})
+	require.Error(t, err)
+}
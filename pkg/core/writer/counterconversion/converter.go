@@ -0,0 +1,227 @@
+// Package counterconversion converts cumulative counters to per-second rates
+// or deltas, keeping track of the last value of each series.
+package counterconversion
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/golib/v3/sfxclient"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/dpfilters"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+)
+
+const defaultStaleTimeout = 5 * time.Minute
+
+// The last value seen for a single series
+type lastValue struct {
+	value     float64
+	timestamp time.Time
+	lastSeen  time.Time
+}
+
+type rule struct {
+	conf         *config.CounterConversionConfig
+	filter       dpfilters.DatapointFilter
+	convert      string
+	staleTimeout time.Duration
+
+	lock   sync.Mutex
+	series map[string]*lastValue
+}
+
+// Converter converts the cumulative counters that match its rules in place.
+// It is thread-safe.
+type Converter struct {
+	rules   []*rule
+	timeNow func() time.Time
+
+	// Internal metrics
+	datapointsConverted int64
+	firstDatapoints     int64
+	counterResets       int64
+}
+
+// New creates a Converter from the given rules
+func New(confs []config.CounterConversionConfig) (*Converter, error) {
+	c := &Converter{
+		timeNow: time.Now,
+	}
+
+	for i := range confs {
+		conf := &confs[i]
+		if err := conf.Validate(); err != nil {
+			return nil, err
+		}
+
+		// Normalize modifies the filter so use a copy
+		mf := conf.MetricFilter
+		mf.MetricNames = append([]string(nil), conf.MetricNames...)
+		filter, err := mf.MakeFilter()
+		if err != nil {
+			return nil, err
+		}
+
+		r := &rule{
+			conf:         conf,
+			filter:       filter,
+			convert:      conf.Convert,
+			staleTimeout: conf.StaleTimeout.AsDuration(),
+			series:       make(map[string]*lastValue),
+		}
+		if r.convert == "" {
+			r.convert = config.CounterConversionRate
+		}
+		if r.staleTimeout == 0 {
+			r.staleTimeout = defaultStaleTimeout
+		}
+		c.rules = append(c.rules, r)
+	}
+
+	return c, nil
+}
+
+// Start purging the series that haven't been seen within the stale timeout
+// of their rule until the context is cancelled
+func (c *Converter) Start(ctx context.Context) {
+	for i := range c.rules {
+		r := c.rules[i]
+		utils.RunOnInterval(ctx, func() {
+			r.purgeStaleSeries(c.timeNow())
+		}, r.staleTimeout)
+	}
+}
+
+// Convert the datapoint in place if it is a cumulative counter that matches
+// one of the rules.  Only the first matching rule applies.  Returns false if
+// the datapoint should not be sent, which is the case for the first datapoint
+// of each series and datapoints that are older than the last one.
+func (c *Converter) Convert(dp *datapoint.Datapoint) bool {
+	if dp.MetricType != datapoint.Counter {
+		return true
+	}
+
+	for _, r := range c.rules {
+		if !r.filter.Matches(dp) {
+			continue
+		}
+		return c.convert(r, dp)
+	}
+	return true
+}
+
+func (c *Converter) convert(r *rule, dp *datapoint.Datapoint) bool {
+	value, ok := floatValue(dp.Value)
+	if !ok {
+		return true
+	}
+
+	now := c.timeNow()
+	timestamp := dp.Timestamp
+	if timestamp.IsZero() {
+		timestamp = now
+	}
+	key := seriesKey(dp.Metric, dp.Dimensions)
+
+	r.lock.Lock()
+	last, ok := r.series[key]
+	if !ok {
+		r.series[key] = &lastValue{value: value, timestamp: timestamp, lastSeen: now}
+		r.lock.Unlock()
+		atomic.AddInt64(&c.firstDatapoints, 1)
+		return false
+	}
+
+	if !timestamp.After(last.timestamp) {
+		r.lock.Unlock()
+		return false
+	}
+
+	delta := value - last.value
+	if delta < 0 {
+		// The counter was reset so it has increased by its current value
+		delta = value
+		atomic.AddInt64(&c.counterResets, 1)
+	}
+	elapsed := timestamp.Sub(last.timestamp)
+
+	last.value = value
+	last.timestamp = timestamp
+	last.lastSeen = now
+	r.lock.Unlock()
+
+	switch r.convert {
+	case config.CounterConversionDelta:
+		dp.Value = datapoint.NewFloatValue(delta)
+		dp.MetricType = datapoint.Count
+	default:
+		dp.Value = datapoint.NewFloatValue(delta / elapsed.Seconds())
+		dp.MetricType = datapoint.Gauge
+	}
+	dp.Metric += r.conf.MetricNameSuffix
+
+	atomic.AddInt64(&c.datapointsConverted, 1)
+	return true
+}
+
+func (r *rule) purgeStaleSeries(now time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for key, last := range r.series {
+		if now.Sub(last.lastSeen) >= r.staleTimeout {
+			delete(r.series, key)
+		}
+	}
+}
+
+func seriesKey(metric string, dims map[string]string) string {
+	keys := make([]string, 0, len(dims))
+	for k := range dims {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(metric)
+	for _, k := range keys {
+		sb.WriteByte(0)
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(dims[k])
+	}
+	return sb.String()
+}
+
+func floatValue(v datapoint.Value) (float64, bool) {
+	switch val := v.(type) {
+	case datapoint.IntValue:
+		return float64(val.Int()), true
+	case datapoint.FloatValue:
+		return val.Float(), true
+	}
+	return 0, false
+}
+
+// InternalMetrics returns datapoints describing the state of the converter
+func (c *Converter) InternalMetrics() []*datapoint.Datapoint {
+	var activeSeries int64
+	for _, r := range c.rules {
+		r.lock.Lock()
+		activeSeries += int64(len(r.series))
+		r.lock.Unlock()
+	}
+
+	return []*datapoint.Datapoint{
+		sfxclient.Gauge("sfxagent.counter_conversion_active_series", nil, activeSeries),
+		sfxclient.CumulativeP("sfxagent.counter_conversion_datapoints_converted", nil, &c.datapointsConverted),
+		sfxclient.CumulativeP("sfxagent.counter_conversion_first_datapoints", nil, &c.firstDatapoints),
+		sfxclient.CumulativeP("sfxagent.counter_conversion_counter_resets", nil, &c.counterResets),
+	}
+}
@@ -0,0 +1,103 @@
+package counterconversion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/core/common/dpmeta"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Unix(1000, 0)
+
+func makeCounter(metric string, dims map[string]string, value int64, secs int) *datapoint.Datapoint {
+	dp := datapoint.New(metric, dims, datapoint.NewIntValue(value), datapoint.Counter, start.Add(time.Duration(secs)*time.Second))
+	dp.Meta[dpmeta.MonitorTypeMeta] = "prometheus-exporter"
+	return dp
+}
+
+func TestRates(t *testing.T) {
+	c, err := New([]config.CounterConversionConfig{
+		{
+			MetricFilter: config.MetricFilter{
+				MonitorType: "prometheus-exporter",
+				MetricNames: []string{"http_requests_total"},
+			},
+			MetricNameSuffix: ".rate",
+		},
+	})
+	require.NoError(t, err)
+
+	dims := map[string]string{"pod": "a"}
+
+	// There is nothing to compare the first datapoint to
+	require.False(t, c.Convert(makeCounter("http_requests_total", dims, 100, 0)))
+
+	dp := makeCounter("http_requests_total", dims, 150, 10)
+	require.True(t, c.Convert(dp))
+	require.Equal(t, "http_requests_total.rate", dp.Metric)
+	require.Equal(t, datapoint.Gauge, dp.MetricType)
+	require.Equal(t, "5", dp.Value.String())
+
+	// Other series are tracked separately
+	require.False(t, c.Convert(makeCounter("http_requests_total", map[string]string{"pod": "b"}, 1000, 10)))
+
+	// A reset counts the new value as the increase
+	dp = makeCounter("http_requests_total", dims, 20, 20)
+	require.True(t, c.Convert(dp))
+	require.Equal(t, "2", dp.Value.String())
+	require.Equal(t, int64(1), c.counterResets)
+
+	// Datapoints that are not newer than the last one are dropped
+	require.False(t, c.Convert(makeCounter("http_requests_total", dims, 30, 20)))
+
+	// Non-matching metrics and other metric types are left alone
+	other := makeCounter("process_cpu_seconds_total", dims, 5, 0)
+	require.True(t, c.Convert(other))
+	require.Equal(t, datapoint.Counter, other.MetricType)
+	gauge := makeCounter("http_requests_total", dims, 5, 30)
+	gauge.MetricType = datapoint.Gauge
+	require.True(t, c.Convert(gauge))
+	require.Equal(t, "5", gauge.Value.String())
+}
+
+func TestDeltasAndStaleSeries(t *testing.T) {
+	c, err := New([]config.CounterConversionConfig{
+		{
+			MetricFilter: config.MetricFilter{
+				MetricName: "bytes_*",
+			},
+			Convert: config.CounterConversionDelta,
+		},
+	})
+	require.NoError(t, err)
+	now := start
+	c.timeNow = func() time.Time { return now }
+
+	require.False(t, c.Convert(makeCounter("bytes_sent", nil, 100, 0)))
+	dp := makeCounter("bytes_sent", nil, 130, 10)
+	require.True(t, c.Convert(dp))
+	require.Equal(t, "bytes_sent", dp.Metric)
+	require.Equal(t, datapoint.Count, dp.MetricType)
+	require.Equal(t, "30", dp.Value.String())
+
+	// The series is forgotten once it is stale so it starts over
+	now = now.Add(defaultStaleTimeout)
+	c.rules[0].purgeStaleSeries(now)
+	require.False(t, c.Convert(makeCounter("bytes_sent", nil, 200, 400)))
+}
+
+func TestInvalidConversion(t *testing.T) {
+	_, err := New([]config.CounterConversionConfig{
+		{
+			MetricFilter: config.MetricFilter{MetricName: "a"},
+			Convert:      "ratio",
+		},
+	})
+	require.Error(t, err)
+
+	_, err = New([]config.CounterConversionConfig{{Convert: "rate"}})
+	require.Error(t, err)
+}
@@ -66,7 +66,7 @@ func (sw *SignalFxWriter) DiagnosticText() string {
 // InternalMetrics returns a set of metrics showing how the writer is currently
 // doing.
 func (sw *SignalFxWriter) InternalMetrics() []*datapoint.Datapoint {
-	return append(append(append(append(append([]*datapoint.Datapoint{
+	dps := append(append(append(append(append([]*datapoint.Datapoint{
 		sfxclient.CumulativeP("sfxagent.events_sent", nil, &sw.eventsSent),
 		sfxclient.Gauge("sfxagent.datapoint_channel_len", nil, int64(len(sw.dpChan))),
 		sfxclient.Gauge("sfxagent.events_buffered", nil, int64(len(sw.eventBuffer))),
@@ -76,4 +76,21 @@ func (sw *SignalFxWriter) InternalMetrics() []*datapoint.Datapoint {
 		sw.serviceTracker.InternalMetrics()...),
 		sw.dimensionClient.InternalMetrics()...),
 		sw.spanSourceTracker.InternalMetrics()...)
+
+	if sw.spanProcessors != nil {
+		dps = append(dps, sw.spanProcessors.InternalMetrics()...)
+	}
+	if sw.spanMetrics != nil {
+		dps = append(dps, sw.spanMetrics.InternalMetrics()...)
+	}
+	if sw.sampler != nil {
+		dps = append(dps, sw.sampler.InternalMetrics()...)
+	}
+	if sw.counterConverter != nil {
+		dps = append(dps, sw.counterConverter.InternalMetrics()...)
+	}
+	if sw.aggregator != nil {
+		dps = append(dps, sw.aggregator.InternalMetrics()...)
+	}
+	return dps
 }
@@ -165,7 +165,13 @@ func (dc *DimensionClient) processQueue() {
 			now := dc.now()
 			if now.Before(delayedDim.TimeToSend) {
 				// dims are always in the channel in order of TimeToSend
-				time.Sleep(delayedDim.TimeToSend.Sub(now))
+				select {
+				case <-dc.ctx.Done():
+					// It is still in delayedSet so it will be returned by
+					// Drain.
+					return
+				case <-time.After(delayedDim.TimeToSend.Sub(now)):
+				}
 			}
 
 			atomic.AddInt64(&dc.DimensionsCurrentlyDelayed, int64(-1))
@@ -181,6 +187,23 @@ func (dc *DimensionClient) processQueue() {
 	}
 }
 
+// Drain returns the dimension updates that are waiting to be sent and
+// forgets about them, so that they can be given to another client.  It should
+// only be called after the client's context is cancelled.
+func (dc *DimensionClient) Drain() []*types.Dimension {
+	dc.Lock()
+	defer dc.Unlock()
+
+	out := make([]*types.Dimension, 0, len(dc.delayedSet))
+	for _, dim := range dc.delayedSet {
+		out = append(out, dim)
+	}
+	dc.delayedSet = make(map[types.DimensionKey]*types.Dimension)
+	atomic.StoreInt64(&dc.DimensionsCurrentlyDelayed, 0)
+
+	return out
+}
+
 // setPropertiesOnDimension will set custom properties on a specific dimension
 // value.  It will wipe out any description on the dimension.
 func (dc *DimensionClient) setPropertiesOnDimension(dim *types.Dimension) error {
@@ -492,3 +492,27 @@ func TestInvalidUpdatesNotSent(t *testing.T) {
 	dims := waitForDims(dimCh, 2, 3)
 	require.Len(t, dims, 0)
 }
+
+func TestDrainPendingUpdates(t *testing.T) {
+	client, dimCh, _, cancel := setup()
+
+	for _, value := range []string{"a", "b"} {
+		require.NoError(t, client.AcceptDimension(&types.Dimension{
+			Name:       "pod_uid",
+			Value:      value,
+			Properties: map[string]string{"app": "web"},
+		}))
+	}
+
+	// Neither update is due to be sent yet
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+
+	drained := client.Drain()
+	require.Len(t, drained, 2)
+	require.ElementsMatch(t, []string{"a", "b"}, []string{drained[0].Value, drained[1].Value})
+	require.Equal(t, int64(0), atomic.LoadInt64(&client.DimensionsCurrentlyDelayed))
+	require.Len(t, client.Drain(), 0)
+
+	require.Len(t, waitForDims(dimCh, 1, 2), 0)
+}
@@ -0,0 +1,109 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/golib/v3/event"
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
+)
+
+// sink is where the writer sends what it has processed, which is normally
+// ingest
+type sink interface {
+	AddDatapoints(context.Context, []*datapoint.Datapoint) error
+	AddEvents(context.Context, []*event.Event) error
+	AddSpans(context.Context, []*trace.Span) error
+}
+
+// replayOutput is used as the sink instead of ingest while monitor inputs are
+// being replayed, so that replaying never sends anything.  Everything is
+// written to a file, one JSON object per line, or discarded if there is no
+// file.
+type replayOutput struct {
+	lock    sync.Mutex
+	out     io.WriteCloser
+	encoder *json.Encoder
+}
+
+type replayOutputEntry struct {
+	Datapoint *datapoint.Datapoint `json:"datapoint,omitempty"`
+	Event     *event.Event         `json:"event,omitempty"`
+	Span      *trace.Span          `json:"span,omitempty"`
+	Dimension *types.Dimension     `json:"dimension,omitempty"`
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func newReplayOutput(path string) (*replayOutput, error) {
+	var out io.WriteCloser = nopWriteCloser{ioutil.Discard}
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		out = f
+	}
+
+	return &replayOutput{
+		out:     out,
+		encoder: json.NewEncoder(out),
+	}, nil
+}
+
+func (ro *replayOutput) write(entries []replayOutputEntry) error {
+	ro.lock.Lock()
+	defer ro.lock.Unlock()
+
+	for i := range entries {
+		if err := ro.encoder.Encode(&entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ro *replayOutput) AddDatapoints(_ context.Context, dps []*datapoint.Datapoint) error {
+	entries := make([]replayOutputEntry, len(dps))
+	for i := range dps {
+		entries[i].Datapoint = dps[i]
+	}
+	return ro.write(entries)
+}
+
+func (ro *replayOutput) AddEvents(_ context.Context, events []*event.Event) error {
+	entries := make([]replayOutputEntry, len(events))
+	for i := range events {
+		entries[i].Event = events[i]
+	}
+	return ro.write(entries)
+}
+
+func (ro *replayOutput) AddSpans(_ context.Context, spans []*trace.Span) error {
+	entries := make([]replayOutputEntry, len(spans))
+	for i := range spans {
+		entries[i].Span = spans[i]
+	}
+	return ro.write(entries)
+}
+
+func (ro *replayOutput) AddDimension(dim *types.Dimension) error {
+	return ro.write([]replayOutputEntry{{Dimension: dim}})
+}
+
+func (ro *replayOutput) Close() error {
+	ro.lock.Lock()
+	defer ro.lock.Unlock()
+
+	return ro.out.Close()
+}
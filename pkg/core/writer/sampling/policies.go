@@ -0,0 +1,153 @@
+package sampling
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/writer/spanutil"
+	"github.com/signalfx/signalfx-agent/pkg/utils/filter"
+)
+
+// The reasons that a trace can be kept, which are used as a dimension on
+// the internal metrics
+const (
+	reasonError         = "error"
+	reasonLatency       = "latency"
+	reasonAlwaysKeep    = "always_keep"
+	reasonProbabilistic = "probabilistic"
+)
+
+var allReasons = []string{reasonError, reasonLatency, reasonAlwaysKeep, reasonProbabilistic}
+
+type spanMatcher struct {
+	service   filter.StringFilter
+	operation filter.StringFilter
+}
+
+func (m *spanMatcher) matches(span *trace.Span) bool {
+	if m.service != nil && !m.service.Matches(spanService(span)) {
+		return false
+	}
+	if m.operation != nil {
+		if span.Name == nil || !m.operation.Matches(*span.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+type policies struct {
+	keepErrors       bool
+	latencyThreshold time.Duration
+	alwaysKeep       []*spanMatcher
+	// Out of 10000, to allow for fractional percentages
+	probabilisticThreshold uint32
+	maxPerSecond           int
+
+	// Traces kept per root service in the current second, for rate limiting
+	currentSecond  int64
+	keptThisSecond map[string]int
+}
+
+func newPolicies(conf *config.TraceSamplingConfig) (*policies, error) {
+	p := &policies{
+		keepErrors:             conf.KeepErrors == nil || *conf.KeepErrors,
+		latencyThreshold:       conf.LatencyThreshold.AsDuration(),
+		probabilisticThreshold: uint32(conf.SamplingPercentage * 100),
+		maxPerSecond:           conf.MaxTracesPerSecondPerService,
+		keptThisSecond:         map[string]int{},
+	}
+
+	for i, match := range conf.AlwaysKeep {
+		m := &spanMatcher{}
+		var err error
+		if match.Service != "" {
+			if m.service, err = filter.NewBasicStringFilter([]string{match.Service}); err != nil {
+				return nil, fmt.Errorf("invalid alwaysKeep service %d: %v", i, err)
+			}
+		}
+		if match.Operation != "" {
+			if m.operation, err = filter.NewBasicStringFilter([]string{match.Operation}); err != nil {
+				return nil, fmt.Errorf("invalid alwaysKeep operation %d: %v", i, err)
+			}
+		}
+		p.alwaysKeep = append(p.alwaysKeep, m)
+	}
+	return p, nil
+}
+
+// evaluate returns whether the trace should be kept and, if so, why
+func (p *policies) evaluate(traceID string, spans []*trace.Span, now time.Time) (string, bool) {
+	if p.keepErrors {
+		for _, span := range spans {
+			if spanutil.IsError(span) {
+				return reasonError, true
+			}
+		}
+	}
+
+	if p.latencyThreshold > 0 {
+		for _, span := range spans {
+			// Span durations are in microseconds
+			if span.Duration != nil && time.Duration(*span.Duration)*time.Microsecond >= p.latencyThreshold {
+				return reasonLatency, true
+			}
+		}
+	}
+
+	for _, m := range p.alwaysKeep {
+		for _, span := range spans {
+			if m.matches(span) {
+				return reasonAlwaysKeep, true
+			}
+		}
+	}
+
+	if hashTraceID(traceID)%10000 >= p.probabilisticThreshold {
+		return "", false
+	}
+
+	if p.maxPerSecond > 0 {
+		if sec := now.Unix(); sec != p.currentSecond {
+			p.currentSecond = sec
+			p.keptThisSecond = map[string]int{}
+		}
+
+		service := rootService(spans)
+		if p.keptThisSecond[service] >= p.maxPerSecond {
+			return "", false
+		}
+		p.keptThisSecond[service]++
+	}
+
+	return reasonProbabilistic, true
+}
+
+func hashTraceID(traceID string) uint32 {
+	h := fnv.New32a()
+	// Normalize so that IDs with and without leading zeros are the same
+	_, _ = h.Write([]byte(strings.TrimLeft(strings.ToLower(traceID), "0")))
+	return h.Sum32()
+}
+
+func spanService(span *trace.Span) string {
+	if span.LocalEndpoint == nil || span.LocalEndpoint.ServiceName == nil {
+		return ""
+	}
+	return *span.LocalEndpoint.ServiceName
+}
+
+// rootService returns the service of the root span of the trace, or of the
+// first span if the root span hasn't been received.
+func rootService(spans []*trace.Span) string {
+	for _, span := range spans {
+		if span.ParentID == nil || *span.ParentID == "" {
+			return spanService(span)
+		}
+	}
+	return spanService(spans[0])
+}
@@ -0,0 +1,273 @@
+// Package sampling contains a tail-based trace sampler that buffers trace
+// spans by trace ID and decides whether to keep each trace once all of its
+// spans have likely been received.
+package sampling
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/golib/v3/sfxclient"
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+type pendingTrace struct {
+	traceID   string
+	spans     []*trace.Span
+	firstSeen time.Time
+	elem      *list.Element
+}
+
+// Sampler reads spans from an input channel, buffers them by trace ID, and
+// sends the spans of the traces that are kept to an output channel.  All of
+// the buffering and decision making happens on a single goroutine.
+type Sampler struct {
+	conf     *config.TraceSamplingConfig
+	policies *policies
+	input    <-chan []*trace.Span
+	output   chan<- []*trace.Span
+	// Called with every span received, before sampling.  Spans that it
+	// returns false for are dropped.
+	preprocess     func(*trace.Span) bool
+	preprocessLock sync.Mutex
+	timeNow        func() time.Time
+
+	pending map[string]*pendingTrace
+	// Pending traces, ordered by when they were first seen
+	pendingOrder *list.List
+	// Trace ID -> bool of whether the trace was kept
+	decisions *lru.Cache
+	stopped   chan struct{}
+	// Closed by Abort to stop waiting on the output channel
+	aborted   chan struct{}
+	abortOnce sync.Once
+
+	// Internal metrics
+	spansBuffered  int64
+	tracesPending  int64
+	keptByReason   map[string]*int64
+	tracesDropped  int64
+	lateSpans      int64
+	earlyDecisions int64
+	abortedSpans   int64
+}
+
+// New creates a new sampler.  preprocess can be nil.
+func New(conf *config.TraceSamplingConfig, input <-chan []*trace.Span, output chan<- []*trace.Span,
+	preprocess func(*trace.Span) bool) (*Sampler, error) {
+	pols, err := newPolicies(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	decisions, err := lru.New(conf.DecisionCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	keptByReason := map[string]*int64{}
+	for _, reason := range allReasons {
+		keptByReason[reason] = new(int64)
+	}
+
+	return &Sampler{
+		conf:         conf,
+		policies:     pols,
+		input:        input,
+		output:       output,
+		preprocess:   preprocess,
+		timeNow:      time.Now,
+		pending:      make(map[string]*pendingTrace),
+		pendingOrder: list.New(),
+		decisions:    decisions,
+		stopped:      make(chan struct{}),
+		aborted:      make(chan struct{}),
+		keptByReason: keptByReason,
+	}, nil
+}
+
+// Start processing spans until the context is cancelled.  Decisions are made
+// right away for any traces that are still pending when the context is
+// cancelled, so that the kept ones still go to the output channel.  The
+// channel returned by Stopped is closed once that is done.
+func (s *Sampler) Start(ctx context.Context) {
+	checkInterval := time.Second
+	if wait := s.conf.DecisionWait.AsDuration(); wait < checkInterval {
+		checkInterval = wait
+	}
+
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		defer close(s.stopped)
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.decideAll()
+				return
+			case spans := <-s.input:
+				s.addSpans(spans)
+			case <-ticker.C:
+				s.decideExpired()
+			}
+		}
+	}()
+}
+
+// SetPreprocess replaces the function that spans are preprocessed with.  It
+// can be called while the sampler is running, which lets a new writer take
+// over the sampler without losing its pending traces.
+func (s *Sampler) SetPreprocess(preprocess func(*trace.Span) bool) {
+	s.preprocessLock.Lock()
+	defer s.preprocessLock.Unlock()
+	s.preprocess = preprocess
+}
+
+func (s *Sampler) addSpans(spans []*trace.Span) {
+	now := s.timeNow()
+
+	s.preprocessLock.Lock()
+	preprocess := s.preprocess
+	s.preprocessLock.Unlock()
+
+	var lateKept []*trace.Span
+	for _, span := range spans {
+		if preprocess != nil && !preprocess(span) {
+			continue
+		}
+
+		if pt, ok := s.pending[span.TraceID]; ok {
+			pt.spans = append(pt.spans, span)
+			atomic.AddInt64(&s.spansBuffered, 1)
+			continue
+		}
+
+		if kept, ok := s.decisions.Get(span.TraceID); ok {
+			atomic.AddInt64(&s.lateSpans, 1)
+			if kept.(bool) {
+				lateKept = append(lateKept, span)
+			}
+			continue
+		}
+
+		pt := &pendingTrace{
+			traceID:   span.TraceID,
+			spans:     []*trace.Span{span},
+			firstSeen: now,
+		}
+		pt.elem = s.pendingOrder.PushBack(pt)
+		s.pending[span.TraceID] = pt
+		atomic.AddInt64(&s.spansBuffered, 1)
+		atomic.AddInt64(&s.tracesPending, 1)
+	}
+
+	if len(lateKept) > 0 {
+		s.send(lateKept)
+	}
+
+	// Make decisions early for the oldest traces if too much is buffered
+	for atomic.LoadInt64(&s.spansBuffered) > int64(s.conf.MaxSpansBuffered) {
+		front := s.pendingOrder.Front()
+		if front == nil {
+			break
+		}
+		atomic.AddInt64(&s.earlyDecisions, 1)
+		s.decide(front.Value.(*pendingTrace), now)
+	}
+}
+
+// decideExpired makes decisions for all of the traces that have waited for
+// at least the decision wait
+func (s *Sampler) decideExpired() {
+	now := s.timeNow()
+	wait := s.conf.DecisionWait.AsDuration()
+
+	for {
+		front := s.pendingOrder.Front()
+		if front == nil {
+			return
+		}
+		pt := front.Value.(*pendingTrace)
+		// The list is ordered so nothing after this is expired either
+		if now.Sub(pt.firstSeen) < wait {
+			return
+		}
+		s.decide(pt, now)
+	}
+}
+
+// decideAll makes decisions for all of the pending traces
+func (s *Sampler) decideAll() {
+	now := s.timeNow()
+	for front := s.pendingOrder.Front(); front != nil; front = s.pendingOrder.Front() {
+		s.decide(front.Value.(*pendingTrace), now)
+	}
+}
+
+// Stopped returns a channel that is closed once the sampler has stopped and
+// sent the spans of all the traces it kept
+func (s *Sampler) Stopped() <-chan struct{} {
+	return s.stopped
+}
+
+// Abort makes the sampler drop the spans of kept traces instead of waiting
+// for room in the output channel, so that it can stop even if nothing reads
+// the output anymore, e.g. after the writer timed out flushing on shutdown.
+func (s *Sampler) Abort() {
+	s.abortOnce.Do(func() {
+		close(s.aborted)
+	})
+}
+
+// Sends the spans to the output unless the sampler has been aborted, in
+// which case they are dropped
+func (s *Sampler) send(spans []*trace.Span) {
+	select {
+	case s.output <- spans:
+	case <-s.aborted:
+		atomic.AddInt64(&s.abortedSpans, int64(len(spans)))
+	}
+}
+
+func (s *Sampler) decide(pt *pendingTrace, now time.Time) {
+	s.pendingOrder.Remove(pt.elem)
+	delete(s.pending, pt.traceID)
+	atomic.AddInt64(&s.spansBuffered, -int64(len(pt.spans)))
+	atomic.AddInt64(&s.tracesPending, -1)
+
+	reason, keep := s.policies.evaluate(pt.traceID, pt.spans, now)
+	s.decisions.Add(pt.traceID, keep)
+
+	if !keep {
+		atomic.AddInt64(&s.tracesDropped, 1)
+		return
+	}
+
+	atomic.AddInt64(s.keptByReason[reason], 1)
+	s.send(pt.spans)
+}
+
+// InternalMetrics returns datapoints describing the state of the sampler
+func (s *Sampler) InternalMetrics() []*datapoint.Datapoint {
+	dps := []*datapoint.Datapoint{
+		sfxclient.Gauge("sfxagent.trace_sampling_spans_buffered", nil, atomic.LoadInt64(&s.spansBuffered)),
+		sfxclient.Gauge("sfxagent.trace_sampling_traces_pending", nil, atomic.LoadInt64(&s.tracesPending)),
+		sfxclient.CumulativeP("sfxagent.trace_sampling_traces_dropped", nil, &s.tracesDropped),
+		sfxclient.CumulativeP("sfxagent.trace_sampling_late_spans", nil, &s.lateSpans),
+		sfxclient.CumulativeP("sfxagent.trace_sampling_early_decisions", nil, &s.earlyDecisions),
+		sfxclient.CumulativeP("sfxagent.trace_sampling_spans_aborted", nil, &s.abortedSpans),
+	}
+	for _, reason := range allReasons {
+		dps = append(dps, sfxclient.CumulativeP("sfxagent.trace_sampling_traces_kept",
+			map[string]string{"reason": reason}, s.keptByReason[reason]))
+	}
+	return dps
+}
@@ -0,0 +1,193 @@
+package sampling
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/signalfx/golib/v3/pointer"
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/utils/timeutil"
+	"github.com/stretchr/testify/require"
+)
+
+func makeSpan(traceID, service, name string, durationMicros int64, tags map[string]string) *trace.Span {
+	return &trace.Span{
+		TraceID:       traceID,
+		ID:            fmt.Sprintf("%s-%s", traceID, name),
+		Name:          pointer.String(name),
+		Duration:      pointer.Int64(durationMicros),
+		LocalEndpoint: &trace.Endpoint{ServiceName: pointer.String(service)},
+		Tags:          tags,
+	}
+}
+
+func newTestSampler(t *testing.T, conf *config.TraceSamplingConfig) (*Sampler, chan []*trace.Span, *time.Time) {
+	if conf.DecisionWait == 0 {
+		conf.DecisionWait = timeutil.Duration(10 * time.Second)
+	}
+	if conf.MaxSpansBuffered == 0 {
+		conf.MaxSpansBuffered = 1000
+	}
+	conf.DecisionCacheSize = 100
+
+	output := make(chan []*trace.Span, 100)
+	s, err := New(conf, nil, output, nil)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	s.timeNow = func() time.Time { return now }
+	return s, output, &now
+}
+
+func keptTraceIDs(output chan []*trace.Span) map[string]int {
+	out := map[string]int{}
+	for {
+		select {
+		case spans := <-output:
+			for _, span := range spans {
+				out[span.TraceID]++
+			}
+		default:
+			return out
+		}
+	}
+}
+
+func TestSamplerPolicies(t *testing.T) {
+	s, output, now := newTestSampler(t, &config.TraceSamplingConfig{
+		LatencyThreshold:   timeutil.Duration(time.Second),
+		AlwaysKeep:         []config.TraceSamplingMatch{{Service: "checkout", Operation: "pay*"}},
+		SamplingPercentage: 0,
+	})
+
+	s.addSpans([]*trace.Span{
+		makeSpan("1", "api", "a", 1000, nil),
+		makeSpan("1", "db", "b", 1000, map[string]string{"error": "true"}),
+		makeSpan("2", "api", "a", 2000000, nil),
+		makeSpan("3", "checkout", "payment", 1000, nil),
+		makeSpan("4", "checkout", "cart", 1000, nil),
+	})
+
+	// Nothing is decided before the decision wait
+	s.decideExpired()
+	require.Len(t, keptTraceIDs(output), 0)
+
+	*now = now.Add(10 * time.Second)
+	s.decideExpired()
+	require.Equal(t, map[string]int{"1": 2, "2": 1, "3": 1}, keptTraceIDs(output))
+	require.Equal(t, int64(1), s.tracesDropped)
+	require.Equal(t, int64(1), *s.keptByReason[reasonError])
+
+	// Late spans follow the decision for their trace
+	s.addSpans([]*trace.Span{
+		makeSpan("1", "api", "late", 1000, nil),
+		makeSpan("4", "checkout", "late", 1000, nil),
+	})
+	require.Equal(t, map[string]int{"1": 1}, keptTraceIDs(output))
+	require.Equal(t, int64(2), s.lateSpans)
+	require.Equal(t, int64(0), s.spansBuffered)
+}
+
+func TestSamplerRateLimit(t *testing.T) {
+	s, output, now := newTestSampler(t, &config.TraceSamplingConfig{
+		SamplingPercentage:           100,
+		MaxTracesPerSecondPerService: 2,
+	})
+
+	for i := 0; i < 5; i++ {
+		s.addSpans([]*trace.Span{makeSpan(fmt.Sprint(i), "api", "a", 1000, nil)})
+	}
+	s.addSpans([]*trace.Span{makeSpan("other", "web", "a", 1000, nil)})
+
+	*now = now.Add(10 * time.Second)
+	s.decideExpired()
+
+	kept := keptTraceIDs(output)
+	require.Len(t, kept, 3)
+	require.Contains(t, kept, "other")
+}
+
+func TestSamplerMemoryBound(t *testing.T) {
+	s, output, _ := newTestSampler(t, &config.TraceSamplingConfig{
+		SamplingPercentage: 100,
+		MaxSpansBuffered:   2,
+	})
+
+	s.addSpans([]*trace.Span{
+		makeSpan("1", "api", "a", 1000, nil),
+		makeSpan("2", "api", "a", 1000, nil),
+		makeSpan("3", "api", "a", 1000, nil),
+	})
+
+	// The oldest trace is decided early to stay within the bound
+	require.Equal(t, map[string]int{"1": 1}, keptTraceIDs(output))
+	require.Equal(t, int64(2), s.spansBuffered)
+	require.Equal(t, int64(1), s.earlyDecisions)
+}
+
+func TestSamplerDecidesPendingOnStop(t *testing.T) {
+	s, output, _ := newTestSampler(t, &config.TraceSamplingConfig{
+		SamplingPercentage: 100,
+	})
+
+	s.addSpans([]*trace.Span{
+		makeSpan("1", "api", "a", 1000, nil),
+		makeSpan("2", "api", "a", 1000, nil),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	cancel()
+
+	select {
+	case <-s.Stopped():
+	case <-time.After(5 * time.Second):
+		t.Fatal("sampler did not stop")
+	}
+
+	// The traces are kept even though the decision wait hasn't passed
+	require.Equal(t, map[string]int{"1": 1, "2": 1}, keptTraceIDs(output))
+	require.Equal(t, int64(0), s.tracesPending)
+}
+
+func TestSamplerAbortDropsSpansThatCannotBeSent(t *testing.T) {
+	conf := &config.TraceSamplingConfig{
+		SamplingPercentage: 100,
+		DecisionWait:       timeutil.Duration(10 * time.Second),
+		MaxSpansBuffered:   1000,
+		DecisionCacheSize:  100,
+	}
+	// Nothing ever reads the output, like after the writer has shut down
+	output := make(chan []*trace.Span, 1)
+	s, err := New(conf, nil, output, nil)
+	require.NoError(t, err)
+
+	s.addSpans([]*trace.Span{
+		makeSpan("1", "api", "a", 1000, nil),
+		makeSpan("2", "api", "a", 1000, nil),
+		makeSpan("3", "api", "a", 1000, nil),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	cancel()
+
+	select {
+	case <-s.Stopped():
+		t.Fatal("sampler should be blocked on the full output")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.Abort()
+	select {
+	case <-s.Stopped():
+	case <-time.After(5 * time.Second):
+		t.Fatal("sampler did not stop after being aborted")
+	}
+
+	require.Len(t, output, 1)
+	require.Equal(t, int64(2), s.abortedSpans)
+}
@@ -0,0 +1,247 @@
+// Package spanmetrics generates request rate, error and duration (RED)
+// metrics from trace spans.
+package spanmetrics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/golib/v3/sfxclient"
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/core/common/dpmeta"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/writer/spanutil"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+)
+
+const (
+	countMetric       = "spans.count"
+	errorsMetric      = "spans.errors"
+	durationSumMetric = "spans.duration_ms.sum"
+	bucketMetric      = "spans.duration_ms.bucket"
+
+	// The operation dimension value that spans are counted against once the
+	// max number of series is reached
+	overflowOperation = "_overflow"
+)
+
+type series struct {
+	dims            map[string]string
+	notHostSpecific bool
+	overflow        bool
+	lastSeen        time.Time
+
+	count         int64
+	errors        int64
+	durationSumMs float64
+	// Non-cumulative counts of spans in each bucket
+	bucketCounts []int64
+}
+
+// Aggregator accumulates span metrics for each distinct set of dimensions.
+// It is thread-safe.
+type Aggregator struct {
+	sync.Mutex
+	conf *config.SpanMetricsConfig
+	// The dimension name to use for each of conf.DimensionTags
+	tagDimensions []string
+	series        map[string]*series
+	// The number of series that count against conf.MaxTimeSeries (i.e. not
+	// overflow series)
+	regularSeries int
+	timeNow       func() time.Time
+
+	// Internal metrics
+	spansProcessed  int64
+	spansOverflowed int64
+	seriesPurged    int64
+}
+
+// New creates an Aggregator from the given config
+func New(conf *config.SpanMetricsConfig) *Aggregator {
+	tagDimensions := make([]string, len(conf.DimensionTags))
+	for i := range conf.DimensionTags {
+		tagDimensions[i] = strings.ReplaceAll(conf.DimensionTags[i], ".", "_")
+	}
+
+	return &Aggregator{
+		conf:          conf,
+		tagDimensions: tagDimensions,
+		series:        make(map[string]*series),
+		timeNow:       time.Now,
+	}
+}
+
+// AddSpan records the span in the metrics.  notHostSpecific should be true if
+// the span should not have host-specific dimensions added.
+func (a *Aggregator) AddSpan(span *trace.Span, notHostSpecific bool) {
+	atomic.AddInt64(&a.spansProcessed, 1)
+
+	// Can't do anything if the spans don't have a local service name
+	if span.LocalEndpoint == nil || span.LocalEndpoint.ServiceName == nil {
+		return
+	}
+
+	dims := map[string]string{
+		"service": *span.LocalEndpoint.ServiceName,
+	}
+	if span.Name != nil {
+		dims["operation"] = *span.Name
+	}
+	if span.Kind != nil && *span.Kind != "" {
+		dims["kind"] = strings.ToUpper(*span.Kind)
+	}
+	for i, tag := range a.conf.DimensionTags {
+		if v, ok := span.Tags[tag]; ok && v != "" {
+			dims[a.tagDimensions[i]] = v
+		}
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	s := a.seriesForDims(dims, notHostSpecific)
+	s.lastSeen = a.timeNow()
+	s.count++
+	if spanutil.IsError(span) {
+		s.errors++
+	}
+
+	if span.Duration != nil {
+		// Span durations are in microseconds
+		durationMs := float64(*span.Duration) / 1000
+		s.durationSumMs += durationMs
+
+		idx := sort.SearchFloat64s(a.conf.HistogramBuckets, durationMs)
+		if idx < len(s.bucketCounts) {
+			s.bucketCounts[idx]++
+		}
+	}
+}
+
+// seriesForDims gets or creates the series for the given dims, falling back
+// to the overflow series for the service if there are too many series.  Must
+// be called with the lock held.
+func (a *Aggregator) seriesForDims(dims map[string]string, notHostSpecific bool) *series {
+	key := seriesKey(dims, notHostSpecific)
+	if s, ok := a.series[key]; ok {
+		return s
+	}
+
+	overflow := a.regularSeries >= a.conf.MaxTimeSeries
+	if overflow {
+		atomic.AddInt64(&a.spansOverflowed, 1)
+
+		dims = map[string]string{
+			"service":   dims["service"],
+			"operation": overflowOperation,
+		}
+		key = seriesKey(dims, notHostSpecific) + "!overflow"
+		if s, ok := a.series[key]; ok {
+			return s
+		}
+	} else {
+		a.regularSeries++
+	}
+
+	s := &series{
+		dims:            dims,
+		notHostSpecific: notHostSpecific,
+		overflow:        overflow,
+		bucketCounts:    make([]int64, len(a.conf.HistogramBuckets)),
+	}
+	a.series[key] = s
+	return s
+}
+
+func seriesKey(dims map[string]string, notHostSpecific bool) string {
+	keys := make([]string, 0, len(dims))
+	for k := range dims {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(dims[k])
+		sb.WriteByte(0)
+	}
+	if notHostSpecific {
+		sb.WriteString("!host")
+	}
+	return sb.String()
+}
+
+// Datapoints returns the current cumulative datapoints for all of the series
+// that have had spans within the stale timeout.
+func (a *Aggregator) Datapoints() []*datapoint.Datapoint {
+	a.Lock()
+	defer a.Unlock()
+
+	now := a.timeNow()
+	a.purgeStaleSeries(now)
+
+	var out []*datapoint.Datapoint
+	for _, s := range a.series {
+		// The dims are copied for each datapoint since the writer mutates
+		// them in place
+		dps := []*datapoint.Datapoint{
+			datapoint.New(countMetric, utils.CloneStringMap(s.dims), datapoint.NewIntValue(s.count), datapoint.Counter, now),
+			datapoint.New(errorsMetric, utils.CloneStringMap(s.dims), datapoint.NewIntValue(s.errors), datapoint.Counter, now),
+			datapoint.New(durationSumMetric, utils.CloneStringMap(s.dims), datapoint.NewFloatValue(s.durationSumMs), datapoint.Counter, now),
+		}
+
+		var cumulative int64
+		for i, bound := range a.conf.HistogramBuckets {
+			cumulative += s.bucketCounts[i]
+			dims := utils.MergeStringMaps(s.dims, map[string]string{
+				"upper_bound": strconv.FormatFloat(bound, 'f', -1, 64),
+			})
+			dps = append(dps, datapoint.New(bucketMetric, dims, datapoint.NewIntValue(cumulative), datapoint.Counter, now))
+		}
+
+		if s.notHostSpecific {
+			for i := range dps {
+				dps[i].Meta[dpmeta.NotHostSpecificMeta] = true
+			}
+		}
+		out = append(out, dps...)
+	}
+	return out
+}
+
+// Must be called with the lock held
+func (a *Aggregator) purgeStaleSeries(now time.Time) {
+	timeout := a.conf.StaleTimeout.AsDuration()
+	for key, s := range a.series {
+		if now.Sub(s.lastSeen) < timeout {
+			continue
+		}
+		delete(a.series, key)
+		if !s.overflow {
+			a.regularSeries--
+		}
+		atomic.AddInt64(&a.seriesPurged, 1)
+	}
+}
+
+// InternalMetrics returns datapoints describing the state of the aggregator
+func (a *Aggregator) InternalMetrics() []*datapoint.Datapoint {
+	a.Lock()
+	activeSeries := int64(len(a.series))
+	a.Unlock()
+
+	return []*datapoint.Datapoint{
+		sfxclient.Gauge("sfxagent.span_metrics_active_series", nil, activeSeries),
+		sfxclient.CumulativeP("sfxagent.span_metrics_spans_processed", nil, &a.spansProcessed),
+		sfxclient.CumulativeP("sfxagent.span_metrics_spans_overflowed", nil, &a.spansOverflowed),
+		sfxclient.CumulativeP("sfxagent.span_metrics_series_purged", nil, &a.seriesPurged),
+	}
+}
@@ -0,0 +1,91 @@
+package spanmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/golib/v3/pointer"
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/utils/timeutil"
+	"github.com/stretchr/testify/require"
+)
+
+func makeSpan(service, name string, durationMicros int64, tags map[string]string) *trace.Span {
+	return &trace.Span{
+		Name:          pointer.String(name),
+		Kind:          pointer.String("server"),
+		Duration:      pointer.Int64(durationMicros),
+		LocalEndpoint: &trace.Endpoint{ServiceName: pointer.String(service)},
+		Tags:          tags,
+	}
+}
+
+func valuesByMetric(dps []*datapoint.Datapoint, dims map[string]string) map[string]string {
+	out := map[string]string{}
+outer:
+	for _, dp := range dps {
+		for k, v := range dims {
+			if dp.Dimensions[k] != v {
+				continue outer
+			}
+		}
+		key := dp.Metric
+		if ub, ok := dp.Dimensions["upper_bound"]; ok {
+			key += ":" + ub
+		}
+		out[key] = dp.Value.String()
+	}
+	return out
+}
+
+func TestAggregator(t *testing.T) {
+	a := New(&config.SpanMetricsConfig{
+		DimensionTags:    []string{"http.status_code"},
+		HistogramBuckets: []float64{10, 100},
+		MaxTimeSeries:    2,
+		StaleTimeout:     timeutil.Duration(time.Minute),
+	})
+	now := time.Unix(1000, 0)
+	a.timeNow = func() time.Time { return now }
+
+	a.AddSpan(makeSpan("api", "GET /", 5000, map[string]string{"http.status_code": "200"}), false)
+	a.AddSpan(makeSpan("api", "GET /", 50000, map[string]string{"http.status_code": "200"}), false)
+	a.AddSpan(makeSpan("api", "GET /", 500000, map[string]string{"http.status_code": "200", "error": "true"}), false)
+	a.AddSpan(makeSpan("api", "POST /", 1000, map[string]string{"http.status_code": "500"}), false)
+	// This one goes over the max series and so is counted as overflow
+	a.AddSpan(makeSpan("api", "PUT /", 1000, nil), false)
+	// No service name so it is ignored
+	a.AddSpan(&trace.Span{Name: pointer.String("x")}, false)
+
+	dps := a.Datapoints()
+
+	require.Equal(t, map[string]string{
+		countMetric:           "3",
+		errorsMetric:          "1",
+		durationSumMetric:     "555",
+		bucketMetric + ":10":  "1",
+		bucketMetric + ":100": "2",
+	}, valuesByMetric(dps, map[string]string{
+		"service":          "api",
+		"operation":        "GET /",
+		"kind":             "SERVER",
+		"http_status_code": "200",
+	}))
+
+	require.Equal(t, "1", valuesByMetric(dps, map[string]string{
+		"service":   "api",
+		"operation": overflowOperation,
+	})[countMetric])
+
+	// Everything expires after the stale timeout
+	now = now.Add(2 * time.Minute)
+	require.Len(t, a.Datapoints(), 0)
+
+	a.AddSpan(makeSpan("api", "PUT /", 1000, nil), false)
+	require.Equal(t, "1", valuesByMetric(a.Datapoints(), map[string]string{
+		"service":   "api",
+		"operation": "PUT /",
+	})[countMetric])
+}
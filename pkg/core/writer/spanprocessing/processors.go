@@ -0,0 +1,173 @@
+// Package spanprocessing contains configurable processors that transform or
+// drop trace spans before they are sent out of the agent.
+package spanprocessing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/golib/v3/sfxclient"
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/utils/filter"
+)
+
+const defaultMaskReplacement = "****"
+
+type processor struct {
+	conf *config.SpanProcessorConfig
+
+	service   filter.StringFilter
+	operation filter.StringFilter
+	matchTags map[string]filter.StringFilter
+	pattern   *regexp.Regexp
+	// What to replace pattern matches with
+	replacement string
+}
+
+// Pipeline applies a list of processors to spans in order
+type Pipeline struct {
+	processors []*processor
+
+	spansDropped int64
+}
+
+// New creates a pipeline from the given processor configs
+func New(confs []config.SpanProcessorConfig) (*Pipeline, error) {
+	p := &Pipeline{}
+	for i := range confs {
+		proc, err := newProcessor(&confs[i])
+		if err != nil {
+			return nil, fmt.Errorf("span processor %d is invalid: %v", i, err)
+		}
+		p.processors = append(p.processors, proc)
+	}
+	return p, nil
+}
+
+func newProcessor(conf *config.SpanProcessorConfig) (*processor, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	proc := &processor{
+		conf:        conf,
+		replacement: defaultMaskReplacement,
+	}
+	if conf.Replacement != nil {
+		proc.replacement = *conf.Replacement
+	}
+
+	var err error
+	if conf.Service != "" {
+		if proc.service, err = filter.NewBasicStringFilter([]string{conf.Service}); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Operation != "" {
+		if proc.operation, err = filter.NewBasicStringFilter([]string{conf.Operation}); err != nil {
+			return nil, err
+		}
+	}
+	if len(conf.MatchTags) > 0 {
+		proc.matchTags = make(map[string]filter.StringFilter, len(conf.MatchTags))
+		for tag, value := range conf.MatchTags {
+			if proc.matchTags[tag], err = filter.NewBasicStringFilter([]string{value}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if conf.Pattern != "" {
+		if proc.pattern, err = regexp.Compile(conf.Pattern); err != nil {
+			return nil, err
+		}
+	}
+	return proc, nil
+}
+
+// Process applies all of the processors to the span in place, and returns
+// false if the span should be dropped.
+func (p *Pipeline) Process(span *trace.Span) bool {
+	for _, proc := range p.processors {
+		if !proc.matches(span) {
+			continue
+		}
+		if !proc.apply(span) {
+			atomic.AddInt64(&p.spansDropped, 1)
+			return false
+		}
+	}
+	return true
+}
+
+// InternalMetrics returns datapoints describing the pipeline
+func (p *Pipeline) InternalMetrics() []*datapoint.Datapoint {
+	return []*datapoint.Datapoint{
+		sfxclient.CumulativeP("sfxagent.span_processors_spans_dropped", nil, &p.spansDropped),
+	}
+}
+
+func (proc *processor) matches(span *trace.Span) bool {
+	if proc.service != nil {
+		if span.LocalEndpoint == nil || span.LocalEndpoint.ServiceName == nil ||
+			!proc.service.Matches(*span.LocalEndpoint.ServiceName) {
+			return false
+		}
+	}
+	if proc.operation != nil {
+		if span.Name == nil || !proc.operation.Matches(*span.Name) {
+			return false
+		}
+	}
+	for tag, f := range proc.matchTags {
+		v, ok := span.Tags[tag]
+		if !ok || !f.Matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply the processor to the span, returning false if the span should be
+// dropped
+func (proc *processor) apply(span *trace.Span) bool {
+	switch proc.conf.Action {
+	case config.SpanActionDrop:
+		return false
+	case config.SpanActionRename:
+		for oldName, newName := range proc.conf.Renames {
+			if v, ok := span.Tags[oldName]; ok {
+				delete(span.Tags, oldName)
+				span.Tags[newName] = v
+			}
+		}
+	case config.SpanActionDelete:
+		for _, tag := range proc.conf.Tags {
+			delete(span.Tags, tag)
+		}
+	case config.SpanActionHash:
+		proc.transformTags(span, func(v string) string {
+			sum := sha256.Sum256([]byte(v))
+			return hex.EncodeToString(sum[:])
+		})
+	case config.SpanActionMask:
+		proc.transformTags(span, func(v string) string {
+			return proc.pattern.ReplaceAllString(v, proc.replacement)
+		})
+	case config.SpanActionNormalizeSQL:
+		proc.transformTags(span, NormalizeSQL)
+	}
+	return true
+}
+
+func (proc *processor) transformTags(span *trace.Span, transform func(string) string) {
+	for _, tag := range proc.conf.Tags {
+		if v, ok := span.Tags[tag]; ok {
+			span.Tags[tag] = transform(v)
+		}
+	}
+}
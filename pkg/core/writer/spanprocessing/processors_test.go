@@ -0,0 +1,125 @@
+package spanprocessing
+
+import (
+	"testing"
+
+	"github.com/signalfx/golib/v3/pointer"
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/stretchr/testify/require"
+)
+
+func makeSpan(service, name string, tags map[string]string) *trace.Span {
+	return &trace.Span{
+		TraceID:       "abc",
+		ID:            "def",
+		Name:          pointer.String(name),
+		LocalEndpoint: &trace.Endpoint{ServiceName: pointer.String(service)},
+		Tags:          tags,
+	}
+}
+
+func TestProcessors(t *testing.T) {
+	p, err := New([]config.SpanProcessorConfig{
+		{
+			Action: config.SpanActionDelete,
+			Tags:   []string{"http.request.header.authorization"},
+		},
+		{
+			Action: config.SpanActionHash,
+			Tags:   []string{"user.email"},
+		},
+		{
+			Action:  config.SpanActionMask,
+			Tags:    []string{"http.url"},
+			Pattern: `token=[^&]+`,
+		},
+		{
+			Action:  config.SpanActionRename,
+			Service: "legacy-*",
+			Renames: map[string]string{"url": "http.url"},
+		},
+		{
+			Action:    config.SpanActionDrop,
+			Operation: "/healthz",
+		},
+		{
+			Action:    config.SpanActionDrop,
+			MatchTags: map[string]string{"synthetic": "true"},
+		},
+		{
+			Action: config.SpanActionNormalizeSQL,
+			Tags:   []string{"db.statement"},
+		},
+	})
+	require.NoError(t, err)
+
+	span := makeSpan("api", "GET /users", map[string]string{
+		"http.request.header.authorization": "Bearer secret",
+		"user.email":                        "bob@example.com",
+		"http.url":                          "http://api/users?token=abc123&page=2",
+		"db.statement":                      "SELECT * FROM users WHERE id = 5 AND name = 'bob'",
+	})
+	require.True(t, p.Process(span))
+	require.Equal(t, map[string]string{
+		"user.email":   "5ff860bf1190596c7188ab851db691f0f3169c453936e9e1eba2f9a47f7a0018",
+		"http.url":     "http://api/users?****&page=2",
+		"db.statement": "SELECT * FROM users WHERE id = ? AND name = ?",
+	}, span.Tags)
+
+	t.Run("rename only applies to matching service", func(t *testing.T) {
+		span := makeSpan("legacy-billing", "charge", map[string]string{"url": "http://billing"})
+		require.True(t, p.Process(span))
+		require.Equal(t, map[string]string{"http.url": "http://billing"}, span.Tags)
+
+		span = makeSpan("billing", "charge", map[string]string{"url": "http://billing"})
+		require.True(t, p.Process(span))
+		require.Equal(t, map[string]string{"url": "http://billing"}, span.Tags)
+	})
+
+	t.Run("spans are dropped", func(t *testing.T) {
+		require.False(t, p.Process(makeSpan("api", "/healthz", nil)))
+		require.False(t, p.Process(makeSpan("api", "GET /", map[string]string{"synthetic": "true"})))
+		require.True(t, p.Process(makeSpan("api", "GET /", map[string]string{"synthetic": "false"})))
+		require.Equal(t, int64(2), p.spansDropped)
+	})
+}
+
+func TestCustomMaskReplacement(t *testing.T) {
+	p, err := New([]config.SpanProcessorConfig{
+		{
+			Action:      config.SpanActionMask,
+			Tags:        []string{"card"},
+			Pattern:     `\d{12}(\d{4})`,
+			Replacement: pointer.String("XXXX$1"),
+		},
+	})
+	require.NoError(t, err)
+
+	span := makeSpan("api", "pay", map[string]string{"card": "4111111111111111"})
+	require.True(t, p.Process(span))
+	require.Equal(t, "XXXX1111", span.Tags["card"])
+}
+
+func TestInvalidProcessors(t *testing.T) {
+	for _, conf := range []config.SpanProcessorConfig{
+		{Action: "explode"},
+		{Action: config.SpanActionHash},
+		{Action: config.SpanActionMask, Tags: []string{"a"}, Pattern: "("},
+		{Action: config.SpanActionDrop},
+	} {
+		_, err := New([]config.SpanProcessorConfig{conf})
+		require.Error(t, err, "action %s", conf.Action)
+	}
+}
+
+func TestNormalizeSQL(t *testing.T) {
+	for in, expected := range map[string]string{
+		"SELECT * FROM t1 WHERE a = 1.5 AND b = -3":           "SELECT * FROM t1 WHERE a = ? AND b = ?",
+		"select name from users where id in (1, 2,3)":         "select name from users where id in (?)",
+		"INSERT INTO logs (msg, n)\n  VALUES ('it''s', 0x1F)": "INSERT INTO logs (msg, n) VALUES (?)",
+		"UPDATE t SET col2 = 'x' WHERE id = $1":               "UPDATE t SET col2 = ? WHERE id = $1",
+	} {
+		require.Equal(t, expected, NormalizeSQL(in), in)
+	}
+}
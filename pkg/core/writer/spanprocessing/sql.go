@@ -0,0 +1,33 @@
+package spanprocessing
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlHexLiteral    = regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b`)
+	// Identifiers like `table1` are not matched since there is no word
+	// boundary between the letters and the digits.
+	sqlNumberLiteral   = regexp.MustCompile(`(?:^|[^\w.$])(-?\d+(?:\.\d+)?(?:e[+-]?\d+)?)\b`)
+	sqlPlaceholderList = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	sqlWhitespace      = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeSQL replaces the literal values in a SQL statement with `?` so
+// that statements that only differ in their values are the same and don't
+// contain any sensitive data.  Lists of values, such as in `IN (1, 2, 3)`,
+// are collapsed to a single `(?)`.
+func NormalizeSQL(statement string) string {
+	s := sqlStringLiteral.ReplaceAllString(statement, "?")
+	s = sqlHexLiteral.ReplaceAllString(s, "?")
+	s = sqlNumberLiteral.ReplaceAllStringFunc(s, func(m string) string {
+		sub := sqlNumberLiteral.FindStringSubmatchIndex(m)
+		// Keep whatever character came before the number
+		return m[:sub[2]] + "?"
+	})
+	s = sqlPlaceholderList.ReplaceAllString(s, "(?)")
+	s = sqlWhitespace.ReplaceAllString(s, " ")
+	return strings.TrimSpace(s)
+}
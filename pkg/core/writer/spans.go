@@ -13,20 +13,26 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func (sw *SignalFxWriter) sendSpans(ctx context.Context, spans []*trace.Span) error {
+func (sw *SignalFxWriter) sendSpans(_ context.Context, spans []*trace.Span) error {
 	if *sw.conf.SendTraceHostCorrelationMetrics {
 		sw.serviceTracker.AddSpans(sw.ctx, spans)
 	}
 
 	// This sends synchonously
-	return sw.client.AddSpans(context.Background(), spans)
+	return sw.currentSink().AddSpans(sw.sendCtx, spans)
 }
 
 func (sw *SignalFxWriter) preprocessSpan(span *trace.Span) bool {
+	// The sampler does the processing itself before it buffers spans
+	if sw.sampler == nil && !sw.processSpan(span) {
+		return false
+	}
+
 	// Some spans aren't really specific to the host they are running
 	// on and shouldn't have any host-specific tags.  This is indicated by a
 	// special tag key (value is irrelevant).
-	if _, ok := span.Tags[dpmeta.NotHostSpecificMeta]; !ok {
+	_, notHostSpecific := span.Tags[dpmeta.NotHostSpecificMeta]
+	if !notHostSpecific {
 		span.Tags = sw.addhostIDFields(span.Tags)
 	} else {
 		// Get rid of the tag so it doesn't pass through to the backend
@@ -37,6 +43,7 @@ func (sw *SignalFxWriter) preprocessSpan(span *trace.Span) bool {
 
 	// adding smart agent version as a tag
 	span.Tags["signalfx.smartagent.version"] = constants.Version
+
 	if sw.conf.LogTraceSpans {
 		jsonEncoded, _ := json.Marshal(span)
 		log.Infof("Sending trace span:\n%s", string(jsonEncoded))
@@ -61,3 +68,26 @@ func (sw *SignalFxWriter) startGeneratingHostCorrelationMetrics() *tracetracker.
 
 	return tracker
 }
+
+// processSpan runs the configured span processors on the span and then adds
+// it to the span metrics.  It returns false if the span should be dropped.
+// This happens before sampling, if it is enabled, so that the span metrics
+// include every span and no unredacted data is buffered.
+func (sw *SignalFxWriter) processSpan(span *trace.Span) bool {
+	if sw.spanProcessors != nil && !sw.spanProcessors.Process(span) {
+		return false
+	}
+	if sw.spanMetrics != nil {
+		_, notHostSpecific := span.Tags[dpmeta.NotHostSpecificMeta]
+		sw.spanMetrics.AddSpan(span, notHostSpecific)
+	}
+	return true
+}
+
+func (sw *SignalFxWriter) startGeneratingSpanMetrics() {
+	utils.RunOnInterval(sw.ctx, func() {
+		if dps := sw.spanMetrics.Datapoints(); len(dps) > 0 {
+			sw.dpChan <- dps
+		}
+	}, sw.conf.SpanMetrics.Interval.AsDuration())
+}
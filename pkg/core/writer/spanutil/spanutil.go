@@ -0,0 +1,17 @@
+// Package spanutil contains helpers for classifying trace spans that are
+// shared by the span processing stages of the writer, so that they all agree
+// on things like what counts as an error.
+package spanutil
+
+import (
+	"strings"
+
+	"github.com/signalfx/golib/v3/trace"
+)
+
+// IsError returns true if the span has the `error` tag set to `true` or
+// `otel.status_code` set to `ERROR`
+func IsError(span *trace.Span) bool {
+	return strings.EqualFold(span.Tags["error"], "true") ||
+		strings.EqualFold(span.Tags["otel.status_code"], "ERROR")
+}
@@ -0,0 +1,16 @@
+package spanutil
+
+import (
+	"testing"
+
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsError(t *testing.T) {
+	require.False(t, IsError(&trace.Span{}))
+	require.False(t, IsError(&trace.Span{Tags: map[string]string{"error": "false"}}))
+	require.True(t, IsError(&trace.Span{Tags: map[string]string{"error": "True"}}))
+	require.True(t, IsError(&trace.Span{Tags: map[string]string{"otel.status_code": "ERROR"}}))
+	require.False(t, IsError(&trace.Span{Tags: map[string]string{"otel.status_code": "OK"}}))
+}
@@ -13,7 +13,10 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
@@ -24,7 +27,12 @@ import (
 	"github.com/signalfx/signalfx-agent/pkg/core/common/dpmeta"
 	"github.com/signalfx/signalfx-agent/pkg/core/config"
 	"github.com/signalfx/signalfx-agent/pkg/core/dpfilters"
+	"github.com/signalfx/signalfx-agent/pkg/core/writer/aggregation"
+	"github.com/signalfx/signalfx-agent/pkg/core/writer/counterconversion"
 	"github.com/signalfx/signalfx-agent/pkg/core/writer/dimensions"
+	"github.com/signalfx/signalfx-agent/pkg/core/writer/sampling"
+	"github.com/signalfx/signalfx-agent/pkg/core/writer/spanmetrics"
+	"github.com/signalfx/signalfx-agent/pkg/core/writer/spanprocessing"
 	"github.com/signalfx/signalfx-agent/pkg/core/writer/tap"
 	"github.com/signalfx/signalfx-agent/pkg/core/writer/tracetracker"
 	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
@@ -49,12 +57,32 @@ type SignalFxWriter struct {
 	datapointWriter *sfxwriter.DatapointWriter
 	spanWriter      *sfxwriter.SpanWriter
 
+	// Where datapoints, events and spans are sent, which is client unless
+	// monitor inputs are being replayed.  It is changed to the sink of the
+	// writer that replaces this one so that what is still buffered is sent
+	// with the new config.
+	sink     sink
+	sinkLock sync.RWMutex
+	// Set while monitor inputs are being replayed, in which case nothing is
+	// sent to SignalFx
+	replayOutput *replayOutput
+
 	// Monitors should send events to this
 	eventChan     chan *event.Event
 	dimensionChan chan *types.Dimension
 
 	ctx    context.Context
 	cancel context.CancelFunc
+	// Requests to ingest use their own context so that whatever is buffered
+	// can still be sent after ctx is cancelled on shutdown.
+	sendCtx     context.Context
+	cancelSends context.CancelFunc
+	// Stops the sampler separately so that it can flush its pending traces
+	// to the span writer before that stops.
+	stopSampler context.CancelFunc
+	// Closed once the events buffered at shutdown have been sent
+	eventsFlushed chan struct{}
+
 	conf   *config.WriterConfig
 	logger *utils.ThrottledLogger
 	dpTap  *tap.DatapointTap
@@ -62,6 +90,12 @@ type SignalFxWriter struct {
 	// map that holds host-specific ids like AWSUniqueID
 	hostIDDims       map[string]string
 	datapointFilters *dpfilters.FilterSet
+	// Converts cumulative counters to rates or deltas, nil if there are no
+	// conversions configured
+	counterConverter *counterconversion.Converter
+	// Rolls up datapoints according to the aggregation rules, nil if there
+	// are none
+	aggregator *aggregation.Aggregator
 
 	eventBuffer []*event.Event
 
@@ -69,6 +103,14 @@ type SignalFxWriter struct {
 	// emitted by the agent
 	serviceTracker    *tracetracker.ActiveServiceTracker
 	spanSourceTracker *tracetracker.SpanSourceTracker
+	// Redacts, transforms and drops trace spans, nil if none are configured
+	spanProcessors *spanprocessing.Pipeline
+	// Generates metrics from trace spans, nil if disabled
+	spanMetrics *spanmetrics.Aggregator
+	// Samples trace spans before they go to the span writer, nil if disabled
+	sampler *sampling.Sampler
+	// Where the sampler sends the spans of the traces it keeps
+	sampledChan chan []*trace.Span
 
 	// Datapoints sent in the last minute
 	datapointsLastMinute int64
@@ -85,25 +127,80 @@ type SignalFxWriter struct {
 	traceSpansDropped int64
 	eventsSent        int64
 	startTime         time.Time
+
+	// When datapoints were last sent successfully and when sending them
+	// last failed, as Unix nanoseconds
+	lastSuccessfulSend int64
+	lastFailedSend     int64
+	lastSendError      atomic.Value
 }
 
-// New creates a new un-configured writer
+// New creates a new writer that starts taking input right away
 func New(conf *config.WriterConfig, dpChan chan []*datapoint.Datapoint, eventChan chan *event.Event,
 	dimensionChan chan *types.Dimension, spanChan chan []*trace.Span,
 	spanSourceTracker *tracetracker.SpanSourceTracker) (*SignalFxWriter, error) {
+	sw, err := newWriter(conf, dpChan, eventChan, dimensionChan, spanChan, spanSourceTracker, nil)
+	if err != nil {
+		return nil, err
+	}
+	sw.start()
+	return sw, nil
+}
+
+// Replace this writer with a new one that has the new config.  This writer
+// stops taking input and sends what it has buffered through the new writer's
+// client, so that it goes out with the new token and URLs, before the new
+// writer starts taking input.  The counter conversions, datapoint
+// aggregations, span metrics and trace sampler are kept by the new writer if
+// their config hasn't changed, so that their state isn't lost.  This writer
+// keeps running if the new one can't be created.
+func (sw *SignalFxWriter) Replace(conf *config.WriterConfig,
+	spanSourceTracker *tracetracker.SpanSourceTracker) (*SignalFxWriter, error) {
+	next, err := newWriter(conf, sw.dpChan, sw.eventChan, sw.dimensionChan, sw.spanChan, spanSourceTracker, sw)
+	if err != nil {
+		return nil, err
+	}
+
+	sw.setSink(next.currentSink())
+	sw.stop(next.sampler != nil && next.sampler == sw.sampler)
+	if sw.replayOutput != next.replayOutput {
+		sw.closeReplayOutput()
+	}
+
+	next.start()
+	log.Debug("Replaced datapoint writer")
+	return next, nil
+}
+
+// Creates the writer without starting anything.  The stateful parts of prev,
+// if given, are used instead of new ones if their config is the same.
+func newWriter(conf *config.WriterConfig, dpChan chan []*datapoint.Datapoint, eventChan chan *event.Event,
+	dimensionChan chan *types.Dimension, spanChan chan []*trace.Span,
+	spanSourceTracker *tracetracker.SpanSourceTracker, prev *SignalFxWriter) (*SignalFxWriter, error) {
 	logger := utils.NewThrottledLogger(logrus.WithFields(log.Fields{"component": "writer"}), 20*time.Second)
 
 	ctx, cancel := context.WithCancel(context.Background())
+	sendCtx, cancelSends := context.WithCancel(context.Background())
+	fail := func(err error) (*SignalFxWriter, error) {
+		cancel()
+		cancelSends()
+		return nil, err
+	}
+	unchanged := func(field func(*config.WriterConfig) interface{}) bool {
+		return prev != nil && reflect.DeepEqual(field(prev.conf), field(conf))
+	}
 
 	dimensionClient, err := dimensions.NewDimensionClient(ctx, conf)
 	if err != nil {
-		cancel()
-		return nil, err
+		return fail(err)
 	}
 
 	sw := &SignalFxWriter{
 		ctx:               ctx,
 		cancel:            cancel,
+		sendCtx:           sendCtx,
+		cancelSends:       cancelSends,
+		eventsFlushed:     make(chan struct{}),
 		conf:              conf,
 		logger:            logger,
 		dimensionClient:   dimensionClient,
@@ -123,11 +220,25 @@ func New(conf *config.WriterConfig, dpChan chan []*datapoint.Datapoint, eventCha
 	case config.TraceExportFormatSAPM:
 		sinkOptions = append(sinkOptions, sfxclient.WithSAPMTraceExporter())
 	default:
-		return nil, fmt.Errorf("trace export format '%s' is not supported", conf.TraceExportFormat)
+		return fail(fmt.Errorf("trace export format '%s' is not supported", conf.TraceExportFormat))
 	}
 	sw.client = sfxclient.NewHTTPSink(sinkOptions...)
+	sw.sink = sw.client
 
-	go sw.maintainLastMinuteActivity()
+	if prev != nil {
+		sw.dpTap = prev.dpTap
+	}
+
+	if conf.Replaying {
+		// Keep writing to the same file so that what was already written
+		// isn't truncated
+		if prev != nil && prev.replayOutput != nil && prev.conf.ReplayOutputPath == conf.ReplayOutputPath {
+			sw.replayOutput = prev.replayOutput
+		} else if sw.replayOutput, err = newReplayOutput(conf.ReplayOutputPath); err != nil {
+			return fail(err)
+		}
+		sw.sink = sw.replayOutput
+	}
 
 	sw.client.AuthToken = conf.SignalFxAccessToken
 
@@ -150,7 +261,7 @@ func New(conf *config.WriterConfig, dpChan chan []*datapoint.Datapoint, eventCha
 			"error":     err,
 			"ingestURL": conf.ParsedIngestURL().String(),
 		}).Error("Could not construct datapoint ingest URL")
-		return nil, err
+		return fail(err)
 	}
 	sw.client.DatapointEndpoint = dpEndpointURL.String()
 
@@ -163,7 +274,7 @@ func New(conf *config.WriterConfig, dpChan chan []*datapoint.Datapoint, eventCha
 				"error":     err,
 				"ingestURL": conf.ParsedIngestURL().String(),
 			}).Error("Could not construct event ingest URL")
-			return nil, err
+			return fail(err)
 		}
 	}
 	sw.client.EventEndpoint = eventEndpointURL.String()
@@ -177,19 +288,31 @@ func New(conf *config.WriterConfig, dpChan chan []*datapoint.Datapoint, eventCha
 				"error":     err,
 				"ingestURL": conf.ParsedIngestURL().String(),
 			}).Error("Could not construct trace ingest URL")
-			return nil, err
+			return fail(err)
 		}
 	}
 	sw.client.TraceEndpoint = traceEndpointURL.String()
 
 	sw.datapointFilters, err = sw.conf.DatapointFilters()
 	if err != nil {
-		return nil, err
+		return fail(err)
 	}
 
-	sw.dimensionClient.Start()
+	if len(conf.CounterConversions) > 0 {
+		if unchanged(func(c *config.WriterConfig) interface{} { return c.CounterConversions }) {
+			sw.counterConverter = prev.counterConverter
+		} else if sw.counterConverter, err = counterconversion.New(conf.CounterConversions); err != nil {
+			return fail(err)
+		}
+	}
 
-	go sw.listenForEventsAndDimensionUpdates()
+	if len(conf.DatapointAggregations) > 0 {
+		if unchanged(func(c *config.WriterConfig) interface{} { return c.DatapointAggregations }) {
+			sw.aggregator = prev.aggregator
+		} else if sw.aggregator, err = aggregation.New(conf.DatapointAggregations); err != nil {
+			return fail(err)
+		}
+	}
 
 	sw.datapointWriter = &sfxwriter.DatapointWriter{
 		PreprocessFunc: sw.preprocessDatapoint,
@@ -203,11 +326,37 @@ func New(conf *config.WriterConfig, dpChan chan []*datapoint.Datapoint, eventCha
 		InputChan:    sw.dpChan,
 	}
 
-	sw.datapointWriter.Start(ctx)
+	if len(conf.SpanProcessors) > 0 {
+		sw.spanProcessors, err = spanprocessing.New(conf.SpanProcessors)
+		if err != nil {
+			return fail(err)
+		}
+	}
 
-	// The only reason this is on the struct and not a local var is so we can
-	// easily get diagnostic metrics from it
-	sw.serviceTracker = sw.startGeneratingHostCorrelationMetrics()
+	if conf.SpanMetrics.Enabled {
+		if unchanged(func(c *config.WriterConfig) interface{} { return c.SpanMetrics }) {
+			sw.spanMetrics = prev.spanMetrics
+		} else {
+			sw.spanMetrics = spanmetrics.New(&conf.SpanMetrics)
+		}
+	}
+
+	spanInputChan := sw.spanChan
+	if conf.TraceSampling.Enabled {
+		if unchanged(func(c *config.WriterConfig) interface{} { return c.TraceSampling }) {
+			// The sampler keeps running while prev is replaced
+			sw.sampler = prev.sampler
+			sw.sampledChan = prev.sampledChan
+			sw.stopSampler = prev.stopSampler
+		} else {
+			sw.sampledChan = make(chan []*trace.Span, cap(sw.spanChan))
+			sw.sampler, err = sampling.New(&conf.TraceSampling, sw.spanChan, sw.sampledChan, sw.processSpan)
+			if err != nil {
+				return fail(err)
+			}
+		}
+		spanInputChan = sw.sampledChan
+	}
 
 	sw.spanWriter = &sfxwriter.SpanWriter{
 		PreprocessFunc: sw.preprocessSpan,
@@ -215,17 +364,60 @@ func New(conf *config.WriterConfig, dpChan chan []*datapoint.Datapoint, eventCha
 		MaxBatchSize:   conf.TraceSpanMaxBatchSize,
 		MaxRequests:    conf.MaxRequests,
 		MaxBuffered:    int(conf.MaxTraceSpansInFlight),
-		InputChan:      sw.spanChan,
+		InputChan:      spanInputChan,
 	}
-	sw.spanWriter.Start(ctx)
-
-	log.Infof("Sending datapoints to %s", sw.client.DatapointEndpoint)
-	log.Infof("Sending events to %s", sw.client.EventEndpoint)
-	log.Infof("Sending trace spans to %s", sw.client.TraceEndpoint)
 
 	return sw, nil
 }
 
+// Starts taking input and sending it
+func (sw *SignalFxWriter) start() {
+	go sw.maintainLastMinuteActivity()
+
+	if sw.counterConverter != nil {
+		sw.counterConverter.Start(sw.ctx)
+	}
+	if sw.aggregator != nil {
+		sw.aggregator.Start(sw.ctx, func(dps []*datapoint.Datapoint) {
+			sw.dpChan <- dps
+		})
+	}
+
+	sw.dimensionClient.Start()
+
+	go sw.listenForEventsAndDimensionUpdates()
+
+	sw.datapointWriter.Start(sw.ctx)
+
+	// The only reason this is on the struct and not a local var is so we can
+	// easily get diagnostic metrics from it
+	sw.serviceTracker = sw.startGeneratingHostCorrelationMetrics()
+
+	if sw.spanMetrics != nil {
+		sw.startGeneratingSpanMetrics()
+	}
+
+	if sw.sampler != nil {
+		if sw.stopSampler == nil {
+			var samplerCtx context.Context
+			samplerCtx, sw.stopSampler = context.WithCancel(context.Background())
+			sw.sampler.Start(samplerCtx)
+		} else {
+			sw.sampler.SetPreprocess(sw.processSpan)
+		}
+	}
+
+	sw.spanWriter.Start(sw.ctx)
+
+	if sw.replayOutput != nil {
+		log.Infof("Replaying monitor inputs, nothing will be sent to SignalFx")
+	} else {
+		log.Infof("Sending datapoints to %s", sw.client.DatapointEndpoint)
+		log.Infof("Sending events to %s", sw.client.EventEndpoint)
+		log.Infof("Sending trace spans to %s", sw.client.TraceEndpoint)
+	}
+}
+
 func (sw *SignalFxWriter) shouldSendDatapoint(dp *datapoint.Datapoint) bool {
 	return sw.datapointFilters == nil || !sw.datapointFilters.Matches(dp)
 }
@@ -235,6 +427,16 @@ func (sw *SignalFxWriter) preprocessDatapoint(dp *datapoint.Datapoint) bool {
 		return false
 	}
 
+	if sw.counterConverter != nil && !sw.counterConverter.Convert(dp) {
+		return false
+	}
+
+	// The aggregates come back through here, where they get the global
+	// dimensions and host id dimensions
+	if sw.aggregator != nil && !sw.aggregator.Add(dp) {
+		return false
+	}
+
 	dp.Dimensions = sw.addGlobalDims(dp.Dimensions)
 
 	// Some metrics aren't really specific to the host they are running
@@ -252,17 +454,20 @@ func (sw *SignalFxWriter) preprocessDatapoint(dp *datapoint.Datapoint) bool {
 	return true
 }
 
-func (sw *SignalFxWriter) sendDatapoints(ctx context.Context, dps []*datapoint.Datapoint) error {
+func (sw *SignalFxWriter) sendDatapoints(_ context.Context, dps []*datapoint.Datapoint) error {
 	// This sends synchonously
-	err := sw.client.AddDatapoints(ctx, dps)
+	err := sw.currentSink().AddDatapoints(sw.sendCtx, dps)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"error": err,
-		}).Error("Error shipping datapoints to SignalFx")
+		// This can happen on every send if there is a network issue so
+		// don't flood the logs
+		sw.logger.WithError(err).ThrottledError("Error shipping datapoints to SignalFx")
+		atomic.StoreInt64(&sw.lastFailedSend, time.Now().UnixNano())
+		sw.lastSendError.Store(err.Error())
 		// If there is an error sending datapoints then just forget about them.
 		return err
 	}
 	log.Debugf("Sent %d datapoints out of the agent", len(dps))
+	atomic.StoreInt64(&sw.lastSuccessfulSend, time.Now().UnixNano())
 
 	// dpTap.Accept handles the receiver being nil
 	sw.dpTap.Accept(dps)
@@ -295,7 +500,7 @@ func (sw *SignalFxWriter) sendEvents(events []*event.Event) error {
 		}
 	}
 
-	err := sw.client.AddEvents(context.Background(), events)
+	err := sw.currentSink().AddEvents(sw.sendCtx, events)
 	if err != nil {
 		return err
 	}
@@ -345,6 +550,12 @@ func (sw *SignalFxWriter) listenForEventsAndDimensionUpdates() {
 	for {
 		select {
 		case <-sw.ctx.Done():
+			if len(sw.eventBuffer) > 0 {
+				if err := sw.sendEvents(sw.eventBuffer); err != nil {
+					log.WithError(err).Error("Error shipping events to SignalFx on shutdown")
+				}
+			}
+			close(sw.eventsFlushed)
 			return
 
 		case event := <-sw.eventChan:
@@ -368,6 +579,12 @@ func (sw *SignalFxWriter) listenForEventsAndDimensionUpdates() {
 				initEventBuffer()
 			}
 		case dim := <-sw.dimensionChan:
+			if sw.replayOutput != nil {
+				if err := sw.replayOutput.AddDimension(dim); err != nil {
+					log.WithError(err).Error("Could not write dimension update to replay output")
+				}
+				continue
+			}
 			if err := sw.dimensionClient.AcceptDimension(dim); err != nil {
 				log.WithFields(log.Fields{
 					"dimName":  dim.Name,
@@ -378,16 +595,113 @@ func (sw *SignalFxWriter) listenForEventsAndDimensionUpdates() {
 	}
 }
 
+// SendStatus returns when datapoints were last sent successfully and when,
+// and with what error, sending them last failed.  The times are zero if it
+// hasn't happened yet.
+func (sw *SignalFxWriter) SendStatus() (lastSuccess time.Time, lastFailure time.Time, lastErr string) {
+	if ns := atomic.LoadInt64(&sw.lastSuccessfulSend); ns > 0 {
+		lastSuccess = time.Unix(0, ns)
+	}
+	if ns := atomic.LoadInt64(&sw.lastFailedSend); ns > 0 {
+		lastFailure = time.Unix(0, ns)
+	}
+	lastErr, _ = sw.lastSendError.Load().(string)
+	return lastSuccess, lastFailure, lastErr
+}
+
+// StartTime returns when the writer was created
+func (sw *SignalFxWriter) StartTime() time.Time {
+	return sw.startTime
+}
+
 // SetTap allows you to set one datapoint tap at a time to inspect datapoints
 // going out of the agent.
 func (sw *SignalFxWriter) SetTap(dpTap *tap.DatapointTap) {
 	sw.dpTap = dpTap
 }
 
-// Shutdown the writer and stop sending datapoints
+func (sw *SignalFxWriter) currentSink() sink {
+	sw.sinkLock.RLock()
+	defer sw.sinkLock.RUnlock()
+	return sw.sink
+}
+
+func (sw *SignalFxWriter) setSink(s sink) {
+	sw.sinkLock.Lock()
+	defer sw.sinkLock.Unlock()
+	sw.sink = s
+}
+
+// Shutdown the writer.  It stops taking input right away and then keeps
+// sending what it has buffered for up to the shutdown flush timeout.
 func (sw *SignalFxWriter) Shutdown() {
-	if sw.cancel != nil {
-		sw.cancel()
-	}
+	sw.stop(false)
+	sw.closeReplayOutput()
 	log.Debug("Stopped datapoint writer")
 }
+
+// Stops taking input and sends what is buffered, for up to the shutdown
+// flush timeout.  The sampler is left running if it is kept by the writer
+// that replaces this one.  Pending dimension updates are put back on the
+// dimension channel so that a writer that replaces this one picks them up.
+func (sw *SignalFxWriter) stop(keepSampler bool) {
+	// Shared by both waits below, so it can't be a channel from time.After
+	// that only fires once
+	timeoutCtx, cancelTimeout := context.WithTimeout(context.Background(), sw.conf.ShutdownFlushTimeout.AsDuration())
+	defer cancelTimeout()
+	timeout := timeoutCtx.Done()
+
+	if sw.stopSampler != nil && !keepSampler {
+		sw.stopSampler()
+		select {
+		case <-sw.sampler.Stopped():
+		case <-timeout:
+			// Nothing reads the sampled spans once the span writer is
+			// cancelled below, so the sampler would block forever
+			sw.logger.Warn("Timed out sending sampled traces while shutting down the trace sampler")
+			sw.sampler.Abort()
+		}
+	}
+
+	sw.cancel()
+
+	flushed := make(chan struct{})
+	go func() {
+		sw.datapointWriter.WaitForShutdown()
+		sw.spanWriter.WaitForShutdown()
+		<-sw.eventsFlushed
+		close(flushed)
+	}()
+
+	select {
+	case <-flushed:
+		log.Debug("Flushed datapoint writer buffers")
+	case <-timeout:
+		sw.logger.Warn("Timed out sending buffered data while shutting down the datapoint writer")
+	}
+	sw.cancelSends()
+
+	sw.handOffDimensions()
+}
+
+func (sw *SignalFxWriter) closeReplayOutput() {
+	if sw.replayOutput == nil {
+		return
+	}
+	if err := sw.replayOutput.Close(); err != nil {
+		log.WithError(err).Error("Could not close replay output")
+	}
+}
+
+// Puts the dimension updates that haven't been sent back on the dimension
+// channel.  The channel is buffered, so this drops them instead of blocking
+// if nothing is receiving on it.
+func (sw *SignalFxWriter) handOffDimensions() {
+	for _, dim := range sw.dimensionClient.Drain() {
+		select {
+		case sw.dimensionChan <- dim:
+		default:
+			sw.logger.WithField("dimName", dim.Name).ThrottledWarning("Dropping dimension update on shutdown")
+		}
+	}
+}
@@ -1,12 +1,29 @@
 package writer
 
 import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
-	"github.com/signalfx/signalfx-agent/pkg/utils/timeutil"
-
+	"github.com/gogo/protobuf/proto"
+	"github.com/signalfx/com_signalfx_metrics_protobuf"
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/golib/v3/event"
+	"github.com/signalfx/golib/v3/sfxclient"
+	"github.com/signalfx/golib/v3/trace"
 	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	"github.com/signalfx/signalfx-agent/pkg/utils/timeutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -39,3 +56,222 @@ func TestWriterSetup(t *testing.T) {
 		require.Equal(t, "http://example.com/v2/event", writer.client.EventEndpoint)
 	})
 }
+
+func TestShutdownFlushesBufferedData(t *testing.T) {
+	var lock sync.Mutex
+	var metrics []string
+	var eventRequests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// Slow enough that datapoints are still buffered when the writer is
+		// shut down
+		time.Sleep(200 * time.Millisecond)
+
+		var body io.Reader = req.Body
+		if req.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(req.Body)
+			if err != nil {
+				rw.WriteHeader(400)
+				return
+			}
+			body = gz
+		}
+		payload, err := ioutil.ReadAll(body)
+		if err != nil {
+			rw.WriteHeader(400)
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch req.URL.Path {
+		case "/v2/datapoint":
+			var msg com_signalfx_metrics_protobuf.DataPointUploadMessage
+			if err := proto.Unmarshal(payload, &msg); err != nil {
+				rw.WriteHeader(400)
+				return
+			}
+			for _, dp := range msg.GetDatapoints() {
+				metrics = append(metrics, dp.GetMetric())
+			}
+		case "/v2/event":
+			eventRequests++
+		}
+		_, _ = rw.Write([]byte(`"OK"`))
+	}))
+	defer server.Close()
+
+	conf := essentialWriterConfig
+	conf.IngestURL = server.URL
+	conf.DatapointMaxBatchSize = 1
+	conf.MaxRequests = 1
+	conf.EventSendIntervalSeconds = 60
+	conf.ShutdownFlushTimeout = timeutil.Duration(5 * time.Second)
+
+	dpChan := make(chan []*datapoint.Datapoint, 10)
+	eventChan := make(chan *event.Event, 10)
+	writer, err := New(&conf, dpChan, eventChan, make(chan *types.Dimension, 10), make(chan []*trace.Span, 10), nil)
+	require.Nil(t, err)
+
+	dpChan <- []*datapoint.Datapoint{
+		datapoint.New("a", nil, datapoint.NewIntValue(1), datapoint.Gauge, time.Now()),
+		datapoint.New("b", nil, datapoint.NewIntValue(1), datapoint.Gauge, time.Now()),
+		datapoint.New("c", nil, datapoint.NewIntValue(1), datapoint.Gauge, time.Now()),
+	}
+	eventChan <- event.New("restart", event.AGENT, nil, time.Now())
+
+	// Let the writer take them off of the channels.  Only one request can
+	// be active so the last two datapoints and the event are still buffered.
+	time.Sleep(50 * time.Millisecond)
+	writer.Shutdown()
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, []string{"a", "b", "c"}, metrics)
+	require.Equal(t, 1, eventRequests)
+}
+
+func TestReplayingWritesToOutputFile(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		_, _ = rw.Write([]byte(`"OK"`))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "writer")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	outPath := filepath.Join(dir, "output.json")
+
+	conf := essentialWriterConfig
+	conf.IngestURL = server.URL
+	conf.APIURL = server.URL
+	conf.MaxRequests = 1
+	conf.ShutdownFlushTimeout = timeutil.Duration(5 * time.Second)
+	conf.Replaying = true
+	conf.ReplayOutputPath = outPath
+
+	dpChan := make(chan []*datapoint.Datapoint, 10)
+	dimChan := make(chan *types.Dimension, 10)
+	writer, err := New(&conf, dpChan, make(chan *event.Event, 10), dimChan, make(chan []*trace.Span, 10), nil)
+	require.Nil(t, err)
+
+	dpChan <- []*datapoint.Datapoint{
+		datapoint.New("a", map[string]string{"host": "x"}, datapoint.NewIntValue(1), datapoint.Gauge, time.Now()),
+	}
+	dimChan <- &types.Dimension{Name: "host", Value: "x", Properties: map[string]string{"role": "db"}}
+
+	time.Sleep(100 * time.Millisecond)
+	writer.Shutdown()
+
+	require.Equal(t, int64(0), atomic.LoadInt64(&requests))
+
+	content, err := ioutil.ReadFile(outPath)
+	require.Nil(t, err)
+
+	var dpSeen, dimSeen bool
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var entry replayOutputEntry
+		require.Nil(t, json.Unmarshal([]byte(line), &entry))
+		if entry.Datapoint != nil {
+			require.Equal(t, "a", entry.Datapoint.Metric)
+			dpSeen = true
+		}
+		if entry.Dimension != nil {
+			require.Equal(t, "db", entry.Dimension.Properties["role"])
+			dimSeen = true
+		}
+	}
+	require.True(t, dpSeen)
+	require.True(t, dimSeen)
+}
+
+func TestReplaceSendsBufferedDataWithNewConfig(t *testing.T) {
+	var lock sync.Mutex
+	tokensByMetric := map[string][]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+
+		if req.URL.Path != "/v2/datapoint" {
+			_, _ = rw.Write([]byte(`"OK"`))
+			return
+		}
+
+		var body io.Reader = req.Body
+		if req.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(req.Body)
+			if err != nil {
+				rw.WriteHeader(400)
+				return
+			}
+			body = gz
+		}
+		payload, err := ioutil.ReadAll(body)
+		if err != nil {
+			rw.WriteHeader(400)
+			return
+		}
+		var msg com_signalfx_metrics_protobuf.DataPointUploadMessage
+		if err := proto.Unmarshal(payload, &msg); err != nil {
+			rw.WriteHeader(400)
+			return
+		}
+
+		lock.Lock()
+		for _, dp := range msg.GetDatapoints() {
+			tokensByMetric[dp.GetMetric()] = append(tokensByMetric[dp.GetMetric()], req.Header.Get(sfxclient.TokenHeaderName))
+		}
+		lock.Unlock()
+		_, _ = rw.Write([]byte(`"OK"`))
+	}))
+	defer server.Close()
+
+	newConf := func(token string) *config.WriterConfig {
+		conf := essentialWriterConfig
+		conf.IngestURL = server.URL
+		conf.APIURL = server.URL
+		conf.SignalFxAccessToken = token
+		conf.DatapointMaxBatchSize = 1
+		conf.MaxRequests = 1
+		conf.ShutdownFlushTimeout = timeutil.Duration(5 * time.Second)
+		conf.CounterConversions = []config.CounterConversionConfig{
+			{MetricFilter: config.MetricFilter{MetricName: "requests"}, Convert: config.CounterConversionDelta},
+		}
+		return &conf
+	}
+
+	dpChan := make(chan []*datapoint.Datapoint, 10)
+	writer, err := New(newConf("old"), dpChan, make(chan *event.Event, 10), make(chan *types.Dimension, 10), make(chan []*trace.Span, 10), nil)
+	require.Nil(t, err)
+
+	dpChan <- []*datapoint.Datapoint{
+		datapoint.New("requests", nil, datapoint.NewIntValue(10), datapoint.Counter, time.Now()),
+		datapoint.New("a", nil, datapoint.NewIntValue(1), datapoint.Gauge, time.Now()),
+		datapoint.New("b", nil, datapoint.NewIntValue(1), datapoint.Gauge, time.Now()),
+		datapoint.New("c", nil, datapoint.NewIntValue(1), datapoint.Gauge, time.Now()),
+	}
+
+	// Only a is in flight when the writer is replaced
+	time.Sleep(50 * time.Millisecond)
+	writer, err = writer.Replace(newConf("new"), nil)
+	require.Nil(t, err)
+
+	// Converted since the last value of the first writer is kept
+	dpChan <- []*datapoint.Datapoint{
+		datapoint.New("requests", nil, datapoint.NewIntValue(15), datapoint.Counter, time.Now().Add(time.Second)),
+	}
+	time.Sleep(50 * time.Millisecond)
+	writer.Shutdown()
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, map[string][]string{
+		"a":        {"old"},
+		"b":        {"new"},
+		"c":        {"new"},
+		"requests": {"new"},
+	}, tokensByMetric)
+}
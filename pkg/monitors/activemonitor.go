@@ -3,6 +3,7 @@ package monitors
 import (
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/signalfx/defaults"
@@ -26,8 +27,17 @@ type ActiveMonitor struct {
 	output     types.FilteringOutput
 	config     config.MonitorCustomConfig
 	endpoint   services.Endpoint
+	health     *monitorHealth
 	// Is the monitor marked for deletion?
 	doomed bool
+
+	// The key of the monitor's inputs in the input recording bundle
+	recordingKey string
+	// Set if the monitor was shut down through the control API but is
+	// still managed so it can be enabled again
+	disabled      bool
+	disabledUntil time.Time
+	enableTimer   *time.Timer
 }
 
 func renderConfig(monConfig config.MonitorCustomConfig, endpoint services.Endpoint) (config.MonitorCustomConfig, error) {
@@ -132,6 +142,11 @@ func (am *ActiveMonitor) injectAgentMetaIfNeeded() bool {
 
 // Shutdown calls Shutdown on the monitor instance if it is provided.
 func (am *ActiveMonitor) Shutdown() {
+	am.stopEnableTimer()
+	if am.disabled {
+		// The instance was already shut down when it was disabled
+		return
+	}
 	if sh, ok := am.instance.(Shutdownable); ok {
 		sh.Shutdown()
 	}
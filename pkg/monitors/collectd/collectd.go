@@ -59,6 +59,11 @@ type Manager struct {
 	terminated     chan struct{}
 	requestRestart chan struct{}
 
+	// The state of the state machine and when it changed to that state
+	stateLock  sync.Mutex
+	state      string
+	stateSince time.Time
+
 	logger *log.Entry
 }
 
@@ -73,6 +78,31 @@ func MainInstance() *Manager {
 	return collectdSingleton
 }
 
+// MainManagedConfigDir returns the managed config dir of the main collectd
+// instance, or an empty string if it hasn't been configured yet.
+func MainManagedConfigDir() string {
+	if collectdSingleton == nil {
+		return ""
+	}
+	return collectdSingleton.ManagedConfigDir()
+}
+
+// MainState returns the state of the main collectd instance and when it
+// changed to that state, along with whether any monitors are using it.
+func MainState() (string, time.Time, bool) {
+	if collectdSingleton == nil {
+		return "", time.Time{}, false
+	}
+	cm := collectdSingleton
+
+	cm.configMutex.Lock()
+	inUse := len(cm.activeMonitors) > 0
+	cm.configMutex.Unlock()
+
+	state, since := cm.State()
+	return state, since, inUse
+}
+
 // InitCollectd makes a new instance of a manager and initializes it, but does
 // not start collectd
 func InitCollectd(conf *config.CollectdConfig) *Manager {
@@ -81,6 +111,8 @@ func InitCollectd(conf *config.CollectdConfig) *Manager {
 		activeMonitors:  make(map[types.MonitorID]types.Output),
 		genericJMXUsers: make(map[types.MonitorID]bool),
 		requestRestart:  make(chan struct{}),
+		state:           Uninitialized,
+		stateSince:      time.Now(),
 		logger:          log.WithField("collectdInstance", conf.InstanceName),
 	}
 	manager.deleteExistingConfig()
@@ -226,6 +258,23 @@ func (cm *Manager) BundleDir() string {
 	return cm.conf.BundleDir
 }
 
+// State returns the current state of collectd and when it changed to that
+// state
+func (cm *Manager) State() (string, time.Time) {
+	cm.stateLock.Lock()
+	defer cm.stateLock.Unlock()
+	return cm.state, cm.stateSince
+}
+
+func (cm *Manager) setState(state string) {
+	cm.stateLock.Lock()
+	defer cm.stateLock.Unlock()
+	if cm.state != state {
+		cm.state = state
+		cm.stateSince = time.Now()
+	}
+}
+
 // Manage the subprocess with a basic state machine.  This is a bit tricky
 // since we have config coming in asynchronously from multiple sources.  This
 // function should never return.  waitCh will be closed once the write server
@@ -255,6 +304,7 @@ func (cm *Manager) manageCollectd(initCh chan<- struct{}, terminated chan struct
 
 	for {
 		cm.logger.Debugf("Collectd is now %s", state)
+		cm.setState(state)
 
 		switch state {
 
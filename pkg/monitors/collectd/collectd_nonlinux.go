@@ -3,6 +3,8 @@
 package collectd
 
 import (
+	"time"
+
 	"github.com/signalfx/signalfx-agent/pkg/core/config"
 )
 
@@ -11,3 +13,15 @@ import (
 func ConfigureMainCollectd(conf *config.CollectdConfig) error {
 	return nil
 }
+
+// MainManagedConfigDir returns an empty string on windows because collectd
+// does not run on windows
+func MainManagedConfigDir() string {
+	return ""
+}
+
+// MainState returns an empty state on windows because collectd does not run
+// on windows
+func MainState() (string, time.Time, bool) {
+	return "", time.Time{}, false
+}
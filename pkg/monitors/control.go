@@ -0,0 +1,270 @@
+package monitors
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrMonitorNotFound is returned by the control methods of the
+// MonitorManager when there is no active monitor with the given id
+var ErrMonitorNotFound = errors.New("monitor not found")
+
+// ErrCollectNowNotSupported is returned by CollectNow when the monitor doesn't
+// implement OnDemandCollector
+var ErrCollectNowNotSupported = errors.New("monitor does not support collecting on demand")
+
+// CollectionError is returned by CollectNow when the monitor tried to collect
+// but failed, e.g. because the service it monitors is down.
+type CollectionError struct {
+	Err error
+}
+
+func (e *CollectionError) Error() string {
+	return "monitor failed to collect: " + e.Err.Error()
+}
+
+// OnDemandCollector should be implemented by monitors that can collect once
+// outside of their regular interval without otherwise changing their state.
+// Monitors that only receive data, or that run a subprocess such as collectd,
+// usually can't.
+type OnDemandCollector interface {
+	CollectNow() error
+}
+
+// CollectAll runs all of the given collect functions, e.g. for the parts or
+// nested monitors of a monitor that implements OnDemandCollector, and returns
+// their errors combined.
+func CollectAll(collectors ...func() error) error {
+	var msgs []string
+	for _, collect := range collectors {
+		if err := collect(); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// Must be called with the lock held
+func (mm *MonitorManager) activeMonitorByID(id types.MonitorID) *ActiveMonitor {
+	for _, am := range mm.activeMonitors {
+		if am.id == id {
+			return am
+		}
+	}
+	return nil
+}
+
+func (am *ActiveMonitor) stopEnableTimer() {
+	if am.enableTimer != nil {
+		am.enableTimer.Stop()
+		am.enableTimer = nil
+	}
+}
+
+func (am *ActiveMonitor) logFields() log.Fields {
+	return log.Fields{
+		"monitorID":   am.id,
+		"monitorType": am.config.MonitorConfigCore().Type,
+	}
+}
+
+// DisableMonitor shuts down the monitor with the given id but keeps track of
+// it so that it can be enabled again with EnableMonitor.  If duration is
+// non-zero, the monitor is enabled again after that long.  Disabled monitors
+// are still removed if their config is removed, and are not disabled anymore
+// when the agent restarts.
+func (mm *MonitorManager) DisableMonitor(id types.MonitorID, duration time.Duration) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+
+	am := mm.activeMonitorByID(id)
+	if am == nil {
+		return ErrMonitorNotFound
+	}
+
+	am.stopEnableTimer()
+	if !am.disabled {
+		logger.WithFields(am.logFields()).Info("Disabling monitor")
+		am.Shutdown()
+		am.disabled = true
+	}
+
+	am.disabledUntil = time.Time{}
+	if duration > 0 {
+		am.disabledUntil = time.Now().Add(duration)
+
+		var timer *time.Timer
+		timer = time.AfterFunc(duration, func() {
+			mm.enableAfterTimer(id, timer)
+		})
+		am.enableTimer = timer
+	}
+	return nil
+}
+
+func (mm *MonitorManager) enableAfterTimer(id types.MonitorID, timer *time.Timer) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+
+	am := mm.activeMonitorByID(id)
+	// The monitor might have been removed or enabled or disabled again in
+	// the meantime
+	if am == nil || am.enableTimer != timer {
+		return
+	}
+	am.enableTimer = nil
+
+	logger.WithFields(am.logFields()).Info("Enabling monitor since it was disabled for a limited time")
+	if err := mm.startMonitorAgain(am); err != nil {
+		logger.WithFields(am.logFields()).WithError(err).Error("Could not enable monitor")
+	}
+}
+
+// EnableMonitor starts a monitor that was disabled by DisableMonitor again
+func (mm *MonitorManager) EnableMonitor(id types.MonitorID) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+
+	am := mm.activeMonitorByID(id)
+	if am == nil {
+		return ErrMonitorNotFound
+	}
+
+	am.stopEnableTimer()
+	if !am.disabled {
+		return nil
+	}
+
+	logger.WithFields(am.logFields()).Info("Enabling monitor")
+	return mm.startMonitorAgain(am)
+}
+
+// CollectNow makes the monitor with the given id collect once right away, if
+// it implements OnDemandCollector.  A *CollectionError is returned if the
+// monitor failed to collect.
+func (mm *MonitorManager) CollectNow(id types.MonitorID) error {
+	mm.lock.Lock()
+	am := mm.activeMonitorByID(id)
+	if am == nil {
+		mm.lock.Unlock()
+		return ErrMonitorNotFound
+	}
+	if am.disabled {
+		mm.lock.Unlock()
+		return errors.Errorf("monitor %s is disabled", id)
+	}
+	collector, ok := am.instance.(OnDemandCollector)
+	fields := am.logFields()
+	// The collection can take a while, so don't block the other control
+	// methods or config changes on it
+	mm.lock.Unlock()
+
+	if !ok {
+		return ErrCollectNowNotSupported
+	}
+
+	logger.WithFields(fields).Info("Collecting from monitor on demand")
+	if err := collector.CollectNow(); err != nil {
+		return &CollectionError{Err: err}
+	}
+	return nil
+}
+
+// Starts a new instance of a monitor whose old instance was shut down, with
+// the same config.  The monitor is left disabled if it can't be started.
+// Must be called with the lock held.
+func (mm *MonitorManager) startMonitorAgain(am *ActiveMonitor) error {
+	// It isn't running either way, so make sure it isn't shut down again
+	am.disabled = true
+
+	instance := newMonitor(am.config.MonitorConfigCore().Type)
+	if instance == nil {
+		return errors.Errorf("Could not create new monitor of type %s", am.config.MonitorConfigCore().Type)
+	}
+	am.instance = instance
+
+	if hr, ok := instance.(HealthReportable); ok {
+		hr.SetHealthReporter(am.health)
+	}
+
+	if mm.inputRecording != nil {
+		AttachInputRecorder(instance, am.config, mm.inputRecording.Monitor(am.recordingKey))
+	}
+
+	am.health.restarted()
+	if err := am.configureMonitor(am.config); err != nil {
+		return errors.Wrap(err, "monitor could not be started again and is disabled")
+	}
+
+	am.disabled = false
+	am.disabledUntil = time.Time{}
+	return nil
+}
+
+// AddEphemeralMonitor creates monitors from a config that isn't in the agent
+// config, e.g. from the control API.  They run until the agent restarts or
+// they are removed with RemoveEphemeralMonitor.  The ids of the monitors
+// created right away are returned, which is none if the config has a
+// discovery rule that doesn't match any endpoints yet.
+func (mm *MonitorManager) AddEphemeralMonitor(conf config.MonitorConfig) ([]types.MonitorID, error) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+
+	conf.IntervalSeconds = utils.FirstNonZero(conf.IntervalSeconds, mm.intervalSeconds)
+	hash := conf.Hash()
+	if _, ok := mm.monitorConfigs[hash]; ok {
+		return nil, errors.New("an identical monitor is already in the config")
+	}
+	if _, ok := mm.ephemeralConfigs[hash]; ok {
+		return nil, errors.New("an identical monitor was already added")
+	}
+
+	numActive := len(mm.activeMonitors)
+	monConfig, err := mm.handleNewConfig(&conf)
+	if err != nil {
+		return nil, err
+	}
+	mm.ephemeralConfigs[hash] = monConfig
+
+	var ids []types.MonitorID
+	for _, am := range mm.activeMonitors[numActive:] {
+		ids = append(ids, am.id)
+	}
+
+	logger.WithFields(log.Fields{
+		"monitorType": conf.Type,
+		"monitorIDs":  ids,
+	}).Info("Added ephemeral monitor")
+	return ids, nil
+}
+
+// RemoveEphemeralMonitor removes the config that the monitor with the given
+// id was created from by AddEphemeralMonitor, along with all of the monitors
+// created from it.
+func (mm *MonitorManager) RemoveEphemeralMonitor(id types.MonitorID) error {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+
+	am := mm.activeMonitorByID(id)
+	if am == nil {
+		return ErrMonitorNotFound
+	}
+	hash := am.configHash
+	if _, ok := mm.ephemeralConfigs[hash]; !ok {
+		return errors.Errorf("monitor %s is from the agent config and can only be disabled", id)
+	}
+
+	logger.WithFields(am.logFields()).Info("Removing ephemeral monitor")
+	mm.deleteMonitorsByConfigHash(hash)
+	delete(mm.ephemeralConfigs, hash)
+	return nil
+}
@@ -3,6 +3,7 @@ package monitors
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/go-wordwrap"
 
@@ -56,14 +57,84 @@ func (mm *MonitorManager) SummaryDiagnosticText() string {
 		"Active Monitors:                  %d\n"+
 			"Configured Monitors:              %d\n"+
 			"Discovered Endpoint Count:        %d\n"+
-			"Bad Monitor Config:               %s",
+			"Bad Monitor Config:               %s\n"+
+			"Unhealthy Monitors:               %s",
 		len(mm.activeMonitors),
 		len(mm.monitorConfigs),
 		len(mm.discoveredEndpoints),
 		mm.BadConfigDiagnosticText(),
+		mm.UnhealthyDiagnosticText(),
 	)
 }
 
+// UnhealthyDiagnosticText returns a text representation of the monitors that
+// are stale, failing to collect, or that failed to configure for a discovered
+// endpoint.
+func (mm *MonitorManager) UnhealthyDiagnosticText() string {
+	var texts []string
+	for _, status := range mm.MonitorStatuses() {
+		var problem string
+		switch {
+		case status.Disabled:
+			continue
+		case status.ConfigureError != "" && status.EndpointID != "":
+			problem = "could not configure: " + status.ConfigureError
+		case status.ConsecutiveErrors > 0:
+			problem = fmt.Sprintf("%d consecutive errors, last: %s", status.ConsecutiveErrors, status.LastError)
+		case status.Stale:
+			problem = "stale, " + lastEmissionText(status)
+		default:
+			continue
+		}
+
+		name := status.MonitorType
+		if status.MonitorID != "" {
+			name = fmt.Sprintf("%s (%s)", status.MonitorID, status.MonitorType)
+		}
+		if status.EndpointID != "" {
+			name += " on endpoint " + string(status.EndpointID)
+		}
+		texts = append(texts, fmt.Sprintf("[%s: %s]", name, problem))
+	}
+
+	if len(texts) == 0 {
+		return "None"
+	}
+	return strings.Join(texts, " ")
+}
+
+func lastEmissionText(status MonitorStatus) string {
+	if status.LastEmission == nil {
+		return "nothing sent since created " + time.Since(*status.CreatedAt).Round(time.Second).String() + " ago"
+	}
+	return "last sent data " + time.Since(*status.LastEmission).Round(time.Second).String() + " ago"
+}
+
+func healthDiagnosticText(status MonitorStatus) string {
+	out := fmt.Sprintf(`Health: %s
+Datapoints Last Interval: %d
+Events Last Interval: %d
+Consecutive Errors: %d`,
+		lastEmissionText(status),
+		status.DatapointsLastInterval,
+		status.EventsLastInterval,
+		status.ConsecutiveErrors)
+
+	if status.Stale {
+		out = "Health: STALE, " + strings.TrimPrefix(out, "Health: ")
+	}
+	if status.Disabled {
+		out = "Health: DISABLED, " + strings.TrimPrefix(out, "Health: ")
+	}
+	if status.LastError != "" {
+		out += "\nLast Error: " + status.LastError
+	}
+	if status.SubprocessRestarts > 0 {
+		out += fmt.Sprintf("\nSubprocess Restarts: %d", status.SubprocessRestarts)
+	}
+	return out
+}
+
 func formatEnabledMetrics(metrics []string, indent int) string {
 	metricList := strings.Join(metrics, ", ")
 	enabledMetricsPrefix := utils.IndentLines("Enabled Metrics: ", indent)
@@ -91,6 +162,11 @@ func (mm *MonitorManager) DiagnosticText() string {
 	for i := range mm.activeMonitors {
 		am := mm.activeMonitors[i]
 
+		configFile := ""
+		if f := am.config.MonitorConfigCore().ConfigFile; f != "" {
+			configFile = "\n    Config File: " + f
+		}
+
 		serviceStats := "Not using auto-discovery"
 		if am.endpoint != nil {
 			serviceStats = fmt.Sprintf(
@@ -101,7 +177,8 @@ Monitored Endpoint ID: %s`,
 		}
 		activeMonText += fmt.Sprintf(
 			`%s. %s
-    Reporting Interval (seconds): %d
+    Reporting Interval (seconds): %d%s
+%s
 %s
 %s
     Config:
@@ -110,8 +187,10 @@ Monitored Endpoint ID: %s`,
 			am.config.MonitorConfigCore().MonitorID,
 			am.config.MonitorConfigCore().Type,
 			am.config.MonitorConfigCore().IntervalSeconds,
+			configFile,
 			formatEnabledMetrics(am.output.EnabledMetrics(), 4),
 			utils.IndentLines(serviceStats, 4),
+			utils.IndentLines(healthDiagnosticText(am.status()), 4),
 			utils.IndentLines(config.ToString(am.config), 6))
 	}
 	return "Active Monitors:\n" + activeMonText
@@ -127,6 +206,11 @@ func (mm *MonitorManager) BadConfigDiagnosticText() string {
 		var texts []string
 		for k := range mm.badConfigs {
 			conf := mm.badConfigs[k]
+			if conf.ConfigFile != "" {
+				texts = append(texts, fmt.Sprintf("[type: %s, file: %s, error: %s]",
+					conf.Type, conf.ConfigFile, conf.ValidationError))
+				continue
+			}
 			texts = append(texts, fmt.Sprintf("[type: %s, error: %s]",
 				conf.Type, conf.ValidationError))
 		}
@@ -138,10 +222,41 @@ func (mm *MonitorManager) BadConfigDiagnosticText() string {
 // InternalMetrics returns a list of datapoints about the internal status of
 // the monitors
 func (mm *MonitorManager) InternalMetrics() []*datapoint.Datapoint {
-	return []*datapoint.Datapoint{
+	statuses := mm.MonitorStatuses()
+
+	var stale, failed int64
+	out := make([]*datapoint.Datapoint, 0, 4+5*len(statuses))
+	for _, status := range statuses {
+		if status.ConfigureError != "" {
+			failed++
+			continue
+		}
+		if status.Stale {
+			stale++
+		}
+
+		dims := map[string]string{
+			"monitor_type": status.MonitorType,
+			"monitor_id":   string(status.MonitorID),
+		}
+		last := status.CreatedAt
+		if status.LastEmission != nil {
+			last = status.LastEmission
+		}
+		out = append(out,
+			sfxclient.Cumulative("sfxagent.monitor_datapoints_sent", dims, status.DatapointsSent),
+			sfxclient.Cumulative("sfxagent.monitor_events_sent", dims, status.EventsSent),
+			sfxclient.Gauge("sfxagent.monitor_consecutive_errors", dims, status.ConsecutiveErrors),
+			sfxclient.Cumulative("sfxagent.monitor_subprocess_restarts", dims, status.SubprocessRestarts),
+			sfxclient.Gauge("sfxagent.monitor_seconds_since_last_emission", dims, int64(time.Since(*last).Seconds())))
+	}
+
+	return append(out,
 		sfxclient.Gauge("sfxagent.active_monitors", nil, int64(len(mm.activeMonitors))),
 		sfxclient.Gauge("sfxagent.configured_monitors", nil, int64(len(mm.monitorConfigs))),
 		sfxclient.Gauge("sfxagent.discovered_endpoints", nil, int64(len(mm.discoveredEndpoints))),
+		sfxclient.Gauge("sfxagent.stale_monitors", nil, stale),
+		sfxclient.Gauge("sfxagent.failed_monitors", nil, failed),
 		sfxclient.Gauge("sfxagent.k8s_leader", map[string]string{"leader_node": leadership.CurrentLeader()}, 1),
-	}
+	)
 }
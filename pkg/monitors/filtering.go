@@ -31,6 +31,30 @@ func newMonitorFiltering(conf config.MonitorCustomConfig, metadata *Metadata) (*
 	}, nil
 }
 
+// MetricFilter applies the same filtering to datapoints that the agent does to
+// the output of a monitor, based on the monitor metadata and the
+// extraMetrics/extraGroups and datapoint filter config.  It is meant for
+// harnesses that run monitors outside of the MonitorManager, such as tests.
+type MetricFilter struct {
+	*monitorFiltering
+}
+
+// NewMetricFilter creates a MetricFilter for a monitor with the given config
+// and metadata
+func NewMetricFilter(conf config.MonitorCustomConfig, metadata *Metadata) (*MetricFilter, error) {
+	mf, err := newMonitorFiltering(conf, metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricFilter{mf}, nil
+}
+
+// Excludes returns true if the datapoint would be dropped from the monitor's
+// output
+func (mf *MetricFilter) Excludes(dp *datapoint.Datapoint) bool {
+	return mf.filterSet.Matches(dp)
+}
+
 // AddDatapointExclusionFilter to the monitor's filter set.  Make sure you do this
 // before any datapoints are sent as it is not thread-safe with SendDatapoint.
 func (mf *monitorFiltering) AddDatapointExclusionFilter(filter dpfilters.DatapointFilter) {
@@ -0,0 +1,324 @@
+package monitors
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/services"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
+)
+
+// HealthReportable should be implemented by monitors that report the outcome
+// of their collection attempts, or the restarts of their subprocess.
+// SetHealthReporter is called before Configure.
+type HealthReportable interface {
+	SetHealthReporter(types.HealthReporter)
+}
+
+// A monitor is considered stale if it hasn't sent anything for this many
+// intervals, or for minStaleAge, whichever is longer.
+const (
+	staleIntervals = 3
+	minStaleAge    = 2 * time.Minute
+)
+
+// MonitorStatus is the health of a single monitor instance, or of a monitor
+// that could not be configured
+type MonitorStatus struct {
+	MonitorID       types.MonitorID `json:"monitorID,omitempty"`
+	MonitorType     string          `json:"monitorType"`
+	DiscoveryRule   string          `json:"discoveryRule,omitempty"`
+	EndpointID      services.ID     `json:"endpointID,omitempty"`
+	IntervalSeconds int             `json:"intervalSeconds"`
+	// The error from configuring the monitor, in which case it isn't running
+	ConfigureError string     `json:"configureError,omitempty"`
+	CreatedAt      *time.Time `json:"createdAt,omitempty"`
+	// The last time the monitor sent a datapoint, event or span
+	LastEmission           *time.Time `json:"lastEmission,omitempty"`
+	DatapointsSent         int64      `json:"datapointsSent"`
+	EventsSent             int64      `json:"eventsSent"`
+	SpansSent              int64      `json:"spansSent"`
+	DatapointsLastInterval int64      `json:"datapointsLastInterval"`
+	EventsLastInterval     int64      `json:"eventsLastInterval"`
+	ConsecutiveErrors      int64      `json:"consecutiveErrors"`
+	LastError              string     `json:"lastError,omitempty"`
+	LastErrorTime          *time.Time `json:"lastErrorTime,omitempty"`
+	SubprocessRestarts     int64      `json:"subprocessRestarts"`
+	// True if the monitor hasn't sent anything in a while
+	Stale bool `json:"stale"`
+
+	// True if the monitor was disabled through the control API
+	Disabled bool `json:"disabled,omitempty"`
+	// When the monitor will be enabled again, if it was disabled for a
+	// limited time
+	DisabledUntil *time.Time `json:"disabledUntil,omitempty"`
+	// True if the monitor was added through the control API and isn't in
+	// the config
+	Ephemeral bool `json:"ephemeral,omitempty"`
+	// The drop-in file in `monitorsDir` that the monitor's config came from
+	ConfigFile string `json:"configFile,omitempty"`
+}
+
+// monitorHealth tracks what a single monitor instance sends and the problems
+// it reports
+type monitorHealth struct {
+	interval  time.Duration
+	createdAt time.Time
+	// When the monitor instance was last started, which is later than
+	// createdAt if it was restarted through the control API
+	startedAt time.Time
+
+	lock           sync.Mutex
+	lastEmission   time.Time
+	datapointsSent int64
+	eventsSent     int64
+	spansSent      int64
+	// When the current interval started, and the counts within it and the
+	// previous one
+	intervalStart          time.Time
+	datapointsThisInterval int64
+	eventsThisInterval     int64
+	datapointsLastInterval int64
+	eventsLastInterval     int64
+
+	consecutiveErrors  int64
+	lastError          string
+	lastErrorTime      time.Time
+	subprocessRestarts int64
+
+	// For unit testing
+	now func() time.Time
+}
+
+var _ types.HealthReporter = &monitorHealth{}
+
+func newMonitorHealth(intervalSeconds int) *monitorHealth {
+	now := time.Now()
+	return &monitorHealth{
+		interval:      time.Duration(intervalSeconds) * time.Second,
+		createdAt:     now,
+		startedAt:     now,
+		intervalStart: now,
+		now:           time.Now,
+	}
+}
+
+// Moves the counts of the current interval to the last interval if it has
+// passed.  Must be called with the lock held.
+func (mh *monitorHealth) rollInterval(now time.Time) {
+	if mh.interval <= 0 {
+		return
+	}
+
+	elapsed := now.Sub(mh.intervalStart)
+	if elapsed < mh.interval {
+		return
+	}
+
+	if elapsed < 2*mh.interval {
+		mh.datapointsLastInterval = mh.datapointsThisInterval
+		mh.eventsLastInterval = mh.eventsThisInterval
+	} else {
+		// Nothing came in during the entire last interval
+		mh.datapointsLastInterval = 0
+		mh.eventsLastInterval = 0
+	}
+	mh.datapointsThisInterval = 0
+	mh.eventsThisInterval = 0
+	mh.intervalStart = now.Add(-(elapsed % mh.interval))
+}
+
+func (mh *monitorHealth) emitted(datapoints, events, spans int) {
+	mh.lock.Lock()
+	defer mh.lock.Unlock()
+
+	now := mh.now()
+	mh.rollInterval(now)
+
+	mh.lastEmission = now
+	mh.datapointsSent += int64(datapoints)
+	mh.eventsSent += int64(events)
+	mh.spansSent += int64(spans)
+	mh.datapointsThisInterval += int64(datapoints)
+	mh.eventsThisInterval += int64(events)
+}
+
+// CollectionSucceeded resets the consecutive error count
+func (mh *monitorHealth) CollectionSucceeded() {
+	mh.lock.Lock()
+	defer mh.lock.Unlock()
+	mh.consecutiveErrors = 0
+}
+
+// CollectionFailed counts an error collecting data
+func (mh *monitorHealth) CollectionFailed(err error) {
+	mh.lock.Lock()
+	defer mh.lock.Unlock()
+
+	mh.consecutiveErrors++
+	mh.lastError = err.Error()
+	mh.lastErrorTime = mh.now()
+}
+
+// SubprocessRestarted counts a restart of the monitor's subprocess
+func (mh *monitorHealth) SubprocessRestarted() {
+	mh.lock.Lock()
+	defer mh.lock.Unlock()
+	mh.subprocessRestarts++
+}
+
+// Restarted should be called when a new instance of the monitor is started
+// so that it isn't considered stale right away
+func (mh *monitorHealth) restarted() {
+	mh.lock.Lock()
+	defer mh.lock.Unlock()
+	mh.startedAt = mh.now()
+}
+
+func (mh *monitorHealth) isStale(now time.Time) bool {
+	last := mh.lastEmission
+	if last.Before(mh.startedAt) {
+		last = mh.startedAt
+	}
+
+	maxAge := staleIntervals * mh.interval
+	if maxAge < minStaleAge {
+		maxAge = minStaleAge
+	}
+	return now.Sub(last) > maxAge
+}
+
+// Fills in the health fields of the status
+func (mh *monitorHealth) fillStatus(status *MonitorStatus) {
+	mh.lock.Lock()
+	defer mh.lock.Unlock()
+
+	now := mh.now()
+	mh.rollInterval(now)
+
+	createdAt := mh.createdAt
+	status.CreatedAt = &createdAt
+	if !mh.lastEmission.IsZero() {
+		lastEmission := mh.lastEmission
+		status.LastEmission = &lastEmission
+	}
+	status.DatapointsSent = mh.datapointsSent
+	status.EventsSent = mh.eventsSent
+	status.SpansSent = mh.spansSent
+	status.DatapointsLastInterval = mh.datapointsLastInterval
+	status.EventsLastInterval = mh.eventsLastInterval
+	status.ConsecutiveErrors = mh.consecutiveErrors
+	status.LastError = mh.lastError
+	if !mh.lastErrorTime.IsZero() {
+		lastErrorTime := mh.lastErrorTime
+		status.LastErrorTime = &lastErrorTime
+	}
+	status.SubprocessRestarts = mh.subprocessRestarts
+	status.Stale = mh.isStale(now)
+}
+
+// A monitor for a specific config and endpoint that failed to configure
+type failedMonitor struct {
+	configHash uint64
+	status     MonitorStatus
+}
+
+func failedMonitorKey(configHash uint64, endpoint services.Endpoint) string {
+	key := fmt.Sprintf("%d", configHash)
+	if endpoint != nil {
+		key += "/" + string(endpoint.Core().ID)
+	}
+	return key
+}
+
+// Must be called with the lock held
+func (mm *MonitorManager) recordConfigureError(conf config.MonitorCustomConfig, configHash uint64, endpoint services.Endpoint, err error) {
+	coreConf := conf.MonitorConfigCore()
+	status := MonitorStatus{
+		MonitorType:     coreConf.Type,
+		DiscoveryRule:   coreConf.DiscoveryRule,
+		IntervalSeconds: coreConf.IntervalSeconds,
+		ConfigureError:  err.Error(),
+		ConfigFile:      coreConf.ConfigFile,
+	}
+	if endpoint != nil {
+		status.EndpointID = endpoint.Core().ID
+	}
+
+	mm.failedMonitors[failedMonitorKey(configHash, endpoint)] = &failedMonitor{
+		configHash: configHash,
+		status:     status,
+	}
+}
+
+// Must be called with the lock held
+func (mm *MonitorManager) clearConfigureErrors(matches func(*failedMonitor) bool) {
+	for k, fm := range mm.failedMonitors {
+		if matches(fm) {
+			delete(mm.failedMonitors, k)
+		}
+	}
+}
+
+func (am *ActiveMonitor) status() MonitorStatus {
+	coreConf := am.config.MonitorConfigCore()
+	status := MonitorStatus{
+		MonitorID:       am.id,
+		MonitorType:     coreConf.Type,
+		DiscoveryRule:   coreConf.DiscoveryRule,
+		EndpointID:      am.endpointID(),
+		IntervalSeconds: coreConf.IntervalSeconds,
+		ConfigFile:      coreConf.ConfigFile,
+	}
+	am.health.fillStatus(&status)
+
+	if am.disabled {
+		status.Disabled = true
+		// Disabled monitors aren't expected to send anything
+		status.Stale = false
+		if !am.disabledUntil.IsZero() {
+			disabledUntil := am.disabledUntil
+			status.DisabledUntil = &disabledUntil
+		}
+	}
+	return status
+}
+
+// MonitorStatuses returns the health of all of the active monitors, followed
+// by monitors that could not be configured.
+func (mm *MonitorManager) MonitorStatuses() []MonitorStatus {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+
+	out := make([]MonitorStatus, 0, len(mm.activeMonitors))
+	for _, am := range mm.activeMonitors {
+		status := am.status()
+		_, status.Ephemeral = mm.ephemeralConfigs[am.configHash]
+		out = append(out, status)
+	}
+
+	var failed []MonitorStatus
+	for _, conf := range mm.badConfigs {
+		failed = append(failed, MonitorStatus{
+			MonitorType:     conf.Type,
+			DiscoveryRule:   conf.DiscoveryRule,
+			IntervalSeconds: conf.IntervalSeconds,
+			ConfigureError:  conf.ValidationError,
+			ConfigFile:      conf.ConfigFile,
+		})
+	}
+	for _, fm := range mm.failedMonitors {
+		failed = append(failed, fm.status)
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		if failed[i].MonitorType != failed[j].MonitorType {
+			return failed[i].MonitorType < failed[j].MonitorType
+		}
+		return failed[i].EndpointID < failed[j].EndpointID
+	})
+
+	return append(out, failed...)
+}
@@ -0,0 +1,66 @@
+package monitors
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitorHealth(t *testing.T) {
+	mh := newMonitorHealth(10)
+	now := mh.createdAt
+	mh.now = func() time.Time { return now }
+
+	var status MonitorStatus
+	mh.fillStatus(&status)
+	assert.Nil(t, status.LastEmission)
+	assert.False(t, status.Stale)
+
+	mh.emitted(5, 0, 0)
+	now = now.Add(2 * time.Second)
+	mh.emitted(3, 1, 0)
+	mh.CollectionFailed(errors.New("connection refused"))
+	mh.CollectionFailed(errors.New("timeout"))
+	mh.SubprocessRestarted()
+
+	// The counts for the first interval show up once it is over
+	now = now.Add(9 * time.Second)
+	status = MonitorStatus{}
+	mh.fillStatus(&status)
+	assert.Equal(t, int64(8), status.DatapointsSent)
+	assert.Equal(t, int64(8), status.DatapointsLastInterval)
+	assert.Equal(t, int64(1), status.EventsLastInterval)
+	assert.Equal(t, int64(2), status.ConsecutiveErrors)
+	assert.Equal(t, "timeout", status.LastError)
+	assert.Equal(t, int64(1), status.SubprocessRestarts)
+	assert.False(t, status.Stale)
+
+	mh.CollectionSucceeded()
+
+	// Nothing was sent in the whole interval before this one
+	now = now.Add(25 * time.Second)
+	status = MonitorStatus{}
+	mh.fillStatus(&status)
+	assert.Equal(t, int64(0), status.DatapointsLastInterval)
+	assert.Equal(t, int64(0), status.ConsecutiveErrors)
+	assert.False(t, status.Stale)
+
+	now = now.Add(minStaleAge)
+	status = MonitorStatus{}
+	mh.fillStatus(&status)
+	assert.True(t, status.Stale)
+}
+
+func TestMonitorHealthStaleUsesInterval(t *testing.T) {
+	mh := newMonitorHealth(60)
+	now := mh.createdAt
+	mh.now = func() time.Time { return now }
+
+	now = now.Add(minStaleAge + time.Second)
+	assert.False(t, mh.isStale(now))
+
+	now = now.Add(time.Minute)
+	assert.True(t, mh.isStale(now))
+}
@@ -1,6 +1,8 @@
 package monitors
 
 import (
+	"errors"
+
 	"github.com/signalfx/signalfx-agent/pkg/core/config"
 	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
 )
@@ -41,6 +43,7 @@ type _MockMonitor struct {
 	MPassword     string
 	shutdownHooks []func()
 	configHook    func(types.MonitorID, MockMonitor)
+	collections   int
 }
 
 func (m *_MockMonitor) Configure(conf *Config) error {
@@ -93,6 +96,15 @@ type Static2 struct{ _MockMonitor }
 type Dynamic1 struct{ _MockServiceMonitor }
 type Dynamic2 struct{ _MockServiceMonitor }
 
+// Only static2 monitors can collect on demand, and fail to if myVar is "fail"
+func (m *Static2) CollectNow() error {
+	m.collections++
+	if m.MMyVar == "fail" {
+		return errors.New("could not collect")
+	}
+	return nil
+}
+
 func RegisterFakeMonitors() func() map[types.MonitorID]MockMonitor {
 	instances := map[types.MonitorID]MockMonitor{}
 
@@ -12,75 +12,89 @@ const monitorType = "internal-metrics"
 var groupSet = map[string]bool{}
 
 const (
-	sfxagentActiveMonitors             = "sfxagent.active_monitors"
-	sfxagentActiveObservers            = "sfxagent.active_observers"
-	sfxagentConfiguredMonitors         = "sfxagent.configured_monitors"
-	sfxagentDatapointChannelLen        = "sfxagent.datapoint_channel_len"
-	sfxagentDatapointRequestsActive    = "sfxagent.datapoint_requests_active"
-	sfxagentDatapointsFailed           = "sfxagent.datapoints_failed"
-	sfxagentDatapointsFiltered         = "sfxagent.datapoints_filtered"
-	sfxagentDatapointsInFlight         = "sfxagent.datapoints_in_flight"
-	sfxagentDatapointsReceived         = "sfxagent.datapoints_received"
-	sfxagentDatapointsSent             = "sfxagent.datapoints_sent"
-	sfxagentDatapointsWaiting          = "sfxagent.datapoints_waiting"
-	sfxagentDimRequestSenders          = "sfxagent.dim_request_senders"
-	sfxagentDimUpdatesCompleted        = "sfxagent.dim_updates_completed"
-	sfxagentDimUpdatesCurrentlyDelayed = "sfxagent.dim_updates_currently_delayed"
-	sfxagentDimUpdatesDropped          = "sfxagent.dim_updates_dropped"
-	sfxagentDimUpdatesFailed           = "sfxagent.dim_updates_failed"
-	sfxagentDimUpdatesFlappyTotal      = "sfxagent.dim_updates_flappy_total"
-	sfxagentDimUpdatesStarted          = "sfxagent.dim_updates_started"
-	sfxagentDiscoveredEndpoints        = "sfxagent.discovered_endpoints"
-	sfxagentEventsBuffered             = "sfxagent.events_buffered"
-	sfxagentEventsSent                 = "sfxagent.events_sent"
-	sfxagentGoFrees                    = "sfxagent.go_frees"
-	sfxagentGoHeapAlloc                = "sfxagent.go_heap_alloc"
-	sfxagentGoHeapIdle                 = "sfxagent.go_heap_idle"
-	sfxagentGoHeapInuse                = "sfxagent.go_heap_inuse"
-	sfxagentGoHeapReleased             = "sfxagent.go_heap_released"
-	sfxagentGoHeapSys                  = "sfxagent.go_heap_sys"
-	sfxagentGoMallocs                  = "sfxagent.go_mallocs"
-	sfxagentGoNextGc                   = "sfxagent.go_next_gc"
-	sfxagentGoNumGc                    = "sfxagent.go_num_gc"
-	sfxagentGoStackInuse               = "sfxagent.go_stack_inuse"
-	sfxagentGoTotalAlloc               = "sfxagent.go_total_alloc"
-	sfxgentGoNumGoroutine              = "sfxgent.go_num_goroutine"
+	sfxagentActiveMonitors                  = "sfxagent.active_monitors"
+	sfxagentActiveObservers                 = "sfxagent.active_observers"
+	sfxagentConfiguredMonitors              = "sfxagent.configured_monitors"
+	sfxagentDatapointChannelLen             = "sfxagent.datapoint_channel_len"
+	sfxagentDatapointRequestsActive         = "sfxagent.datapoint_requests_active"
+	sfxagentDatapointsFailed                = "sfxagent.datapoints_failed"
+	sfxagentDatapointsFiltered              = "sfxagent.datapoints_filtered"
+	sfxagentDatapointsInFlight              = "sfxagent.datapoints_in_flight"
+	sfxagentDatapointsReceived              = "sfxagent.datapoints_received"
+	sfxagentDatapointsSent                  = "sfxagent.datapoints_sent"
+	sfxagentDatapointsWaiting               = "sfxagent.datapoints_waiting"
+	sfxagentDimRequestSenders               = "sfxagent.dim_request_senders"
+	sfxagentDimUpdatesCompleted             = "sfxagent.dim_updates_completed"
+	sfxagentDimUpdatesCurrentlyDelayed      = "sfxagent.dim_updates_currently_delayed"
+	sfxagentDimUpdatesDropped               = "sfxagent.dim_updates_dropped"
+	sfxagentDimUpdatesFailed                = "sfxagent.dim_updates_failed"
+	sfxagentDimUpdatesFlappyTotal           = "sfxagent.dim_updates_flappy_total"
+	sfxagentDimUpdatesStarted               = "sfxagent.dim_updates_started"
+	sfxagentDiscoveredEndpoints             = "sfxagent.discovered_endpoints"
+	sfxagentEventsBuffered                  = "sfxagent.events_buffered"
+	sfxagentEventsSent                      = "sfxagent.events_sent"
+	sfxagentFailedMonitors                  = "sfxagent.failed_monitors"
+	sfxagentGoFrees                         = "sfxagent.go_frees"
+	sfxagentGoHeapAlloc                     = "sfxagent.go_heap_alloc"
+	sfxagentGoHeapIdle                      = "sfxagent.go_heap_idle"
+	sfxagentGoHeapInuse                     = "sfxagent.go_heap_inuse"
+	sfxagentGoHeapReleased                  = "sfxagent.go_heap_released"
+	sfxagentGoHeapSys                       = "sfxagent.go_heap_sys"
+	sfxagentGoMallocs                       = "sfxagent.go_mallocs"
+	sfxagentGoNextGc                        = "sfxagent.go_next_gc"
+	sfxagentGoNumGc                         = "sfxagent.go_num_gc"
+	sfxagentGoStackInuse                    = "sfxagent.go_stack_inuse"
+	sfxagentGoTotalAlloc                    = "sfxagent.go_total_alloc"
+	sfxagentMonitorConsecutiveErrors        = "sfxagent.monitor_consecutive_errors"
+	sfxagentMonitorDatapointsSent           = "sfxagent.monitor_datapoints_sent"
+	sfxagentMonitorEventsSent               = "sfxagent.monitor_events_sent"
+	sfxagentMonitorSecondsSinceLastEmission = "sfxagent.monitor_seconds_since_last_emission"
+	sfxagentMonitorSubprocessRestarts       = "sfxagent.monitor_subprocess_restarts"
+	sfxagentStaleMonitors                   = "sfxagent.stale_monitors"
+	sfxgentGoNumGoroutine                   = "sfxgent.go_num_goroutine"
 )
 
 var metricSet = map[string]monitors.MetricInfo{
-	sfxagentActiveMonitors:             {Type: datapoint.Gauge},
-	sfxagentActiveObservers:            {Type: datapoint.Gauge},
-	sfxagentConfiguredMonitors:         {Type: datapoint.Gauge},
-	sfxagentDatapointChannelLen:        {Type: datapoint.Gauge},
-	sfxagentDatapointRequestsActive:    {Type: datapoint.Gauge},
-	sfxagentDatapointsFailed:           {Type: datapoint.Counter},
-	sfxagentDatapointsFiltered:         {Type: datapoint.Counter},
-	sfxagentDatapointsInFlight:         {Type: datapoint.Gauge},
-	sfxagentDatapointsReceived:         {Type: datapoint.Counter},
-	sfxagentDatapointsSent:             {Type: datapoint.Counter},
-	sfxagentDatapointsWaiting:          {Type: datapoint.Gauge},
-	sfxagentDimRequestSenders:          {Type: datapoint.Gauge},
-	sfxagentDimUpdatesCompleted:        {Type: datapoint.Counter},
-	sfxagentDimUpdatesCurrentlyDelayed: {Type: datapoint.Gauge},
-	sfxagentDimUpdatesDropped:          {Type: datapoint.Counter},
-	sfxagentDimUpdatesFailed:           {Type: datapoint.Counter},
-	sfxagentDimUpdatesFlappyTotal:      {Type: datapoint.Counter},
-	sfxagentDimUpdatesStarted:          {Type: datapoint.Counter},
-	sfxagentDiscoveredEndpoints:        {Type: datapoint.Gauge},
-	sfxagentEventsBuffered:             {Type: datapoint.Gauge},
-	sfxagentEventsSent:                 {Type: datapoint.Counter},
-	sfxagentGoFrees:                    {Type: datapoint.Counter},
-	sfxagentGoHeapAlloc:                {Type: datapoint.Gauge},
-	sfxagentGoHeapIdle:                 {Type: datapoint.Gauge},
-	sfxagentGoHeapInuse:                {Type: datapoint.Gauge},
-	sfxagentGoHeapReleased:             {Type: datapoint.Gauge},
-	sfxagentGoHeapSys:                  {Type: datapoint.Gauge},
-	sfxagentGoMallocs:                  {Type: datapoint.Counter},
-	sfxagentGoNextGc:                   {Type: datapoint.Gauge},
-	sfxagentGoNumGc:                    {Type: datapoint.Gauge},
-	sfxagentGoStackInuse:               {Type: datapoint.Gauge},
-	sfxagentGoTotalAlloc:               {Type: datapoint.Counter},
-	sfxgentGoNumGoroutine:              {Type: datapoint.Gauge},
+	sfxagentActiveMonitors:                  {Type: datapoint.Gauge},
+	sfxagentActiveObservers:                 {Type: datapoint.Gauge},
+	sfxagentConfiguredMonitors:              {Type: datapoint.Gauge},
+	sfxagentDatapointChannelLen:             {Type: datapoint.Gauge},
+	sfxagentDatapointRequestsActive:         {Type: datapoint.Gauge},
+	sfxagentDatapointsFailed:                {Type: datapoint.Counter},
+	sfxagentDatapointsFiltered:              {Type: datapoint.Counter},
+	sfxagentDatapointsInFlight:              {Type: datapoint.Gauge},
+	sfxagentDatapointsReceived:              {Type: datapoint.Counter},
+	sfxagentDatapointsSent:                  {Type: datapoint.Counter},
+	sfxagentDatapointsWaiting:               {Type: datapoint.Gauge},
+	sfxagentDimRequestSenders:               {Type: datapoint.Gauge},
+	sfxagentDimUpdatesCompleted:             {Type: datapoint.Counter},
+	sfxagentDimUpdatesCurrentlyDelayed:      {Type: datapoint.Gauge},
+	sfxagentDimUpdatesDropped:               {Type: datapoint.Counter},
+	sfxagentDimUpdatesFailed:                {Type: datapoint.Counter},
+	sfxagentDimUpdatesFlappyTotal:           {Type: datapoint.Counter},
+	sfxagentDimUpdatesStarted:               {Type: datapoint.Counter},
+	sfxagentDiscoveredEndpoints:             {Type: datapoint.Gauge},
+	sfxagentEventsBuffered:                  {Type: datapoint.Gauge},
+	sfxagentEventsSent:                      {Type: datapoint.Counter},
+	sfxagentFailedMonitors:                  {Type: datapoint.Gauge},
+	sfxagentGoFrees:                         {Type: datapoint.Counter},
+	sfxagentGoHeapAlloc:                     {Type: datapoint.Gauge},
+	sfxagentGoHeapIdle:                      {Type: datapoint.Gauge},
+	sfxagentGoHeapInuse:                     {Type: datapoint.Gauge},
+	sfxagentGoHeapReleased:                  {Type: datapoint.Gauge},
+	sfxagentGoHeapSys:                       {Type: datapoint.Gauge},
+	sfxagentGoMallocs:                       {Type: datapoint.Counter},
+	sfxagentGoNextGc:                        {Type: datapoint.Gauge},
+	sfxagentGoNumGc:                         {Type: datapoint.Gauge},
+	sfxagentGoStackInuse:                    {Type: datapoint.Gauge},
+	sfxagentGoTotalAlloc:                    {Type: datapoint.Counter},
+	sfxagentMonitorConsecutiveErrors:        {Type: datapoint.Gauge},
+	sfxagentMonitorDatapointsSent:           {Type: datapoint.Counter},
+	sfxagentMonitorEventsSent:               {Type: datapoint.Counter},
+	sfxagentMonitorSecondsSinceLastEmission: {Type: datapoint.Gauge},
+	sfxagentMonitorSubprocessRestarts:       {Type: datapoint.Counter},
+	sfxagentStaleMonitors:                   {Type: datapoint.Gauge},
+	sfxgentGoNumGoroutine:                   {Type: datapoint.Gauge},
 }
 
 var defaultMetrics = map[string]bool{
@@ -105,6 +119,7 @@ var defaultMetrics = map[string]bool{
 	sfxagentDiscoveredEndpoints:        true,
 	sfxagentEventsBuffered:             true,
 	sfxagentEventsSent:                 true,
+	sfxagentFailedMonitors:             true,
 	sfxagentGoFrees:                    true,
 	sfxagentGoHeapAlloc:                true,
 	sfxagentGoHeapIdle:                 true,
@@ -116,6 +131,7 @@ var defaultMetrics = map[string]bool{
 	sfxagentGoNumGc:                    true,
 	sfxagentGoStackInuse:               true,
 	sfxagentGoTotalAlloc:               true,
+	sfxagentStaleMonitors:              true,
 	sfxgentGoNumGoroutine:              true,
 }
 
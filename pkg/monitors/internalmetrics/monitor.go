@@ -3,6 +3,7 @@ package internalmetrics
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -20,7 +21,9 @@ import (
 type Config struct {
 	config.MonitorConfig `yaml:",inline" acceptsEndpoints:"true"`
 
-	// Defaults to the top-level `internalStatusHost` option
+	// Defaults to the top-level `internalStatusHost` option.  If not set,
+	// the TLS and auth options in the top-level `internalStatusServer` option
+	// are used to connect.
 	Host string `yaml:"host"`
 	// Defaults to the top-level `internalStatusPort` option
 	Port uint16 `yaml:"port" noDefault:"true"`
@@ -34,6 +37,8 @@ type Monitor struct {
 	Output    types.Output
 	AgentMeta *meta.AgentMeta
 	cancel    func()
+
+	collectNow func() error
 }
 
 func init() {
@@ -46,23 +51,43 @@ func (m *Monitor) Configure(conf *Config) error {
 	var ctx context.Context
 	ctx, m.cancel = context.WithCancel(context.Background())
 
-	client := &http.Client{
+	plainClient := &http.Client{
 		Timeout: 5 * time.Second,
 	}
 
+	// The client for the agent's own status server, remade if its config
+	// changes
+	var agentClient *http.Client
+	var agentClientConf config.InternalStatusServerConfig
+
 	// Mark the first interval so that errors are suppressed due to the race
 	// between this monitor starting and the internal metrics service getting
 	// initialized
 	firstTime := true
 
-	utils.RunOnInterval(ctx, func() {
+	collect := func() error {
 		defer func() { firstTime = false }()
 
 		// Derive the url each time since the AgentMeta data can change but
 		// there is no notification system for it.
+		client := plainClient
+		scheme := "http"
 		host := conf.Host
 		if host == "" {
 			host = m.AgentMeta.InternalStatusHost
+
+			serverConf := m.AgentMeta.InternalStatusServer
+			if agentClient == nil || serverConf != agentClientConf {
+				var err error
+				agentClient, err = serverConf.NewClient(5 * time.Second)
+				if err != nil {
+					log.WithError(err).Error("Could not make client for internal status server")
+					return err
+				}
+				agentClientConf = serverConf
+			}
+			client = agentClient
+			scheme = serverConf.Scheme()
 		}
 
 		port := conf.Port
@@ -70,7 +95,7 @@ func (m *Monitor) Configure(conf *Config) error {
 			port = m.AgentMeta.InternalStatusPort
 		}
 
-		url := fmt.Sprintf("http://%s:%d%s", host, port, conf.Path)
+		url := fmt.Sprintf("%s://%s:%d%s", scheme, host, port, conf.Path)
 
 		logger := log.WithFields(log.Fields{
 			"monitorType": monitorType,
@@ -82,23 +107,39 @@ func (m *Monitor) Configure(conf *Config) error {
 			if !firstTime {
 				logger.WithError(err).Error("Could not connect to internal metric server")
 			}
-			return
+			return err
 		}
 		defer resp.Body.Close()
 
+		if resp.StatusCode != 200 {
+			logger.Errorf("Internal metric server responded with status %d", resp.StatusCode)
+			return fmt.Errorf("internal metric server responded with status %d", resp.StatusCode)
+		}
+
 		dps := make([]*datapoint.Datapoint, 0)
 		err = json.NewDecoder(resp.Body).Decode(&dps)
 		if err != nil {
 			logger.WithError(err).Error("Could not parse metrics from internal metric server")
-			return
+			return err
 		}
 
 		m.Output.SendDatapoints(dps...)
-	}, time.Duration(conf.IntervalSeconds)*time.Second)
+		return nil
+	}
+
+	m.collectNow = utils.RunOnIntervalOrDemand(ctx, collect, time.Duration(conf.IntervalSeconds)*time.Second)
 
 	return nil
 }
 
+// CollectNow fetches and sends the internal metrics once right away
+func (m *Monitor) CollectNow() error {
+	if m.collectNow == nil {
+		return errors.New("monitor is not configured")
+	}
+	return m.collectNow()
+}
+
 // Shutdown the internal metric collection
 func (m *Monitor) Shutdown() {
 	if m.cancel != nil {
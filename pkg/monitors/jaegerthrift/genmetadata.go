@@ -0,0 +1,27 @@
+// Code generated by monitor-code-gen. DO NOT EDIT.
+
+package jaegerthrift
+
+import (
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+)
+
+const monitorType = "jaeger-thrift"
+
+var groupSet = map[string]bool{}
+
+var metricSet = map[string]monitors.MetricInfo{}
+
+var defaultMetrics = map[string]bool{}
+
+var groupMetricsMap = map[string][]string{}
+
+var monitorMetadata = monitors.Metadata{
+	MonitorType:       "jaeger-thrift",
+	DefaultMetrics:    defaultMetrics,
+	Metrics:           metricSet,
+	MetricsExhaustive: false,
+	Groups:            groupSet,
+	GroupMetricsMap:   groupMetricsMap,
+	SendAll:           false,
+}
@@ -0,0 +1,80 @@
+package jaegerthrift
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+)
+
+func (m *Monitor) startHTTPServer(ctx context.Context, conf *Config, address string, handler http.Handler) {
+	go func() {
+		var ln net.Listener
+		err := retryUntilSuccess(ctx, conf, address, func() (err error) {
+			ln, err = net.Listen("tcp", address)
+			return err
+		})
+		if err != nil {
+			return
+		}
+
+		server := &http.Server{Handler: handler}
+
+		m.lock.Lock()
+		if ctx.Err() != nil {
+			// Shutdown happened while we were setting up the listener
+			m.lock.Unlock()
+			ln.Close()
+			return
+		}
+		m.httpServers[ln.Addr().String()] = server
+		m.lock.Unlock()
+
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.WithError(err).Errorf("HTTP server on %s failed", address)
+		}
+	}()
+}
+
+// newCollectorHandler returns an HTTP handler that accepts Thrift batches
+// in the same way as the Jaeger collector
+func (m *Monitor) newCollectorHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/traces", func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(rw, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch req.Header.Get("Content-Type") {
+		case "application/x-thrift", "application/vnd.apache.thrift.binary":
+		default:
+			http.Error(rw, "unsupported content type", http.StatusBadRequest)
+			return
+		}
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		batch := &jaeger.Batch{}
+		if err := thrift.NewTDeserializer().Read(batch, body); err != nil {
+			http.Error(rw, "could not decode Thrift batch: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var source net.IP
+		if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+			source = net.ParseIP(host)
+		}
+
+		m.sendBatch(batch, source)
+		rw.WriteHeader(http.StatusAccepted)
+	})
+	return mux
+}
@@ -0,0 +1,192 @@
+package jaegerthrift
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/jaegertracing/jaeger/model"
+	jaegerthriftconv "github.com/jaegertracing/jaeger/model/converter/thrift/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/signalfx/defaults"
+	"github.com/signalfx/signalfx-agent/pkg/core/common/constants"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/jaegergrpc/jaegerprotobuf"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const gracefulShutdownTimeout = time.Second * 5
+
+var logger = utils.NewThrottledLogger(log.WithFields(log.Fields{"monitorType": monitorType}), 30*time.Second)
+
+func init() {
+	monitors.Register(&monitorMetadata, func() interface{} { return &Monitor{} }, &Config{})
+}
+
+// Config for this monitor
+type Config struct {
+	config.MonitorConfig `yaml:",inline" acceptsEndpoints:"false" singleInstance:"true"`
+	// The host:port on which to listen for Jaeger Thrift batches encoded with
+	// the compact protocol over UDP.  This is what most Jaeger clients send
+	// by default.  Set to an empty string to disable.
+	CompactListenAddress string `yaml:"compactListenAddress" default:"0.0.0.0:6831"`
+	// The host:port on which to listen for Jaeger Thrift batches encoded with
+	// the binary protocol over UDP.  Set to an empty string to disable.
+	BinaryListenAddress string `yaml:"binaryListenAddress" default:"0.0.0.0:6832"`
+	// The host:port on which to serve the sampling strategy HTTP endpoint
+	// that Jaeger clients poll (`/sampling?service=<name>`).  Set to an empty
+	// string to disable.
+	SamplingListenAddress string `yaml:"samplingListenAddress" default:"0.0.0.0:5778"`
+	// The host:port on which to accept Jaeger Thrift batches over HTTP in the
+	// same way as the Jaeger collector (`POST /api/traces`).  Disabled by
+	// default.
+	CollectorListenAddress string `yaml:"collectorListenAddress"`
+	// The largest UDP packet that will be accepted.  Jaeger clients limit
+	// their packets to 65000 bytes by default.
+	MaxPacketSize int `yaml:"maxPacketSize" default:"65000"`
+	// The sampling strategy to return to services that don't have one
+	// configured in `serviceSamplingStrategies`.
+	DefaultSamplingStrategy SamplingStrategy `yaml:"defaultSamplingStrategy" default:"{}"`
+	// A map of service name to the sampling strategy that should be returned
+	// to that service.  The strategies have the same defaults as
+	// `defaultSamplingStrategy`.
+	ServiceSamplingStrategies map[string]SamplingStrategy `yaml:"serviceSamplingStrategies"`
+}
+
+// Validate the config
+func (c *Config) Validate() error {
+	if err := c.DefaultSamplingStrategy.Validate(); err != nil {
+		return err
+	}
+	if err := c.setServiceStrategyDefaults(); err != nil {
+		return err
+	}
+	for service, s := range c.ServiceSamplingStrategies {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("sampling strategy for service %s is invalid: %v", service, err)
+		}
+	}
+	return nil
+}
+
+// The defaults aren't set on map values when the config is loaded, so they
+// are set here, including on services that were given no strategy at all.
+func (c *Config) setServiceStrategyDefaults() error {
+	for service, s := range c.ServiceSamplingStrategies {
+		if err := defaults.Set(&s); err != nil {
+			return err
+		}
+		c.ServiceSamplingStrategies[service] = s
+	}
+	return nil
+}
+
+// Monitor that accepts Jaeger Thrift spans in the same way as the Jaeger
+// agent
+type Monitor struct {
+	Output types.Output
+	cancel context.CancelFunc
+
+	lock sync.Mutex
+	// Keyed by the bound address so that tests can find them
+	udpConns    map[string]net.PacketConn
+	httpServers map[string]*http.Server
+}
+
+// Configure the monitor and start the listeners
+func (m *Monitor) Configure(conf *Config) error {
+	if err := conf.setServiceStrategyDefaults(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.udpConns = map[string]net.PacketConn{}
+	m.httpServers = map[string]*http.Server{}
+
+	if conf.CompactListenAddress != "" {
+		m.startUDPServer(ctx, conf, conf.CompactListenAddress, thrift.NewTCompactProtocolFactory())
+	}
+	if conf.BinaryListenAddress != "" {
+		m.startUDPServer(ctx, conf, conf.BinaryListenAddress, thrift.NewTBinaryProtocolFactoryDefault())
+	}
+	if conf.SamplingListenAddress != "" {
+		m.startHTTPServer(ctx, conf, conf.SamplingListenAddress, newSamplingHandler(conf))
+	}
+	if conf.CollectorListenAddress != "" {
+		m.startHTTPServer(ctx, conf, conf.CollectorListenAddress, m.newCollectorHandler())
+	}
+
+	return nil
+}
+
+// sendBatch converts the Thrift batch to SignalFx spans and sends them
+func (m *Monitor) sendBatch(batch *jaeger.Batch, source net.IP) {
+	if batch == nil || len(batch.Spans) == 0 {
+		return
+	}
+
+	// Convert to the Jaeger domain model first so that the same conversion
+	// as the jaeger-grpc monitor can be used
+	spans := jaegerprotobuf.JaegerProtoBatchToSFX(&model.Batch{
+		Spans:   jaegerthriftconv.ToDomain(batch.Spans, batch.Process),
+		Process: jaegerthriftconv.ToDomainProcess(batch.Process),
+	})
+
+	if source != nil {
+		for i := range spans {
+			if spans[i].Meta == nil {
+				spans[i].Meta = map[interface{}]interface{}{}
+			}
+			spans[i].Meta[constants.DataSourceIPKey] = source
+		}
+	}
+
+	m.Output.SendSpans(spans...)
+}
+
+// retryUntilSuccess calls listen until it succeeds or the context is
+// cancelled, waiting for the monitor interval between attempts.
+func retryUntilSuccess(ctx context.Context, conf *Config, address string, listen func() error) error {
+	for ctx.Err() == nil {
+		err := listen()
+		if err == nil {
+			return nil
+		}
+
+		logger.WithError(err).Errorf("Could not listen on %s", address)
+
+		select {
+		case <-time.After(time.Duration(conf.IntervalSeconds) * time.Second):
+		case <-ctx.Done():
+		}
+	}
+	return ctx.Err()
+}
+
+// Shutdown stops all of the listeners
+func (m *Monitor) Shutdown() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, conn := range m.udpConns {
+		conn.Close()
+	}
+
+	for _, server := range m.httpServers {
+		ctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownTimeout)
+		_ = server.Shutdown(ctx)
+		cancel()
+	}
+}
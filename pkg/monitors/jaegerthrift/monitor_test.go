@@ -0,0 +1,232 @@
+package jaegerthrift
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/jaegertracing/jaeger/thrift-gen/agent"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/signalfx/defaults"
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/core/common/constants"
+	"github.com/signalfx/signalfx-agent/pkg/neotest"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func testBatch() *jaeger.Batch {
+	hostname := "api246-sjc1"
+	url := "http://127.0.0.1:15598/client_transactions"
+	kind := "server"
+	parentID := int64(6866147)
+	return &jaeger.Batch{
+		Process: &jaeger.Process{
+			ServiceName: "api",
+			Tags: []*jaeger.Tag{
+				{Key: "hostname", VType: jaeger.TagType_STRING, VStr: &hostname},
+			},
+		},
+		Spans: []*jaeger.Span{
+			{
+				TraceIdLow:    5951113872249657919,
+				SpanId:        6585752,
+				ParentSpanId:  parentID,
+				OperationName: "get",
+				StartTime:     1485467191639875,
+				Duration:      22938,
+				Tags: []*jaeger.Tag{
+					{Key: "http.url", VType: jaeger.TagType_STRING, VStr: &url},
+					{Key: "span.kind", VType: jaeger.TagType_STRING, VStr: &kind},
+				},
+			},
+		},
+	}
+}
+
+func requireTestSpans(t *testing.T, spans []*trace.Span) {
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, "52969a8955571a3f", span.TraceID)
+	require.Equal(t, "0000000000647d98", span.ID)
+	require.Equal(t, "000000000068c4e3", *span.ParentID)
+	require.Equal(t, "get", *span.Name)
+	require.Equal(t, "SERVER", *span.Kind)
+	require.Equal(t, int64(22938), *span.Duration)
+	require.Equal(t, "api", *span.LocalEndpoint.ServiceName)
+	require.Equal(t, map[string]string{
+		"http.url": "http://127.0.0.1:15598/client_transactions",
+		"hostname": "api246-sjc1",
+	}, span.Tags)
+	require.Equal(t, net.ParseIP("127.0.0.1").To4(), span.Meta[constants.DataSourceIPKey].(net.IP).To4())
+}
+
+func waitForSpans(t *testing.T, output *neotest.TestOutput) []*trace.Span {
+	var spans []*trace.Span
+	require.Eventually(t, func() bool {
+		spans = append(spans, output.FlushSpans()...)
+		return len(spans) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	return spans
+}
+
+func waitForUDPAddress(t *testing.T, m *Monitor) string {
+	var address string
+	require.Eventually(t, func() bool {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		for addr := range m.udpConns {
+			address = addr
+		}
+		return address != ""
+	}, 5*time.Second, 10*time.Millisecond)
+	return address
+}
+
+func TestUDPServers(t *testing.T) {
+	for name, factory := range map[string]thrift.TProtocolFactory{
+		"compact": thrift.NewTCompactProtocolFactory(),
+		"binary":  thrift.NewTBinaryProtocolFactoryDefault(),
+	} {
+		factory := factory
+		t.Run(name, func(t *testing.T) {
+			conf := &Config{MaxPacketSize: 65000}
+			if name == "compact" {
+				conf.CompactListenAddress = "127.0.0.1:0"
+			} else {
+				conf.BinaryListenAddress = "127.0.0.1:0"
+			}
+
+			output := neotest.NewTestOutput()
+			m := &Monitor{Output: output}
+			require.NoError(t, m.Configure(conf))
+			defer m.Shutdown()
+
+			buf := thrift.NewTMemoryBuffer()
+			require.NoError(t, agent.NewAgentClientFactory(buf, factory).EmitBatch(testBatch()))
+
+			conn, err := net.Dial("udp", waitForUDPAddress(t, m))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write(buf.Bytes())
+			require.NoError(t, err)
+
+			requireTestSpans(t, waitForSpans(t, output))
+		})
+	}
+}
+
+func TestCollectorHandler(t *testing.T) {
+	output := neotest.NewTestOutput()
+	m := &Monitor{Output: output}
+	server := httptest.NewServer(m.newCollectorHandler())
+	defer server.Close()
+
+	body, err := thrift.NewTSerializer().Write(testBatch())
+	require.NoError(t, err)
+
+	resp, err := http.Post(server.URL+"/api/traces", "application/x-thrift", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	requireTestSpans(t, output.FlushSpans())
+
+	resp, err = http.Post(server.URL+"/api/traces", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServiceSamplingStrategyDefaults(t *testing.T) {
+	var conf Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+serviceSamplingStrategies:
+  checkout:
+  frontend:
+    param: 0.5
+`), &conf))
+	require.NoError(t, defaults.Set(&conf))
+	require.NoError(t, conf.Validate())
+
+	require.Equal(t, SamplingStrategy{Type: strategyProbabilistic, Param: 0.001}, conf.ServiceSamplingStrategies["checkout"])
+	require.Equal(t, SamplingStrategy{Type: strategyProbabilistic, Param: 0.5}, conf.ServiceSamplingStrategies["frontend"])
+
+	conf.ServiceSamplingStrategies["frontend"] = SamplingStrategy{Type: "bad"}
+	require.Error(t, conf.Validate())
+}
+
+func TestSamplingHandler(t *testing.T) {
+	conf := &Config{
+		DefaultSamplingStrategy: SamplingStrategy{Type: strategyProbabilistic, Param: 0.001},
+		ServiceSamplingStrategies: map[string]SamplingStrategy{
+			"checkout": {Type: strategyRateLimiting, Param: 50},
+			"frontend": {
+				Type:                   strategyProbabilistic,
+				Param:                  0.1,
+				OperationSamplingRates: map[string]float64{"GET /healthz": 0},
+			},
+		},
+	}
+	require.NoError(t, conf.Validate())
+
+	server := httptest.NewServer(newSamplingHandler(conf))
+	defer server.Close()
+
+	get := func(path string) (int, map[string]interface{}) {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var out map[string]interface{}
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		}
+		return resp.StatusCode, out
+	}
+
+	code, out := get("/sampling?service=other")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]interface{}{
+		"strategyType":          "PROBABILISTIC",
+		"probabilisticSampling": map[string]interface{}{"samplingRate": 0.001},
+	}, out)
+
+	code, out = get("/?service=checkout")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]interface{}{
+		"strategyType":         "RATE_LIMITING",
+		"rateLimitingSampling": map[string]interface{}{"maxTracesPerSecond": float64(50)},
+	}, out)
+
+	code, out = get("/sampling?service=frontend")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]interface{}{
+		"defaultSamplingProbability":       0.1,
+		"defaultLowerBoundTracesPerSecond": float64(0),
+		"perOperationStrategies": []interface{}{
+			map[string]interface{}{
+				"operation":             "GET /healthz",
+				"probabilisticSampling": map[string]interface{}{"samplingRate": float64(0)},
+			},
+		},
+	}, out["operationSampling"])
+
+	code, _ = get("/sampling")
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestSamplingStrategyValidation(t *testing.T) {
+	require.Error(t, (&SamplingStrategy{Type: "always"}).Validate())
+	require.Error(t, (&SamplingStrategy{Type: strategyProbabilistic, Param: 2}).Validate())
+	require.Error(t, (&SamplingStrategy{
+		Type:                   strategyRateLimiting,
+		Param:                  10,
+		OperationSamplingRates: map[string]float64{"a": 0.5},
+	}).Validate())
+}
@@ -0,0 +1,131 @@
+package jaegerthrift
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/jaegertracing/jaeger/thrift-gen/sampling"
+)
+
+// The types of sampling strategy
+const (
+	strategyProbabilistic = "probabilistic"
+	strategyRateLimiting  = "rateLimiting"
+)
+
+// SamplingStrategy is the sampling strategy that Jaeger clients are told to
+// use
+type SamplingStrategy struct {
+	// Either `probabilistic` or `rateLimiting`
+	Type string `yaml:"type" default:"probabilistic"`
+	// For `probabilistic`, the fraction of traces (between 0 and 1) to
+	// sample.  For `rateLimiting`, the max number of traces per second to
+	// sample.
+	Param float64 `yaml:"param" default:"0.001"`
+	// A map of operation name to the fraction of traces to sample for that
+	// operation.  Only applies to the `probabilistic` type, where `param` is
+	// used for operations that aren't in this map.
+	OperationSamplingRates map[string]float64 `yaml:"operationSamplingRates"`
+}
+
+// Validate the sampling strategy
+func (s *SamplingStrategy) Validate() error {
+	switch s.Type {
+	case strategyProbabilistic:
+		if s.Param < 0 || s.Param > 1 {
+			return fmt.Errorf("probabilistic sampling param must be between 0 and 1, got %f", s.Param)
+		}
+		for op, rate := range s.OperationSamplingRates {
+			if rate < 0 || rate > 1 {
+				return fmt.Errorf("sampling rate for operation %s must be between 0 and 1, got %f", op, rate)
+			}
+		}
+	case strategyRateLimiting:
+		if s.Param < 0 || s.Param > math.MaxInt16 {
+			return fmt.Errorf("rateLimiting sampling param must be between 0 and %d, got %f", math.MaxInt16, s.Param)
+		}
+		if len(s.OperationSamplingRates) > 0 {
+			return fmt.Errorf("operationSamplingRates can only be used with the %s type", strategyProbabilistic)
+		}
+	default:
+		return fmt.Errorf("sampling strategy type %q is invalid, must be %s or %s",
+			s.Type, strategyProbabilistic, strategyRateLimiting)
+	}
+	return nil
+}
+
+// toThrift converts the strategy to the response that Jaeger clients expect
+func (s *SamplingStrategy) toThrift() *sampling.SamplingStrategyResponse {
+	if s.Type == strategyRateLimiting {
+		return &sampling.SamplingStrategyResponse{
+			StrategyType: sampling.SamplingStrategyType_RATE_LIMITING,
+			RateLimitingSampling: &sampling.RateLimitingSamplingStrategy{
+				MaxTracesPerSecond: int16(s.Param),
+			},
+		}
+	}
+
+	resp := &sampling.SamplingStrategyResponse{
+		StrategyType: sampling.SamplingStrategyType_PROBABILISTIC,
+		ProbabilisticSampling: &sampling.ProbabilisticSamplingStrategy{
+			SamplingRate: s.Param,
+		},
+	}
+
+	if len(s.OperationSamplingRates) > 0 {
+		ops := make([]string, 0, len(s.OperationSamplingRates))
+		for op := range s.OperationSamplingRates {
+			ops = append(ops, op)
+		}
+		sort.Strings(ops)
+
+		resp.OperationSampling = &sampling.PerOperationSamplingStrategies{
+			DefaultSamplingProbability: s.Param,
+		}
+		for _, op := range ops {
+			resp.OperationSampling.PerOperationStrategies = append(resp.OperationSampling.PerOperationStrategies,
+				&sampling.OperationSamplingStrategy{
+					Operation: op,
+					ProbabilisticSampling: &sampling.ProbabilisticSamplingStrategy{
+						SamplingRate: s.OperationSamplingRates[op],
+					},
+				})
+		}
+	}
+	return resp
+}
+
+// newSamplingHandler returns an HTTP handler that serves sampling strategies
+// in the same way as the Jaeger agent
+func newSamplingHandler(conf *Config) http.Handler {
+	handler := func(rw http.ResponseWriter, req *http.Request) {
+		services := req.URL.Query()["service"]
+		if len(services) != 1 {
+			http.Error(rw, "'service' parameter must be provided once", http.StatusBadRequest)
+			return
+		}
+
+		strategy := &conf.DefaultSamplingStrategy
+		if s, ok := conf.ServiceSamplingStrategies[services[0]]; ok {
+			strategy = &s
+		}
+
+		body, err := json.Marshal(strategy.toThrift())
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write(body)
+	}
+
+	mux := http.NewServeMux()
+	// Older clients use the root path
+	mux.HandleFunc("/", handler)
+	mux.HandleFunc("/sampling", handler)
+	return mux
+}
@@ -0,0 +1,89 @@
+package jaegerthrift
+
+import (
+	"context"
+	"net"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/jaegertracing/jaeger/thrift-gen/agent"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+// agentHandler implements the Thrift agent service for the batches in a
+// single UDP packet
+type agentHandler struct {
+	monitor *Monitor
+	source  net.IP
+}
+
+var _ agent.Agent = (*agentHandler)(nil)
+
+func (h *agentHandler) EmitBatch(batch *jaeger.Batch) error {
+	h.monitor.sendBatch(batch, h.source)
+	return nil
+}
+
+func (h *agentHandler) EmitZipkinBatch(spans []*zipkincore.Span) error {
+	logger.ThrottledWarning("Zipkin Thrift batches are not supported, use the trace-forwarder monitor instead")
+	return nil
+}
+
+func (m *Monitor) startUDPServer(ctx context.Context, conf *Config, address string, protocolFactory thrift.TProtocolFactory) {
+	go func() {
+		var conn net.PacketConn
+		err := retryUntilSuccess(ctx, conf, address, func() (err error) {
+			conn, err = net.ListenPacket("udp", address)
+			return err
+		})
+		if err != nil {
+			return
+		}
+
+		m.lock.Lock()
+		if ctx.Err() != nil {
+			// Shutdown happened while we were setting up the listener
+			m.lock.Unlock()
+			conn.Close()
+			return
+		}
+		m.udpConns[conn.LocalAddr().String()] = conn
+		m.lock.Unlock()
+
+		buf := make([]byte, conf.MaxPacketSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				logger.WithError(err).ThrottledError("Could not read UDP packet")
+				continue
+			}
+
+			var source net.IP
+			if udpAddr, ok := addr.(*net.UDPAddr); ok {
+				source = udpAddr.IP
+			}
+
+			if err := m.processPacket(buf[:n], protocolFactory, source); err != nil {
+				logger.WithError(err).ThrottledError("Could not decode Jaeger Thrift packet")
+			}
+		}
+	}()
+}
+
+// processPacket decodes a single UDP packet containing a Thrift agent
+// service call and sends any spans in it.
+func (m *Monitor) processPacket(packet []byte, protocolFactory thrift.TProtocolFactory, source net.IP) error {
+	trans := thrift.NewTMemoryBufferLen(len(packet))
+	if _, err := trans.Write(packet); err != nil {
+		return err
+	}
+
+	protocol := protocolFactory.GetProtocol(trans)
+	processor := agent.NewAgentProcessor(&agentHandler{monitor: m, source: source})
+	// Agent calls are oneway so nothing is ever written back
+	_, err := processor.Process(protocol, protocol)
+	return err
+}
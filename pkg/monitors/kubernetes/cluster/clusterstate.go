@@ -18,6 +18,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8s "k8s.io/client-go/kubernetes"
@@ -79,6 +80,7 @@ func (cs *State) Start() {
 			cs.quotaClient.RESTClient())
 	}
 	hpaV2Beta1Client := cs.clientset.AutoscalingV2beta1().RESTClient()
+	policyV1Beta1Client := cs.clientset.PolicyV1beta1().RESTClient()
 
 	cs.beginSyncForType(ctx, &v1.Pod{}, "pods", cs.namespace, coreClient)
 	cs.beginSyncForType(ctx, &appsv1.DaemonSet{}, "daemonsets", cs.namespace, appsV1Client)
@@ -90,6 +92,8 @@ func (cs *State) Start() {
 	cs.beginSyncForType(ctx, &v1.Service{}, "services", cs.namespace, coreClient)
 	cs.beginSyncForType(ctx, &batchv1.Job{}, "jobs", cs.namespace, batchV1Client)
 	cs.beginSyncForType(ctx, &batchv1beta1.CronJob{}, "cronjobs", cs.namespace, batchBetaV1Client)
+	cs.beginSyncForType(ctx, &v1.PersistentVolumeClaim{}, "persistentvolumeclaims", cs.namespace, coreClient)
+	cs.beginSyncForType(ctx, &policyv1beta1.PodDisruptionBudget{}, "poddisruptionbudgets", cs.namespace, policyV1Beta1Client)
 	// Node and Namespace are NOT namespaced resources, so we don't need to
 	// fetch them if we are scoped to a specific namespace
 	if cs.namespace == "" {
@@ -10,11 +10,19 @@ import (
 const MonitorType = "kubernetes-cluster"
 
 const (
-	GroupHpa = "hpa"
+	GroupContainerStatus       = "container_status"
+	GroupHpa                   = "hpa"
+	GroupNodeResources         = "node_resources"
+	GroupPersistentVolumeClaim = "persistent_volume_claim"
+	GroupPodDisruptionBudget   = "pod_disruption_budget"
 )
 
 var GroupSet = map[string]bool{
-	GroupHpa: true,
+	GroupContainerStatus:       true,
+	GroupHpa:                   true,
+	GroupNodeResources:         true,
+	GroupPersistentVolumeClaim: true,
+	GroupPodDisruptionBudget:   true,
 }
 
 const (
@@ -22,10 +30,13 @@ const (
 	KubernetesContainerCPURequest                          = "kubernetes.container_cpu_request"
 	KubernetesContainerEphemeralStorageLimit               = "kubernetes.container_ephemeral_storage_limit"
 	KubernetesContainerEphemeralStorageRequest             = "kubernetes.container_ephemeral_storage_request"
+	KubernetesContainerLastTerminatedReason                = "kubernetes.container_last_terminated_reason"
 	KubernetesContainerMemoryLimit                         = "kubernetes.container_memory_limit"
 	KubernetesContainerMemoryRequest                       = "kubernetes.container_memory_request"
 	KubernetesContainerReady                               = "kubernetes.container_ready"
 	KubernetesContainerRestartCount                        = "kubernetes.container_restart_count"
+	KubernetesContainerTerminatedReason                    = "kubernetes.container_terminated_reason"
+	KubernetesContainerWaitingReason                       = "kubernetes.container_waiting_reason"
 	KubernetesCronjobActive                                = "kubernetes.cronjob.active"
 	KubernetesDaemonSetCurrentScheduled                    = "kubernetes.daemon_set.current_scheduled"
 	KubernetesDaemonSetDesiredScheduled                    = "kubernetes.daemon_set.desired_scheduled"
@@ -46,7 +57,16 @@ const (
 	KubernetesJobParallelism                               = "kubernetes.job.parallelism"
 	KubernetesJobSucceeded                                 = "kubernetes.job.succeeded"
 	KubernetesNamespacePhase                               = "kubernetes.namespace_phase"
+	KubernetesNodeAllocatable                              = "kubernetes.node_allocatable"
+	KubernetesNodeCapacity                                 = "kubernetes.node_capacity"
 	KubernetesNodeReady                                    = "kubernetes.node_ready"
+	KubernetesPersistentVolumeClaimCapacity                = "kubernetes.persistent_volume_claim.capacity"
+	KubernetesPersistentVolumeClaimPhase                   = "kubernetes.persistent_volume_claim.phase"
+	KubernetesPersistentVolumeClaimRequestedStorage        = "kubernetes.persistent_volume_claim.requested_storage"
+	KubernetesPodDisruptionBudgetCurrentHealthy            = "kubernetes.pod_disruption_budget.current_healthy"
+	KubernetesPodDisruptionBudgetDesiredHealthy            = "kubernetes.pod_disruption_budget.desired_healthy"
+	KubernetesPodDisruptionBudgetDisruptionsAllowed        = "kubernetes.pod_disruption_budget.disruptions_allowed"
+	KubernetesPodDisruptionBudgetExpectedPods              = "kubernetes.pod_disruption_budget.expected_pods"
 	KubernetesPodPhase                                     = "kubernetes.pod_phase"
 	KubernetesReplicaSetAvailable                          = "kubernetes.replica_set.available"
 	KubernetesReplicaSetDesired                            = "kubernetes.replica_set.desired"
@@ -93,10 +113,13 @@ var MetricSet = map[string]monitors.MetricInfo{
 	KubernetesContainerCPURequest:                          {Type: datapoint.Gauge},
 	KubernetesContainerEphemeralStorageLimit:               {Type: datapoint.Gauge},
 	KubernetesContainerEphemeralStorageRequest:             {Type: datapoint.Gauge},
+	KubernetesContainerLastTerminatedReason:                {Type: datapoint.Gauge, Group: GroupContainerStatus},
 	KubernetesContainerMemoryLimit:                         {Type: datapoint.Gauge},
 	KubernetesContainerMemoryRequest:                       {Type: datapoint.Gauge},
 	KubernetesContainerReady:                               {Type: datapoint.Gauge},
 	KubernetesContainerRestartCount:                        {Type: datapoint.Gauge},
+	KubernetesContainerTerminatedReason:                    {Type: datapoint.Gauge, Group: GroupContainerStatus},
+	KubernetesContainerWaitingReason:                       {Type: datapoint.Gauge, Group: GroupContainerStatus},
 	KubernetesCronjobActive:                                {Type: datapoint.Gauge},
 	KubernetesDaemonSetCurrentScheduled:                    {Type: datapoint.Gauge},
 	KubernetesDaemonSetDesiredScheduled:                    {Type: datapoint.Gauge},
@@ -117,7 +140,16 @@ var MetricSet = map[string]monitors.MetricInfo{
 	KubernetesJobParallelism:                               {Type: datapoint.Gauge},
 	KubernetesJobSucceeded:                                 {Type: datapoint.Count},
 	KubernetesNamespacePhase:                               {Type: datapoint.Gauge},
+	KubernetesNodeAllocatable:                              {Type: datapoint.Gauge, Group: GroupNodeResources},
+	KubernetesNodeCapacity:                                 {Type: datapoint.Gauge, Group: GroupNodeResources},
 	KubernetesNodeReady:                                    {Type: datapoint.Gauge},
+	KubernetesPersistentVolumeClaimCapacity:                {Type: datapoint.Gauge, Group: GroupPersistentVolumeClaim},
+	KubernetesPersistentVolumeClaimPhase:                   {Type: datapoint.Gauge, Group: GroupPersistentVolumeClaim},
+	KubernetesPersistentVolumeClaimRequestedStorage:        {Type: datapoint.Gauge, Group: GroupPersistentVolumeClaim},
+	KubernetesPodDisruptionBudgetCurrentHealthy:            {Type: datapoint.Gauge, Group: GroupPodDisruptionBudget},
+	KubernetesPodDisruptionBudgetDesiredHealthy:            {Type: datapoint.Gauge, Group: GroupPodDisruptionBudget},
+	KubernetesPodDisruptionBudgetDisruptionsAllowed:        {Type: datapoint.Gauge, Group: GroupPodDisruptionBudget},
+	KubernetesPodDisruptionBudgetExpectedPods:              {Type: datapoint.Gauge, Group: GroupPodDisruptionBudget},
 	KubernetesPodPhase:                                     {Type: datapoint.Gauge},
 	KubernetesReplicaSetAvailable:                          {Type: datapoint.Gauge},
 	KubernetesReplicaSetDesired:                            {Type: datapoint.Gauge},
@@ -208,6 +240,14 @@ var DefaultMetrics = map[string]bool{
 }
 
 var GroupMetricsMap = map[string][]string{
+	GroupContainerStatus: []string{
+		KubernetesContainerLastTerminatedReason,
+		KubernetesContainerLastTerminatedReason,
+		KubernetesContainerTerminatedReason,
+		KubernetesContainerTerminatedReason,
+		KubernetesContainerWaitingReason,
+		KubernetesContainerWaitingReason,
+	},
 	GroupHpa: []string{
 		KubernetesHpaSpecMaxReplicas,
 		KubernetesHpaSpecMaxReplicas,
@@ -224,6 +264,30 @@ var GroupMetricsMap = map[string][]string{
 		KubernetesHpaStatusDesiredReplicas,
 		KubernetesHpaStatusDesiredReplicas,
 	},
+	GroupNodeResources: []string{
+		KubernetesNodeAllocatable,
+		KubernetesNodeAllocatable,
+		KubernetesNodeCapacity,
+		KubernetesNodeCapacity,
+	},
+	GroupPersistentVolumeClaim: []string{
+		KubernetesPersistentVolumeClaimCapacity,
+		KubernetesPersistentVolumeClaimCapacity,
+		KubernetesPersistentVolumeClaimPhase,
+		KubernetesPersistentVolumeClaimPhase,
+		KubernetesPersistentVolumeClaimRequestedStorage,
+		KubernetesPersistentVolumeClaimRequestedStorage,
+	},
+	GroupPodDisruptionBudget: []string{
+		KubernetesPodDisruptionBudgetCurrentHealthy,
+		KubernetesPodDisruptionBudgetCurrentHealthy,
+		KubernetesPodDisruptionBudgetDesiredHealthy,
+		KubernetesPodDisruptionBudgetDesiredHealthy,
+		KubernetesPodDisruptionBudgetDisruptionsAllowed,
+		KubernetesPodDisruptionBudgetDisruptionsAllowed,
+		KubernetesPodDisruptionBudgetExpectedPods,
+		KubernetesPodDisruptionBudgetExpectedPods,
+	},
 }
 
 var KubernetesClusterMonitorMetadata = monitors.Metadata{
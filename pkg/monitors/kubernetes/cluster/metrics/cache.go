@@ -14,6 +14,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -111,6 +112,10 @@ func (dc *DatapointCache) HandleAdd(newObj runtime.Object) interface{} {
 		dps = datapointsForCronJob(o)
 	case *v2beta1.HorizontalPodAutoscaler:
 		dps = datapointsForHpa(o)
+	case *v1.PersistentVolumeClaim:
+		dps = datapointsForPersistentVolumeClaim(o)
+	case *policyv1beta1.PodDisruptionBudget:
+		dps = datapointsForPodDisruptionBudget(o)
 	default:
 		log.WithFields(log.Fields{
 			"obj": spew.Sdump(newObj),
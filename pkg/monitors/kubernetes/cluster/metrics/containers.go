@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/cluster/meta"
 	atypes "github.com/signalfx/signalfx-agent/pkg/monitors/types"
 	"github.com/signalfx/signalfx-agent/pkg/utils"
 	"github.com/signalfx/signalfx-agent/pkg/utils/k8sutil"
@@ -26,6 +27,36 @@ func datapointsForContainerStatus(cs v1.ContainerStatus, contDims map[string]str
 			time.Time{}),
 	}
 
+	return append(dps, datapointsForContainerStateReasons(cs, contDims)...)
+}
+
+// datapointsForContainerStateReasons returns a datapoint with a `reason`
+// dimension for each of the container's current waiting or terminated state
+// and its last terminated state.
+func datapointsForContainerStateReasons(cs v1.ContainerStatus, contDims map[string]string) []*datapoint.Datapoint {
+	var dps []*datapoint.Datapoint
+
+	reasonDP := func(metric, reason string) *datapoint.Datapoint {
+		return datapoint.New(
+			metric,
+			utils.MergeStringMaps(contDims, map[string]string{"reason": reason}),
+			datapoint.NewIntValue(1),
+			datapoint.Gauge,
+			time.Time{})
+	}
+
+	if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+		dps = append(dps, reasonDP(meta.KubernetesContainerWaitingReason, cs.State.Waiting.Reason))
+	}
+
+	if cs.State.Terminated != nil && cs.State.Terminated.Reason != "" {
+		dps = append(dps, reasonDP(meta.KubernetesContainerTerminatedReason, cs.State.Terminated.Reason))
+	}
+
+	if cs.LastTerminationState.Terminated != nil && cs.LastTerminationState.Terminated.Reason != "" {
+		dps = append(dps, reasonDP(meta.KubernetesContainerLastTerminatedReason, cs.LastTerminationState.Terminated.Reason))
+	}
+
 	return dps
 }
 
@@ -12,6 +12,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -82,6 +83,12 @@ func (dh *DimensionHandler) HandleAdd(newObj runtime.Object) interface{} {
 	case *v2beta1.HorizontalPodAutoscaler:
 		dh.sendDimensionFunc(dimensionForHpa(o))
 		kind = "HorizontalPodAutoscaler"
+	case *v1.PersistentVolumeClaim:
+		dh.sendDimensionFunc(dimensionForPersistentVolumeClaim(o))
+		kind = "PersistentVolumeClaim"
+	case *policyv1beta1.PodDisruptionBudget:
+		dh.sendDimensionFunc(dimensionForPodDisruptionBudget(o))
+		kind = "PodDisruptionBudget"
 	default:
 		return nil
 	}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/cluster/meta"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDatapointsForContainerStateReasons(t *testing.T) {
+	dps := datapointsForContainerStateReasons(v1.ContainerStatus{
+		State: v1.ContainerState{
+			Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+		},
+		LastTerminationState: v1.ContainerState{
+			Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled"},
+		},
+	}, map[string]string{"container_id": "abc"})
+
+	require.Len(t, dps, 2)
+	require.Equal(t, meta.KubernetesContainerWaitingReason, dps[0].Metric)
+	require.Equal(t, map[string]string{"container_id": "abc", "reason": "CrashLoopBackOff"}, dps[0].Dimensions)
+	require.Equal(t, meta.KubernetesContainerLastTerminatedReason, dps[1].Metric)
+	require.Equal(t, "OOMKilled", dps[1].Dimensions["reason"])
+}
+
+func TestDatapointsForNodeResources(t *testing.T) {
+	dps := datapointsForNodeResources(meta.KubernetesNodeAllocatable, v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("1500m"),
+		v1.ResourceMemory: resource.MustParse("1Ki"),
+	}, map[string]string{"kubernetes_node": "n1"})
+
+	values := map[string]string{}
+	for _, dp := range dps {
+		require.Equal(t, "n1", dp.Dimensions["kubernetes_node"])
+		values[dp.Dimensions["resource"]] = dp.Value.String()
+	}
+	require.Equal(t, map[string]string{"cpu": "1500", "memory": "1024"}, values)
+}
+
+func TestDatapointsForPersistentVolumeClaim(t *testing.T) {
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default", UID: "1234"},
+		Spec: v1.PersistentVolumeClaimSpec{
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		},
+		Status: v1.PersistentVolumeClaimStatus{Phase: v1.ClaimPending},
+	}
+
+	dps := datapointsForPersistentVolumeClaim(pvc)
+	require.Len(t, dps, 2)
+	require.Equal(t, meta.KubernetesPersistentVolumeClaimPhase, dps[0].Metric)
+	require.Equal(t, "1", dps[0].Value.String())
+	require.Equal(t, meta.KubernetesPersistentVolumeClaimRequestedStorage, dps[1].Metric)
+	require.Equal(t, "10737418240", dps[1].Value.String())
+
+	// An empty phase must not be reported as pending
+	pvc.Status.Phase = ""
+	dps = datapointsForPersistentVolumeClaim(pvc)
+	require.Equal(t, "4", dps[0].Value.String())
+}
@@ -8,8 +8,10 @@ import (
 
 	"github.com/signalfx/golib/v3/datapoint"
 	"github.com/signalfx/golib/v3/sfxclient"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/cluster/meta"
 	k8sutil "github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/utils"
 	atypes "github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -43,9 +45,28 @@ func datapointsForNode(
 			),
 		)
 	}
+
+	datapoints = append(datapoints, datapointsForNodeResources(meta.KubernetesNodeCapacity, node.Status.Capacity, dims)...)
+	datapoints = append(datapoints, datapointsForNodeResources(meta.KubernetesNodeAllocatable, node.Status.Allocatable, dims)...)
+
 	return datapoints
 }
 
+func datapointsForNodeResources(metric string, resources v1.ResourceList, dims map[string]string) []*datapoint.Datapoint {
+	dps := make([]*datapoint.Datapoint, 0, len(resources))
+	for k, v := range resources {
+		val := v.Value()
+		if k == v1.ResourceCPU {
+			val = v.MilliValue()
+		}
+
+		dps = append(dps, sfxclient.Gauge(metric, utils.MergeStringMaps(dims, map[string]string{
+			"resource": string(k),
+		}), val))
+	}
+	return dps
+}
+
 func dimensionForNode(node *v1.Node, useNodeName bool) *atypes.Dimension {
 	props, tags := k8sutil.PropsAndTagsFromLabels(node.Labels)
 	_ = getPropsFromTaints(node.Spec.Taints)
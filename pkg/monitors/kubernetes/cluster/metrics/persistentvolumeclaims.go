@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/cluster/meta"
+	k8sutil "github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/utils"
+	atypes "github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	v1 "k8s.io/api/core/v1"
+)
+
+func datapointsForPersistentVolumeClaim(pvc *v1.PersistentVolumeClaim) []*datapoint.Datapoint {
+	dimensions := map[string]string{
+		"metric_source":        "kubernetes",
+		"kubernetes_namespace": pvc.Namespace,
+		"kubernetes_uid":       string(pvc.UID),
+		"kubernetes_name":      pvc.Name,
+	}
+
+	dps := []*datapoint.Datapoint{
+		datapoint.New(
+			meta.KubernetesPersistentVolumeClaimPhase,
+			dimensions,
+			datapoint.NewIntValue(pvcPhaseToInt(pvc.Status.Phase)),
+			datapoint.Gauge,
+			time.Time{}),
+	}
+
+	if val, ok := pvc.Spec.Resources.Requests[v1.ResourceStorage]; ok {
+		dps = append(dps,
+			datapoint.New(
+				meta.KubernetesPersistentVolumeClaimRequestedStorage,
+				dimensions,
+				datapoint.NewIntValue(val.Value()),
+				datapoint.Gauge,
+				time.Time{}))
+	}
+
+	if val, ok := pvc.Status.Capacity[v1.ResourceStorage]; ok {
+		dps = append(dps,
+			datapoint.New(
+				meta.KubernetesPersistentVolumeClaimCapacity,
+				dimensions,
+				datapoint.NewIntValue(val.Value()),
+				datapoint.Gauge,
+				time.Time{}))
+	}
+
+	return dps
+}
+
+func dimensionForPersistentVolumeClaim(pvc *v1.PersistentVolumeClaim) *atypes.Dimension {
+	props, tags := k8sutil.PropsAndTagsFromLabels(pvc.Labels)
+
+	if pvc.Spec.StorageClassName != nil {
+		props["storage_class"] = *pvc.Spec.StorageClassName
+	}
+	if pvc.Spec.VolumeName != "" {
+		props["volume_name"] = pvc.Spec.VolumeName
+	}
+
+	var accessModes []string
+	for _, mode := range pvc.Spec.AccessModes {
+		accessModes = append(accessModes, string(mode))
+	}
+	if len(accessModes) > 0 {
+		sort.Strings(accessModes)
+		props["access_modes"] = strings.Join(accessModes, ",")
+	}
+
+	return &atypes.Dimension{
+		Name:       "kubernetes_uid",
+		Value:      string(pvc.UID),
+		Properties: props,
+		Tags:       tags,
+	}
+}
+
+func pvcPhaseToInt(phase v1.PersistentVolumeClaimPhase) int64 {
+	switch phase {
+	case v1.ClaimPending:
+		return 1
+	case v1.ClaimBound:
+		return 2
+	case v1.ClaimLost:
+		return 3
+	default:
+		return 4
+	}
+}
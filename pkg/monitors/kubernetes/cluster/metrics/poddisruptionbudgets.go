@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/cluster/meta"
+	k8sutil "github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/utils"
+	atypes "github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+)
+
+func datapointsForPodDisruptionBudget(pdb *policyv1beta1.PodDisruptionBudget) []*datapoint.Datapoint {
+	dimensions := map[string]string{
+		"metric_source":        "kubernetes",
+		"kubernetes_namespace": pdb.Namespace,
+		"kubernetes_uid":       string(pdb.UID),
+		"kubernetes_name":      pdb.Name,
+	}
+
+	return []*datapoint.Datapoint{
+		datapoint.New(
+			meta.KubernetesPodDisruptionBudgetCurrentHealthy,
+			dimensions,
+			datapoint.NewIntValue(int64(pdb.Status.CurrentHealthy)),
+			datapoint.Gauge,
+			time.Time{}),
+		datapoint.New(
+			meta.KubernetesPodDisruptionBudgetDesiredHealthy,
+			dimensions,
+			datapoint.NewIntValue(int64(pdb.Status.DesiredHealthy)),
+			datapoint.Gauge,
+			time.Time{}),
+		datapoint.New(
+			meta.KubernetesPodDisruptionBudgetDisruptionsAllowed,
+			dimensions,
+			datapoint.NewIntValue(int64(pdb.Status.PodDisruptionsAllowed)),
+			datapoint.Gauge,
+			time.Time{}),
+		datapoint.New(
+			meta.KubernetesPodDisruptionBudgetExpectedPods,
+			dimensions,
+			datapoint.NewIntValue(int64(pdb.Status.ExpectedPods)),
+			datapoint.Gauge,
+			time.Time{}),
+	}
+}
+
+func dimensionForPodDisruptionBudget(pdb *policyv1beta1.PodDisruptionBudget) *atypes.Dimension {
+	props, tags := k8sutil.PropsAndTagsFromLabels(pdb.Labels)
+
+	if pdb.Spec.MinAvailable != nil {
+		props["min_available"] = pdb.Spec.MinAvailable.String()
+	}
+	if pdb.Spec.MaxUnavailable != nil {
+		props["max_unavailable"] = pdb.Spec.MaxUnavailable.String()
+	}
+
+	return &atypes.Dimension{
+		Name:       "kubernetes_uid",
+		Value:      string(pdb.UID),
+		Properties: props,
+		Tags:       tags,
+	}
+}
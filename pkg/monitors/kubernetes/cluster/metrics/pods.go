@@ -36,6 +36,14 @@ func datapointsForPod(pod *v1.Pod) []*datapoint.Datapoint {
 
 	for _, cs := range pod.Status.ContainerStatuses {
 		if cs.ContainerID == "" {
+			// Containers that have never been created can still be waiting
+			// for a reason that is worth knowing about (e.g.
+			// ImagePullBackOff), they just don't have a container id yet.
+			contDims := utils.MergeStringMaps(dimensions, map[string]string{
+				"container_spec_name": cs.Name,
+				"container_image":     cs.Image,
+			})
+			dps = append(dps, datapointsForContainerStateReasons(cs, contDims)...)
 			continue
 		}
 
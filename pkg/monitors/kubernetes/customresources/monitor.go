@@ -0,0 +1,220 @@
+// Package customresources contains a monitor that generates metrics from the
+// state of arbitrary Kubernetes resources, such as those defined by CRDs,
+// using JSONPath expressions.
+package customresources
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/common/dpmeta"
+	"github.com/signalfx/signalfx-agent/pkg/core/common/kubernetes"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/leadership"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+var logger = logrus.WithFields(logrus.Fields{"monitorType": monitorType})
+
+func init() {
+	monitors.Register(&monitorMetadata, func() interface{} { return &Monitor{} }, &Config{})
+}
+
+// Config for the kubernetes-custom-resources monitor
+type Config struct {
+	config.MonitorConfig
+	// Config for the K8s API client
+	KubernetesAPI *kubernetes.APIConfig `yaml:"kubernetesAPI" default:"{}"`
+	// If `true`, leader election is skipped and metrics are always reported.
+	AlwaysClusterReporter bool `yaml:"alwaysClusterReporter"`
+	// The resources to watch and generate metrics from
+	Resources []ResourceConfig `yaml:"resources" validate:"required"`
+}
+
+// ResourceConfig describes a single type of resource to watch and how to
+// turn each object of that type into datapoints
+type ResourceConfig struct {
+	// The API group of the resource (e.g. `argoproj.io`).  Leave blank for
+	// resources in the core API group.
+	Group string `yaml:"group"`
+	// The API version of the resource (e.g. `v1alpha1`)
+	Version string `yaml:"version" validate:"required"`
+	// The plural name of the resource, as used in the API path (e.g.
+	// `rollouts`)
+	Resource string `yaml:"resource" validate:"required"`
+	// If specified, only objects within the given namespace will be
+	// watched.  If omitted (blank) objects across all namespaces will be
+	// watched.
+	Namespace string `yaml:"namespace"`
+	// A map of dimension name to a JSONPath expression that is evaluated
+	// against each object to get the dimension value (e.g.
+	// `{.metadata.labels.app}`).  Dimensions with an empty value are not
+	// sent.
+	Dimensions map[string]string `yaml:"dimensions"`
+	// The metrics to generate from each object
+	Metrics []MetricConfig `yaml:"metrics" validate:"required"`
+}
+
+// MetricConfig describes how to extract a single metric value from an object
+type MetricConfig struct {
+	// The name of the metric
+	MetricName string `yaml:"metricName" validate:"required"`
+	// A JSONPath expression that is evaluated against each object to get the
+	// metric value (e.g. `{.status.replicas}`)
+	JSONPath string `yaml:"jsonPath" validate:"required"`
+	// A map of the string form of the value extracted by `jsonPath` to the
+	// numeric value to send.  Values that aren't in the map are not sent.
+	ValueMapping map[string]float64 `yaml:"valueMapping"`
+	// If true, the extracted value is parsed as an RFC 3339 timestamp and
+	// sent as seconds since the Unix epoch
+	IsTimestamp bool `yaml:"isTimestamp"`
+	// Whether the value is a cumulative counter (true) or gauge (false).
+	IsCumulative bool `yaml:"isCumulative"`
+}
+
+// GroupVersionResource returns the fully qualified resource that is described
+func (rc *ResourceConfig) GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    rc.Group,
+		Version:  rc.Version,
+		Resource: rc.Resource,
+	}
+}
+
+// Validate the config
+func (c *Config) Validate() error {
+	if err := c.KubernetesAPI.Validate(); err != nil {
+		return err
+	}
+
+	for i := range c.Resources {
+		if _, err := newResourceMetrics(&c.Resources[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Monitor for K8s custom resource state
+type Monitor struct {
+	Output types.Output
+	stop   chan struct{}
+}
+
+// Configure the monitor and kick off syncing
+func (m *Monitor) Configure(conf *Config) error {
+	restConfig, err := kubernetes.CreateRestConfig(conf.KubernetesAPI)
+	if err != nil {
+		return fmt.Errorf("could not create Kubernetes REST config: %s", err)
+	}
+
+	dynClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("could not create Kubernetes dynamic client: %s", err)
+	}
+
+	var resources []*resourceMetrics
+	for i := range conf.Resources {
+		rm, err := newResourceMetrics(&conf.Resources[i])
+		if err != nil {
+			return err
+		}
+		resources = append(resources, rm)
+	}
+
+	var leaderCh <-chan bool
+	var unregister func()
+
+	if !conf.AlwaysClusterReporter {
+		clientset, err := k8s.NewForConfig(restConfig)
+		if err != nil {
+			return fmt.Errorf("could not create Kubernetes API client: %s", err)
+		}
+
+		leaderCh, unregister, err = leadership.RequestLeaderNotification(clientset.CoreV1())
+		if err != nil {
+			return err
+		}
+	}
+
+	m.stop = make(chan struct{})
+
+	var syncStopper chan struct{}
+	startSync := func() {
+		syncStopper = make(chan struct{})
+		for _, rm := range resources {
+			rm.sync(dynClient, syncStopper)
+		}
+	}
+	stopSync := func() {
+		if syncStopper != nil {
+			close(syncStopper)
+			syncStopper = nil
+		}
+		for _, rm := range resources {
+			rm.clear()
+		}
+	}
+
+	shouldReport := conf.AlwaysClusterReporter
+	if shouldReport {
+		startSync()
+	}
+
+	ticker := time.NewTicker(time.Duration(conf.IntervalSeconds) * time.Second)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stop:
+				if unregister != nil {
+					unregister()
+				}
+				stopSync()
+				return
+			case isLeader := <-leaderCh:
+				if isLeader && !shouldReport {
+					logger.Info("This instance is now the leader and will send custom resource metrics")
+					shouldReport = true
+					startSync()
+				} else if !isLeader && shouldReport {
+					logger.Info("No longer leader")
+					shouldReport = false
+					stopSync()
+				}
+			case <-ticker.C:
+				if shouldReport {
+					m.sendDatapoints(resources)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (m *Monitor) sendDatapoints(resources []*resourceMetrics) {
+	now := time.Now()
+	for _, rm := range resources {
+		dps := rm.datapoints()
+		for i := range dps {
+			dps[i].Timestamp = now
+			dps[i].Meta[dpmeta.NotHostSpecificMeta] = true
+		}
+		m.Output.SendDatapoints(dps...)
+	}
+}
+
+// Shutdown stops all syncing
+func (m *Monitor) Shutdown() {
+	if m.stop != nil {
+		close(m.stop)
+	}
+}
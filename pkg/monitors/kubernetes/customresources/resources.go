@@ -0,0 +1,264 @@
+package customresources
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+type compiledMetric struct {
+	conf *MetricConfig
+	path *jsonpath.JSONPath
+}
+
+// resourceMetrics keeps an up to date copy of all of the objects of a single
+// resource type and generates datapoints from them.
+type resourceMetrics struct {
+	sync.Mutex
+	conf *ResourceConfig
+
+	dimensions map[string]*jsonpath.JSONPath
+	metrics    []compiledMetric
+
+	objects map[k8stypes.UID]*unstructured.Unstructured
+}
+
+func newResourceMetrics(conf *ResourceConfig) (*resourceMetrics, error) {
+	rm := &resourceMetrics{
+		conf:       conf,
+		dimensions: make(map[string]*jsonpath.JSONPath, len(conf.Dimensions)),
+		objects:    make(map[k8stypes.UID]*unstructured.Unstructured),
+	}
+
+	for dim, expr := range conf.Dimensions {
+		path, err := parseJSONPath(dim, expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSONPath for dimension %s of resource %s: %v", dim, conf.GroupVersionResource(), err)
+		}
+		rm.dimensions[dim] = path
+	}
+
+	for i := range conf.Metrics {
+		mc := &conf.Metrics[i]
+		path, err := parseJSONPath(mc.MetricName, mc.JSONPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSONPath for metric %s of resource %s: %v", mc.MetricName, conf.GroupVersionResource(), err)
+		}
+		rm.metrics = append(rm.metrics, compiledMetric{conf: mc, path: path})
+	}
+
+	return rm, nil
+}
+
+func parseJSONPath(name, expr string) (*jsonpath.JSONPath, error) {
+	path := jsonpath.New(name).AllowMissingKeys(true)
+	if err := path.Parse(expr); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// sync starts watching the resource until stopper is closed
+func (rm *resourceMetrics) sync(client dynamic.Interface, stopper <-chan struct{}) {
+	resClient := client.Resource(rm.conf.GroupVersionResource()).Namespace(rm.conf.Namespace)
+
+	watchList := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return resClient.List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return resClient.Watch(options)
+		},
+	}
+
+	_, controller := cache.NewInformer(watchList, &unstructured.Unstructured{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: rm.handleAdd,
+		UpdateFunc: func(_, newObj interface{}) {
+			rm.handleAdd(newObj)
+		},
+		DeleteFunc: rm.handleDelete,
+	})
+
+	go controller.Run(stopper)
+}
+
+func (rm *resourceMetrics) handleAdd(obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	rm.Lock()
+	defer rm.Unlock()
+	rm.objects[u.GetUID()] = u
+}
+
+func (rm *resourceMetrics) handleDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	rm.Lock()
+	defer rm.Unlock()
+	delete(rm.objects, u.GetUID())
+}
+
+// clear forgets all objects, which is done when syncing stops so that stale
+// state is not reported if syncing is later restarted.
+func (rm *resourceMetrics) clear() {
+	rm.Lock()
+	defer rm.Unlock()
+	rm.objects = make(map[k8stypes.UID]*unstructured.Unstructured)
+}
+
+// datapoints generates the datapoints for all of the known objects
+func (rm *resourceMetrics) datapoints() []*datapoint.Datapoint {
+	rm.Lock()
+	defer rm.Unlock()
+
+	var dps []*datapoint.Datapoint
+	for _, obj := range rm.objects {
+		dps = append(dps, rm.datapointsForObject(obj)...)
+	}
+	return dps
+}
+
+// datapointsForObject must be called with the lock held since the JSONPath
+// evaluators are not safe for concurrent use.
+func (rm *resourceMetrics) datapointsForObject(obj *unstructured.Unstructured) []*datapoint.Datapoint {
+	content := obj.UnstructuredContent()
+
+	dims := map[string]string{
+		"kubernetes_kind":      obj.GetKind(),
+		"kubernetes_name":      obj.GetName(),
+		"kubernetes_namespace": obj.GetNamespace(),
+		"kubernetes_uid":       string(obj.GetUID()),
+	}
+	for dim, path := range rm.dimensions {
+		if val, ok := firstResult(path, content); ok {
+			dims[dim] = fmt.Sprint(val)
+		}
+	}
+	dims = utils.RemoveEmptyMapValues(dims)
+
+	var dps []*datapoint.Datapoint
+	for _, m := range rm.metrics {
+		raw, ok := firstResult(m.path, content)
+		if !ok {
+			continue
+		}
+
+		value, err := convertValue(m.conf, raw)
+		if err != nil {
+			logger.WithError(err).Debugf("Could not convert value of %s for %s %s/%s",
+				m.conf.MetricName, obj.GetKind(), obj.GetNamespace(), obj.GetName())
+			continue
+		}
+		if value == nil {
+			continue
+		}
+
+		typ := datapoint.Gauge
+		if m.conf.IsCumulative {
+			typ = datapoint.Counter
+		}
+
+		dps = append(dps, datapoint.New(m.conf.MetricName, utils.CloneStringMap(dims), value, typ, time.Time{}))
+	}
+	return dps
+}
+
+func firstResult(path *jsonpath.JSONPath, content map[string]interface{}) (interface{}, bool) {
+	results, err := path.FindResults(content)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, r := range results {
+		for _, v := range r {
+			if !v.IsValid() {
+				continue
+			}
+			if v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					continue
+				}
+			}
+			return v.Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// convertValue converts a value extracted from an object to a datapoint
+// value.  A nil value with no error means that the value should be skipped.
+func convertValue(conf *MetricConfig, raw interface{}) (datapoint.Value, error) {
+	if conf.ValueMapping != nil {
+		if mapped, ok := conf.ValueMapping[fmt.Sprint(raw)]; ok {
+			return datapoint.NewFloatValue(mapped), nil
+		}
+		return nil, nil
+	}
+
+	if conf.IsTimestamp {
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("timestamp value %v is not a string", raw)
+		}
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, err
+		}
+		return datapoint.NewIntValue(ts.Unix()), nil
+	}
+
+	switch v := raw.(type) {
+	case int64:
+		return datapoint.NewIntValue(v), nil
+	case int:
+		return datapoint.NewIntValue(int64(v)), nil
+	case float64:
+		return datapoint.NewFloatValue(v), nil
+	case bool:
+		if v {
+			return datapoint.NewIntValue(1), nil
+		}
+		return datapoint.NewIntValue(0), nil
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return datapoint.NewIntValue(i), nil
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return datapoint.NewFloatValue(f), nil
+		}
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not numeric", v)
+		}
+		if q.MilliValue()%1000 != 0 {
+			return datapoint.NewFloatValue(float64(q.MilliValue()) / 1000), nil
+		}
+		return datapoint.NewIntValue(q.Value()), nil
+	default:
+		return nil, fmt.Errorf("value %v of type %T is not numeric", raw, raw)
+	}
+}
@@ -0,0 +1,97 @@
+package customresources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDatapointsForObject(t *testing.T) {
+	rm, err := newResourceMetrics(&ResourceConfig{
+		Group:    "cert-manager.io",
+		Version:  "v1",
+		Resource: "certificates",
+		Dimensions: map[string]string{
+			"issuer":  "{.spec.issuerRef.name}",
+			"missing": "{.spec.doesNotExist}",
+		},
+		Metrics: []MetricConfig{
+			{
+				MetricName:  "expiration",
+				JSONPath:    "{.status.notAfter}",
+				IsTimestamp: true,
+			},
+			{
+				MetricName:   "ready",
+				JSONPath:     `{.status.conditions[?(@.type=="Ready")].status}`,
+				ValueMapping: map[string]float64{"True": 1, "False": 0},
+			},
+			{
+				MetricName: "revision",
+				JSONPath:   "{.status.revision}",
+			},
+			{
+				MetricName: "storage",
+				JSONPath:   "{.spec.storage}",
+			},
+			{
+				MetricName: "not_set",
+				JSONPath:   "{.status.notSet}",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata": map[string]interface{}{
+			"name":      "web",
+			"namespace": "default",
+			"uid":       "abcd",
+		},
+		"spec": map[string]interface{}{
+			"issuerRef": map[string]interface{}{"name": "letsencrypt"},
+			"storage":   "1Ki",
+		},
+		"status": map[string]interface{}{
+			"notAfter": "2020-01-02T03:04:05Z",
+			"revision": int64(3),
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Issuing", "status": "False"},
+				map[string]interface{}{"type": "Ready", "status": "True"},
+			},
+		},
+	}}
+
+	dps := rm.datapointsForObject(obj)
+
+	values := map[string]string{}
+	for _, dp := range dps {
+		require.Equal(t, map[string]string{
+			"kubernetes_kind":      "Certificate",
+			"kubernetes_name":      "web",
+			"kubernetes_namespace": "default",
+			"kubernetes_uid":       "abcd",
+			"issuer":               "letsencrypt",
+		}, dp.Dimensions)
+		values[dp.Metric] = dp.Value.String()
+	}
+
+	require.Equal(t, map[string]string{
+		"expiration": "1577934245",
+		"ready":      "1",
+		"revision":   "3",
+		"storage":    "1024",
+	}, values)
+}
+
+func TestInvalidJSONPath(t *testing.T) {
+	_, err := newResourceMetrics(&ResourceConfig{
+		Version:  "v1",
+		Resource: "pods",
+		Metrics:  []MetricConfig{{MetricName: "bad", JSONPath: "{.status"}},
+	})
+	require.Error(t, err)
+}
@@ -1,10 +1,13 @@
 package events
 
 import (
-	"strings"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/signalfx/golib/v3/datapoint"
 	"github.com/signalfx/golib/v3/event"
+	"github.com/signalfx/signalfx-agent/pkg/core/common/dpmeta"
 	"github.com/signalfx/signalfx-agent/pkg/core/common/kubernetes"
 	"github.com/signalfx/signalfx-agent/pkg/core/config"
 	"github.com/signalfx/signalfx-agent/pkg/monitors"
@@ -15,6 +18,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
@@ -25,10 +29,23 @@ func init() {
 	monitors.Register(&monitorMetadata, func() interface{} { return &Monitor{} }, &Config{})
 }
 
-// EventInclusionSpec specifies a type of event to send
+// EventInclusionSpec specifies a type of event to send.  All of the fields
+// are optional and an event must match all of the fields that are specified.
 type EventInclusionSpec struct {
-	Reason             string `yaml:"reason"`
+	// The reason of the event (e.g. `Created`).  Can be a glob or a regex
+	// surrounded by `/`.
+	Reason string `yaml:"reason"`
+	// The kind of the object the event is about (e.g. `Pod`).  Can be a glob
+	// or a regex surrounded by `/`.
 	InvolvedObjectKind string `yaml:"involvedObjectKind"`
+	// The namespace of the object the event is about.  Can be a glob or a
+	// regex surrounded by `/`.
+	Namespace string `yaml:"namespace"`
+	// The type of the event, either `Normal` or `Warning`
+	Type string `yaml:"type"`
+	// A Kubernetes label selector (e.g. `app=web,tier!=cache`) that is
+	// matched against the labels of the object the event is about
+	LabelSelector string `yaml:"labelSelector"`
 }
 
 // Config for the K8s event monitor
@@ -45,15 +62,61 @@ type Config struct {
 	// Whether to always send events from this agent instance or to do leader
 	// election to only send from one agent instance.
 	AlwaysClusterReporter bool `yaml:"alwaysClusterReporter"`
+	// Whether to send matching events as SignalFx events.  Set to false if
+	// you only want the `kubernetes.events` metric.
+	SendEvents *bool `yaml:"sendEvents" default:"true"`
+	// Whether to send the `kubernetes.events` counter of matching events
+	SendEventMetrics bool `yaml:"sendEventMetrics"`
+	// The minimum number of seconds between sending repeated occurrences of
+	// the same event, as indicated by the event's `count` field.  If
+	// negative, only the first occurrence of an event is sent.
+	RepeatedEventIntervalSeconds int `yaml:"repeatedEventIntervalSeconds" default:"300"`
+	// If true, the `kubernetes_workload` and `kubernetes_workload_name`
+	// dimensions will be added to events about pods, identifying the
+	// Deployment, StatefulSet, etc. that owns the pod.
+	AddWorkloadDimensions bool `yaml:"addWorkloadDimensions"`
+}
+
+// Validate the config
+func (c *Config) Validate() error {
+	if err := c.KubernetesAPI.Validate(); err != nil {
+		return err
+	}
+	for i := range c.WhitelistedEvents {
+		if _, err := newEventMatcher(&c.WhitelistedEvents[i]); err != nil {
+			return fmt.Errorf("whitelistedEvents item %d: %v", i, err)
+		}
+	}
+	return nil
 }
 
 // Monitor for K8s Cluster Metrics.  Also handles syncing certain properties
 // about pods.
 type Monitor struct {
 	Output        types.Output
+	conf          *Config
 	stopper       chan struct{}
 	sendAllEvents bool
-	whitelistSet  map[EventInclusionSpec]bool
+	matchers      []*eventMatcher
+	resolver      *objectResolver
+
+	lock sync.Mutex
+	// The state of each event object that has been seen, used to detect
+	// repeated occurrences of the same event
+	seen     map[k8stypes.UID]*seenEvent
+	counters map[eventCounterKey]int64
+}
+
+type seenEvent struct {
+	count    int32
+	lastSent time.Time
+}
+
+type eventCounterKey struct {
+	reason    string
+	kind      string
+	namespace string
+	eventType string
 }
 
 // Configure the monitor and kick off event syncing
@@ -63,16 +126,23 @@ func (m *Monitor) Configure(conf *Config) error {
 		return err
 	}
 
+	m.conf = conf
 	m.sendAllEvents = conf.SendAllEvents
-	m.whitelistSet = make(map[EventInclusionSpec]bool, len(conf.WhitelistedEvents))
+	m.matchers = nil
 	for i := range conf.WhitelistedEvents {
-		spec := conf.WhitelistedEvents[i]
-		spec.InvolvedObjectKind = strings.ToLower(spec.InvolvedObjectKind)
-		spec.Reason = strings.ToLower(spec.Reason)
-		m.whitelistSet[spec] = true
+		matcher, err := newEventMatcher(&conf.WhitelistedEvents[i])
+		if err != nil {
+			return err
+		}
+		m.matchers = append(m.matchers, matcher)
 	}
 
+	m.resolver = newObjectResolver(k8sClient)
+	m.seen = make(map[k8stypes.UID]*seenEvent)
+	m.counters = make(map[eventCounterKey]int64)
+
 	m.stopper = make(chan struct{})
+	go m.resolver.expireLoop(m.stopper)
 
 	return m.start(k8sClient, conf.AlwaysClusterReporter)
 }
@@ -85,7 +155,21 @@ func (m *Monitor) start(k8sClient *k8s.Clientset, alwaysReport bool) error {
 		syncEvents(k8sClient, cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				ev := obj.(*v1.Event)
-				m.handleNewEvent(ev)
+				m.handleEvent(ev)
+			},
+			UpdateFunc: func(_, newObj interface{}) {
+				ev := newObj.(*v1.Event)
+				m.handleEvent(ev)
+			},
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				if ev, ok := obj.(*v1.Event); ok {
+					m.lock.Lock()
+					delete(m.seen, ev.UID)
+					m.lock.Unlock()
+				}
 			},
 		}, syncStopper)
 	}
@@ -104,6 +188,13 @@ func (m *Monitor) start(k8sClient *k8s.Clientset, alwaysReport bool) error {
 	}
 
 	go func() {
+		var metricsTicker <-chan time.Time
+		if m.conf.SendEventMetrics {
+			ticker := time.NewTicker(time.Duration(m.conf.IntervalSeconds) * time.Second)
+			defer ticker.Stop()
+			metricsTicker = ticker.C
+		}
+
 		for {
 			select {
 			case isLeader := <-leaderCh:
@@ -115,6 +206,10 @@ func (m *Monitor) start(k8sClient *k8s.Clientset, alwaysReport bool) error {
 					close(syncStopper)
 					syncStopper = nil
 				}
+			case <-metricsTicker:
+				if syncStopper != nil {
+					m.sendEventMetrics()
+				}
 			case <-m.stopper:
 				logger.Info("Stopping k8s event syncing")
 				if unregister != nil {
@@ -130,32 +225,174 @@ func (m *Monitor) start(k8sClient *k8s.Clientset, alwaysReport bool) error {
 	return nil
 }
 
-func (m *Monitor) shouldSendEvent(ev *v1.Event) bool {
+// eventCount returns the total number of occurrences of the event
+func eventCount(ev *v1.Event) int32 {
+	count := ev.Count
+	if ev.Series != nil && ev.Series.Count > count {
+		count = ev.Series.Count
+	}
+	if count < 1 {
+		return 1
+	}
+	return count
+}
+
+// eventTime returns the time of the latest occurrence of the event
+func eventTime(ev *v1.Event) time.Time {
+	if ev.Series != nil && ev.Series.LastObservedTime.After(ev.LastTimestamp.Time) {
+		return ev.Series.LastObservedTime.Time
+	}
+	if ev.LastTimestamp.IsZero() {
+		return ev.EventTime.Time
+	}
+	return ev.LastTimestamp.Time
+}
+
+func (m *Monitor) matchesFilters(ev *v1.Event) bool {
+	if m.sendAllEvents {
+		return true
+	}
+
+	objLabels := func() (map[string]string, bool) {
+		info := m.resolver.info(&ev.InvolvedObject)
+		return info.labels, info.found
+	}
+
+	for _, matcher := range m.matchers {
+		if matcher.matches(ev, objLabels) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordOccurrences updates the seen state of the event and returns how many
+// new occurrences of it there are since it was last seen, and whether this
+// is the first time that the event has been seen.
+func (m *Monitor) recordOccurrences(ev *v1.Event) (int32, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	count := eventCount(ev)
+
+	state, ok := m.seen[ev.UID]
+	if !ok {
+		m.seen[ev.UID] = &seenEvent{count: count}
+		// Earlier occurrences could have been before the agent started or
+		// handled by another agent instance, so only count this one.
+		return 1, true
+	}
+
+	if count <= state.count {
+		// This is a resync or a change to something other than the count
+		return 0, false
+	}
+	newOccurrences := count - state.count
+	state.count = count
+	return newOccurrences, false
+}
+
+// shouldSendRepeat returns whether a repeated occurrence of an event should
+// be sent as a SignalFx event, and marks it as sent if so.
+func (m *Monitor) shouldSendRepeat(ev *v1.Event, now time.Time) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	state, ok := m.seen[ev.UID]
+	if !ok || m.conf.RepeatedEventIntervalSeconds < 0 {
+		return false
+	}
+	if now.Sub(state.lastSent) < time.Duration(m.conf.RepeatedEventIntervalSeconds)*time.Second {
+		return false
+	}
+	state.lastSent = now
+	return true
+}
+
+func (m *Monitor) markSent(ev *v1.Event, now time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if state, ok := m.seen[ev.UID]; ok {
+		state.lastSent = now
+	}
+}
+
+func (m *Monitor) handleEvent(ev *v1.Event) {
+	newOccurrences, isFirst := m.recordOccurrences(ev)
+	if newOccurrences == 0 {
+		return
+	}
+
 	// Always ignore any events older than 1 minute so we don't cause an event
 	// flood upon agent restarts.  This doesn't eliminate the possibility of
 	// duplicated events but should limit them to a fairly narrow time window.
-	if ev.LastTimestamp.Time.Before(time.Now().Add(-1 * time.Minute)) {
-		return false
+	now := time.Now()
+	if eventTime(ev).Before(now.Add(-1 * time.Minute)) {
+		return
 	}
 
-	if m.sendAllEvents {
-		return true
+	if !m.matchesFilters(ev) {
+		return
 	}
 
-	return m.whitelistSet[EventInclusionSpec{
-		Reason:             strings.ToLower(ev.Reason),
-		InvolvedObjectKind: strings.ToLower(ev.InvolvedObject.Kind),
-	}]
+	if m.conf.SendEventMetrics {
+		m.incrementCounter(ev, newOccurrences)
+	}
+
+	if m.conf.SendEvents != nil && !*m.conf.SendEvents {
+		return
+	}
+
+	if isFirst {
+		m.markSent(ev, now)
+	} else if !m.shouldSendRepeat(ev, now) {
+		return
+	}
+
+	var extraDims map[string]string
+	if m.conf.AddWorkloadDimensions && ev.InvolvedObject.Kind == "Pod" {
+		info := m.resolver.info(&ev.InvolvedObject)
+		extraDims = map[string]string{
+			"kubernetes_workload":      info.workload,
+			"kubernetes_workload_name": info.workloadName,
+		}
+	}
+
+	m.Output.SendEvent(k8sEventToSignalFxEvent(ev, extraDims))
 }
 
-func (m *Monitor) handleNewEvent(ev *v1.Event) {
-	if m.shouldSendEvent(ev) {
-		sfxEvent := k8sEventToSignalFxEvent(ev)
-		m.Output.SendEvent(sfxEvent)
+func (m *Monitor) incrementCounter(ev *v1.Event, occurrences int32) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.counters[eventCounterKey{
+		reason:    ev.Reason,
+		kind:      ev.InvolvedObject.Kind,
+		namespace: ev.InvolvedObject.Namespace,
+		eventType: ev.Type,
+	}] += int64(occurrences)
+}
+
+func (m *Monitor) sendEventMetrics() {
+	m.lock.Lock()
+	dps := make([]*datapoint.Datapoint, 0, len(m.counters))
+	for key, count := range m.counters {
+		dp := datapoint.New(kubernetesEvents, utils.RemoveEmptyMapValues(map[string]string{
+			"reason":                key.reason,
+			"kubernetes_kind":       key.kind,
+			"kubernetes_namespace":  key.namespace,
+			"kubernetes_event_type": key.eventType,
+		}), datapoint.NewIntValue(count), datapoint.Counter, time.Time{})
+		dp.Meta[dpmeta.NotHostSpecificMeta] = true
+		dps = append(dps, dp)
 	}
+	m.lock.Unlock()
+
+	m.Output.SendDatapoints(dps...)
 }
 
-func k8sEventToSignalFxEvent(ev *v1.Event) *event.Event {
+func k8sEventToSignalFxEvent(ev *v1.Event, extraDims map[string]string) *event.Event {
 	dims := map[string]string{
 		"kubernetes_kind":      ev.InvolvedObject.Kind,
 		"kubernetes_namespace": ev.InvolvedObject.Namespace,
@@ -171,20 +408,23 @@ func k8sEventToSignalFxEvent(ev *v1.Event) *event.Event {
 		dims["kubernetes_uid"] = string(ev.InvolvedObject.UID)
 	}
 
-	properties := utils.RemoveEmptyMapValues(map[string]string{
+	properties := utils.StringMapToInterfaceMap(utils.RemoveEmptyMapValues(map[string]string{
 		"message":                     ev.Message,
 		"source_component":            ev.Source.Component,
 		"source_host":                 ev.Source.Host,
 		"kubernetes_event_type":       ev.Type,
 		"kubernetes_resource_version": ev.InvolvedObject.ResourceVersion,
-	})
+	}))
+	if count := eventCount(ev); count > 1 {
+		properties["count"] = count
+	}
 
 	return event.NewWithProperties(
 		ev.Reason,
 		event.AGENT,
-		utils.RemoveEmptyMapValues(dims),
-		utils.StringMapToInterfaceMap(properties),
-		ev.LastTimestamp.Time)
+		utils.RemoveEmptyMapValues(utils.MergeStringMaps(dims, extraDims)),
+		properties,
+		eventTime(ev))
 }
 
 func syncEvents(clientset *k8s.Clientset, handlers cache.ResourceEventHandlerFuncs, stopper chan struct{}) {
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+func makeEvent(reason, kind, namespace, eventType string) *v1.Event {
+	return &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{UID: "ev1"},
+		InvolvedObject: v1.ObjectReference{
+			Kind:      kind,
+			Namespace: namespace,
+			Name:      "obj",
+		},
+		Reason:        reason,
+		Type:          eventType,
+		Count:         1,
+		LastTimestamp: metav1.NewTime(time.Now()),
+	}
+}
+
+func TestEventMatcher(t *testing.T) {
+	noLabels := func() (map[string]string, bool) { return nil, false }
+	webLabels := func() (map[string]string, bool) { return map[string]string{"app": "web"}, true }
+
+	for _, tc := range []struct {
+		name    string
+		spec    EventInclusionSpec
+		ev      *v1.Event
+		labels  func() (map[string]string, bool)
+		matches bool
+	}{
+		{"exact case-insensitive", EventInclusionSpec{Reason: "created", InvolvedObjectKind: "pod"},
+			makeEvent("Created", "Pod", "default", "Normal"), noLabels, true},
+		{"exact kind mismatch", EventInclusionSpec{Reason: "Created", InvolvedObjectKind: "Pod"},
+			makeEvent("Created", "ReplicaSet", "default", "Normal"), noLabels, false},
+		{"regex reason", EventInclusionSpec{Reason: "/^(backoff|unhealthy)$/"},
+			makeEvent("BackOff", "Pod", "default", "Warning"), noLabels, true},
+		{"glob namespace", EventInclusionSpec{Namespace: "prod-*", Type: "Warning"},
+			makeEvent("Failed", "Pod", "prod-east", "Warning"), noLabels, true},
+		{"type mismatch", EventInclusionSpec{Namespace: "prod-*", Type: "Warning"},
+			makeEvent("Started", "Pod", "prod-east", "Normal"), noLabels, false},
+		{"label selector", EventInclusionSpec{LabelSelector: "app=web"},
+			makeEvent("Started", "Pod", "default", "Normal"), webLabels, true},
+		{"label selector mismatch", EventInclusionSpec{LabelSelector: "app!=web"},
+			makeEvent("Started", "Pod", "default", "Normal"), webLabels, false},
+		{"label selector unknown object", EventInclusionSpec{LabelSelector: "app=web"},
+			makeEvent("Started", "Pod", "default", "Normal"), noLabels, false},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := newEventMatcher(&tc.spec)
+			require.NoError(t, err)
+			require.Equal(t, tc.matches, m.matches(tc.ev, tc.labels))
+		})
+	}
+
+	_, err := newEventMatcher(&EventInclusionSpec{LabelSelector: "app in ("})
+	require.Error(t, err)
+}
+
+func TestRecordOccurrences(t *testing.T) {
+	m := &Monitor{seen: map[k8stypes.UID]*seenEvent{}}
+	ev := makeEvent("BackOff", "Pod", "default", "Warning")
+	ev.Count = 4
+
+	n, first := m.recordOccurrences(ev)
+	require.Equal(t, int32(1), n)
+	require.True(t, first)
+
+	n, first = m.recordOccurrences(ev)
+	require.Equal(t, int32(0), n)
+	require.False(t, first)
+
+	ev.Count = 7
+	n, first = m.recordOccurrences(ev)
+	require.Equal(t, int32(3), n)
+	require.False(t, first)
+}
+
+func TestObjectResolverCache(t *testing.T) {
+	r := newObjectResolver(nil)
+	pod := &v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web"}
+	r.cache[cacheKey(pod)] = &objectInfo{found: true, workload: "Deployment", expiration: time.Now().Add(time.Minute)}
+
+	// Cached objects don't hit the API server
+	require.Equal(t, "Deployment", r.info(pod).workload)
+
+	// Unsupported kinds are remembered as not found
+	other := &v1.ObjectReference{Kind: "Widget", Namespace: "default", Name: "w"}
+	require.False(t, r.info(other).found)
+	require.Len(t, r.cache, 2)
+
+	r.expire(time.Now().Add(2 * time.Minute))
+	require.Len(t, r.cache, 1)
+	r.expire(time.Now().Add(objectCacheTTL + time.Minute))
+	require.Len(t, r.cache, 0)
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/signalfx/signalfx-agent/pkg/utils/filter"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// eventMatcher is the compiled form of an EventInclusionSpec.  Nil fields
+// match anything.
+type eventMatcher struct {
+	reason        filter.StringFilter
+	kind          filter.StringFilter
+	namespace     filter.StringFilter
+	eventType     string
+	labelSelector labels.Selector
+}
+
+func newEventMatcher(spec *EventInclusionSpec) (*eventMatcher, error) {
+	var m eventMatcher
+	var err error
+
+	if m.reason, err = newCaseInsensitiveFilter(spec.Reason); err != nil {
+		return nil, fmt.Errorf("invalid reason %q: %v", spec.Reason, err)
+	}
+	if m.kind, err = newCaseInsensitiveFilter(spec.InvolvedObjectKind); err != nil {
+		return nil, fmt.Errorf("invalid involvedObjectKind %q: %v", spec.InvolvedObjectKind, err)
+	}
+	if spec.Namespace != "" {
+		if m.namespace, err = filter.NewBasicStringFilter([]string{spec.Namespace}); err != nil {
+			return nil, fmt.Errorf("invalid namespace %q: %v", spec.Namespace, err)
+		}
+	}
+	if spec.LabelSelector != "" {
+		if m.labelSelector, err = labels.Parse(spec.LabelSelector); err != nil {
+			return nil, fmt.Errorf("invalid labelSelector %q: %v", spec.LabelSelector, err)
+		}
+	}
+	m.eventType = strings.ToLower(spec.Type)
+
+	return &m, nil
+}
+
+// newCaseInsensitiveFilter makes a filter that matches lowercased values.
+// Regexes are left as they are so that they can use character classes, but
+// they will only ever be matched against lowercased values.
+func newCaseInsensitiveFilter(value string) (filter.StringFilter, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if !(len(value) > 1 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/")) {
+		value = strings.ToLower(value)
+	}
+	return filter.NewBasicStringFilter([]string{value})
+}
+
+// matches returns whether the event matches all of the configured fields.
+// objLabels is called lazily to get the labels of the involved object, only
+// if a label selector is configured, since it can require an API call.
+func (m *eventMatcher) matches(ev *v1.Event, objLabels func() (map[string]string, bool)) bool {
+	if m.reason != nil && !m.reason.Matches(strings.ToLower(ev.Reason)) {
+		return false
+	}
+	if m.kind != nil && !m.kind.Matches(strings.ToLower(ev.InvolvedObject.Kind)) {
+		return false
+	}
+	if m.namespace != nil && !m.namespace.Matches(ev.InvolvedObject.Namespace) {
+		return false
+	}
+	if m.eventType != "" && m.eventType != strings.ToLower(ev.Type) {
+		return false
+	}
+	if m.labelSelector != nil {
+		l, ok := objLabels()
+		if !ok || !m.labelSelector.Matches(labels.Set(l)) {
+			return false
+		}
+	}
+	return true
+}
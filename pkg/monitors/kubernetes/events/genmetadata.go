@@ -3,6 +3,7 @@
 package events
 
 import (
+	"github.com/signalfx/golib/v3/datapoint"
 	"github.com/signalfx/signalfx-agent/pkg/monitors"
 )
 
@@ -10,9 +11,17 @@ const monitorType = "kubernetes-events"
 
 var groupSet = map[string]bool{}
 
-var metricSet = map[string]monitors.MetricInfo{}
+const (
+	kubernetesEvents = "kubernetes.events"
+)
+
+var metricSet = map[string]monitors.MetricInfo{
+	kubernetesEvents: {Type: datapoint.Counter},
+}
 
-var defaultMetrics = map[string]bool{}
+var defaultMetrics = map[string]bool{
+	kubernetesEvents: true,
+}
 
 var groupMetricsMap = map[string][]string{}
 
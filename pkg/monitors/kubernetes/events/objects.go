@@ -0,0 +1,219 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+)
+
+// How long looked up objects are remembered.  Events tend to come in bursts
+// about the same object so this saves a lot of API calls.
+const objectCacheTTL = 5 * time.Minute
+
+type objectInfo struct {
+	found        bool
+	labels       map[string]string
+	workload     string
+	workloadName string
+	expiration   time.Time
+}
+
+// objectResolver looks up the objects that events are about from the API
+// server to get their labels and, for pods, the workload that owns them.
+// The lock is only held to access the cache, so that a slow API server
+// doesn't block events about objects that are already cached, and
+// concurrent lookups of the same object are merged.
+type objectResolver struct {
+	sync.Mutex
+	client  *k8s.Clientset
+	cache   map[v1.ObjectReference]*objectInfo
+	lookups singleflight.Group
+}
+
+func newObjectResolver(client *k8s.Clientset) *objectResolver {
+	return &objectResolver{
+		client: client,
+		cache:  make(map[v1.ObjectReference]*objectInfo),
+	}
+}
+
+func cacheKey(ref *v1.ObjectReference) v1.ObjectReference {
+	return v1.ObjectReference{
+		Kind:      ref.Kind,
+		Namespace: ref.Namespace,
+		Name:      ref.Name,
+		UID:       ref.UID,
+	}
+}
+
+func (r *objectResolver) cached(key v1.ObjectReference, now time.Time) *objectInfo {
+	r.Lock()
+	defer r.Unlock()
+
+	if info, ok := r.cache[key]; ok && now.Before(info.expiration) {
+		return info
+	}
+	return nil
+}
+
+// info returns what is known about the involved object of an event
+func (r *objectResolver) info(ref *v1.ObjectReference) *objectInfo {
+	key := cacheKey(ref)
+	if info := r.cached(key, time.Now()); info != nil {
+		return info
+	}
+
+	lookupKey := fmt.Sprintf("%s/%s/%s/%s", key.Kind, key.Namespace, key.Name, key.UID)
+	info, _, _ := r.lookups.Do(lookupKey, func() (interface{}, error) {
+		info := r.lookup(&key)
+
+		r.Lock()
+		r.cache[key] = info
+		r.Unlock()
+
+		return info, nil
+	})
+	return info.(*objectInfo)
+}
+
+// lookup fetches the object from the API server without holding the lock
+func (r *objectResolver) lookup(ref *v1.ObjectReference) *objectInfo {
+	info := &objectInfo{expiration: time.Now().Add(objectCacheTTL)}
+
+	meta, err := r.objectMeta(ref.Kind, ref.Namespace, ref.Name)
+	if err != nil {
+		logger.WithError(err).Debugf("Could not look up %s %s/%s", ref.Kind, ref.Namespace, ref.Name)
+	}
+	if meta != nil && (ref.UID == "" || meta.UID == ref.UID) {
+		info.found = true
+		info.labels = meta.Labels
+		if ref.Kind == "Pod" {
+			info.workload, info.workloadName = r.workloadForOwners(meta.Namespace, meta.OwnerReferences)
+		}
+	}
+	return info
+}
+
+// expire removes the expired entries from the cache
+func (r *objectResolver) expire(now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+
+	for k, info := range r.cache {
+		if now.After(info.expiration) {
+			delete(r.cache, k)
+		}
+	}
+}
+
+// expireLoop periodically cleans out the cache until stop is closed, so
+// that lookups don't have to
+func (r *objectResolver) expireLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(objectCacheTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			r.expire(now)
+		}
+	}
+}
+
+// objectMeta fetches the metadata of the given object.  It returns nil with
+// no error for kinds that aren't supported.
+func (r *objectResolver) objectMeta(kind, namespace, name string) (*metav1.ObjectMeta, error) {
+	opts := metav1.GetOptions{}
+
+	switch kind {
+	case "Pod":
+		obj, err := r.client.CoreV1().Pods(namespace).Get(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &obj.ObjectMeta, nil
+	case "Node":
+		obj, err := r.client.CoreV1().Nodes().Get(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &obj.ObjectMeta, nil
+	case "Service":
+		obj, err := r.client.CoreV1().Services(namespace).Get(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &obj.ObjectMeta, nil
+	case "Deployment":
+		obj, err := r.client.AppsV1().Deployments(namespace).Get(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &obj.ObjectMeta, nil
+	case "ReplicaSet":
+		obj, err := r.client.AppsV1().ReplicaSets(namespace).Get(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &obj.ObjectMeta, nil
+	case "StatefulSet":
+		obj, err := r.client.AppsV1().StatefulSets(namespace).Get(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &obj.ObjectMeta, nil
+	case "DaemonSet":
+		obj, err := r.client.AppsV1().DaemonSets(namespace).Get(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &obj.ObjectMeta, nil
+	case "Job":
+		obj, err := r.client.BatchV1().Jobs(namespace).Get(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &obj.ObjectMeta, nil
+	case "CronJob":
+		obj, err := r.client.BatchV1beta1().CronJobs(namespace).Get(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &obj.ObjectMeta, nil
+	default:
+		return nil, nil
+	}
+}
+
+// workloadForOwners returns the kind and name of the top-level workload from
+// a pod's owner references.  ReplicaSets and Jobs are followed up to their
+// Deployment or CronJob, if they have one.
+func (r *objectResolver) workloadForOwners(namespace string, owners []metav1.OwnerReference) (string, string) {
+	for _, or := range owners {
+		if or.Controller == nil || !*or.Controller {
+			continue
+		}
+
+		if or.Kind == "ReplicaSet" || or.Kind == "Job" {
+			meta, err := r.objectMeta(or.Kind, namespace, or.Name)
+			if err != nil {
+				logger.WithError(err).Debugf("Could not look up %s %s/%s", or.Kind, namespace, or.Name)
+			} else if meta != nil {
+				for _, parent := range meta.OwnerReferences {
+					if parent.Controller != nil && *parent.Controller {
+						return parent.Kind, parent.Name
+					}
+				}
+			}
+		}
+		return or.Kind, or.Name
+	}
+	return "", ""
+}
@@ -5,6 +5,7 @@ import (
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/apiserver"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/cluster"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/controllermanager"
+	_ "github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/customresources"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/events"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/proxy"
 	_ "github.com/signalfx/signalfx-agent/pkg/monitors/kubernetes/scheduler"
@@ -11,6 +11,7 @@ import (
 	"github.com/signalfx/golib/v3/trace"
 	"github.com/signalfx/signalfx-agent/pkg/core/config"
 	"github.com/signalfx/signalfx-agent/pkg/core/meta"
+	"github.com/signalfx/signalfx-agent/pkg/core/recording"
 	"github.com/signalfx/signalfx-agent/pkg/core/services"
 	"github.com/signalfx/signalfx-agent/pkg/monitors/collectd"
 	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
@@ -18,6 +19,8 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+var logger = log.WithFields(log.Fields{"component": "monitorManager"})
+
 // MonitorManager coordinates the startup and shutdown of monitors based on the
 // configuration provided by the user.  Monitors that have discovery rules can
 // be injected with multiple services.  If a monitor does not have a discovery
@@ -25,9 +28,14 @@ import (
 // Configure is called).
 type MonitorManager struct {
 	monitorConfigs map[uint64]config.MonitorCustomConfig
+	// Configs added through the control API, which are kept until the agent
+	// restarts
+	ephemeralConfigs map[uint64]config.MonitorCustomConfig
 	// Keep track of which services go with which monitor
 	activeMonitors []*ActiveMonitor
 	badConfigs     map[uint64]*config.MonitorConfig
+	// Monitors for discovered endpoints that could not be configured
+	failedMonitors map[string]*failedMonitor
 	lock           sync.Mutex
 	// Map of service endpoints that have been discovered
 	discoveredEndpoints map[services.ID]services.Endpoint
@@ -42,6 +50,7 @@ type MonitorManager struct {
 	agentMeta              *meta.AgentMeta
 	intervalSeconds        int
 	enableBuiltInFiltering bool
+	inputRecording         *recording.Bundle
 
 	idGenerator func() string
 }
@@ -50,8 +59,10 @@ type MonitorManager struct {
 func NewMonitorManager(agentMeta *meta.AgentMeta) *MonitorManager {
 	return &MonitorManager{
 		monitorConfigs:      make(map[uint64]config.MonitorCustomConfig),
+		ephemeralConfigs:    make(map[uint64]config.MonitorCustomConfig),
 		activeMonitors:      make([]*ActiveMonitor, 0),
 		badConfigs:          make(map[uint64]*config.MonitorConfig),
+		failedMonitors:      make(map[string]*failedMonitor),
 		discoveredEndpoints: make(map[services.ID]services.Endpoint),
 		idGenerator:         utils.NewIDGenerator(),
 		agentMeta:           agentMeta,
@@ -80,7 +91,7 @@ func (mm *MonitorManager) Configure(confs []config.MonitorConfig, collectdConf *
 		// instances of having to know about collectd config, which makes it easier
 		// to create monitor config from disparate sources such as from observers.
 		if err := collectd.ConfigureMainCollectd(collectdConf); err != nil {
-			log.WithFields(log.Fields{
+			logger.WithFields(log.Fields{
 				"error":          err,
 				"collectdConfig": spew.Sdump(collectdConf),
 			}).Error("Could not configure collectd")
@@ -99,14 +110,21 @@ func (mm *MonitorManager) Configure(confs []config.MonitorConfig, collectdConf *
 		hash := conf.Hash()
 
 		if requireSoloTrue && !conf.Solo {
-			log.Infof("Solo mode is active, skipping monitor of type %s", conf.Type)
+			logger.Infof("Solo mode is active, skipping monitor of type %s", conf.Type)
 			continue
 		}
 
+		if _, ok := mm.ephemeralConfigs[hash]; ok {
+			logger.Infof("Replacing ephemeral monitor of type %s with the identical one in the config", conf.Type)
+			mm.deleteMonitorsByConfigHash(hash)
+			delete(mm.ephemeralConfigs, hash)
+		}
+
 		monConfig, err := mm.handleNewConfig(&conf)
 		if err != nil {
-			log.WithFields(log.Fields{
+			logger.WithFields(log.Fields{
 				"monitorType": conf.Type,
+				"configFile":  conf.ConfigFile,
 				"error":       err,
 			}).Error("Could not process configuration for monitor")
 			conf.ValidationError = err.Error()
@@ -140,7 +158,7 @@ func diffNewConfig(confs []config.MonitorConfig, oldHashes map[uint64]bool) ([]c
 		}
 
 		if newConfigHashes[hash] {
-			log.WithFields(log.Fields{
+			logger.WithFields(log.Fields{
 				"monitorType": confs[i].Type,
 				"config":      confs[i],
 			}).Error("Monitor config is duplicated")
@@ -193,32 +211,32 @@ func (mm *MonitorManager) makeMonitorsForMatchingEndpoints(conf config.MonitorCu
 			continue
 		}
 
-		log.WithFields(log.Fields{
+		logger.WithFields(log.Fields{
 			"monitorType":   conf.MonitorConfigCore().Type,
 			"discoveryRule": conf.MonitorConfigCore().DiscoveryRule,
 			"endpoint":      endpoint,
 		}).Debug("Trying to find config that matches discovered endpoint")
 
 		if mm.isEndpointIDMonitoredByConfig(conf, id) {
-			log.Debug("The endpoint is already monitored")
+			logger.Debug("The endpoint is already monitored")
 			continue
 		}
 
 		if matched, err := mm.monitorEndpointIfRuleMatches(conf, endpoint); matched {
 			if err != nil {
-				log.WithFields(log.Fields{
+				logger.WithFields(log.Fields{
 					"error":       err,
 					"endpointID":  endpoint.Core().ID,
 					"monitorType": conf.MonitorConfigCore().Type,
 				}).Error("Error monitoring endpoint that matched rule")
 			} else {
-				log.WithFields(log.Fields{
+				logger.WithFields(log.Fields{
 					"endpointID":  endpoint.Core().ID,
 					"monitorType": conf.MonitorConfigCore().Type,
 				}).Info("Now monitoring discovered endpoint")
 			}
 		} else {
-			log.Debug("The monitor did not match")
+			logger.Debug("The monitor did not match")
 		}
 	}
 }
@@ -261,7 +279,7 @@ func (mm *MonitorManager) EndpointAdded(endpoint services.Endpoint) {
 	// rules will be ignored.
 	if endpoint.Core().IsSelfConfigured() {
 		if err := mm.monitorSelfConfiguredEndpoint(endpoint); err != nil {
-			log.WithFields(log.Fields{
+			logger.WithFields(log.Fields{
 				"error":       err,
 				"monitorType": endpoint.Core().MonitorType,
 				"endpoint":    endpoint,
@@ -296,32 +314,47 @@ func (mm *MonitorManager) monitorSelfConfiguredEndpoint(endpoint services.Endpoi
 func (mm *MonitorManager) findConfigForMonitorAndRun(endpoint services.Endpoint) {
 	monitoring := false
 
-	for _, config := range mm.monitorConfigs {
-		matched, err := mm.monitorEndpointIfRuleMatches(config, endpoint)
-		monitoring = matched || monitoring
-		if err != nil {
-			log.WithFields(log.Fields{
-				"error":    err,
-				"config":   config,
-				"endpoint": endpoint,
-			}).Error("Could not monitor new endpoint")
+	for _, configs := range []map[uint64]config.MonitorCustomConfig{mm.monitorConfigs, mm.ephemeralConfigs} {
+		for _, config := range configs {
+			matched, err := mm.monitorEndpointIfRuleMatches(config, endpoint)
+			monitoring = matched || monitoring
+			if err != nil {
+				logger.WithFields(log.Fields{
+					"error":    err,
+					"config":   config,
+					"endpoint": endpoint,
+				}).Error("Could not monitor new endpoint")
+			}
 		}
 	}
 
 	if !monitoring {
-		log.WithFields(log.Fields{
+		logger.WithFields(log.Fields{
 			"endpoint": endpoint,
 		}).Debug("Endpoint added that doesn't match any discovery rules")
 	}
 }
 
 // endpoint may be nil for static monitors
-func (mm *MonitorManager) createAndConfigureNewMonitor(config config.MonitorCustomConfig, endpoint services.Endpoint) error {
+func (mm *MonitorManager) createAndConfigureNewMonitor(config config.MonitorCustomConfig, endpoint services.Endpoint) (err error) {
+	// Static monitors that fail to configure are tracked as bad configs
+	// instead
+	if endpoint != nil {
+		defer func() {
+			configHash := config.MonitorConfigCore().Hash()
+			if err != nil {
+				mm.recordConfigureError(config, configHash, endpoint, err)
+			} else {
+				delete(mm.failedMonitors, failedMonitorKey(configHash, endpoint))
+			}
+		}()
+	}
+
 	id := types.MonitorID(mm.idGenerator())
 	coreConfig := config.MonitorConfigCore()
 	monitorType := coreConfig.Type
 
-	log.WithFields(log.Fields{
+	logger.WithFields(log.Fields{
 		"monitorType":   monitorType,
 		"discoveryRule": coreConfig.DiscoveryRule,
 		"monitorID":     id,
@@ -352,17 +385,18 @@ func (mm *MonitorManager) createAndConfigureNewMonitor(config config.MonitorCust
 		return err
 	}
 
+	renderedConf, err := renderConfig(config, endpoint)
+	if err != nil {
+		return err
+	}
+
 	am := &ActiveMonitor{
 		id:         id,
 		configHash: configHash,
 		instance:   instance,
 		endpoint:   endpoint,
 		agentMeta:  mm.agentMeta,
-	}
-
-	renderedConf, err := renderConfig(config, endpoint)
-	if err != nil {
-		return err
+		health:     newMonitorHealth(renderedConf.MonitorConfigCore().IntervalSeconds),
 	}
 
 	output := &monitorOutput{
@@ -379,10 +413,20 @@ func (mm *MonitorManager) createAndConfigureNewMonitor(config config.MonitorCust
 		extraDims:                 map[string]string{},
 		dimensionTransformations:  renderedConf.MonitorConfigCore().DimensionTransformations,
 		monitorFiltering:          monFiltering,
+		health:                    am.health,
 	}
 
 	am.output = output
 
+	if hr, ok := instance.(HealthReportable); ok {
+		hr.SetHealthReporter(am.health)
+	}
+
+	am.recordingKey = inputRecordingKey(config, endpoint)
+	if mm.inputRecording != nil {
+		AttachInputRecorder(instance, renderedConf, mm.inputRecording.Monitor(am.recordingKey))
+	}
+
 	if err := am.configureMonitor(renderedConf); err != nil {
 		return err
 	}
@@ -402,10 +446,12 @@ func (mm *MonitorManager) monitorsForEndpointID(id services.ID) (out []*ActiveMo
 
 func (mm *MonitorManager) monitorConfigsForType(monitorType string) []*config.MonitorCustomConfig {
 	var out []*config.MonitorCustomConfig
-	for i := range mm.monitorConfigs {
-		conf := mm.monitorConfigs[i]
-		if conf.MonitorConfigCore().Type == monitorType {
-			out = append(out, &conf)
+	for _, configs := range []map[uint64]config.MonitorCustomConfig{mm.monitorConfigs, mm.ephemeralConfigs} {
+		for i := range configs {
+			conf := configs[i]
+			if conf.MonitorConfigCore().Type == monitorType {
+				out = append(out, &conf)
+			}
 		}
 	}
 	return out
@@ -424,8 +470,11 @@ func (mm *MonitorManager) EndpointRemoved(endpoint services.Endpoint) {
 		am.doomed = true
 	}
 	mm.deleteDoomedMonitors()
+	mm.clearConfigureErrors(func(fm *failedMonitor) bool {
+		return fm.status.EndpointID == endpoint.Core().ID
+	})
 
-	log.WithFields(log.Fields{
+	logger.WithFields(log.Fields{
 		"endpoint": endpoint,
 	}).Debug("No longer considering endpoint")
 }
@@ -438,13 +487,16 @@ func (mm *MonitorManager) isEndpointMonitored(endpoint services.Endpoint) bool {
 func (mm *MonitorManager) deleteMonitorsByConfigHash(hash uint64) {
 	for i := range mm.activeMonitors {
 		if mm.activeMonitors[i].configHash == hash {
-			log.WithFields(log.Fields{
+			logger.WithFields(log.Fields{
 				"config": mm.activeMonitors[i].config,
 			}).Info("Shutting down monitor due to config hash change")
 			mm.activeMonitors[i].doomed = true
 		}
 	}
 	mm.deleteDoomedMonitors()
+	mm.clearConfigureErrors(func(fm *failedMonitor) bool {
+		return fm.configHash == hash
+	})
 }
 
 func (mm *MonitorManager) deleteDoomedMonitors() {
@@ -453,7 +505,7 @@ func (mm *MonitorManager) deleteDoomedMonitors() {
 	for i := range mm.activeMonitors {
 		am := mm.activeMonitors[i]
 		if am.doomed {
-			log.WithFields(log.Fields{
+			logger.WithFields(log.Fields{
 				"monitorID":     am.id,
 				"monitorType":   am.config.MonitorConfigCore().Type,
 				"discoveryRule": am.config.MonitorConfigCore().DiscoveryRule,
@@ -479,5 +531,7 @@ func (mm *MonitorManager) Shutdown() {
 	mm.deleteDoomedMonitors()
 
 	mm.activeMonitors = nil
+	mm.ephemeralConfigs = make(map[uint64]config.MonitorCustomConfig)
 	mm.discoveredEndpoints = nil
+	mm.failedMonitors = make(map[string]*failedMonitor)
 }
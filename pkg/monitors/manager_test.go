@@ -1,7 +1,9 @@
 package monitors
 
 import (
+	"strconv"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -20,7 +22,7 @@ var serviceID = 0
 func newService(imageName string, publicPort int) services.Endpoint {
 	serviceID++
 
-	endpoint := services.NewEndpointCore(string(serviceID), "", "test", nil)
+	endpoint := services.NewEndpointCore(strconv.Itoa(serviceID), "", "test", nil)
 	endpoint.Host = "example.com"
 	endpoint.Port = uint16(publicPort)
 
@@ -490,6 +492,139 @@ var _ = Describe("Monitor Manager", func() {
 		mons = findMonitorsByType(getMonitors(), "dynamic1")
 		Expect(len(mons)).To(Equal(0))
 	})
+
+	It("Disables and enables monitors by id", func() {
+		manager.Configure([]config.MonitorConfig{
+			{
+				Type: "static1",
+			},
+		}, &config.CollectdConfig{}, 10, true)
+
+		var id types.MonitorID
+		for monID := range getMonitors() {
+			id = monID
+		}
+
+		Expect(manager.DisableMonitor(id, 0)).To(Succeed())
+		Expect(len(getMonitors())).To(Equal(0))
+		statuses := manager.MonitorStatuses()
+		Expect(statuses[0].Disabled).To(BeTrue())
+		Expect(statuses[0].Stale).To(BeFalse())
+
+		// Disabling again doesn't shut it down twice
+		Expect(manager.DisableMonitor(id, 0)).To(Succeed())
+
+		Expect(manager.EnableMonitor(id)).To(Succeed())
+		Expect(len(getMonitors())).To(Equal(1))
+		Expect(getMonitors()[id].Type()).To(Equal("static1"))
+		Expect(manager.MonitorStatuses()[0].Disabled).To(BeFalse())
+
+		Expect(manager.CollectNow(id)).To(Equal(ErrCollectNowNotSupported))
+		Expect(len(getMonitors())).To(Equal(1))
+
+		Expect(manager.EnableMonitor("nope")).To(Equal(ErrMonitorNotFound))
+		// Only ephemeral monitors can be removed
+		Expect(manager.RemoveEphemeralMonitor(id)).ToNot(Succeed())
+	})
+
+	It("Collects on demand from monitors that support it without restarting them", func() {
+		manager.Configure([]config.MonitorConfig{
+			{
+				Type: "static2",
+			},
+		}, &config.CollectdConfig{}, 10, true)
+
+		var id types.MonitorID
+		var mon MockMonitor
+		for monID, m := range getMonitors() {
+			id = monID
+			mon = m
+		}
+
+		Expect(manager.CollectNow(id)).To(Succeed())
+		Expect(manager.CollectNow(id)).To(Succeed())
+		Expect(getMonitors()[id]).To(BeIdenticalTo(mon))
+		Expect(mon.(*_MockMonitor).collections).To(Equal(2))
+
+		Expect(manager.DisableMonitor(id, 0)).To(Succeed())
+		Expect(manager.CollectNow(id)).ToNot(Succeed())
+		Expect(manager.CollectNow("nope")).To(Equal(ErrMonitorNotFound))
+	})
+
+	It("Returns the error of monitors that fail to collect on demand", func() {
+		manager.Configure([]config.MonitorConfig{
+			{
+				Type:        "static2",
+				OtherConfig: map[string]interface{}{"myVar": "fail"},
+			},
+		}, &config.CollectdConfig{}, 10, true)
+
+		var id types.MonitorID
+		for monID := range getMonitors() {
+			id = monID
+		}
+
+		err := manager.CollectNow(id)
+		Expect(err).To(BeAssignableToTypeOf(&CollectionError{}))
+		Expect(err.Error()).To(ContainSubstring("could not collect"))
+	})
+
+	It("Enables monitors again after the disable duration", func() {
+		manager.Configure([]config.MonitorConfig{
+			{
+				Type: "static1",
+			},
+		}, &config.CollectdConfig{}, 10, true)
+
+		var id types.MonitorID
+		for monID := range getMonitors() {
+			id = monID
+		}
+
+		Expect(manager.DisableMonitor(id, 50*time.Millisecond)).To(Succeed())
+		Expect(manager.MonitorStatuses()[0].DisabledUntil).ToNot(BeNil())
+		Eventually(func() bool {
+			return manager.MonitorStatuses()[0].Disabled
+		}).Should(BeFalse())
+	})
+
+	It("Keeps ephemeral monitors when the config changes", func() {
+		manager.Configure([]config.MonitorConfig{
+			{
+				Type: "static1",
+			},
+		}, &config.CollectdConfig{}, 10, true)
+
+		ids, err := manager.AddEphemeralMonitor(config.MonitorConfig{
+			Type: "static2",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(ids)).To(Equal(1))
+		Expect(getMonitors()[ids[0]].Type()).To(Equal("static2"))
+
+		_, err = manager.AddEphemeralMonitor(config.MonitorConfig{
+			Type: "static2",
+		})
+		Expect(err).To(HaveOccurred())
+
+		manager.Configure([]config.MonitorConfig{
+			{
+				Type: "static1",
+				OtherConfig: map[string]interface{}{
+					"myVar": "changed",
+				},
+			},
+		}, &config.CollectdConfig{}, 10, true)
+
+		Expect(len(findMonitorsByType(getMonitors(), "static2"))).To(Equal(1))
+		for _, status := range manager.MonitorStatuses() {
+			Expect(status.Ephemeral).To(Equal(status.MonitorType == "static2"))
+		}
+
+		Expect(manager.RemoveEphemeralMonitor(ids[0])).To(Succeed())
+		Expect(len(findMonitorsByType(getMonitors(), "static2"))).To(Equal(0))
+		Expect(len(findMonitorsByType(getMonitors(), "static1"))).To(Equal(1))
+	})
 })
 
 func TestMonitors(t *testing.T) {
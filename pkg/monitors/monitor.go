@@ -110,7 +110,7 @@ func newUninitializedMonitor(_type string) interface{} {
 		return factory()
 	}
 
-	log.WithFields(log.Fields{
+	logger.WithFields(log.Fields{
 		"monitorType": _type,
 	}).Error("Monitor type not supported")
 	return nil
@@ -122,7 +122,7 @@ func newMonitor(_type string) interface{} {
 	mon := newUninitializedMonitor(_type)
 	if initMon, ok := mon.(Initializable); ok {
 		if err := initMon.Init(); err != nil {
-			log.WithFields(log.Fields{
+			logger.WithFields(log.Fields{
 				"error":       err,
 				"monitorType": _type,
 			}).Error("Could not initialize monitor")
@@ -0,0 +1,186 @@
+// Code generated by monitor-code-gen. DO NOT EDIT.
+
+package mysql
+
+import (
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+)
+
+const monitorType = "mysql"
+
+const (
+	groupInnodb      = "innodb"
+	groupQueries     = "queries"
+	groupReplication = "replication"
+	groupSchemas     = "schemas"
+)
+
+var groupSet = map[string]bool{
+	groupInnodb:      true,
+	groupQueries:     true,
+	groupReplication: true,
+	groupSchemas:     true,
+}
+
+const (
+	mysqlAbortedClients                 = "mysql_aborted_clients"
+	mysqlAbortedConnects                = "mysql_aborted_connects"
+	mysqlBytesReceived                  = "mysql_bytes_received"
+	mysqlBytesSent                      = "mysql_bytes_sent"
+	mysqlCommands                       = "mysql_commands"
+	mysqlConnections                    = "mysql_connections"
+	mysqlCreatedTmpDiskTables           = "mysql_created_tmp_disk_tables"
+	mysqlCreatedTmpTables               = "mysql_created_tmp_tables"
+	mysqlInnodbBufferPoolBytesData      = "mysql_innodb_buffer_pool_bytes_data"
+	mysqlInnodbBufferPoolPagesData      = "mysql_innodb_buffer_pool_pages_data"
+	mysqlInnodbBufferPoolPagesDirty     = "mysql_innodb_buffer_pool_pages_dirty"
+	mysqlInnodbBufferPoolPagesFree      = "mysql_innodb_buffer_pool_pages_free"
+	mysqlInnodbBufferPoolPagesTotal     = "mysql_innodb_buffer_pool_pages_total"
+	mysqlInnodbBufferPoolReadRequests   = "mysql_innodb_buffer_pool_read_requests"
+	mysqlInnodbBufferPoolReads          = "mysql_innodb_buffer_pool_reads"
+	mysqlInnodbDataRead                 = "mysql_innodb_data_read"
+	mysqlInnodbDataWritten              = "mysql_innodb_data_written"
+	mysqlInnodbLogWaits                 = "mysql_innodb_log_waits"
+	mysqlInnodbRowLockCurrentWaits      = "mysql_innodb_row_lock_current_waits"
+	mysqlInnodbRowLockTime              = "mysql_innodb_row_lock_time"
+	mysqlInnodbRowLockWaits             = "mysql_innodb_row_lock_waits"
+	mysqlInnodbRowsDeleted              = "mysql_innodb_rows_deleted"
+	mysqlInnodbRowsInserted             = "mysql_innodb_rows_inserted"
+	mysqlInnodbRowsRead                 = "mysql_innodb_rows_read"
+	mysqlInnodbRowsUpdated              = "mysql_innodb_rows_updated"
+	mysqlOpenTables                     = "mysql_open_tables"
+	mysqlOpenedTables                   = "mysql_opened_tables"
+	mysqlQueries                        = "mysql_queries"
+	mysqlQueriesAverageTime             = "mysql_queries_average_time"
+	mysqlQueriesCalls                   = "mysql_queries_calls"
+	mysqlQueriesRowsExamined            = "mysql_queries_rows_examined"
+	mysqlQueriesTotalTime               = "mysql_queries_total_time"
+	mysqlReplicationGtidLag             = "mysql_replication_gtid_lag"
+	mysqlReplicationIoRunning           = "mysql_replication_io_running"
+	mysqlReplicationSecondsBehindMaster = "mysql_replication_seconds_behind_master"
+	mysqlReplicationSQLRunning          = "mysql_replication_sql_running"
+	mysqlSchemaSize                     = "mysql_schema_size"
+	mysqlSelectFullJoin                 = "mysql_select_full_join"
+	mysqlSlowQueries                    = "mysql_slow_queries"
+	mysqlTableLocksImmediate            = "mysql_table_locks_immediate"
+	mysqlTableLocksWaited               = "mysql_table_locks_waited"
+	mysqlTableRows                      = "mysql_table_rows"
+	mysqlTableSize                      = "mysql_table_size"
+	mysqlThreadsConnected               = "mysql_threads_connected"
+	mysqlThreadsRunning                 = "mysql_threads_running"
+	mysqlUptime                         = "mysql_uptime"
+)
+
+var metricSet = map[string]monitors.MetricInfo{
+	mysqlAbortedClients:                 {Type: datapoint.Counter},
+	mysqlAbortedConnects:                {Type: datapoint.Counter},
+	mysqlBytesReceived:                  {Type: datapoint.Counter},
+	mysqlBytesSent:                      {Type: datapoint.Counter},
+	mysqlCommands:                       {Type: datapoint.Counter},
+	mysqlConnections:                    {Type: datapoint.Counter},
+	mysqlCreatedTmpDiskTables:           {Type: datapoint.Counter},
+	mysqlCreatedTmpTables:               {Type: datapoint.Counter},
+	mysqlInnodbBufferPoolBytesData:      {Type: datapoint.Gauge, Group: groupInnodb},
+	mysqlInnodbBufferPoolPagesData:      {Type: datapoint.Gauge, Group: groupInnodb},
+	mysqlInnodbBufferPoolPagesDirty:     {Type: datapoint.Gauge, Group: groupInnodb},
+	mysqlInnodbBufferPoolPagesFree:      {Type: datapoint.Gauge, Group: groupInnodb},
+	mysqlInnodbBufferPoolPagesTotal:     {Type: datapoint.Gauge, Group: groupInnodb},
+	mysqlInnodbBufferPoolReadRequests:   {Type: datapoint.Counter, Group: groupInnodb},
+	mysqlInnodbBufferPoolReads:          {Type: datapoint.Counter, Group: groupInnodb},
+	mysqlInnodbDataRead:                 {Type: datapoint.Counter, Group: groupInnodb},
+	mysqlInnodbDataWritten:              {Type: datapoint.Counter, Group: groupInnodb},
+	mysqlInnodbLogWaits:                 {Type: datapoint.Counter, Group: groupInnodb},
+	mysqlInnodbRowLockCurrentWaits:      {Type: datapoint.Gauge, Group: groupInnodb},
+	mysqlInnodbRowLockTime:              {Type: datapoint.Counter, Group: groupInnodb},
+	mysqlInnodbRowLockWaits:             {Type: datapoint.Counter, Group: groupInnodb},
+	mysqlInnodbRowsDeleted:              {Type: datapoint.Counter, Group: groupInnodb},
+	mysqlInnodbRowsInserted:             {Type: datapoint.Counter, Group: groupInnodb},
+	mysqlInnodbRowsRead:                 {Type: datapoint.Counter, Group: groupInnodb},
+	mysqlInnodbRowsUpdated:              {Type: datapoint.Counter, Group: groupInnodb},
+	mysqlOpenTables:                     {Type: datapoint.Gauge},
+	mysqlOpenedTables:                   {Type: datapoint.Counter},
+	mysqlQueries:                        {Type: datapoint.Counter},
+	mysqlQueriesAverageTime:             {Type: datapoint.Gauge, Group: groupQueries},
+	mysqlQueriesCalls:                   {Type: datapoint.Counter, Group: groupQueries},
+	mysqlQueriesRowsExamined:            {Type: datapoint.Counter, Group: groupQueries},
+	mysqlQueriesTotalTime:               {Type: datapoint.Counter, Group: groupQueries},
+	mysqlReplicationGtidLag:             {Type: datapoint.Gauge, Group: groupReplication},
+	mysqlReplicationIoRunning:           {Type: datapoint.Gauge, Group: groupReplication},
+	mysqlReplicationSecondsBehindMaster: {Type: datapoint.Gauge, Group: groupReplication},
+	mysqlReplicationSQLRunning:          {Type: datapoint.Gauge, Group: groupReplication},
+	mysqlSchemaSize:                     {Type: datapoint.Gauge, Group: groupSchemas},
+	mysqlSelectFullJoin:                 {Type: datapoint.Counter},
+	mysqlSlowQueries:                    {Type: datapoint.Counter},
+	mysqlTableLocksImmediate:            {Type: datapoint.Counter},
+	mysqlTableLocksWaited:               {Type: datapoint.Counter},
+	mysqlTableRows:                      {Type: datapoint.Gauge, Group: groupSchemas},
+	mysqlTableSize:                      {Type: datapoint.Gauge, Group: groupSchemas},
+	mysqlThreadsConnected:               {Type: datapoint.Gauge},
+	mysqlThreadsRunning:                 {Type: datapoint.Gauge},
+	mysqlUptime:                         {Type: datapoint.Gauge},
+}
+
+var defaultMetrics = map[string]bool{
+	mysqlAbortedConnects:      true,
+	mysqlBytesReceived:        true,
+	mysqlBytesSent:            true,
+	mysqlCommands:             true,
+	mysqlConnections:          true,
+	mysqlCreatedTmpDiskTables: true,
+	mysqlQueries:              true,
+	mysqlSlowQueries:          true,
+	mysqlTableLocksWaited:     true,
+	mysqlThreadsConnected:     true,
+	mysqlThreadsRunning:       true,
+	mysqlUptime:               true,
+}
+
+var groupMetricsMap = map[string][]string{
+	groupInnodb: []string{
+		mysqlInnodbBufferPoolBytesData,
+		mysqlInnodbBufferPoolPagesData,
+		mysqlInnodbBufferPoolPagesDirty,
+		mysqlInnodbBufferPoolPagesFree,
+		mysqlInnodbBufferPoolPagesTotal,
+		mysqlInnodbBufferPoolReadRequests,
+		mysqlInnodbBufferPoolReads,
+		mysqlInnodbDataRead,
+		mysqlInnodbDataWritten,
+		mysqlInnodbLogWaits,
+		mysqlInnodbRowLockCurrentWaits,
+		mysqlInnodbRowLockTime,
+		mysqlInnodbRowLockWaits,
+		mysqlInnodbRowsDeleted,
+		mysqlInnodbRowsInserted,
+		mysqlInnodbRowsRead,
+		mysqlInnodbRowsUpdated,
+	},
+	groupQueries: []string{
+		mysqlQueriesAverageTime,
+		mysqlQueriesCalls,
+		mysqlQueriesRowsExamined,
+		mysqlQueriesTotalTime,
+	},
+	groupReplication: []string{
+		mysqlReplicationGtidLag,
+		mysqlReplicationIoRunning,
+		mysqlReplicationSecondsBehindMaster,
+		mysqlReplicationSQLRunning,
+	},
+	groupSchemas: []string{
+		mysqlSchemaSize,
+		mysqlTableRows,
+		mysqlTableSize,
+	},
+}
+
+var monitorMetadata = monitors.Metadata{
+	MonitorType:       "mysql",
+	DefaultMetrics:    defaultMetrics,
+	Metrics:           metricSet,
+	MetricsExhaustive: false,
+	Groups:            groupSet,
+	GroupMetricsMap:   groupMetricsMap,
+	SendAll:           false,
+}
@@ -0,0 +1,279 @@
+package mysql
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/dpfilters"
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/sql"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var logger = logrus.WithFields(logrus.Fields{"monitorType": monitorMetadata.MonitorType})
+
+func init() {
+	monitors.Register(&monitorMetadata, func() interface{} { return &Monitor{} }, &Config{})
+}
+
+// Config for the mysql monitor
+type Config struct {
+	config.MonitorConfig `yaml:",inline" acceptsEndpoints:"true"`
+
+	Host string `yaml:"host"`
+	Port uint16 `yaml:"port" default:"3306"`
+
+	// The user to connect as.  Ignored if `connectionString` is provided.
+	Username string `yaml:"username"`
+	// The password of the user to connect as.  Ignored if `connectionString`
+	// is provided.
+	Password string `yaml:"password" neverLog:"true"`
+
+	// A full [DSN](https://github.com/go-sql-driver/mysql#dsn-data-source-name)
+	// used to connect to the server.  If provided, `host`, `port`,
+	// `username` and `password` are not used to connect.
+	ConnectionString string `yaml:"connectionString"`
+	// Parameters to the connection string that can be templated into the
+	// connection string with the syntax `{{.key}}`.
+	Params map[string]string `yaml:"params" neverLog:"true"`
+
+	// List of schemas to send schema-specific metrics (sizes and top
+	// queries) about.  If omitted, metrics about all non-system schemas will
+	// be sent.  This is an
+	// [overridable set](https://docs.signalfx.com/en/latest/integrations/agent/filtering.html#overridable-filters).
+	Schemas []string `yaml:"schemas" default:"[\"*\"]"`
+
+	// The number of top statements to consider when publishing query-related
+	// metrics
+	TopQueryLimit int `yaml:"topQueryLimit" default:"10"`
+}
+
+// Validate the config
+func (c *Config) Validate() error {
+	if c.ConnectionString == "" && c.Host == "" {
+		return fmt.Errorf("either host or connectionString must be provided")
+	}
+	return nil
+}
+
+func (c *Config) dsn() (string, error) {
+	if c.ConnectionString != "" {
+		return utils.RenderSimpleTemplate(c.ConnectionString, c.Params)
+	}
+
+	mc := mysql.NewConfig()
+	mc.User = c.Username
+	mc.Passwd = c.Password
+	mc.Net = "tcp"
+	mc.Addr = fmt.Sprintf("%s:%d", c.Host, c.Port)
+	return mc.FormatDSN(), nil
+}
+
+// Monitor that collects MySQL/MariaDB stats
+type Monitor struct {
+	sync.Mutex
+
+	Output types.FilteringOutput
+	ctx    context.Context
+	cancel context.CancelFunc
+	conf   *Config
+
+	database *dbsql.DB
+
+	schemasMonitor    *sql.Monitor
+	statementsMonitor *sql.Monitor
+
+	collectNow func() error
+}
+
+// Configure the monitor and kick off metric collection
+func (m *Monitor) Configure(conf *Config) error {
+	m.conf = conf
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
+	dsn, err := conf.dsn()
+	if err != nil {
+		return fmt.Errorf("could not render connectionString template: %v", err)
+	}
+
+	m.database, err = dbsql.Open("mysql", dsn)
+	if err != nil {
+		return err
+	}
+
+	schemaDatapointFilter, err := dpfilters.NewOverridable(nil, map[string][]string{
+		"schema": conf.Schemas,
+	})
+	if err != nil {
+		m.database.Close()
+		return fmt.Errorf("problem with schemas filter: %v", err)
+	}
+	m.Output.AddDatapointExclusionFilter(dpfilters.Negate(schemaDatapointFilter))
+
+	if m.Output.HasEnabledMetricInGroup(groupSchemas) {
+		m.schemasMonitor = &sql.Monitor{Output: m.Output.Copy()}
+		if err := m.configureSQLMonitor(m.schemasMonitor, dsn, defaultSchemaQueries); err != nil {
+			logger.WithError(err).Error("Could not monitor schema sizes")
+		}
+	}
+
+	if m.Output.HasEnabledMetricInGroup(groupQueries) {
+		m.statementsMonitor = &sql.Monitor{Output: m.Output.Copy()}
+		if err := m.configureSQLMonitor(m.statementsMonitor, dsn, makeDefaultStatementsQueries(conf.TopQueryLimit)); err != nil {
+			logger.WithError(err).Error("Could not monitor queries")
+		}
+	}
+
+	replicationEnabled := m.Output.HasEnabledMetricInGroup(groupReplication)
+
+	m.collectNow = utils.RunOnIntervalOrDemand(m.ctx, func() error {
+		m.Lock()
+		defer m.Unlock()
+
+		// This means the monitor is shutdown
+		if m.ctx.Err() != nil {
+			return m.ctx.Err()
+		}
+
+		collectors := []func() error{func() error {
+			err := m.sendGlobalStatus()
+			if err != nil {
+				logger.WithError(err).Error("Could not get MySQL global status")
+			}
+			return err
+		}}
+
+		if replicationEnabled {
+			collectors = append(collectors, func() error {
+				err := m.sendReplicationStatus()
+				if err != nil {
+					logger.WithError(err).Error("Could not get MySQL replication status")
+				}
+				return err
+			})
+		}
+		return monitors.CollectAll(collectors...)
+	}, time.Duration(conf.IntervalSeconds)*time.Second)
+
+	return nil
+}
+
+// CollectNow gets the global and replication status and runs the queries of
+// the nested sql monitors once right away
+func (m *Monitor) CollectNow() error {
+	if m.collectNow == nil {
+		return errors.New("monitor is not configured")
+	}
+
+	collectors := []func() error{m.collectNow}
+	for _, sqlMon := range []*sql.Monitor{m.schemasMonitor, m.statementsMonitor} {
+		if sqlMon != nil {
+			collectors = append(collectors, sqlMon.CollectNow)
+		}
+	}
+	return monitors.CollectAll(collectors...)
+}
+
+func (m *Monitor) configureSQLMonitor(sqlMon *sql.Monitor, dsn string, queries []sql.Query) error {
+	return sqlMon.Configure(&sql.Config{
+		MonitorConfig:    m.conf.MonitorConfig,
+		ConnectionString: dsn,
+		DBDriver:         "mysql",
+		Queries:          queries,
+	})
+}
+
+func (m *Monitor) sendGlobalStatus() error {
+	rows, err := m.database.QueryContext(m.ctx, "SHOW GLOBAL STATUS;")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	status := map[string]string{}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		status[strings.ToLower(name)] = value
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	m.Output.SendDatapoints(datapointsFromGlobalStatus(status)...)
+	return nil
+}
+
+// datapointsFromGlobalStatus converts the variables from SHOW GLOBAL STATUS,
+// keyed by lowercased variable name, to datapoints.
+func datapointsFromGlobalStatus(status map[string]string) []*datapoint.Datapoint {
+	var dps []*datapoint.Datapoint
+	for name, value := range status {
+		if metric, ok := statusVariableMetrics[name]; ok {
+			if dp := makeDatapoint(metric, nil, value); dp != nil {
+				dps = append(dps, dp)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(name, "com_") {
+			command := strings.TrimPrefix(name, "com_")
+			if !trackedCommands[command] {
+				continue
+			}
+			if dp := makeDatapoint(mysqlCommands, map[string]string{"command": command}, value); dp != nil {
+				dps = append(dps, dp)
+			}
+		}
+	}
+	return dps
+}
+
+// makeDatapoint creates a datapoint of the type declared in the metadata for
+// the given metric, returning nil if the value cannot be parsed as a number.
+func makeDatapoint(metric string, dims map[string]string, value string) *datapoint.Datapoint {
+	typ := metricSet[metric].Type
+
+	if intVal, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return datapoint.New(metric, dims, datapoint.NewIntValue(intVal), typ, time.Time{})
+	}
+	if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+		return datapoint.New(metric, dims, datapoint.NewFloatValue(floatVal), typ, time.Time{})
+	}
+	return nil
+}
+
+// Shutdown this monitor and the nested sql ones
+func (m *Monitor) Shutdown() {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.cancel != nil {
+		m.cancel()
+	}
+
+	if m.database != nil {
+		_ = m.database.Close()
+	}
+
+	if m.schemasMonitor != nil {
+		m.schemasMonitor.Shutdown()
+	}
+
+	if m.statementsMonitor != nil {
+		m.statementsMonitor.Shutdown()
+	}
+}
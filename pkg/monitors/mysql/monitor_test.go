@@ -0,0 +1,26 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatapointsFromGlobalStatus(t *testing.T) {
+	dps := datapointsFromGlobalStatus(map[string]string{
+		"threads_connected": "5",
+		"com_select":        "12",
+		"com_show_fields":   "3",
+		"innodb_page_size":  "16384",
+		"uptime":            "not a number",
+	})
+
+	byMetric := map[string]string{}
+	for _, dp := range dps {
+		byMetric[dp.Metric+dp.Dimensions["command"]] = dp.Value.String()
+	}
+	require.Equal(t, map[string]string{
+		mysqlThreadsConnected:    "5",
+		mysqlCommands + "select": "12",
+	}, byMetric)
+}
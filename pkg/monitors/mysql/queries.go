@@ -0,0 +1,139 @@
+package mysql
+
+import "github.com/signalfx/signalfx-agent/pkg/monitors/sql"
+
+// Variables from SHOW GLOBAL STATUS (lowercased) that map directly to a
+// metric without any dimensions.
+var statusVariableMetrics = map[string]string{
+	"aborted_clients":         mysqlAbortedClients,
+	"aborted_connects":        mysqlAbortedConnects,
+	"bytes_received":          mysqlBytesReceived,
+	"bytes_sent":              mysqlBytesSent,
+	"connections":             mysqlConnections,
+	"created_tmp_disk_tables": mysqlCreatedTmpDiskTables,
+	"created_tmp_tables":      mysqlCreatedTmpTables,
+	"open_tables":             mysqlOpenTables,
+	"opened_tables":           mysqlOpenedTables,
+	"queries":                 mysqlQueries,
+	"select_full_join":        mysqlSelectFullJoin,
+	"slow_queries":            mysqlSlowQueries,
+	"table_locks_immediate":   mysqlTableLocksImmediate,
+	"table_locks_waited":      mysqlTableLocksWaited,
+	"threads_connected":       mysqlThreadsConnected,
+	"threads_running":         mysqlThreadsRunning,
+	"uptime":                  mysqlUptime,
+
+	"innodb_buffer_pool_bytes_data":    mysqlInnodbBufferPoolBytesData,
+	"innodb_buffer_pool_pages_data":    mysqlInnodbBufferPoolPagesData,
+	"innodb_buffer_pool_pages_dirty":   mysqlInnodbBufferPoolPagesDirty,
+	"innodb_buffer_pool_pages_free":    mysqlInnodbBufferPoolPagesFree,
+	"innodb_buffer_pool_pages_total":   mysqlInnodbBufferPoolPagesTotal,
+	"innodb_buffer_pool_read_requests": mysqlInnodbBufferPoolReadRequests,
+	"innodb_buffer_pool_reads":         mysqlInnodbBufferPoolReads,
+	"innodb_data_read":                 mysqlInnodbDataRead,
+	"innodb_data_written":              mysqlInnodbDataWritten,
+	"innodb_log_waits":                 mysqlInnodbLogWaits,
+	"innodb_row_lock_current_waits":    mysqlInnodbRowLockCurrentWaits,
+	"innodb_row_lock_time":             mysqlInnodbRowLockTime,
+	"innodb_row_lock_waits":            mysqlInnodbRowLockWaits,
+	"innodb_rows_deleted":              mysqlInnodbRowsDeleted,
+	"innodb_rows_inserted":             mysqlInnodbRowsInserted,
+	"innodb_rows_read":                 mysqlInnodbRowsRead,
+	"innodb_rows_updated":              mysqlInnodbRowsUpdated,
+}
+
+// The Com_* status variables that are sent as the `mysql_commands` metric.
+// There are well over a hundred of these so only the commonly useful ones
+// are included.
+var trackedCommands = map[string]bool{
+	"begin":          true,
+	"call_procedure": true,
+	"commit":         true,
+	"delete":         true,
+	"delete_multi":   true,
+	"insert":         true,
+	"insert_select":  true,
+	"load":           true,
+	"replace":        true,
+	"replace_select": true,
+	"rollback":       true,
+	"select":         true,
+	"update":         true,
+	"update_multi":   true,
+}
+
+const systemSchemas = `('information_schema', 'performance_schema', 'mysql', 'sys')`
+
+var defaultSchemaQueries = []sql.Query{
+	{
+		Query: `SELECT table_schema AS ` + "`schema`" + `, SUM(COALESCE(data_length, 0) + COALESCE(index_length, 0)) AS size FROM information_schema.tables WHERE table_schema NOT IN ` + systemSchemas + ` GROUP BY table_schema;`,
+		Metrics: []sql.Metric{
+			{
+				MetricName:       "mysql_schema_size",
+				ValueColumn:      "size",
+				DimensionColumns: []string{"schema"},
+			},
+		},
+	},
+	{
+		Query: `SELECT table_schema AS ` + "`schema`" + `, table_name AS ` + "`table`" + `, COALESCE(data_length, 0) + COALESCE(index_length, 0) AS size, COALESCE(table_rows, 0) AS row_count FROM information_schema.tables WHERE table_type = 'BASE TABLE' AND table_schema NOT IN ` + systemSchemas + `;`,
+		Metrics: []sql.Metric{
+			{
+				MetricName:       "mysql_table_size",
+				ValueColumn:      "size",
+				DimensionColumns: []string{"schema", "table"},
+			},
+			{
+				MetricName:       "mysql_table_rows",
+				ValueColumn:      "row_count",
+				DimensionColumns: []string{"schema", "table"},
+			},
+		},
+	},
+}
+
+// Timer columns in the Performance Schema are in picoseconds, so they are
+// divided by 10^9 to get milliseconds.
+var makeDefaultStatementsQueries = func(limit int) []sql.Query {
+	return []sql.Query{
+		{
+			Query:  `SELECT COALESCE(schema_name, '') AS ` + "`schema`" + `, digest, digest_text AS query, count_star AS calls FROM performance_schema.events_statements_summary_by_digest WHERE digest IS NOT NULL ORDER BY count_star DESC LIMIT ?;`,
+			Params: []interface{}{limit},
+			Metrics: []sql.Metric{
+				{
+					MetricName:               "mysql_queries_calls",
+					ValueColumn:              "calls",
+					DimensionColumns:         []string{"schema", "digest"},
+					IsCumulative:             true,
+					DimensionPropertyColumns: map[string][]string{"digest": {"query"}},
+				},
+			},
+		},
+		{
+			Query:  `SELECT COALESCE(schema_name, '') AS ` + "`schema`" + `, digest, digest_text AS query, sum_timer_wait / 1000000000 AS total_time, avg_timer_wait / 1000000000 AS average_time, sum_rows_examined AS rows_examined FROM performance_schema.events_statements_summary_by_digest WHERE digest IS NOT NULL ORDER BY sum_timer_wait DESC LIMIT ?;`,
+			Params: []interface{}{limit},
+			Metrics: []sql.Metric{
+				{
+					MetricName:               "mysql_queries_total_time",
+					ValueColumn:              "total_time",
+					DimensionColumns:         []string{"schema", "digest"},
+					IsCumulative:             true,
+					DimensionPropertyColumns: map[string][]string{"digest": {"query"}},
+				},
+				{
+					MetricName:               "mysql_queries_average_time",
+					ValueColumn:              "average_time",
+					DimensionColumns:         []string{"schema", "digest"},
+					DimensionPropertyColumns: map[string][]string{"digest": {"query"}},
+				},
+				{
+					MetricName:               "mysql_queries_rows_examined",
+					ValueColumn:              "rows_examined",
+					DimensionColumns:         []string{"schema", "digest"},
+					IsCumulative:             true,
+					DimensionPropertyColumns: map[string][]string{"digest": {"query"}},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,262 @@
+package mysql
+
+import (
+	dbsql "database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/signalfx/golib/v3/datapoint"
+)
+
+func (m *Monitor) isMariaDB() (bool, error) {
+	var version string
+	if err := m.database.QueryRowContext(m.ctx, "SELECT VERSION();").Scan(&version); err != nil {
+		return false, err
+	}
+	return strings.Contains(strings.ToLower(version), "mariadb"), nil
+}
+
+// queryReplicationStatus returns one map of lowercased column name to value
+// for each replication channel configured on the server.
+func (m *Monitor) queryReplicationStatus(mariaDB bool) ([]map[string]string, error) {
+	// MariaDB only shows all multi-source connections with the ALL keyword,
+	// whereas MySQL shows all channels by default.
+	statement := "SHOW SLAVE STATUS;"
+	if mariaDB {
+		statement = "SHOW ALL SLAVES STATUS;"
+	}
+
+	rows, err := m.database.QueryContext(m.ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []map[string]string
+	for rows.Next() {
+		values := make([]dbsql.NullString, len(columns))
+		scanArgs := make([]interface{}, len(columns))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+
+		row := map[string]string{}
+		for i := range columns {
+			if values[i].Valid {
+				row[strings.ToLower(columns[i])] = values[i].String
+			}
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+func (m *Monitor) sendReplicationStatus() error {
+	mariaDB, err := m.isMariaDB()
+	if err != nil {
+		return err
+	}
+
+	channels, err := m.queryReplicationStatus(mariaDB)
+	if err != nil {
+		return err
+	}
+
+	var dps []*datapoint.Datapoint
+	var mariaDBSlavePos string
+	for _, status := range channels {
+		channel := status["channel_name"]
+		if mariaDB {
+			channel = status["connection_name"]
+		}
+		dims := map[string]string{
+			"channel":     channel,
+			"master_host": status["master_host"],
+		}
+
+		dps = append(dps,
+			makeDatapoint(mysqlReplicationIoRunning, dims, boolToIntString(status["slave_io_running"] == "Yes")),
+			makeDatapoint(mysqlReplicationSQLRunning, dims, boolToIntString(status["slave_sql_running"] == "Yes")))
+
+		// This is NULL when the SQL thread isn't running.
+		if secondsBehind, ok := status["seconds_behind_master"]; ok {
+			if dp := makeDatapoint(mysqlReplicationSecondsBehindMaster, dims, secondsBehind); dp != nil {
+				dps = append(dps, dp)
+			}
+		}
+
+		var lag int64
+		var lagErr error
+		switch {
+		case status["retrieved_gtid_set"] != "":
+			lag, lagErr = gtidLag(status["retrieved_gtid_set"], status["executed_gtid_set"])
+		case mariaDB && status["gtid_io_pos"] != "":
+			if mariaDBSlavePos == "" {
+				if err := m.database.QueryRowContext(m.ctx, "SELECT @@gtid_slave_pos;").Scan(&mariaDBSlavePos); err != nil {
+					return err
+				}
+			}
+			lag, lagErr = mariaDBGTIDLag(status["gtid_io_pos"], mariaDBSlavePos)
+		default:
+			continue
+		}
+
+		if lagErr != nil {
+			logger.WithError(lagErr).Warnf("Could not determine GTID lag for replication channel '%s'", channel)
+			continue
+		}
+		dps = append(dps, makeDatapoint(mysqlReplicationGtidLag, dims, strconv.FormatInt(lag, 10)))
+	}
+
+	m.Output.SendDatapoints(dps...)
+	return nil
+}
+
+func boolToIntString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+type gtidInterval struct {
+	start, end int64
+}
+
+// parseGTIDSet parses a MySQL GTID set such as
+// `3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:7,4f3b...:1-10` into a map of
+// source UUID to the transaction intervals from that source.
+func parseGTIDSet(set string) (map[string][]gtidInterval, error) {
+	out := map[string][]gtidInterval{}
+
+	for _, sourceSet := range strings.Split(set, ",") {
+		sourceSet = strings.TrimSpace(sourceSet)
+		if sourceSet == "" {
+			continue
+		}
+
+		parts := strings.Split(sourceSet, ":")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid GTID set %q", sourceSet)
+		}
+
+		uuid := strings.ToLower(parts[0])
+		for _, interval := range parts[1:] {
+			bounds := strings.SplitN(interval, "-", 2)
+
+			start, err := strconv.ParseInt(bounds[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid GTID interval %q: %v", interval, err)
+			}
+			end := start
+			if len(bounds) == 2 {
+				end, err = strconv.ParseInt(bounds[1], 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid GTID interval %q: %v", interval, err)
+				}
+			}
+			out[uuid] = append(out[uuid], gtidInterval{start: start, end: end})
+		}
+	}
+	return out, nil
+}
+
+// gtidLag returns the number of transactions that are in the retrieved GTID
+// set but not in the executed GTID set.
+func gtidLag(retrieved, executed string) (int64, error) {
+	retrievedSet, err := parseGTIDSet(retrieved)
+	if err != nil {
+		return 0, err
+	}
+	executedSet, err := parseGTIDSet(executed)
+	if err != nil {
+		return 0, err
+	}
+
+	var lag int64
+	for uuid, intervals := range retrievedSet {
+		for _, r := range intervals {
+			count := r.end - r.start + 1
+			// MySQL always normalizes GTID sets so that intervals from the
+			// same source never overlap.
+			for _, e := range executedSet[uuid] {
+				overlapStart, overlapEnd := max64(r.start, e.start), min64(r.end, e.end)
+				if overlapEnd >= overlapStart {
+					count -= overlapEnd - overlapStart + 1
+				}
+			}
+			lag += count
+		}
+	}
+	return lag, nil
+}
+
+// parseMariaDBGTIDPos parses a MariaDB GTID position such as `0-1-100,1-2-50`
+// into a map of replication domain to sequence number.
+func parseMariaDBGTIDPos(pos string) (map[string]int64, error) {
+	out := map[string]int64{}
+	for _, gtid := range strings.Split(pos, ",") {
+		gtid = strings.TrimSpace(gtid)
+		if gtid == "" {
+			continue
+		}
+
+		parts := strings.Split(gtid, "-")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid MariaDB GTID %q", gtid)
+		}
+
+		seq, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MariaDB GTID %q: %v", gtid, err)
+		}
+		out[parts[0]] = seq
+	}
+	return out, nil
+}
+
+// mariaDBGTIDLag returns how many transactions the slave position is behind
+// the I/O thread position, summed across all replication domains present in
+// the I/O position.
+func mariaDBGTIDLag(ioPos, slavePos string) (int64, error) {
+	io, err := parseMariaDBGTIDPos(ioPos)
+	if err != nil {
+		return 0, err
+	}
+	applied, err := parseMariaDBGTIDPos(slavePos)
+	if err != nil {
+		return 0, err
+	}
+
+	var lag int64
+	for domain, seq := range io {
+		if diff := seq - applied[domain]; diff > 0 {
+			lag += diff
+		}
+	}
+	return lag, nil
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
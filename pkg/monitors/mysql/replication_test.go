@@ -0,0 +1,51 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGTIDLag(t *testing.T) {
+	const uuid1 = "3E11FA47-71CA-11E1-9E33-C80AA9429562"
+	const uuid2 = "4f3b3c6e-71ca-11e1-9e33-c80aa9429563"
+
+	for _, tc := range []struct {
+		name      string
+		retrieved string
+		executed  string
+		lag       int64
+	}{
+		{"caught up", uuid1 + ":1-100", uuid1 + ":1-100", 0},
+		{"simple lag", uuid1 + ":1-100", uuid1 + ":1-90", 10},
+		{"nothing executed", uuid1 + ":1-5", "", 5},
+		{"gaps", uuid1 + ":1-10:20-30", uuid1 + ":1-5:20-25", 10},
+		{"single transactions", uuid1 + ":1:3:5", uuid1 + ":1", 2},
+		{"multiple sources", uuid1 + ":1-10,\n" + uuid2 + ":1-20", uuid1 + ":1-10," + uuid2 + ":1-15", 5},
+		{"executed only from other source", uuid1 + ":1-10", uuid2 + ":1-100", 10},
+		{"case insensitive uuid", uuid1 + ":1-10", "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-10", 0},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			lag, err := gtidLag(tc.retrieved, tc.executed)
+			require.NoError(t, err)
+			require.Equal(t, tc.lag, lag)
+		})
+	}
+
+	_, err := gtidLag(uuid1+":1-abc", "")
+	require.Error(t, err)
+}
+
+func TestMariaDBGTIDLag(t *testing.T) {
+	lag, err := mariaDBGTIDLag("0-1-100,1-2-50", "0-1-90,1-2-50")
+	require.NoError(t, err)
+	require.Equal(t, int64(10), lag)
+
+	lag, err = mariaDBGTIDLag("0-1-100", "")
+	require.NoError(t, err)
+	require.Equal(t, int64(100), lag)
+
+	_, err = mariaDBGTIDLag("0-100", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package otlp
+
+import (
+	"strings"
+
+	"github.com/signalfx/signalfx-agent/pkg/monitors/otlp/otlpproto"
+)
+
+// converter holds the config that affects how OTLP data is converted
+type converter struct {
+	excludedResourceAttributes map[string]bool
+}
+
+func newConverter(conf *Config) *converter {
+	c := &converter{
+		excludedResourceAttributes: map[string]bool{},
+	}
+	for _, attr := range conf.ExcludedResourceAttributes {
+		c.excludedResourceAttributes[attr] = true
+	}
+	return c
+}
+
+// resourceAttributes returns the attributes of the resource that aren't
+// excluded
+func (c *converter) resourceAttributes(resource *otlpproto.Resource) map[string]string {
+	out := map[string]string{}
+	if resource == nil {
+		return out
+	}
+	for _, kv := range resource.Attributes {
+		if c.excludedResourceAttributes[kv.Key] {
+			continue
+		}
+		out[kv.Key] = kv.Value.AsString()
+	}
+	return out
+}
+
+// attributesToDims converts attributes to dimensions.  Dots are not allowed
+// in dimension names so they are replaced with underscores.
+func attributesToDims(dims map[string]string, attrs map[string]string) {
+	for k, v := range attrs {
+		if v == "" {
+			continue
+		}
+		dims[strings.ReplaceAll(k, ".", "_")] = v
+	}
+}
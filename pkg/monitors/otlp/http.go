@@ -0,0 +1,117 @@
+package otlp
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/otlp/otlpproto"
+)
+
+const (
+	protobufContentType = "application/x-protobuf"
+	jsonContentType     = "application/json"
+
+	// The largest (uncompressed) request body that will be accepted
+	maxRequestBodySize = 32 * 1024 * 1024
+)
+
+func (m *Monitor) newHTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/traces", func(rw http.ResponseWriter, req *http.Request) {
+		msg := &otlpproto.ExportTraceServiceRequest{}
+		contentType, ok := decodeRequest(rw, req, msg)
+		if !ok {
+			return
+		}
+		m.sendSpans(m.converter.convertTraces(msg), req.RemoteAddr)
+		writeResponse(rw, contentType, &otlpproto.ExportTraceServiceResponse{})
+	})
+	mux.HandleFunc("/v1/metrics", func(rw http.ResponseWriter, req *http.Request) {
+		msg := &otlpproto.ExportMetricsServiceRequest{}
+		contentType, ok := decodeRequest(rw, req, msg)
+		if !ok {
+			return
+		}
+		m.Output.SendDatapoints(m.converter.convertMetrics(msg)...)
+		writeResponse(rw, contentType, &otlpproto.ExportMetricsServiceResponse{})
+	})
+	return mux
+}
+
+// decodeRequest decodes the protobuf or JSON request body into msg and
+// returns the content type.  If it fails, an error response is written and
+// false is returned.
+func decodeRequest(rw http.ResponseWriter, req *http.Request, msg proto.Message) (string, bool) {
+	if req.Method != http.MethodPost {
+		http.Error(rw, "only POST is supported", http.StatusMethodNotAllowed)
+		return "", false
+	}
+
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if contentType != protobufContentType && contentType != jsonContentType {
+		http.Error(rw, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return "", false
+	}
+
+	var body io.Reader = req.Body
+	switch req.Header.Get("Content-Encoding") {
+	case "":
+	case "gzip":
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return "", false
+		}
+		defer gz.Close()
+		body = gz
+	default:
+		http.Error(rw, "unsupported content encoding", http.StatusUnsupportedMediaType)
+		return "", false
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(body, maxRequestBodySize+1))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	if len(content) > maxRequestBodySize {
+		http.Error(rw, "request body is too large", http.StatusRequestEntityTooLarge)
+		return "", false
+	}
+
+	if contentType == jsonContentType {
+		err = json.Unmarshal(content, msg)
+	} else {
+		err = proto.Unmarshal(content, msg)
+	}
+	if err != nil {
+		logger.WithError(err).ThrottledError("Could not decode OTLP request")
+		http.Error(rw, "could not decode request: "+err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+
+	return contentType, true
+}
+
+func writeResponse(rw http.ResponseWriter, contentType string, msg proto.Message) {
+	var body []byte
+	var err error
+	if contentType == jsonContentType {
+		body, err = json.Marshal(msg)
+	} else {
+		body, err = proto.Marshal(msg)
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", contentType)
+	_, _ = rw.Write(body)
+}
@@ -0,0 +1,155 @@
+package otlp
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/otlp/otlpproto"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+)
+
+func (c *converter) convertMetrics(req *otlpproto.ExportMetricsServiceRequest) []*datapoint.Datapoint {
+	var out []*datapoint.Datapoint
+	for _, rm := range req.ResourceMetrics {
+		resourceDims := map[string]string{}
+		attributesToDims(resourceDims, c.resourceAttributes(rm.Resource))
+
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				out = append(out, convertMetric(m, resourceDims)...)
+			}
+		}
+	}
+	return out
+}
+
+func convertMetric(m *otlpproto.Metric, resourceDims map[string]string) []*datapoint.Datapoint {
+	var out []*datapoint.Datapoint
+	switch {
+	case m.Gauge != nil:
+		for _, dp := range m.Gauge.DataPoints {
+			if v := numberValue(dp); v != nil {
+				out = append(out, datapoint.New(m.Name, pointDims(resourceDims, dp.Attributes), v, datapoint.Gauge, timestamp(dp.TimeUnixNano)))
+			}
+		}
+	case m.Sum != nil:
+		typ := sumType(m.Sum)
+		for _, dp := range m.Sum.DataPoints {
+			if v := numberValue(dp); v != nil {
+				out = append(out, datapoint.New(m.Name, pointDims(resourceDims, dp.Attributes), v, typ, timestamp(dp.TimeUnixNano)))
+			}
+		}
+	case m.Histogram != nil:
+		typ := temporalityType(m.Histogram.AggregationTemporality)
+		for _, dp := range m.Histogram.DataPoints {
+			out = append(out, convertHistogramPoint(m.Name, dp, resourceDims, typ)...)
+		}
+	case m.Summary != nil:
+		for _, dp := range m.Summary.DataPoints {
+			out = append(out, convertSummaryPoint(m.Name, dp, resourceDims)...)
+		}
+	}
+	return out
+}
+
+// sumType returns the datapoint type for a sum.  Non-monotonic sums can go
+// up and down so they are sent as gauges.
+func sumType(sum *otlpproto.Sum) datapoint.MetricType {
+	if !sum.IsMonotonic {
+		return datapoint.Gauge
+	}
+	return temporalityType(sum.AggregationTemporality)
+}
+
+func temporalityType(temporality otlpproto.AggregationTemporality) datapoint.MetricType {
+	if temporality == otlpproto.AggregationTemporalityDelta {
+		return datapoint.Count
+	}
+	return datapoint.Counter
+}
+
+// convertHistogramPoint converts a histogram to a count, sum and cumulative
+// buckets in the same way as the prometheus-exporter monitor
+func convertHistogramPoint(name string, dp *otlpproto.HistogramDataPoint, resourceDims map[string]string, typ datapoint.MetricType) []*datapoint.Datapoint {
+	dims := pointDims(resourceDims, dp.Attributes)
+	ts := timestamp(dp.TimeUnixNano)
+
+	out := []*datapoint.Datapoint{
+		datapoint.New(name+"_count", utils.CloneStringMap(dims), datapoint.NewIntValue(int64(dp.Count)), typ, ts),
+	}
+	if dp.Sum != nil {
+		out = append(out, datapoint.New(name, utils.CloneStringMap(dims), datapoint.NewFloatValue(*dp.Sum), typ, ts))
+	}
+	if dp.Min != nil {
+		out = append(out, datapoint.New(name+"_min", utils.CloneStringMap(dims), datapoint.NewFloatValue(*dp.Min), datapoint.Gauge, ts))
+	}
+	if dp.Max != nil {
+		out = append(out, datapoint.New(name+"_max", utils.CloneStringMap(dims), datapoint.NewFloatValue(*dp.Max), datapoint.Gauge, ts))
+	}
+
+	var cumulative int64
+	for i, count := range dp.BucketCounts {
+		cumulative += int64(count)
+
+		bound := math.Inf(1)
+		if i < len(dp.ExplicitBounds) {
+			bound = dp.ExplicitBounds[i]
+		}
+		bucketDims := utils.MergeStringMaps(dims, map[string]string{
+			"upper_bound": strconv.FormatFloat(bound, 'f', 6, 64),
+		})
+		out = append(out, datapoint.New(name+"_bucket", bucketDims, datapoint.NewIntValue(cumulative), typ, ts))
+	}
+	return out
+}
+
+// convertSummaryPoint converts a summary in the same way as the
+// prometheus-exporter monitor
+func convertSummaryPoint(name string, dp *otlpproto.SummaryDataPoint, resourceDims map[string]string) []*datapoint.Datapoint {
+	dims := pointDims(resourceDims, dp.Attributes)
+	ts := timestamp(dp.TimeUnixNano)
+
+	out := []*datapoint.Datapoint{
+		datapoint.New(name+"_count", utils.CloneStringMap(dims), datapoint.NewIntValue(int64(dp.Count)), datapoint.Counter, ts),
+		datapoint.New(name, utils.CloneStringMap(dims), datapoint.NewFloatValue(dp.Sum), datapoint.Counter, ts),
+	}
+	for _, q := range dp.QuantileValues {
+		quantileDims := utils.MergeStringMaps(dims, map[string]string{
+			"quantile": strconv.FormatFloat(q.Quantile, 'f', 6, 64),
+		})
+		out = append(out, datapoint.New(name+"_quantile", quantileDims, datapoint.NewFloatValue(q.Value), datapoint.Gauge, ts))
+	}
+	return out
+}
+
+func pointDims(resourceDims map[string]string, attributes []*otlpproto.KeyValue) map[string]string {
+	attrs := make(map[string]string, len(attributes))
+	for _, kv := range attributes {
+		attrs[kv.Key] = kv.Value.AsString()
+	}
+
+	dims := utils.CloneStringMap(resourceDims)
+	attributesToDims(dims, attrs)
+	return dims
+}
+
+func numberValue(dp *otlpproto.NumberDataPoint) datapoint.Value {
+	switch {
+	case dp.AsInt != nil:
+		return datapoint.NewIntValue(int64(*dp.AsInt))
+	case dp.AsDouble != nil:
+		return datapoint.NewFloatValue(*dp.AsDouble)
+	}
+	return nil
+}
+
+// timestamp converts the OTLP timestamp, using the current time if it is not
+// set
+func timestamp(unixNano otlpproto.Uint64) time.Time {
+	if unixNano == 0 {
+		return time.Now()
+	}
+	return time.Unix(0, int64(unixNano))
+}
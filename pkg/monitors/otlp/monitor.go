@@ -0,0 +1,182 @@
+package otlp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/core/common/constants"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/otlp/otlpproto"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	// Allow clients to send gzip compressed requests
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/peer"
+)
+
+const gracefulShutdownTimeout = time.Second * 5
+
+var logger = utils.NewThrottledLogger(log.WithFields(log.Fields{"monitorType": monitorType}), 30*time.Second)
+
+func init() {
+	monitors.Register(&monitorMetadata, func() interface{} { return &Monitor{} }, &Config{})
+}
+
+// Config for this monitor
+type Config struct {
+	config.MonitorConfig `yaml:",inline" acceptsEndpoints:"false" singleInstance:"true"`
+	// The host:port on which to listen for OTLP data over gRPC.  Set to an
+	// empty string to disable.
+	GRPCListenAddress string `yaml:"grpcListenAddress" default:"0.0.0.0:4317"`
+	// The host:port on which to listen for OTLP data over HTTP, on the
+	// `/v1/traces` and `/v1/metrics` paths.  Both protobuf and JSON encoded
+	// requests are accepted.  Set to an empty string to disable.
+	HTTPListenAddress string `yaml:"httpListenAddress" default:"0.0.0.0:4318"`
+	// Resource attributes that should not be added as dimensions on
+	// datapoints or tags on spans, e.g. `process.command_line`.
+	ExcludedResourceAttributes []string `yaml:"excludedResourceAttributes"`
+}
+
+// Monitor that accepts OTLP metrics and traces
+type Monitor struct {
+	Output    types.Output
+	cancel    context.CancelFunc
+	converter *converter
+
+	lock       sync.Mutex
+	grpc       *grpc.Server
+	httpServer *http.Server
+	// Kept on the monitor so tests can get the bound addresses
+	grpcLn net.Listener
+	httpLn net.Listener
+}
+
+// Configure the monitor and start the servers
+func (m *Monitor) Configure(conf *Config) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.converter = newConverter(conf)
+
+	if conf.GRPCListenAddress != "" {
+		m.grpc = grpc.NewServer()
+		otlpproto.RegisterTraceServiceServer(m.grpc, &traceService{m})
+		otlpproto.RegisterMetricsServiceServer(m.grpc, &metricsService{m})
+
+		go m.serve(ctx, conf, conf.GRPCListenAddress, &m.grpcLn, m.grpc.Serve)
+	}
+
+	if conf.HTTPListenAddress != "" {
+		m.httpServer = &http.Server{Handler: m.newHTTPHandler()}
+
+		go m.serve(ctx, conf, conf.HTTPListenAddress, &m.httpLn, m.httpServer.Serve)
+	}
+
+	return nil
+}
+
+// serve listens on the address, retrying until it succeeds, and then calls
+// serveFunc with the listener, which is also stored in lnField.
+func (m *Monitor) serve(ctx context.Context, conf *Config, address string, lnField *net.Listener, serveFunc func(net.Listener) error) {
+	var ln net.Listener
+	for ctx.Err() == nil {
+		var err error
+		ln, err = net.Listen("tcp", address)
+		if err == nil {
+			break
+		}
+
+		logger.WithError(err).Errorf("Could not listen on %s", address)
+
+		select {
+		case <-time.After(time.Duration(conf.IntervalSeconds) * time.Second):
+		case <-ctx.Done():
+		}
+	}
+
+	m.lock.Lock()
+	if ctx.Err() != nil {
+		// Shutdown happened while we were setting up the listener
+		m.lock.Unlock()
+		if ln != nil {
+			ln.Close()
+		}
+		return
+	}
+	*lnField = ln
+	m.lock.Unlock()
+
+	if err := serveFunc(ln); err != nil && err != http.ErrServerClosed && err != grpc.ErrServerStopped {
+		logger.WithError(err).Errorf("Server on %s failed", address)
+	}
+}
+
+// sendSpans sends the spans, tagging them with the IP address that they
+// came from
+func (m *Monitor) sendSpans(spans []*trace.Span, remoteAddr string) {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		if source := net.ParseIP(host); source != nil {
+			for i := range spans {
+				if spans[i].Meta == nil {
+					spans[i].Meta = map[interface{}]interface{}{}
+				}
+				spans[i].Meta[constants.DataSourceIPKey] = source
+			}
+		}
+	}
+
+	m.Output.SendSpans(spans...)
+}
+
+type traceService struct {
+	monitor *Monitor
+}
+
+func (s *traceService) Export(ctx context.Context, req *otlpproto.ExportTraceServiceRequest) (*otlpproto.ExportTraceServiceResponse, error) {
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	s.monitor.sendSpans(s.monitor.converter.convertTraces(req), remoteAddr)
+	return &otlpproto.ExportTraceServiceResponse{}, nil
+}
+
+type metricsService struct {
+	monitor *Monitor
+}
+
+func (s *metricsService) Export(ctx context.Context, req *otlpproto.ExportMetricsServiceRequest) (*otlpproto.ExportMetricsServiceResponse, error) {
+	s.monitor.Output.SendDatapoints(s.monitor.converter.convertMetrics(req)...)
+	return &otlpproto.ExportMetricsServiceResponse{}, nil
+}
+
+// Shutdown stops the servers
+func (m *Monitor) Shutdown() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.grpc != nil {
+		// Stop the server forcefully if it doesn't stop gracefully in a
+		// reasonable time
+		timeout := time.AfterFunc(gracefulShutdownTimeout, m.grpc.Stop)
+		m.grpc.GracefulStop()
+		timeout.Stop()
+	}
+
+	if m.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownTimeout)
+		_ = m.httpServer.Shutdown(ctx)
+		cancel()
+	}
+}
@@ -0,0 +1,475 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/otlp/otlpproto"
+	"github.com/signalfx/signalfx-agent/pkg/neotest"
+	"github.com/signalfx/signalfx-agent/pkg/neotest/monitortest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func strValue(s string) *otlpproto.AnyValue {
+	return &otlpproto.AnyValue{StringValue: &s}
+}
+
+func intValue(i int64) *otlpproto.AnyValue {
+	v := otlpproto.Int64(i)
+	return &otlpproto.AnyValue{IntValue: &v}
+}
+
+func testTraceRequest() *otlpproto.ExportTraceServiceRequest {
+	return &otlpproto.ExportTraceServiceRequest{
+		ResourceSpans: []*otlpproto.ResourceSpans{
+			{
+				Resource: &otlpproto.Resource{
+					Attributes: []*otlpproto.KeyValue{
+						{Key: "service.name", Value: strValue("checkout")},
+						{Key: "host.name", Value: strValue("web1")},
+						{Key: "process.pid", Value: intValue(123)},
+					},
+				},
+				ScopeSpans: []*otlpproto.ScopeSpans{
+					{
+						Scope: &otlpproto.InstrumentationScope{Name: "io.opentelemetry.http", Version: "1.0"},
+						Spans: []*otlpproto.Span{
+							{
+								TraceID:           []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+								SpanID:            []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74},
+								ParentSpanID:      []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x73},
+								Name:              "POST /cart",
+								Kind:              otlpproto.SpanKindServer,
+								StartTimeUnixNano: 1544712660000000000,
+								EndTimeUnixNano:   1544712661500000000,
+								Attributes: []*otlpproto.KeyValue{
+									{Key: "http.status_code", Value: intValue(500)},
+								},
+								Events: []*otlpproto.Event{
+									{TimeUnixNano: 1544712660300000000, Name: "retry"},
+									{
+										TimeUnixNano: 1544712660500000000,
+										Name:         "exception",
+										Attributes: []*otlpproto.KeyValue{
+											{Key: "exception.type", Value: strValue("Timeout")},
+										},
+									},
+								},
+								Status: &otlpproto.Status{Code: otlpproto.StatusCodeError, Message: "boom"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func requireTestSpans(t *testing.T, spans []*trace.Span) {
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, "5b8efff798038103d269b633813fc60c", span.TraceID)
+	require.Equal(t, "eee19b7ec3c1b174", span.ID)
+	require.Equal(t, "eee19b7ec3c1b173", *span.ParentID)
+	require.Equal(t, "POST /cart", *span.Name)
+	require.Equal(t, "SERVER", *span.Kind)
+	require.Equal(t, int64(1544712660000000), *span.Timestamp)
+	require.Equal(t, int64(1500000), *span.Duration)
+	require.Equal(t, "checkout", *span.LocalEndpoint.ServiceName)
+	require.Equal(t, map[string]string{
+		"host.name":               "web1",
+		"http.status_code":        "500",
+		"otel.scope.name":         "io.opentelemetry.http",
+		"otel.scope.version":      "1.0",
+		"otel.status_code":        "ERROR",
+		"otel.status_description": "boom",
+		"error":                   "true",
+	}, span.Tags)
+
+	require.Len(t, span.Annotations, 2)
+	require.Equal(t, "retry", *span.Annotations[0].Value)
+	require.Equal(t, int64(1544712660300000), *span.Annotations[0].Timestamp)
+	require.JSONEq(t, `{"event": "exception", "exception.type": "Timeout"}`, *span.Annotations[1].Value)
+}
+
+const testTraceJSON = `{
+  "resourceSpans": [{
+    "resource": {"attributes": [
+      {"key": "service.name", "value": {"stringValue": "checkout"}},
+      {"key": "host.name", "value": {"stringValue": "web1"}},
+      {"key": "process.pid", "value": {"intValue": "123"}}
+    ]},
+    "scopeSpans": [{
+      "scope": {"name": "io.opentelemetry.http", "version": "1.0"},
+      "spans": [{
+        "traceId": "5b8efff798038103d269b633813fc60c",
+        "spanId": "eee19b7ec3c1b174",
+        "parentSpanId": "eee19b7ec3c1b173",
+        "name": "POST /cart",
+        "kind": 2,
+        "startTimeUnixNano": "1544712660000000000",
+        "endTimeUnixNano": 1544712661500000000,
+        "attributes": [{"key": "http.status_code", "value": {"intValue": 500}}],
+        "events": [
+          {"timeUnixNano": "1544712660300000000", "name": "retry"},
+          {"timeUnixNano": "1544712660500000000", "name": "exception",
+           "attributes": [{"key": "exception.type", "value": {"stringValue": "Timeout"}}]}
+        ],
+        "status": {"code": 2, "message": "boom"}
+      }]
+    }]
+  }]
+}`
+
+func TestConvertMetrics(t *testing.T) {
+	five := otlpproto.Int64(5)
+	half := 0.5
+	sum := 12.5
+	c := newConverter(&Config{ExcludedResourceAttributes: []string{"process.pid"}})
+
+	dps := c.convertMetrics(&otlpproto.ExportMetricsServiceRequest{
+		ResourceMetrics: []*otlpproto.ResourceMetrics{
+			{
+				Resource: &otlpproto.Resource{
+					Attributes: []*otlpproto.KeyValue{
+						{Key: "service.name", Value: strValue("checkout")},
+						{Key: "process.pid", Value: intValue(123)},
+					},
+				},
+				ScopeMetrics: []*otlpproto.ScopeMetrics{
+					{
+						Metrics: []*otlpproto.Metric{
+							{
+								Name: "queue.size",
+								Gauge: &otlpproto.Gauge{DataPoints: []*otlpproto.NumberDataPoint{
+									{
+										TimeUnixNano: 1544712660000000000,
+										AsInt:        &five,
+										Attributes:   []*otlpproto.KeyValue{{Key: "queue.name", Value: strValue("orders")}},
+									},
+								}},
+							},
+							{
+								Name: "requests",
+								Sum: &otlpproto.Sum{
+									IsMonotonic:            true,
+									AggregationTemporality: otlpproto.AggregationTemporalityCumulative,
+									DataPoints:             []*otlpproto.NumberDataPoint{{AsInt: &five}},
+								},
+							},
+							{
+								Name: "errors",
+								Sum: &otlpproto.Sum{
+									IsMonotonic:            true,
+									AggregationTemporality: otlpproto.AggregationTemporalityDelta,
+									DataPoints:             []*otlpproto.NumberDataPoint{{AsInt: &five}},
+								},
+							},
+							{
+								Name: "connections",
+								Sum: &otlpproto.Sum{
+									AggregationTemporality: otlpproto.AggregationTemporalityCumulative,
+									DataPoints:             []*otlpproto.NumberDataPoint{{AsDouble: &half}},
+								},
+							},
+							{
+								Name: "latency",
+								Histogram: &otlpproto.Histogram{
+									AggregationTemporality: otlpproto.AggregationTemporalityCumulative,
+									DataPoints: []*otlpproto.HistogramDataPoint{
+										{
+											Count:          6,
+											Sum:            &sum,
+											BucketCounts:   []otlpproto.Uint64{1, 2, 3},
+											ExplicitBounds: []float64{1, 10},
+										},
+									},
+								},
+							},
+							{
+								Name: "duration",
+								Summary: &otlpproto.Summary{
+									DataPoints: []*otlpproto.SummaryDataPoint{
+										{
+											Count: 4,
+											Sum:   10,
+											QuantileValues: []*otlpproto.ValueAtQuantile{
+												{Quantile: 0.99, Value: 3},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	type dpSummary struct {
+		metric string
+		dims   map[string]string
+		value  string
+		typ    datapoint.MetricType
+	}
+	var actual []dpSummary
+	for _, dp := range dps {
+		actual = append(actual, dpSummary{dp.Metric, dp.Dimensions, dp.Value.String(), dp.MetricType})
+	}
+
+	svc := map[string]string{"service_name": "checkout"}
+	require.Equal(t, []dpSummary{
+		{"queue.size", map[string]string{"service_name": "checkout", "queue_name": "orders"}, "5", datapoint.Gauge},
+		{"requests", svc, "5", datapoint.Counter},
+		{"errors", svc, "5", datapoint.Count},
+		{"connections", svc, "0.5", datapoint.Gauge},
+		{"latency_count", svc, "6", datapoint.Counter},
+		{"latency", svc, "12.5", datapoint.Counter},
+		{"latency_bucket", map[string]string{"service_name": "checkout", "upper_bound": "1.000000"}, "1", datapoint.Counter},
+		{"latency_bucket", map[string]string{"service_name": "checkout", "upper_bound": "10.000000"}, "3", datapoint.Counter},
+		{"latency_bucket", map[string]string{"service_name": "checkout", "upper_bound": "+Inf"}, "6", datapoint.Counter},
+		{"duration_count", svc, "4", datapoint.Counter},
+		{"duration", svc, "10", datapoint.Counter},
+		{"duration_quantile", map[string]string{"service_name": "checkout", "quantile": "0.990000"}, "3", datapoint.Gauge},
+	}, actual)
+
+	require.Equal(t, time.Unix(0, 1544712660000000000), dps[0].Timestamp)
+}
+
+// The fixtures in testdata are encoded with the upstream OTLP protos by
+// testdata/gen, so they check that the otlpproto types decode what real
+// clients send.
+func readFixture(t *testing.T, name string) []byte {
+	body, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return body
+}
+
+func TestDecodeUpstreamMetrics(t *testing.T) {
+	var req otlpproto.ExportMetricsServiceRequest
+	require.NoError(t, proto.Unmarshal(readFixture(t, "metrics.pb"), &req))
+
+	require.Len(t, req.ResourceMetrics, 1)
+	rm := req.ResourceMetrics[0]
+	require.Len(t, rm.Resource.Attributes, 4)
+	require.Equal(t, "io.opentelemetry.runtime", rm.ScopeMetrics[0].Scope.Name)
+
+	metrics := rm.ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 4)
+	require.Equal(t, "1", metrics[0].Unit)
+	require.Equal(t, otlpproto.Int64(5), *metrics[0].Gauge.DataPoints[0].AsInt)
+	require.Nil(t, metrics[0].Gauge.DataPoints[0].AsDouble)
+	require.Equal(t, 0.25, *metrics[1].Gauge.DataPoints[0].AsDouble)
+	require.Nil(t, metrics[1].Gauge.DataPoints[0].AsInt)
+	require.Equal(t, otlpproto.Int64(-1), *metrics[2].Sum.DataPoints[0].AsInt)
+
+	hist := metrics[3].Histogram.DataPoints[0]
+	require.Equal(t, otlpproto.Uint64(6), hist.Count)
+	require.Equal(t, 12.5, *hist.Sum)
+	require.Equal(t, []otlpproto.Uint64{1, 2, 3}, hist.BucketCounts)
+	require.Equal(t, []float64{1, 10}, hist.ExplicitBounds)
+	require.Equal(t, 0.5, *hist.Min)
+	require.Equal(t, 20.0, *hist.Max)
+
+	c := newConverter(&Config{ExcludedResourceAttributes: []string{"process.pid"}})
+	var names []string
+	for _, dp := range c.convertMetrics(&req) {
+		names = append(names, dp.Metric)
+		require.Equal(t, "checkout", dp.Dimensions["service_name"])
+		require.Equal(t, "true", dp.Dimensions["cloud_preemptible"])
+		require.NotContains(t, dp.Dimensions, "process_pid")
+	}
+	require.Equal(t, []string{"queue.size", "cpu.utilization", "requests", "latency_count", "latency",
+		"latency_min", "latency_max", "latency_bucket", "latency_bucket", "latency_bucket"}, names)
+}
+
+func TestHTTP(t *testing.T) {
+	output := neotest.NewTestOutput()
+	m := &Monitor{Output: output, converter: newConverter(&Config{ExcludedResourceAttributes: []string{"process.pid"}})}
+	server := httptest.NewServer(m.newHTTPHandler())
+	defer server.Close()
+
+	post := func(body []byte, contentType string, gzipped bool) *http.Response {
+		req, err := http.NewRequest("POST", server.URL+"/v1/traces", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		if gzipped {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			_, _ = gz.Write(body)
+			require.NoError(t, gz.Close())
+			req.Body = ioutil.NopCloser(&buf)
+			req.ContentLength = int64(buf.Len())
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("protobuf", func(t *testing.T) {
+		body, err := proto.Marshal(testTraceRequest())
+		require.NoError(t, err)
+
+		resp := post(body, "application/x-protobuf", false)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		requireTestSpans(t, output.FlushSpans())
+	})
+
+	t.Run("gzipped protobuf", func(t *testing.T) {
+		body, err := proto.Marshal(testTraceRequest())
+		require.NoError(t, err)
+
+		resp := post(body, "application/x-protobuf", true)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		requireTestSpans(t, output.FlushSpans())
+	})
+
+	t.Run("upstream protobuf", func(t *testing.T) {
+		resp := post(readFixture(t, "traces.pb"), "application/x-protobuf", false)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		requireTestSpans(t, output.FlushSpans())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		resp := post([]byte(testTraceJSON), "application/json", false)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		requireTestSpans(t, output.FlushSpans())
+	})
+
+	t.Run("bad requests", func(t *testing.T) {
+		require.Equal(t, http.StatusUnsupportedMediaType, post([]byte("{}"), "text/plain", false).StatusCode)
+		require.Equal(t, http.StatusBadRequest, post([]byte("{"), "application/json", false).StatusCode)
+		require.Empty(t, output.FlushSpans())
+	})
+}
+
+func TestGRPC(t *testing.T) {
+	output := neotest.NewTestOutput()
+	m := &Monitor{Output: output}
+	require.NoError(t, m.Configure(&Config{
+		GRPCListenAddress:          "127.0.0.1:0",
+		ExcludedResourceAttributes: []string{"process.pid"},
+	}))
+	defer m.Shutdown()
+
+	var address string
+	require.Eventually(t, func() bool {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		if m.grpcLn != nil {
+			address = m.grpcLn.Addr().String()
+		}
+		return address != ""
+	}, 5*time.Second, 10*time.Millisecond)
+
+	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = conn.Invoke(ctx, "/opentelemetry.proto.collector.trace.v1.TraceService/Export",
+		testTraceRequest(), &otlpproto.ExportTraceServiceResponse{})
+	require.NoError(t, err)
+	requireTestSpans(t, output.FlushSpans())
+
+	five := otlpproto.Int64(5)
+	err = conn.Invoke(ctx, "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export",
+		&otlpproto.ExportMetricsServiceRequest{
+			ResourceMetrics: []*otlpproto.ResourceMetrics{{
+				ScopeMetrics: []*otlpproto.ScopeMetrics{{
+					Metrics: []*otlpproto.Metric{{
+						Name:  "queue.size",
+						Gauge: &otlpproto.Gauge{DataPoints: []*otlpproto.NumberDataPoint{{AsInt: &five}}},
+					}},
+				}},
+			}},
+		}, &otlpproto.ExportMetricsServiceResponse{})
+	require.NoError(t, err)
+
+	dps := output.FlushDatapoints()
+	require.Len(t, dps, 1)
+	require.Equal(t, "queue.size", dps[0].Metric)
+	require.Equal(t, datapoint.NewIntValue(5), dps[0].Value)
+
+	// Send the bytes that the upstream protos encoded as-is
+	var resp []byte
+	err = conn.Invoke(ctx, "/opentelemetry.proto.collector.trace.v1.TraceService/Export",
+		readFixture(t, "traces.pb"), &resp, grpc.ForceCodec(rawCodec{}))
+	require.NoError(t, err)
+	requireTestSpans(t, output.FlushSpans())
+
+	err = conn.Invoke(ctx, "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export",
+		readFixture(t, "metrics.pb"), &resp, grpc.ForceCodec(rawCodec{}))
+	require.NoError(t, err)
+	require.Len(t, output.FlushDatapoints(), 10)
+}
+
+// Sends and receives already encoded messages
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return v.([]byte), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = data
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+func freeAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func TestContract(t *testing.T) {
+	h := monitortest.New(t, monitorType)
+	// The dimensions come from the OTLP attributes that are sent
+	h.AllowedDimensions = []string{"service_name", "host_name", "cloud_preemptible", "queue_name", "upper_bound"}
+
+	fixture := readFixture(t, "metrics.pb")
+	h.CheckContract(func() config.MonitorCustomConfig {
+		conf := &Config{
+			GRPCListenAddress:          freeAddress(t),
+			HTTPListenAddress:          freeAddress(t),
+			ExcludedResourceAttributes: []string{"process.pid"},
+		}
+
+		// Send the metrics once the monitor is listening
+		go func() {
+			for i := 0; i < 100; i++ {
+				resp, err := http.Post("http://"+conf.HTTPListenAddress+"/v1/metrics", "application/x-protobuf", bytes.NewReader(fixture))
+				if err == nil {
+					resp.Body.Close()
+					return
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		}()
+		return conf
+	})
+}
@@ -0,0 +1,179 @@
+// Package otlpproto contains a hand-maintained subset of the OpenTelemetry
+// protocol (OTLP) protobuf messages and gRPC services, as defined in
+// https://github.com/open-telemetry/opentelemetry-proto.  Only the fields
+// that the agent uses are included; unknown fields are ignored when decoding.
+//
+// The messages can be decoded from both the protobuf encoding and the OTLP
+// JSON encoding, which uses hex for trace and span ids and allows 64-bit
+// integers to be strings.  Oneof fields are represented as optional fields
+// since they are identical on the wire.
+package otlpproto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// AnyValue is the value of an attribute, only one of the fields will be set
+type AnyValue struct {
+	StringValue *string       `protobuf:"bytes,1,opt,name=string_value" json:"stringValue,omitempty"`
+	BoolValue   *bool         `protobuf:"varint,2,opt,name=bool_value" json:"boolValue,omitempty"`
+	IntValue    *Int64        `protobuf:"varint,3,opt,name=int_value" json:"intValue,omitempty"`
+	DoubleValue *float64      `protobuf:"fixed64,4,opt,name=double_value" json:"doubleValue,omitempty"`
+	ArrayValue  *ArrayValue   `protobuf:"bytes,5,opt,name=array_value" json:"arrayValue,omitempty"`
+	KvlistValue *KeyValueList `protobuf:"bytes,6,opt,name=kvlist_value" json:"kvlistValue,omitempty"`
+	BytesValue  []byte        `protobuf:"bytes,7,opt,name=bytes_value" json:"bytesValue,omitempty"`
+}
+
+// ArrayValue is a list of values
+type ArrayValue struct {
+	Values []*AnyValue `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+}
+
+// KeyValueList is a list of key/value pairs
+type KeyValueList struct {
+	Values []*KeyValue `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+}
+
+// KeyValue is a single attribute
+type KeyValue struct {
+	Key   string    `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value *AnyValue `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+// InstrumentationScope describes the library that produced the telemetry
+type InstrumentationScope struct {
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+// Resource describes the entity that produced the telemetry
+type Resource struct {
+	Attributes []*KeyValue `protobuf:"bytes,1,rep,name=attributes,proto3" json:"attributes,omitempty"`
+}
+
+func (m *AnyValue) Reset()                     { *m = AnyValue{} }
+func (m *AnyValue) String() string             { return proto.CompactTextString(m) }
+func (*AnyValue) ProtoMessage()                {}
+func (m *ArrayValue) Reset()                   { *m = ArrayValue{} }
+func (m *ArrayValue) String() string           { return proto.CompactTextString(m) }
+func (*ArrayValue) ProtoMessage()              {}
+func (m *KeyValueList) Reset()                 { *m = KeyValueList{} }
+func (m *KeyValueList) String() string         { return proto.CompactTextString(m) }
+func (*KeyValueList) ProtoMessage()            {}
+func (m *KeyValue) Reset()                     { *m = KeyValue{} }
+func (m *KeyValue) String() string             { return proto.CompactTextString(m) }
+func (*KeyValue) ProtoMessage()                {}
+func (m *InstrumentationScope) Reset()         { *m = InstrumentationScope{} }
+func (m *InstrumentationScope) String() string { return proto.CompactTextString(m) }
+func (*InstrumentationScope) ProtoMessage()    {}
+func (m *Resource) Reset()                     { *m = Resource{} }
+func (m *Resource) String() string             { return proto.CompactTextString(m) }
+func (*Resource) ProtoMessage()                {}
+
+// AsString converts the value to a string.  Arrays and lists of key/values
+// are converted to JSON.
+func (m *AnyValue) AsString() string {
+	switch {
+	case m == nil:
+		return ""
+	case m.StringValue != nil:
+		return *m.StringValue
+	case m.BoolValue != nil:
+		return strconv.FormatBool(*m.BoolValue)
+	case m.IntValue != nil:
+		return strconv.FormatInt(int64(*m.IntValue), 10)
+	case m.DoubleValue != nil:
+		return strconv.FormatFloat(*m.DoubleValue, 'f', -1, 64)
+	case m.ArrayValue != nil, m.KvlistValue != nil:
+		out, _ := json.Marshal(m.asInterface())
+		return string(out)
+	case m.BytesValue != nil:
+		return base64.StdEncoding.EncodeToString(m.BytesValue)
+	}
+	return ""
+}
+
+func (m *AnyValue) asInterface() interface{} {
+	switch {
+	case m == nil:
+		return nil
+	case m.StringValue != nil:
+		return *m.StringValue
+	case m.BoolValue != nil:
+		return *m.BoolValue
+	case m.IntValue != nil:
+		return int64(*m.IntValue)
+	case m.DoubleValue != nil:
+		return *m.DoubleValue
+	case m.ArrayValue != nil:
+		out := make([]interface{}, len(m.ArrayValue.Values))
+		for i := range m.ArrayValue.Values {
+			out[i] = m.ArrayValue.Values[i].asInterface()
+		}
+		return out
+	case m.KvlistValue != nil:
+		out := make(map[string]interface{}, len(m.KvlistValue.Values))
+		for _, kv := range m.KvlistValue.Values {
+			out[kv.Key] = kv.Value.asInterface()
+		}
+		return out
+	case m.BytesValue != nil:
+		return m.BytesValue
+	}
+	return nil
+}
+
+// HexBytes is a byte slice that is hex encoded in JSON, as is done for trace
+// and span ids
+type HexBytes []byte
+
+// UnmarshalJSON decodes a hex string
+func (h *HexBytes) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	out, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*h = out
+	return nil
+}
+
+// MarshalJSON encodes to a hex string
+func (h HexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(h))
+}
+
+// Uint64 is a uint64 that can be a string or a number in JSON
+type Uint64 uint64
+
+// UnmarshalJSON decodes a number or a string containing a number
+func (u *Uint64) UnmarshalJSON(b []byte) error {
+	v, err := strconv.ParseUint(strings.Trim(string(b), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*u = Uint64(v)
+	return nil
+}
+
+// Int64 is an int64 that can be a string or a number in JSON
+type Int64 int64
+
+// UnmarshalJSON decodes a number or a string containing a number
+func (i *Int64) UnmarshalJSON(b []byte) error {
+	v, err := strconv.ParseInt(strings.Trim(string(b), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = Int64(v)
+	return nil
+}
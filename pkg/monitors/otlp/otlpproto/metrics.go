@@ -0,0 +1,152 @@
+package otlpproto
+
+import (
+	"github.com/gogo/protobuf/proto"
+)
+
+// AggregationTemporality describes whether the values of a sum or histogram
+// are deltas since the last report or cumulative since a start time
+type AggregationTemporality int32
+
+// The aggregation temporalities
+const (
+	AggregationTemporalityUnspecified AggregationTemporality = 0
+	AggregationTemporalityDelta       AggregationTemporality = 1
+	AggregationTemporalityCumulative  AggregationTemporality = 2
+)
+
+// ExportMetricsServiceRequest is sent by clients to export metrics
+type ExportMetricsServiceRequest struct {
+	ResourceMetrics []*ResourceMetrics `protobuf:"bytes,1,rep,name=resource_metrics,proto3" json:"resourceMetrics,omitempty"`
+}
+
+// ExportMetricsServiceResponse is the response to an export request
+type ExportMetricsServiceResponse struct{}
+
+// ResourceMetrics is a collection of metrics from a single resource
+type ResourceMetrics struct {
+	Resource     *Resource       `protobuf:"bytes,1,opt,name=resource,proto3" json:"resource,omitempty"`
+	ScopeMetrics []*ScopeMetrics `protobuf:"bytes,2,rep,name=scope_metrics,proto3" json:"scopeMetrics,omitempty"`
+}
+
+// ScopeMetrics is a collection of metrics from a single instrumentation
+// scope
+type ScopeMetrics struct {
+	Scope   *InstrumentationScope `protobuf:"bytes,1,opt,name=scope,proto3" json:"scope,omitempty"`
+	Metrics []*Metric             `protobuf:"bytes,2,rep,name=metrics,proto3" json:"metrics,omitempty"`
+}
+
+// Metric is a single named metric, only one of the data fields will be set
+type Metric struct {
+	Name      string     `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Unit      string     `protobuf:"bytes,3,opt,name=unit,proto3" json:"unit,omitempty"`
+	Gauge     *Gauge     `protobuf:"bytes,5,opt,name=gauge" json:"gauge,omitempty"`
+	Sum       *Sum       `protobuf:"bytes,7,opt,name=sum" json:"sum,omitempty"`
+	Histogram *Histogram `protobuf:"bytes,9,opt,name=histogram" json:"histogram,omitempty"`
+	Summary   *Summary   `protobuf:"bytes,11,opt,name=summary" json:"summary,omitempty"`
+}
+
+// Gauge is a metric whose values are sampled at a point in time
+type Gauge struct {
+	DataPoints []*NumberDataPoint `protobuf:"bytes,1,rep,name=data_points,proto3" json:"dataPoints,omitempty"`
+}
+
+// Sum is a metric whose values are a sum over time
+type Sum struct {
+	DataPoints             []*NumberDataPoint     `protobuf:"bytes,1,rep,name=data_points,proto3" json:"dataPoints,omitempty"`
+	AggregationTemporality AggregationTemporality `protobuf:"varint,2,opt,name=aggregation_temporality,proto3" json:"aggregationTemporality,omitempty"`
+	IsMonotonic            bool                   `protobuf:"varint,3,opt,name=is_monotonic,proto3" json:"isMonotonic,omitempty"`
+}
+
+// Histogram is a metric whose values are a distribution over explicit
+// buckets
+type Histogram struct {
+	DataPoints             []*HistogramDataPoint  `protobuf:"bytes,1,rep,name=data_points,proto3" json:"dataPoints,omitempty"`
+	AggregationTemporality AggregationTemporality `protobuf:"varint,2,opt,name=aggregation_temporality,proto3" json:"aggregationTemporality,omitempty"`
+}
+
+// Summary is a metric whose values are precomputed quantiles
+type Summary struct {
+	DataPoints []*SummaryDataPoint `protobuf:"bytes,1,rep,name=data_points,proto3" json:"dataPoints,omitempty"`
+}
+
+// NumberDataPoint is a single value of a gauge or sum, only one of AsDouble
+// and AsInt will be set
+type NumberDataPoint struct {
+	Attributes        []*KeyValue `protobuf:"bytes,7,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	StartTimeUnixNano Uint64      `protobuf:"fixed64,2,opt,name=start_time_unix_nano,proto3" json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      Uint64      `protobuf:"fixed64,3,opt,name=time_unix_nano,proto3" json:"timeUnixNano,omitempty"`
+	AsDouble          *float64    `protobuf:"fixed64,4,opt,name=as_double" json:"asDouble,omitempty"`
+	AsInt             *Int64      `protobuf:"fixed64,6,opt,name=as_int" json:"asInt,omitempty"`
+}
+
+// HistogramDataPoint is a single value of a histogram.  BucketCounts are not
+// cumulative, and there is one more of them than ExplicitBounds, for the
+// bucket above the largest bound.
+type HistogramDataPoint struct {
+	Attributes        []*KeyValue `protobuf:"bytes,9,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	StartTimeUnixNano Uint64      `protobuf:"fixed64,2,opt,name=start_time_unix_nano,proto3" json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      Uint64      `protobuf:"fixed64,3,opt,name=time_unix_nano,proto3" json:"timeUnixNano,omitempty"`
+	Count             Uint64      `protobuf:"fixed64,4,opt,name=count,proto3" json:"count,omitempty"`
+	Sum               *float64    `protobuf:"fixed64,5,opt,name=sum" json:"sum,omitempty"`
+	BucketCounts      []Uint64    `protobuf:"fixed64,6,rep,packed,name=bucket_counts,proto3" json:"bucketCounts,omitempty"`
+	ExplicitBounds    []float64   `protobuf:"fixed64,7,rep,packed,name=explicit_bounds,proto3" json:"explicitBounds,omitempty"`
+	Min               *float64    `protobuf:"fixed64,11,opt,name=min" json:"min,omitempty"`
+	Max               *float64    `protobuf:"fixed64,12,opt,name=max" json:"max,omitempty"`
+}
+
+// SummaryDataPoint is a single value of a summary
+type SummaryDataPoint struct {
+	Attributes        []*KeyValue        `protobuf:"bytes,7,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	StartTimeUnixNano Uint64             `protobuf:"fixed64,2,opt,name=start_time_unix_nano,proto3" json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      Uint64             `protobuf:"fixed64,3,opt,name=time_unix_nano,proto3" json:"timeUnixNano,omitempty"`
+	Count             Uint64             `protobuf:"fixed64,4,opt,name=count,proto3" json:"count,omitempty"`
+	Sum               float64            `protobuf:"fixed64,5,opt,name=sum,proto3" json:"sum,omitempty"`
+	QuantileValues    []*ValueAtQuantile `protobuf:"bytes,6,rep,name=quantile_values,proto3" json:"quantileValues,omitempty"`
+}
+
+// ValueAtQuantile is the value of a single quantile in a summary
+type ValueAtQuantile struct {
+	Quantile float64 `protobuf:"fixed64,1,opt,name=quantile,proto3" json:"quantile,omitempty"`
+	Value    float64 `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *ExportMetricsServiceRequest) Reset()          { *m = ExportMetricsServiceRequest{} }
+func (m *ExportMetricsServiceRequest) String() string  { return proto.CompactTextString(m) }
+func (*ExportMetricsServiceRequest) ProtoMessage()     {}
+func (m *ExportMetricsServiceResponse) Reset()         { *m = ExportMetricsServiceResponse{} }
+func (m *ExportMetricsServiceResponse) String() string { return proto.CompactTextString(m) }
+func (*ExportMetricsServiceResponse) ProtoMessage()    {}
+func (m *ResourceMetrics) Reset()                      { *m = ResourceMetrics{} }
+func (m *ResourceMetrics) String() string              { return proto.CompactTextString(m) }
+func (*ResourceMetrics) ProtoMessage()                 {}
+func (m *ScopeMetrics) Reset()                         { *m = ScopeMetrics{} }
+func (m *ScopeMetrics) String() string                 { return proto.CompactTextString(m) }
+func (*ScopeMetrics) ProtoMessage()                    {}
+func (m *Metric) Reset()                               { *m = Metric{} }
+func (m *Metric) String() string                       { return proto.CompactTextString(m) }
+func (*Metric) ProtoMessage()                          {}
+func (m *Gauge) Reset()                                { *m = Gauge{} }
+func (m *Gauge) String() string                        { return proto.CompactTextString(m) }
+func (*Gauge) ProtoMessage()                           {}
+func (m *Sum) Reset()                                  { *m = Sum{} }
+func (m *Sum) String() string                          { return proto.CompactTextString(m) }
+func (*Sum) ProtoMessage()                             {}
+func (m *Histogram) Reset()                            { *m = Histogram{} }
+func (m *Histogram) String() string                    { return proto.CompactTextString(m) }
+func (*Histogram) ProtoMessage()                       {}
+func (m *Summary) Reset()                              { *m = Summary{} }
+func (m *Summary) String() string                      { return proto.CompactTextString(m) }
+func (*Summary) ProtoMessage()                         {}
+func (m *NumberDataPoint) Reset()                      { *m = NumberDataPoint{} }
+func (m *NumberDataPoint) String() string              { return proto.CompactTextString(m) }
+func (*NumberDataPoint) ProtoMessage()                 {}
+func (m *HistogramDataPoint) Reset()                   { *m = HistogramDataPoint{} }
+func (m *HistogramDataPoint) String() string           { return proto.CompactTextString(m) }
+func (*HistogramDataPoint) ProtoMessage()              {}
+func (m *SummaryDataPoint) Reset()                     { *m = SummaryDataPoint{} }
+func (m *SummaryDataPoint) String() string             { return proto.CompactTextString(m) }
+func (*SummaryDataPoint) ProtoMessage()                {}
+func (m *ValueAtQuantile) Reset()                      { *m = ValueAtQuantile{} }
+func (m *ValueAtQuantile) String() string              { return proto.CompactTextString(m) }
+func (*ValueAtQuantile) ProtoMessage()                 {}
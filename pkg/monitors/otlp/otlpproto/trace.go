@@ -0,0 +1,97 @@
+package otlpproto
+
+import (
+	"github.com/gogo/protobuf/proto"
+)
+
+// SpanKind is the kind of a span
+type SpanKind int32
+
+// The span kinds
+const (
+	SpanKindUnspecified SpanKind = 0
+	SpanKindInternal    SpanKind = 1
+	SpanKindServer      SpanKind = 2
+	SpanKindClient      SpanKind = 3
+	SpanKindProducer    SpanKind = 4
+	SpanKindConsumer    SpanKind = 5
+)
+
+// StatusCode is the status of a span
+type StatusCode int32
+
+// The status codes
+const (
+	StatusCodeUnset StatusCode = 0
+	StatusCodeOk    StatusCode = 1
+	StatusCodeError StatusCode = 2
+)
+
+// ExportTraceServiceRequest is sent by clients to export spans
+type ExportTraceServiceRequest struct {
+	ResourceSpans []*ResourceSpans `protobuf:"bytes,1,rep,name=resource_spans,proto3" json:"resourceSpans,omitempty"`
+}
+
+// ExportTraceServiceResponse is the response to an export request
+type ExportTraceServiceResponse struct{}
+
+// ResourceSpans is a collection of spans from a single resource
+type ResourceSpans struct {
+	Resource   *Resource     `protobuf:"bytes,1,opt,name=resource,proto3" json:"resource,omitempty"`
+	ScopeSpans []*ScopeSpans `protobuf:"bytes,2,rep,name=scope_spans,proto3" json:"scopeSpans,omitempty"`
+}
+
+// ScopeSpans is a collection of spans from a single instrumentation scope
+type ScopeSpans struct {
+	Scope *InstrumentationScope `protobuf:"bytes,1,opt,name=scope,proto3" json:"scope,omitempty"`
+	Spans []*Span               `protobuf:"bytes,2,rep,name=spans,proto3" json:"spans,omitempty"`
+}
+
+// Span is a single operation within a trace
+type Span struct {
+	TraceID           HexBytes    `protobuf:"bytes,1,opt,name=trace_id,proto3" json:"traceId,omitempty"`
+	SpanID            HexBytes    `protobuf:"bytes,2,opt,name=span_id,proto3" json:"spanId,omitempty"`
+	ParentSpanID      HexBytes    `protobuf:"bytes,4,opt,name=parent_span_id,proto3" json:"parentSpanId,omitempty"`
+	Name              string      `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+	Kind              SpanKind    `protobuf:"varint,6,opt,name=kind,proto3" json:"kind,omitempty"`
+	StartTimeUnixNano Uint64      `protobuf:"fixed64,7,opt,name=start_time_unix_nano,proto3" json:"startTimeUnixNano,omitempty"`
+	EndTimeUnixNano   Uint64      `protobuf:"fixed64,8,opt,name=end_time_unix_nano,proto3" json:"endTimeUnixNano,omitempty"`
+	Attributes        []*KeyValue `protobuf:"bytes,9,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	Events            []*Event    `protobuf:"bytes,11,rep,name=events,proto3" json:"events,omitempty"`
+	Status            *Status     `protobuf:"bytes,15,opt,name=status,proto3" json:"status,omitempty"`
+}
+
+// Event is a timestamped annotation on a span
+type Event struct {
+	TimeUnixNano Uint64      `protobuf:"fixed64,1,opt,name=time_unix_nano,proto3" json:"timeUnixNano,omitempty"`
+	Name         string      `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Attributes   []*KeyValue `protobuf:"bytes,3,rep,name=attributes,proto3" json:"attributes,omitempty"`
+}
+
+// Status is the result of the operation a span represents
+type Status struct {
+	Message string     `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Code    StatusCode `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+}
+
+func (m *ExportTraceServiceRequest) Reset()          { *m = ExportTraceServiceRequest{} }
+func (m *ExportTraceServiceRequest) String() string  { return proto.CompactTextString(m) }
+func (*ExportTraceServiceRequest) ProtoMessage()     {}
+func (m *ExportTraceServiceResponse) Reset()         { *m = ExportTraceServiceResponse{} }
+func (m *ExportTraceServiceResponse) String() string { return proto.CompactTextString(m) }
+func (*ExportTraceServiceResponse) ProtoMessage()    {}
+func (m *ResourceSpans) Reset()                      { *m = ResourceSpans{} }
+func (m *ResourceSpans) String() string              { return proto.CompactTextString(m) }
+func (*ResourceSpans) ProtoMessage()                 {}
+func (m *ScopeSpans) Reset()                         { *m = ScopeSpans{} }
+func (m *ScopeSpans) String() string                 { return proto.CompactTextString(m) }
+func (*ScopeSpans) ProtoMessage()                    {}
+func (m *Span) Reset()                               { *m = Span{} }
+func (m *Span) String() string                       { return proto.CompactTextString(m) }
+func (*Span) ProtoMessage()                          {}
+func (m *Event) Reset()                              { *m = Event{} }
+func (m *Event) String() string                      { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()                         {}
+func (m *Status) Reset()                             { *m = Status{} }
+func (m *Status) String() string                     { return proto.CompactTextString(m) }
+func (*Status) ProtoMessage()                        {}
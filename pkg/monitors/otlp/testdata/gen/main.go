@@ -0,0 +1,136 @@
+// Generates the OTLP protobuf fixtures in testdata with the upstream OTLP
+// protos (go.opentelemetry.io/proto/otlp v0.19.0), so that the monitor's
+// hand-written decoding types are checked against real client bytes.  It is
+// not part of the agent build, run it from a scratch module that requires
+// the upstream protos with:
+//
+//	go run main.go <path to pkg/monitors/otlp/testdata>
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	collmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	colltrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	common "go.opentelemetry.io/proto/otlp/common/v1"
+	metrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resource "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func str(k, v string) *common.KeyValue {
+	return &common.KeyValue{Key: k, Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: v}}}
+}
+
+func i64(k string, v int64) *common.KeyValue {
+	return &common.KeyValue{Key: k, Value: &common.AnyValue{Value: &common.AnyValue_IntValue{IntValue: v}}}
+}
+
+func boolean(k string, v bool) *common.KeyValue {
+	return &common.KeyValue{Key: k, Value: &common.AnyValue{Value: &common.AnyValue_BoolValue{BoolValue: v}}}
+}
+
+func f64p(f float64) *float64 { return &f }
+
+func main() {
+	traceRes := &resource.Resource{Attributes: []*common.KeyValue{
+		str("service.name", "checkout"),
+		str("host.name", "web1"),
+		i64("process.pid", 123),
+	}}
+	res := &resource.Resource{Attributes: append(traceRes.Attributes, boolean("cloud.preemptible", true))}
+
+	metricsReq := &collmetrics.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metrics.ResourceMetrics{{
+			Resource: res,
+			ScopeMetrics: []*metrics.ScopeMetrics{{
+				Scope: &common.InstrumentationScope{Name: "io.opentelemetry.runtime", Version: "1.0"},
+				Metrics: []*metrics.Metric{
+					{
+						Name: "queue.size",
+						Unit: "1",
+						Data: &metrics.Metric_Gauge{Gauge: &metrics.Gauge{DataPoints: []*metrics.NumberDataPoint{{
+							TimeUnixNano: 1544712660000000000,
+							Attributes:   []*common.KeyValue{str("queue.name", "orders")},
+							Value:        &metrics.NumberDataPoint_AsInt{AsInt: 5},
+						}}}},
+					},
+					{
+						Name: "cpu.utilization",
+						Data: &metrics.Metric_Gauge{Gauge: &metrics.Gauge{DataPoints: []*metrics.NumberDataPoint{{
+							TimeUnixNano: 1544712660000000000,
+							Value:        &metrics.NumberDataPoint_AsDouble{AsDouble: 0.25},
+						}}}},
+					},
+					{
+						Name: "requests",
+						Data: &metrics.Metric_Sum{Sum: &metrics.Sum{
+							AggregationTemporality: metrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+							IsMonotonic:            true,
+							DataPoints: []*metrics.NumberDataPoint{{
+								StartTimeUnixNano: 1544712600000000000,
+								TimeUnixNano:      1544712660000000000,
+								Value:             &metrics.NumberDataPoint_AsInt{AsInt: -1},
+							}},
+						}},
+					},
+					{
+						Name: "latency",
+						Unit: "ms",
+						Data: &metrics.Metric_Histogram{Histogram: &metrics.Histogram{
+							AggregationTemporality: metrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+							DataPoints: []*metrics.HistogramDataPoint{{
+								StartTimeUnixNano: 1544712600000000000,
+								TimeUnixNano:      1544712660000000000,
+								Count:             6,
+								Sum:               f64p(12.5),
+								BucketCounts:      []uint64{1, 2, 3},
+								ExplicitBounds:    []float64{1, 10},
+								Min:               f64p(0.5),
+								Max:               f64p(20),
+							}},
+						}},
+					},
+				},
+			}},
+		}},
+	}
+
+	traceReq := &colltrace.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			Resource: traceRes,
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Scope: &common.InstrumentationScope{Name: "io.opentelemetry.http", Version: "1.0"},
+				Spans: []*tracepb.Span{{
+					TraceId:           []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+					SpanId:            []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74},
+					ParentSpanId:      []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x73},
+					Name:              "POST /cart",
+					Kind:              tracepb.Span_SPAN_KIND_SERVER,
+					StartTimeUnixNano: 1544712660000000000,
+					EndTimeUnixNano:   1544712661500000000,
+					Attributes:        []*common.KeyValue{i64("http.status_code", 500)},
+					Events: []*tracepb.Span_Event{
+						{TimeUnixNano: 1544712660300000000, Name: "retry"},
+						{TimeUnixNano: 1544712660500000000, Name: "exception", Attributes: []*common.KeyValue{str("exception.type", "Timeout")}},
+					},
+					Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "boom"},
+				}},
+			}},
+		}},
+	}
+
+	dir := os.Args[1]
+	for name, msg := range map[string]proto.Message{"metrics.pb": metricsReq, "traces.pb": traceReq} {
+		b, err := proto.Marshal(msg)
+		if err != nil {
+			panic(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			panic(err)
+		}
+	}
+}
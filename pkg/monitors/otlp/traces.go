@@ -0,0 +1,130 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/signalfx/golib/v3/pointer"
+	"github.com/signalfx/golib/v3/trace"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/otlp/otlpproto"
+)
+
+const serviceNameAttribute = "service.name"
+
+var spanKinds = map[otlpproto.SpanKind]string{
+	otlpproto.SpanKindServer:   "SERVER",
+	otlpproto.SpanKindClient:   "CLIENT",
+	otlpproto.SpanKindProducer: "PRODUCER",
+	otlpproto.SpanKindConsumer: "CONSUMER",
+}
+
+var statusCodes = map[otlpproto.StatusCode]string{
+	otlpproto.StatusCodeOk:    "OK",
+	otlpproto.StatusCodeError: "ERROR",
+}
+
+// convertTraces converts the OTLP spans in the request to SignalFx spans.
+// The resource attributes, other than the service name, are added as tags on
+// each span.
+func (c *converter) convertTraces(req *otlpproto.ExportTraceServiceRequest) []*trace.Span {
+	var out []*trace.Span
+	for _, rs := range req.ResourceSpans {
+		resourceTags := c.resourceAttributes(rs.Resource)
+		serviceName, hasServiceName := resourceTags[serviceNameAttribute]
+		delete(resourceTags, serviceNameAttribute)
+
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				span := convertSpan(s, resourceTags, ss.Scope)
+				if hasServiceName {
+					span.LocalEndpoint = &trace.Endpoint{ServiceName: pointer.String(serviceName)}
+				}
+				out = append(out, span)
+			}
+		}
+	}
+	return out
+}
+
+func convertSpan(s *otlpproto.Span, resourceTags map[string]string, scope *otlpproto.InstrumentationScope) *trace.Span {
+	tags := make(map[string]string, len(resourceTags)+len(s.Attributes)+3)
+	for k, v := range resourceTags {
+		tags[k] = v
+	}
+	for _, kv := range s.Attributes {
+		tags[kv.Key] = kv.Value.AsString()
+	}
+
+	if scope != nil && scope.Name != "" {
+		tags["otel.scope.name"] = scope.Name
+		if scope.Version != "" {
+			tags["otel.scope.version"] = scope.Version
+		}
+	}
+
+	if s.Status != nil {
+		if code, ok := statusCodes[s.Status.Code]; ok {
+			tags["otel.status_code"] = code
+		}
+		if s.Status.Message != "" {
+			tags["otel.status_description"] = s.Status.Message
+		}
+		if s.Status.Code == otlpproto.StatusCodeError {
+			tags["error"] = "true"
+		}
+	}
+
+	span := &trace.Span{
+		TraceID:     hex.EncodeToString(s.TraceID),
+		ID:          hex.EncodeToString(s.SpanID),
+		Name:        pointer.String(s.Name),
+		Timestamp:   pointer.Int64(int64(s.StartTimeUnixNano) / 1000),
+		Annotations: convertEvents(s.Events),
+		Tags:        tags,
+	}
+
+	// Span durations are in microseconds
+	if s.EndTimeUnixNano >= s.StartTimeUnixNano {
+		span.Duration = pointer.Int64(int64(s.EndTimeUnixNano-s.StartTimeUnixNano) / 1000)
+	}
+
+	if len(s.ParentSpanID) > 0 {
+		span.ParentID = pointer.String(hex.EncodeToString(s.ParentSpanID))
+	}
+
+	if kind, ok := spanKinds[s.Kind]; ok {
+		span.Kind = pointer.String(kind)
+	}
+
+	return span
+}
+
+// convertEvents converts span events to annotations.  Events with attributes
+// are encoded as JSON with the event name in the `event` field, like the
+// Jaeger log conversion.
+func convertEvents(events []*otlpproto.Event) []*trace.Annotation {
+	if len(events) == 0 {
+		return nil
+	}
+
+	out := make([]*trace.Annotation, 0, len(events))
+	for _, e := range events {
+		value := e.Name
+		if len(e.Attributes) > 0 {
+			fields := make(map[string]string, len(e.Attributes)+1)
+			for _, kv := range e.Attributes {
+				fields[kv.Key] = kv.Value.AsString()
+			}
+			fields["event"] = e.Name
+			if encoded, err := json.Marshal(fields); err == nil {
+				value = string(encoded)
+			}
+		}
+
+		out = append(out, &trace.Annotation{
+			Timestamp: pointer.Int64(int64(e.TimeUnixNano) / 1000),
+			Value:     pointer.String(value),
+		})
+	}
+	return out
+}
@@ -25,6 +25,8 @@ type monitorOutput struct {
 	dimensionChan             chan<- *types.Dimension
 	extraDims                 map[string]string
 	dimensionTransformations  map[string]string
+	// Shared by copies of the output, may be nil
+	health *monitorHealth
 }
 
 var _ types.Output = &monitorOutput{}
@@ -50,6 +52,9 @@ func (mo *monitorOutput) SendDatapoints(dps ...*datapoint.Datapoint) {
 
 	if n > 0 {
 		mo.dpChan <- dps[:n]
+		if mo.health != nil {
+			mo.health.emitted(n, 0, 0)
+		}
 	}
 }
 
@@ -103,6 +108,9 @@ func (mo *monitorOutput) SendEvent(event *event.Event) {
 		event.Properties[dpmeta.NotHostSpecificMeta] = true
 	}
 	mo.eventChan <- event
+	if mo.health != nil {
+		mo.health.emitted(0, 1, 0)
+	}
 }
 
 func (mo *monitorOutput) SendSpans(spans ...*trace.Span) {
@@ -114,6 +122,9 @@ func (mo *monitorOutput) SendSpans(spans ...*trace.Span) {
 	}
 
 	mo.spanChan <- spans
+	if mo.health != nil {
+		mo.health.emitted(0, 0, len(spans))
+	}
 }
 
 func (mo *monitorOutput) SendDimensionUpdate(dimensions *types.Dimension) {
@@ -3,6 +3,7 @@ package postgresql
 import (
 	"context"
 	dbsql "database/sql"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -81,6 +82,8 @@ type Monitor struct {
 	monitoredDBs      map[string]*sql.Monitor
 	serverMonitor     *sql.Monitor
 	statementsMonitor *sql.Monitor
+
+	updateDatabasesNow func() error
 }
 
 // Configure the monitor and kick off metric collection
@@ -138,13 +141,13 @@ func (m *Monitor) Configure(conf *Config) error {
 		}
 	}
 
-	utils.RunOnInterval(m.ctx, func() {
+	m.updateDatabasesNow = utils.RunOnIntervalOrDemand(m.ctx, func() error {
 		m.Lock()
 		defer m.Unlock()
 
 		// This means the monitor is shutdown
 		if m.ctx.Err() != nil {
-			return
+			return m.ctx.Err()
 		}
 
 		databases, err := m.determineDatabases()
@@ -180,11 +183,36 @@ func (m *Monitor) Configure(conf *Config) error {
 				delete(m.monitoredDBs, name)
 			}
 		}
+		return err
 	}, dbPollInterval)
 
 	return nil
 }
 
+// CollectNow updates the list of databases and runs the queries of all of
+// the nested sql monitors once right away
+func (m *Monitor) CollectNow() error {
+	if m.updateDatabasesNow == nil {
+		return errors.New("monitor is not configured")
+	}
+	if err := m.updateDatabasesNow(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	collectors := []func() error{m.serverMonitor.CollectNow}
+	if m.statementsMonitor != nil {
+		collectors = append(collectors, m.statementsMonitor.CollectNow)
+	}
+	for _, dbMon := range m.monitoredDBs {
+		collectors = append(collectors, dbMon.CollectNow)
+	}
+	// The queries can take a while, so don't block shutting down on them
+	m.Unlock()
+
+	return monitors.CollectAll(collectors...)
+}
+
 func (m *Monitor) startMonitoringDatabase(name string) (*sql.Monitor, error) {
 	connStr, err := m.conf.connStr()
 	if err != nil {
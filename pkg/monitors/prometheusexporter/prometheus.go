@@ -77,7 +77,15 @@ type Monitor struct {
 
 	monitorName string
 	logger      logrus.FieldLogger
+	health      types.HealthReporter
 	cancel      func()
+	collectNow  func() error
+}
+
+// SetHealthReporter is called by the monitor manager so that scrape errors
+// show up in the health of the monitor
+func (m *Monitor) SetHealthReporter(health types.HealthReporter) {
+	m.health = health
 }
 
 type fetcher func() (io.ReadCloser, expfmt.Format, error)
@@ -134,11 +142,17 @@ func (m *Monitor) Configure(conf *Config) error {
 
 	var ctx context.Context
 	ctx, m.cancel = context.WithCancel(context.Background())
-	utils.RunOnInterval(ctx, func() {
+	m.collectNow = utils.RunOnIntervalOrDemand(ctx, func() error {
 		dps, err := fetchPrometheusMetrics(fetch)
 		if err != nil {
 			m.logger.WithError(err).Error("Could not get prometheus metrics")
-			return
+			if m.health != nil {
+				m.health.CollectionFailed(err)
+			}
+			return err
+		}
+		if m.health != nil {
+			m.health.CollectionSucceeded()
 		}
 
 		now := time.Now()
@@ -146,11 +160,20 @@ func (m *Monitor) Configure(conf *Config) error {
 			dps[i].Timestamp = now
 		}
 		m.Output.SendDatapoints(dps...)
+		return nil
 	}, time.Duration(conf.IntervalSeconds)*time.Second)
 
 	return nil
 }
 
+// CollectNow scrapes the exporter and sends the metrics once right away
+func (m *Monitor) CollectNow() error {
+	if m.collectNow == nil {
+		return errors.New("monitor is not configured")
+	}
+	return m.collectNow()
+}
+
 func fetchPrometheusMetrics(fetch fetcher) ([]*datapoint.Datapoint, error) {
 	metricFamilies, err := doFetch(fetch)
 	if err != nil {
@@ -0,0 +1,156 @@
+package prometheusexporter
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/neotest"
+	"github.com/stretchr/testify/require"
+)
+
+const exporterText = `# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+http_requests_total{method="post",code="400"}    3 1395066363000
+# HELP queue_length Current length of the "queue" \\ with escapes
+# TYPE queue_length gauge
+queue_length{path="C:\\DIR\\FILE.TXT",error="Cannot find file:\n\"FILE.TXT\""} 1.5
+# A comment that is ignored
+plain_value 4
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 4773
+rpc_duration_seconds{quantile="0.99"} 76656
+rpc_duration_seconds_sum 1.7560473e+07
+rpc_duration_seconds_count 2693
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.05"} 24054
+request_duration_seconds_bucket{le="+Inf"} 144320
+request_duration_seconds_sum 53423
+request_duration_seconds_count 144320
+`
+
+func fetcherFor(content []byte, format expfmt.Format) fetcher {
+	return func() (io.ReadCloser, expfmt.Format, error) {
+		return ioutil.NopCloser(bytes.NewReader(content)), format, nil
+	}
+}
+
+func dpsByMetric(dps []*datapoint.Datapoint) map[string][]*datapoint.Datapoint {
+	out := map[string][]*datapoint.Datapoint{}
+	for _, dp := range dps {
+		out[dp.Metric] = append(out[dp.Metric], dp)
+	}
+	return out
+}
+
+func TestFetchPrometheusMetricsText(t *testing.T) {
+	dps, err := fetchPrometheusMetrics(fetcherFor([]byte(exporterText), expfmt.FmtText))
+	require.NoError(t, err)
+
+	byMetric := dpsByMetric(dps)
+	require.Len(t, dps, 12)
+
+	require.Len(t, byMetric["http_requests_total"], 2)
+	require.Equal(t, datapoint.Counter, byMetric["http_requests_total"][0].MetricType)
+	require.Equal(t, map[string]string{"method": "post", "code": "200"}, byMetric["http_requests_total"][0].Dimensions)
+	require.Equal(t, datapoint.NewFloatValue(1027), byMetric["http_requests_total"][0].Value)
+
+	require.Equal(t, map[string]string{
+		"path":  `C:\DIR\FILE.TXT`,
+		"error": "Cannot find file:\n\"FILE.TXT\"",
+	}, byMetric["queue_length"][0].Dimensions)
+	require.Equal(t, datapoint.NewFloatValue(1.5), byMetric["queue_length"][0].Value)
+
+	require.Equal(t, datapoint.Gauge, byMetric["plain_value"][0].MetricType)
+
+	require.Len(t, byMetric["rpc_duration_seconds_quantile"], 2)
+	require.Equal(t, "0.990000", byMetric["rpc_duration_seconds_quantile"][1].Dimensions["quantile"])
+	require.Equal(t, datapoint.NewIntValue(2693), byMetric["rpc_duration_seconds_count"][0].Value)
+
+	require.Len(t, byMetric["request_duration_seconds_bucket"], 2)
+	require.Equal(t, "0.050000", byMetric["request_duration_seconds_bucket"][0].Dimensions["upper_bound"])
+	require.Equal(t, datapoint.NewIntValue(144320), byMetric["request_duration_seconds_count"][0].Value)
+}
+
+func TestFetchPrometheusMetricsProtobuf(t *testing.T) {
+	name := "queue_length"
+	metricType := dto.MetricType_GAUGE
+	value := 7.0
+	labelName, labelValue := "queue", "a"
+
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.FmtProtoDelim)
+	require.NoError(t, enc.Encode(&dto.MetricFamily{
+		Name: &name,
+		Type: &metricType,
+		Metric: []*dto.Metric{{
+			Label: []*dto.LabelPair{{Name: &labelName, Value: &labelValue}},
+			Gauge: &dto.Gauge{Value: &value},
+		}},
+	}))
+
+	dps, err := fetchPrometheusMetrics(fetcherFor(buf.Bytes(), expfmt.FmtProtoDelim))
+	require.NoError(t, err)
+	require.Len(t, dps, 1)
+	require.Equal(t, "queue_length", dps[0].Metric)
+	require.Equal(t, map[string]string{"queue": "a"}, dps[0].Dimensions)
+	require.Equal(t, datapoint.NewFloatValue(7), dps[0].Value)
+}
+
+func TestFetchPrometheusMetricsInvalidText(t *testing.T) {
+	_, err := fetchPrometheusMetrics(fetcherFor([]byte(strings.Replace(exporterText, "1.5", "one", 1)), expfmt.FmtText))
+	require.Error(t, err)
+}
+
+func TestCollectNow(t *testing.T) {
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			rw.WriteHeader(500)
+			return
+		}
+		_, _ = rw.Write([]byte(exporterText))
+	}))
+	defer server.Close()
+
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	require.NoError(t, err)
+
+	output := neotest.NewTestOutput()
+	m := &Monitor{Output: output}
+	require.Error(t, m.CollectNow())
+
+	require.NoError(t, m.Configure(&Config{
+		MonitorConfig: config.MonitorConfig{IntervalSeconds: 3600},
+		Host:          host,
+		Port:          uint16(port),
+		MetricPath:    "/metrics",
+	}))
+	defer m.Shutdown()
+
+	// The first collection on the interval might have happened already
+	require.NoError(t, m.CollectNow())
+	require.Contains(t, []int{12, 24}, len(output.FlushDatapoints()))
+
+	atomic.StoreInt32(&failing, 1)
+	err = m.CollectNow()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "returned status 500")
+
+	m.Shutdown()
+	require.Error(t, m.CollectNow())
+}
@@ -0,0 +1,75 @@
+package monitors
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/common/httpclient"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/recording"
+	"github.com/signalfx/signalfx-agent/pkg/core/services"
+)
+
+// InputRecordable is implemented by monitors that can record and replay the
+// inputs that they receive by some means other than the common HTTP client
+// config, such as subprocess monitors.
+type InputRecordable interface {
+	SetInputRecorder(*recording.MonitorRecorder)
+}
+
+// SetInputRecording makes all monitors created from now on record their
+// inputs to, or replay them from, the bundle.  Pass nil to stop recording.
+func (mm *MonitorManager) SetInputRecording(bundle *recording.Bundle) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+
+	mm.inputRecording = bundle
+}
+
+// Dimensions of discovered endpoints that stay the same when the
+// container or pod behind them is recreated
+//nolint: gochecknoglobals
+var stableEndpointDimensions = []string{"container_image", "container_spec_name", "kubernetes_namespace"}
+
+// inputRecordingKey identifies a monitor instance in a recording bundle.
+// Host specific config is left out so that a bundle can be replayed on
+// another host.  Discovered endpoints are identified by what they are,
+// instead of by their ID, since the ID contains container and pod IDs that
+// are different every time they are discovered.
+func inputRecordingKey(conf config.MonitorCustomConfig, endpoint services.Endpoint) string {
+	core := *conf.MonitorConfigCore()
+	core.Hostname = ""
+	core.ProcPath = ""
+	core.ConfigFile = ""
+
+	key := fmt.Sprintf("%s/%d", core.Type, core.Hash())
+	if endpoint != nil {
+		ec := endpoint.Core()
+		key += fmt.Sprintf("/%s/%s/%s/%s/%d", ec.DiscoveredBy, ec.Target, ec.Name, ec.PortType, ec.Port)
+
+		dims := endpoint.Dimensions()
+		for _, dim := range stableEndpointDimensions {
+			if dims[dim] != "" {
+				key += fmt.Sprintf("/%s=%s", dim, dims[dim])
+			}
+		}
+	}
+	return key
+}
+
+// AttachInputRecorder hooks up the recorder to the HTTP client config of the
+// monitor config, if it has one, and to the monitor itself if it supports
+// recording.  It must be called before the monitor is configured.  The
+// monitor manager does this for the monitors it creates.
+func AttachInputRecorder(instance interface{}, conf config.MonitorCustomConfig, recorder *recording.MonitorRecorder) {
+	httpConfValue := reflect.Indirect(reflect.ValueOf(conf)).FieldByName("HTTPConfig")
+	if httpConfValue.IsValid() && httpConfValue.CanSet() {
+		if httpConf, ok := httpConfValue.Addr().Interface().(*httpclient.HTTPConfig); ok {
+			httpConf.InputRecorder = recorder
+		}
+	}
+
+	if r, ok := instance.(InputRecordable); ok {
+		r.SetInputRecorder(recorder)
+	}
+}
@@ -0,0 +1,43 @@
+package monitors
+
+import (
+	"testing"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInputRecordingKey(t *testing.T) {
+	conf := &config.MonitorConfig{Type: "redis", IntervalSeconds: 10}
+
+	endpoint := func(id, podName string) services.Endpoint {
+		ec := services.NewEndpointCore(id, "redis", "k8s-api", map[string]string{
+			"container_image":      "redis:5",
+			"kubernetes_namespace": "default",
+			"kubernetes_pod_name":  podName,
+			"kubernetes_pod_uid":   id,
+		})
+		ec.Port = 6379
+		return ec
+	}
+
+	t.Run("Is the same when a pod is recreated", func(t *testing.T) {
+		assert.Equal(t,
+			inputRecordingKey(conf, endpoint("abc-6379", "redis-7d8f9")),
+			inputRecordingKey(conf, endpoint("def-6379", "redis-2b4c6")))
+	})
+
+	t.Run("Differs by port", func(t *testing.T) {
+		other := endpoint("abc-6380", "redis-7d8f9")
+		other.Core().Port = 6380
+		assert.NotEqual(t, inputRecordingKey(conf, endpoint("abc-6379", "redis-7d8f9")), inputRecordingKey(conf, other))
+	})
+
+	t.Run("Ignores host specific config", func(t *testing.T) {
+		other := *conf
+		other.Hostname = "other"
+		other.ProcPath = "/hostfs/proc"
+		assert.Equal(t, inputRecordingKey(conf, nil), inputRecordingKey(&other, nil))
+	})
+}
@@ -0,0 +1,36 @@
+package sql
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/signalfx/golib/v3/event"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+)
+
+func makeEvent(conf *Event, row map[string]interface{}) *event.Event {
+	dims := make(map[string]string, len(conf.DimensionColumns))
+	for _, col := range conf.DimensionColumns {
+		dims[col] = rowValueAsString(row[strings.ToLower(col)])
+	}
+
+	props := make(map[string]interface{}, len(conf.PropertyColumns))
+	for _, col := range conf.PropertyColumns {
+		if v := row[strings.ToLower(col)]; v != nil {
+			props[col] = v
+		}
+	}
+
+	return event.NewWithProperties(conf.EventType, event.AGENT, utils.RemoveEmptyMapValues(dims), props, time.Now())
+}
+
+func rowValueAsString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package sql
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+)
+
+var defaultPercentiles = []float64{50, 90, 99}
+
+// histogramAccumulator collects the values for each configured histogram
+// across all of the rows of a single query execution.
+type histogramAccumulator struct {
+	histograms []Histogram
+	// One map per histogram of the encoded dimensions to the group of values
+	// with those dimensions.
+	groups []map[string]*valueGroup
+}
+
+type valueGroup struct {
+	dims   map[string]string
+	values []float64
+}
+
+func newHistogramAccumulator(histograms []Histogram) *histogramAccumulator {
+	groups := make([]map[string]*valueGroup, len(histograms))
+	for i := range groups {
+		groups[i] = map[string]*valueGroup{}
+	}
+	return &histogramAccumulator{
+		histograms: histograms,
+		groups:     groups,
+	}
+}
+
+func (h *histogramAccumulator) addRow(row map[string]interface{}) {
+	for i, hist := range h.histograms {
+		val, ok := row[strings.ToLower(hist.ValueColumn)].(float64)
+		if !ok {
+			// Null values are not part of the distribution
+			continue
+		}
+
+		dims := make(map[string]string, len(hist.DimensionColumns))
+		keyParts := make([]string, len(hist.DimensionColumns))
+		for j, col := range hist.DimensionColumns {
+			dims[col] = rowValueAsString(row[strings.ToLower(col)])
+			keyParts[j] = dims[col]
+		}
+		key := strings.Join(keyParts, "\x00")
+
+		group := h.groups[i][key]
+		if group == nil {
+			group = &valueGroup{dims: dims}
+			h.groups[i][key] = group
+		}
+		group.values = append(group.values, val)
+	}
+}
+
+func (h *histogramAccumulator) datapoints() []*datapoint.Datapoint {
+	var dps []*datapoint.Datapoint
+	for i, hist := range h.histograms {
+		percentiles := hist.Percentiles
+		if len(percentiles) == 0 {
+			percentiles = defaultPercentiles
+		}
+
+		for _, group := range h.groups[i] {
+			dps = append(dps, makeHistogramDatapoints(&hist, percentiles, group)...)
+		}
+	}
+	return dps
+}
+
+func makeHistogramDatapoints(hist *Histogram, percentiles []float64, group *valueGroup) []*datapoint.Datapoint {
+	values := group.values
+	sort.Float64s(values)
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	gauge := func(suffix string, dims map[string]string, val float64) *datapoint.Datapoint {
+		return datapoint.New(hist.MetricName+suffix, dims, datapoint.NewFloatValue(val), datapoint.Gauge, time.Time{})
+	}
+
+	dps := []*datapoint.Datapoint{
+		datapoint.New(hist.MetricName+"_count", group.dims, datapoint.NewIntValue(int64(len(values))), datapoint.Gauge, time.Time{}),
+		gauge("_sum", group.dims, sum),
+		gauge("_min", group.dims, values[0]),
+		gauge("_max", group.dims, values[len(values)-1]),
+	}
+
+	for _, p := range percentiles {
+		quantileDims := utils.MergeStringMaps(group.dims, map[string]string{
+			"quantile": strconv.FormatFloat(p/100, 'f', 6, 64),
+		})
+		dps = append(dps, gauge("_quantile", quantileDims, percentile(values, p)))
+	}
+
+	for _, bound := range hist.Buckets {
+		bucketDims := utils.MergeStringMaps(group.dims, map[string]string{
+			"upper_bound": strconv.FormatFloat(bound, 'f', 6, 64),
+		})
+		// Values are sorted so this is the number of values <= bound
+		count := sort.Search(len(values), func(j int) bool { return values[j] > bound })
+		dps = append(dps, datapoint.New(hist.MetricName+"_bucket", bucketDims, datapoint.NewIntValue(int64(count)), datapoint.Gauge, time.Time{}))
+	}
+
+	return dps
+}
+
+// percentile computes the p-th percentile (0-100) of the sorted values by
+// linearly interpolating between the closest ranks.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
@@ -0,0 +1,57 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5}
+	require.Equal(t, 1.0, percentile(values, 0))
+	require.Equal(t, 3.0, percentile(values, 50))
+	require.Equal(t, 5.0, percentile(values, 100))
+	require.InDelta(t, 4.6, percentile(values, 90), 0.0001)
+	require.Equal(t, 7.0, percentile([]float64{7}, 99))
+}
+
+func TestHistogramAccumulator(t *testing.T) {
+	acc := newHistogramAccumulator([]Histogram{
+		{
+			MetricName:       "latency",
+			ValueColumn:      "Duration",
+			DimensionColumns: []string{"region"},
+			Percentiles:      []float64{50},
+			Buckets:          []float64{10, 100},
+		},
+	})
+
+	acc.addRow(map[string]interface{}{"duration": 5.0, "region": "east"})
+	acc.addRow(map[string]interface{}{"duration": 50.0, "region": "east"})
+	acc.addRow(map[string]interface{}{"duration": 500.0, "region": "east"})
+	acc.addRow(map[string]interface{}{"duration": nil, "region": "east"})
+	acc.addRow(map[string]interface{}{"duration": 1.0, "region": "west"})
+
+	values := map[string]string{}
+	for _, dp := range acc.datapoints() {
+		key := dp.Metric + "/" + dp.Dimensions["region"] + "/" + dp.Dimensions["quantile"] + dp.Dimensions["upper_bound"]
+		values[key] = dp.Value.String()
+	}
+
+	require.Equal(t, map[string]string{
+		"latency_count/east/":            "3",
+		"latency_sum/east/":              "555",
+		"latency_min/east/":              "5",
+		"latency_max/east/":              "500",
+		"latency_quantile/east/0.500000": "50",
+		"latency_bucket/east/10.000000":  "1",
+		"latency_bucket/east/100.000000": "2",
+		"latency_count/west/":            "1",
+		"latency_sum/west/":              "1",
+		"latency_min/west/":              "1",
+		"latency_max/west/":              "1",
+		"latency_quantile/west/0.500000": "1",
+		"latency_bucket/west/10.000000":  "1",
+		"latency_bucket/west/100.000000": "1",
+	}, values)
+}
@@ -17,10 +17,23 @@ import (
 	_ "github.com/denisenkom/go-mssqldb"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
+	_ "github.com/sijms/go-ora"
+	_ "modernc.org/sqlite"
 )
 
 var logger = logrus.WithFields(logrus.Fields{"monitorType": monitorMetadata.MonitorType})
 
+// The names of the database/sql drivers that are registered by the imports
+// above.
+var supportedDrivers = map[string]bool{
+	"postgres":  true,
+	"mysql":     true,
+	"mssql":     true,
+	"sqlserver": true,
+	"sqlite":    true,
+	"oracle":    true,
+}
+
 func init() {
 	monitors.Register(&monitorMetadata, func() interface{} { return &Monitor{} }, &Config{})
 }
@@ -33,6 +46,32 @@ type Query struct {
 	Params []interface{} `yaml:"params"`
 	// Metrics that should be generated from the query.
 	Metrics []Metric `yaml:"metrics"`
+	// Events that should be generated from the query.  One event is sent for
+	// each row of the query result.
+	Events []Event `yaml:"events"`
+	// Distributions that should be computed from the values of a column
+	// across all rows of the query result.
+	Histograms []Histogram `yaml:"histograms"`
+	// How frequently to run this query, in seconds.  If not set, the
+	// monitor's `intervalSeconds` is used.
+	IntervalSeconds int `yaml:"intervalSeconds"`
+	// How long to wait for the query to complete before cancelling it, in
+	// seconds.  If not set, the query's interval is used as the timeout.
+	TimeoutSeconds int `yaml:"timeoutSeconds"`
+}
+
+func (q *Query) interval(defaultInterval int) time.Duration {
+	if q.IntervalSeconds > 0 {
+		return time.Duration(q.IntervalSeconds) * time.Second
+	}
+	return time.Duration(defaultInterval) * time.Second
+}
+
+func (q *Query) timeout(defaultInterval int) time.Duration {
+	if q.TimeoutSeconds > 0 {
+		return time.Duration(q.TimeoutSeconds) * time.Second
+	}
+	return q.interval(defaultInterval)
 }
 
 // Metric describes how to derive a metric from the individual rows of a query
@@ -55,6 +94,44 @@ type Metric struct {
 	DimensionPropertyColumns map[string][]string `yaml:"dimensionPropertyColumns"`
 }
 
+// Event describes how to derive a SignalFx event from the individual rows of
+// a query result.
+type Event struct {
+	// The event type of the events as it will appear in SignalFx.
+	EventType string `yaml:"eventType" validate:"required"`
+	// The names of the columns that should make up the dimensions of the
+	// event.
+	DimensionColumns []string `yaml:"dimensionColumns"`
+	// The names of the columns that should be sent as properties of the
+	// event.
+	PropertyColumns []string `yaml:"propertyColumns"`
+}
+
+// Histogram describes how to summarize the values of a column across all of
+// the rows of a query result into a distribution that is computed in the
+// agent.  The rows are grouped by the values of the dimension columns and the
+// following gauges are sent for each group: `<metricName>_count`,
+// `<metricName>_sum`, `<metricName>_min`, `<metricName>_max`,
+// `<metricName>_quantile` (with a `quantile` dimension for each percentile)
+// and, if `buckets` is set, `<metricName>_bucket` (with an `upper_bound`
+// dimension for each bucket).
+type Histogram struct {
+	// The base name of the metrics as they will appear in SignalFx.
+	MetricName string `yaml:"metricName" validate:"required"`
+	// The column name that holds the values to summarize
+	ValueColumn string `yaml:"valueColumn" validate:"required"`
+	// The names of the columns that should make up the dimensions of the
+	// datapoints.  Rows with the same values in these columns are summarized
+	// together.
+	DimensionColumns []string `yaml:"dimensionColumns"`
+	// The percentiles (between 0 and 100) to compute.  Defaults to 50, 90
+	// and 99 if not set.
+	Percentiles []float64 `yaml:"percentiles"`
+	// The upper bounds of the buckets to count values into.  The count for
+	// each bucket includes all values less than or equal to its upper bound.
+	Buckets []float64 `yaml:"buckets"`
+}
+
 // Config for this monitor
 type Config struct {
 	config.MonitorConfig `yaml:",inline" acceptsEndpoints:"true"`
@@ -66,7 +143,8 @@ type Config struct {
 	// Go template syntax (e.g. `{{.key}}`).
 	Params map[string]string `yaml:"params"`
 
-	// The database driver to use, valid values are `postgres` and `mysql`.
+	// The database driver to use, valid values are `postgres`, `mysql`,
+	// `sqlserver` (or its alias `mssql`), `sqlite` and `oracle`.
 	DBDriver string `yaml:"dbDriver"`
 	// A URL or simple option string used to connect to the database.
 	// If using PostgreSQL, [see the list of connection string
@@ -82,7 +160,7 @@ type Config struct {
 
 // Validate that the config is right
 func (c *Config) Validate() error {
-	if c.DBDriver != "postgres" && c.DBDriver != "mysql" {
+	if !supportedDrivers[c.DBDriver] {
 		return fmt.Errorf("database driver %s is not supported", c.DBDriver)
 	}
 
@@ -91,8 +169,15 @@ func (c *Config) Validate() error {
 	}
 
 	for i := range c.Queries {
-		if len(c.Queries[i].Metrics) == 0 {
-			return errors.New("each SQL query must have at least one metric defined on it")
+		if len(c.Queries[i].Metrics) == 0 && len(c.Queries[i].Events) == 0 && len(c.Queries[i].Histograms) == 0 {
+			return errors.New("each SQL query must have at least one metric, event or histogram defined on it")
+		}
+		for _, h := range c.Queries[i].Histograms {
+			for _, p := range h.Percentiles {
+				if p < 0 || p > 100 {
+					return fmt.Errorf("histogram %s has percentile %v that is not between 0 and 100", h.MetricName, p)
+				}
+			}
 		}
 		valueCols := map[string]bool{}
 		for _, met := range c.Queries[i].Metrics {
@@ -118,10 +203,11 @@ func (c *Config) renderedDataSource() (string, error) {
 
 // Monitor for generic SQL queries -> metrics
 type Monitor struct {
-	Output   types.Output
-	database *sql.DB
-	cancel   context.CancelFunc
-	ctx      context.Context
+	Output     types.Output
+	database   *sql.DB
+	cancel     context.CancelFunc
+	ctx        context.Context
+	collectNow []func() error
 }
 
 // Configure the monitor and kick off metric gathering
@@ -143,18 +229,30 @@ func (m *Monitor) Configure(conf *Config) error {
 	}
 
 	for i := range conf.Queries {
-		querier := newQuerier(&conf.Queries[i], conf.LogQueries)
+		query := &conf.Queries[i]
+		querier := newQuerier(query, conf.LogQueries)
+		timeout := query.timeout(conf.IntervalSeconds)
+
+		m.collectNow = append(m.collectNow, utils.RunOnIntervalOrDemand(m.ctx, func() error {
+			ctx, cancel := context.WithTimeout(m.ctx, timeout)
+			defer cancel()
 
-		utils.RunOnInterval(m.ctx, func() {
-			if err := querier.doQuery(m.ctx, m.database, m.Output); err != nil {
+			err := querier.doQuery(ctx, m.database, m.Output)
+			if err != nil {
 				querier.logger.WithError(err).Error("Problem running SQL query or converting datapoints")
 			}
-		}, time.Duration(conf.IntervalSeconds)*time.Second)
+			return err
+		}, query.interval(conf.IntervalSeconds)))
 	}
 
 	return nil
 }
 
+// CollectNow runs all of the queries once right away
+func (m *Monitor) CollectNow() error {
+	return monitors.CollectAll(m.collectNow...)
+}
+
 // Shutdown the monitor and close the DB connection
 func (m *Monitor) Shutdown() {
 	if m.cancel != nil {
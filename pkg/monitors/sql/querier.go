@@ -92,12 +92,25 @@ func (q *querier) doQuery(ctx context.Context, database *sql.DB, output types.Ou
 	if err != nil {
 		return fmt.Errorf("error executing statement %s: %v", q.query.Query, err)
 	}
+	histograms := newHistogramAccumulator(q.query.Histograms)
 	for rows.Next() {
 		dps, dims, err := q.convertCurrentRowToDatapointAndDimensions(rows)
 		if err != nil {
 			return err
 		}
 
+		if len(q.query.Events) > 0 || len(q.query.Histograms) > 0 {
+			row, err := q.currentRowValues(rows)
+			if err != nil {
+				return err
+			}
+
+			for i := range q.query.Events {
+				output.SendEvent(makeEvent(&q.query.Events[i], row))
+			}
+			histograms.addRow(row)
+		}
+
 		// Make a new slice since this montior reuses the backing array of dps
 		outDPs := make([]*datapoint.Datapoint, 0, len(dps))
 		for i := range dps {
@@ -108,7 +121,9 @@ func (q *querier) doQuery(ctx context.Context, database *sql.DB, output types.Ou
 			}
 			outDPs = append(outDPs, dps[i])
 		}
-		output.SendDatapoints(outDPs...)
+		if len(outDPs) > 0 {
+			output.SendDatapoints(outDPs...)
+		}
 
 		for i := range dims {
 			for _, dim := range dims[i] {
@@ -116,9 +131,44 @@ func (q *querier) doQuery(ctx context.Context, database *sql.DB, output types.Ou
 			}
 		}
 	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+
+	if dps := histograms.datapoints(); len(dps) > 0 {
+		output.SendDatapoints(dps...)
+	}
 	return rows.Close()
 }
 
+// currentRowValues returns the values of the last scanned row keyed by the
+// lowercased column name.  Values are either a float64, a string or nil if
+// the column was null.
+func (q *querier) currentRowValues(rows *sql.Rows) (map[string]interface{}, error) {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(columnNames))
+	for i := range q.rowSliceCached {
+		var val interface{}
+		switch v := q.rowSliceCached[i].(type) {
+		case *sql.NullFloat64:
+			if v.Valid {
+				val = v.Float64
+			}
+		case *sql.NullString:
+			if v.Valid {
+				val = v.String
+			}
+		}
+		row[strings.ToLower(columnNames[i])] = val
+	}
+	return row, nil
+}
+
 func (q *querier) convertCurrentRowToDatapointAndDimensions(rows *sql.Rows) ([]*datapoint.Datapoint, [][]*types.Dimension, error) {
 	rowScanSlice, err := q.getRowSlice(rows)
 	if err != nil {
@@ -162,14 +212,15 @@ func (q *querier) convertCurrentRowToDatapointAndDimensions(rows *sql.Rows) ([]*
 	for i := range rowScanSlice {
 		switch v := rowScanSlice[i].(type) {
 		case *sql.NullFloat64:
-			if !v.Valid {
-				return nil, nil, fmt.Errorf("column %d is null", i)
-			}
-
 			metric, ok := q.valueColumnNamesToMetrics[strings.ToLower(columnNames[i])]
 			if !ok || metric == nil {
-				// This is a logical error in the code, not user input error
-				panic("valueColumn was not properly mapped to metric")
+				// This is a value column for a histogram only, which is
+				// handled separately.
+				continue
+			}
+
+			if !v.Valid {
+				return nil, nil, fmt.Errorf("column %d is null", i)
 			}
 
 			dp := q.datapoints[q.metricToIndex[metric]]
@@ -248,10 +299,40 @@ OUTER:
 			}
 
 		}
-		// This column is unused in generating metrics so just make it a string
+
+		for _, hist := range q.query.Histograms {
+			if strings.EqualFold(ct.Name(), hist.ValueColumn) {
+				rowSlice[i] = &sql.NullFloat64{}
+				continue OUTER
+			}
+		}
+
+		// This column is unused in generating metric values so just make it a
+		// string
 		rowSlice[i] = &sql.NullString{}
 	}
 
+	columnsSeen := map[string]bool{}
+	for _, ct := range cts {
+		columnsSeen[strings.ToLower(ct.Name())] = true
+	}
+
+	for _, ev := range q.query.Events {
+		for _, col := range append(append([]string{}, ev.DimensionColumns...), ev.PropertyColumns...) {
+			if !columnsSeen[strings.ToLower(col)] {
+				return nil, fmt.Errorf("event column '%s' does not exist", col)
+			}
+		}
+	}
+
+	for _, hist := range q.query.Histograms {
+		for _, col := range append([]string{hist.ValueColumn}, hist.DimensionColumns...) {
+			if !columnsSeen[strings.ToLower(col)] {
+				return nil, fmt.Errorf("histogram column '%s' does not exist", col)
+			}
+		}
+	}
+
 	for _, metric := range q.query.Metrics {
 		for _, dimCol := range metric.DimensionColumns {
 			if !dimColsSeen[dimCol] {
@@ -17,6 +17,8 @@ import (
 	"time"
 
 	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/recording"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
 	"github.com/signalfx/signalfx-agent/pkg/utils"
 	log "github.com/sirupsen/logrus"
 )
@@ -60,6 +62,11 @@ type MonitorCore struct {
 	// Flag that should be set atomically to tell the goroutine that manages
 	// the subprocess whether the process is supposed to be alive or not.
 	shutdownCalled int32
+
+	// Set when the agent is recording or replaying monitor inputs
+	inputRecorder *recording.MonitorRecorder
+	// Told about subprocess restarts, may be nil
+	health types.HealthReporter
 }
 
 // New returns a new uninitialized monitor core
@@ -75,6 +82,12 @@ func New() *MonitorCore {
 
 }
 
+// SetHealthReporter is called by the monitor manager so that subprocess
+// restarts show up in the health of the monitor
+func (mc *MonitorCore) SetHealthReporter(health types.HealthReporter) {
+	mc.health = health
+}
+
 // Logger returns the logger that should be used
 func (mc *MonitorCore) Logger() log.FieldLogger {
 	return mc.logger
@@ -145,7 +158,11 @@ func (mc *MonitorCore) runWithRestart(runtimeConf RuntimeConfig, handler Message
 				return
 			}
 
-			handler.ProcessMessages(mc.ctx, messages)
+			var receiver MessageReceiver = messages
+			if mc.inputRecorder != nil {
+				receiver = &recordingReceiver{MessageReceiver: messages, recorder: mc.inputRecorder}
+			}
+			handler.ProcessMessages(mc.ctx, receiver)
 		}()
 
 		err = mc.run(runtimeConf, stdin, stdout)
@@ -162,6 +179,9 @@ func (mc *MonitorCore) runWithRestart(runtimeConf RuntimeConfig, handler Message
 			return
 		}
 		mc.logger.Error("Restarting subprocess runner")
+		if mc.health != nil {
+			mc.health.SubprocessRestarted()
+		}
 
 		time.Sleep(2 * time.Second)
 	}
@@ -212,6 +232,12 @@ func (mc *MonitorCore) ConfigureInSubproc(config config.MonitorCustomConfig,
 		"monitorType": config.MonitorConfigCore().Type,
 	})
 
+	if mc.inputRecorder != nil && mc.inputRecorder.Replaying() {
+		mc.logger.Info("Replaying recorded messages instead of starting subprocess")
+		go handler.ProcessMessages(mc.ctx, &replayingReceiver{ctx: mc.ctx, recorder: mc.inputRecorder})
+		return nil
+	}
+
 	jsonBytes, err := json.Marshal(config)
 	if err != nil {
 		return err
@@ -0,0 +1,63 @@
+package subproc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/recording"
+)
+
+// SetInputRecorder makes the monitor record the messages it receives from
+// the subprocess, or replay previously recorded messages instead of running
+// the subprocess at all.  It must be called before ConfigureInSubproc.
+func (mc *MonitorCore) SetInputRecorder(recorder *recording.MonitorRecorder) {
+	mc.inputRecorder = recorder
+}
+
+// recordingReceiver records every message that is received
+type recordingReceiver struct {
+	MessageReceiver
+	recorder *recording.MonitorRecorder
+}
+
+func (rr *recordingReceiver) RecvMessage() (MessageType, io.Reader, error) {
+	msgType, payloadReader, err := rr.MessageReceiver.RecvMessage()
+	if err != nil {
+		return msgType, payloadReader, err
+	}
+
+	payload, err := ioutil.ReadAll(payloadReader)
+	if err != nil {
+		return MessageTypeNone, nil, err
+	}
+	_ = rr.recorder.RecordMessage(uint32(msgType), payload)
+
+	return msgType, bytes.NewReader(payload), nil
+}
+
+// replayingReceiver returns the recorded messages with the same pacing as
+// they were originally received.  Once they run out it blocks until the
+// monitor is shutdown.
+type replayingReceiver struct {
+	ctx      context.Context
+	recorder *recording.MonitorRecorder
+}
+
+func (rr *replayingReceiver) RecvMessage() (MessageType, io.Reader, error) {
+	e := rr.recorder.NextMessage()
+	if e == nil {
+		<-rr.ctx.Done()
+		return MessageTypeNone, nil, io.EOF
+	}
+
+	select {
+	case <-time.After(e.Delay):
+	case <-rr.ctx.Done():
+		return MessageTypeNone, nil, io.EOF
+	}
+
+	return MessageType(e.MessageType), bytes.NewReader(e.Body), nil
+}
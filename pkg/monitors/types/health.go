@@ -0,0 +1,15 @@
+package types
+
+// HealthReporter is given to monitors that implement
+// monitors.HealthReportable so that they can report problems that the agent
+// can't otherwise see from the data that they send.
+type HealthReporter interface {
+	// CollectionSucceeded should be called when a collection attempt works,
+	// which resets the consecutive error count.
+	CollectionSucceeded()
+	// CollectionFailed should be called when a collection attempt fails.
+	CollectionFailed(err error)
+	// SubprocessRestarted should be called when a subprocess that the
+	// monitor depends on had to be restarted.
+	SubprocessRestarted()
+}
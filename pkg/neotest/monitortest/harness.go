@@ -0,0 +1,454 @@
+// Package monitortest contains a harness that runs a monitor against a fake
+// or recorded backend and checks that what it sends matches what the monitor
+// declares in its metadata.yaml.  It lives outside of the neotest package
+// since it depends on the monitors package, which neotest can't.
+package monitortest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/signalfx/defaults"
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/dpfilters"
+	"github.com/signalfx/signalfx-agent/pkg/core/meta"
+	"github.com/signalfx/signalfx-agent/pkg/core/recording"
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	"github.com/signalfx/signalfx-agent/pkg/neotest"
+	"github.com/signalfx/signalfx-agent/pkg/selfdescribe"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const metadataFile = "metadata.yaml"
+
+// TestingT is the subset of *testing.T that the harness uses
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Helper()
+}
+
+// Harness runs a single monitor type and checks its output against its
+// metadata.
+type Harness struct {
+	t           TestingT
+	monitorType string
+	metadata    *monitors.Metadata
+	dimensions  map[string]bool
+
+	// Dimensions that the monitor may send that aren't declared in
+	// metadata.yaml
+	AllowedDimensions []string
+	// Default or group metrics that the backend used in the test doesn't
+	// cause the monitor to send, so they shouldn't be waited for
+	OptionalMetrics []string
+	// How long to wait for the monitor to send all of the expected metrics
+	Timeout time.Duration
+}
+
+// New creates a harness for the monitor type, which must be registered.  The
+// monitor's metadata.yaml is found by looking in the current directory and
+// then its parents, so this works from within a monitor package's tests.
+func New(t TestingT, monitorType string) *Harness {
+	metadata, ok := monitors.MonitorMetadatas[monitorType]
+	if !ok || metadata == nil {
+		t.Fatalf("Monitor type %s is not registered", monitorType)
+		return nil
+	}
+
+	mmd, err := findMonitorMetadata(monitorType)
+	if err != nil {
+		t.Fatalf("%v", err)
+		return nil
+	}
+
+	dims := map[string]bool{}
+	for name := range mmd.Dimensions {
+		dims[name] = true
+	}
+
+	return &Harness{
+		t:           t,
+		monitorType: monitorType,
+		metadata:    metadata,
+		dimensions:  dims,
+		Timeout:     10 * time.Second,
+	}
+}
+
+func findMonitorMetadata(monitorType string) (*selfdescribe.MonitorMetadata, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		content, err := ioutil.ReadFile(filepath.Join(dir, metadataFile))
+		if err == nil {
+			var pkg selfdescribe.PackageMetadata
+			if err := yaml.Unmarshal(content, &pkg); err != nil {
+				return nil, fmt.Errorf("could not parse %s in %s: %v", metadataFile, dir, err)
+			}
+			for i := range pkg.Monitors {
+				if pkg.Monitors[i].MonitorType == monitorType {
+					return &pkg.Monitors[i], nil
+				}
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("could not find %s declaring monitor type %s", metadataFile, monitorType)
+		}
+		dir = parent
+	}
+}
+
+// Result is what a monitor sent during a single run
+type Result struct {
+	// Every datapoint that the monitor sent
+	Sent []*datapoint.Datapoint
+	// The datapoints that the agent would have emitted after filtering
+	Emitted []*datapoint.Datapoint
+	// The output that was given to the monitor, with the emitted datapoints
+	// already flushed.  Events, spans and dimension updates are left in it.
+	Output *neotest.TestOutput
+}
+
+// EmittedMetrics returns the set of metric names that were emitted
+func (r *Result) EmittedMetrics() map[string]bool {
+	out := map[string]bool{}
+	for _, dp := range r.Emitted {
+		out[dp.Metric] = true
+	}
+	return out
+}
+
+// Run configures a new instance of the monitor with conf and waits until it
+// has emitted all of the metrics that are enabled by conf, or the timeout is
+// reached, before shutting it down.  The monitor type and config defaults
+// are filled in on conf.
+func (h *Harness) Run(conf config.MonitorCustomConfig) *Result {
+	h.t.Helper()
+	return h.run(conf, nil)
+}
+
+// Replay runs the monitor like Run, but with the inputs that were recorded to
+// the bundle at bundlePath with the agent's `inputRecording` option instead
+// of its actual backend.  The bundle must have the inputs of exactly one
+// monitor of the harness' type.  The monitor stops once it has emitted all of
+// the metrics that are enabled by conf, or at the timeout, so the timeout
+// should be lowered if the bundle doesn't cause all of them to be sent.
+func (h *Harness) Replay(conf config.MonitorCustomConfig, bundlePath string) *Result {
+	h.t.Helper()
+
+	bundle, err := recording.Open(bundlePath)
+	if err != nil {
+		h.t.Fatalf("Could not open input recording bundle: %v", err)
+		return &Result{}
+	}
+	defer bundle.Close()
+
+	var keys []string
+	for _, key := range bundle.Monitors() {
+		if strings.HasPrefix(key, h.monitorType+"/") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) != 1 {
+		h.t.Fatalf("Bundle %s must have the inputs of exactly one %s monitor, found %v", bundlePath, h.monitorType, keys)
+		return &Result{}
+	}
+
+	return h.run(conf, bundle.Monitor(keys[0]))
+}
+
+func (h *Harness) run(conf config.MonitorCustomConfig, recorder *recording.MonitorRecorder) *Result {
+	h.t.Helper()
+
+	conf.MonitorConfigCore().Type = h.monitorType
+	if err := defaults.Set(conf); err != nil {
+		h.t.Fatalf("Could not set config defaults: %v", err)
+		return &Result{}
+	}
+
+	filter, err := monitors.NewMetricFilter(conf, h.metadata)
+	if err != nil {
+		h.t.Fatalf("Could not create metric filter: %v", err)
+		return &Result{}
+	}
+
+	output := &contractOutput{
+		TestOutput: neotest.NewTestOutput(),
+		filter:     filter,
+	}
+
+	instance := monitors.MonitorFactories[h.monitorType]()
+	if initMon, ok := instance.(monitors.Initializable); ok {
+		if err := initMon.Init(); err != nil {
+			h.t.Fatalf("Could not initialize monitor: %v", err)
+			return &Result{}
+		}
+	}
+	injectField(instance, "Output", output)
+	injectField(instance, "AgentMeta", &meta.AgentMeta{})
+	if recorder != nil {
+		monitors.AttachInputRecorder(instance, conf, recorder)
+	}
+
+	if err := config.CallConfigure(instance, conf); err != nil {
+		h.t.Fatalf("Could not configure monitor: %v", err)
+		return &Result{}
+	}
+
+	expected := h.expectedMetrics(filter)
+	result := &Result{Output: output.TestOutput}
+
+	timeout := time.After(h.Timeout)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+wait:
+	for {
+		result.Emitted = append(result.Emitted, output.FlushDatapoints()...)
+		if hasAllMetrics(result, expected) {
+			break
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timeout:
+			break wait
+		}
+	}
+
+	if sh, ok := instance.(monitors.Shutdownable); ok {
+		sh.Shutdown()
+	}
+
+	result.Emitted = append(result.Emitted, output.FlushDatapoints()...)
+	result.Sent = output.sentDatapoints()
+	return result
+}
+
+// The metrics that a run should emit before it is considered complete.  If
+// the monitor doesn't declare any metrics that are enabled, a run is
+// complete as soon as anything is emitted.
+func (h *Harness) expectedMetrics(filter *monitors.MetricFilter) map[string]bool {
+	optional := utils.StringSliceToMap(h.OptionalMetrics)
+
+	// Everything is enabled for sendAll monitors so only wait for the ones
+	// that are known to be sent by default.
+	enabled := filter.EnabledMetrics()
+	if h.metadata.SendAll {
+		enabled = nil
+		for metric := range h.metadata.DefaultMetrics {
+			enabled = append(enabled, metric)
+		}
+	}
+
+	out := map[string]bool{}
+	for _, metric := range enabled {
+		if !optional[metric] {
+			out[metric] = true
+		}
+	}
+	return out
+}
+
+func hasAllMetrics(result *Result, expected map[string]bool) bool {
+	if len(expected) == 0 {
+		return len(result.Emitted) > 0
+	}
+
+	emitted := result.EmittedMetrics()
+	for metric := range expected {
+		if !emitted[metric] {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckContract runs the monitor with the config returned by newConf, which
+// should point the monitor at a fake or recorded backend, and asserts that:
+//
+//   - every metric sent is declared in metadata.yaml, unless the monitor is
+//     marked sendAll
+//   - every dimension sent is declared in metadata.yaml or AllowedDimensions
+//   - the type of every declared metric matches its metadata
+//   - every default metric is emitted
+//   - enabling each group through extraGroups emits exactly the metrics of
+//     that group in addition to the default metrics
+//
+// newConf is called for every run and must return a fresh config each time.
+func (h *Harness) CheckContract(newConf func() config.MonitorCustomConfig) {
+	h.t.Helper()
+
+	base := h.Run(newConf())
+	h.checkDatapoints(base.Sent)
+	h.checkEmitted("default config", base, h.metadata.DefaultMetrics)
+
+	baseMetrics := base.EmittedMetrics()
+
+	groups := make([]string, 0, len(h.metadata.GroupMetricsMap))
+	for group := range h.metadata.GroupMetricsMap {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		conf := newConf()
+		conf.MonitorConfigCore().ExtraGroups = []string{group}
+
+		res := h.Run(conf)
+		h.checkDatapoints(res.Sent)
+
+		groupMetrics := utils.StringSliceToMap(h.metadata.GroupMetricsMap[group])
+		expected := utils.StringSliceToMap(h.metadata.GroupMetricsMap[group])
+		for metric := range h.metadata.DefaultMetrics {
+			expected[metric] = true
+		}
+		h.checkEmitted("extraGroups: ["+group+"]", res, expected)
+
+		for metric := range res.EmittedMetrics() {
+			if !baseMetrics[metric] && !groupMetrics[metric] {
+				assert.Failf(h.t, "Metric emitted from outside of group",
+					"Enabling group %s caused metric %s to be emitted, which is not in the group", group, metric)
+			}
+		}
+	}
+}
+
+// checkEmitted asserts that every metric in expected, besides optional ones,
+// was emitted
+func (h *Harness) checkEmitted(runDesc string, result *Result, expected map[string]bool) {
+	optional := utils.StringSliceToMap(h.OptionalMetrics)
+	emitted := result.EmittedMetrics()
+
+	for metric := range expected {
+		if !optional[metric] && !emitted[metric] {
+			assert.Failf(h.t, "Metric not emitted", "Metric %s was not emitted with %s", metric, runDesc)
+		}
+	}
+}
+
+// checkDatapoints asserts that the metric names, types and dimensions of the
+// datapoints are all declared in the metadata.  Each problem is only reported
+// once.
+func (h *Harness) checkDatapoints(dps []*datapoint.Datapoint) {
+	allowedDims := utils.StringSliceToMap(h.AllowedDimensions)
+	reported := map[string]bool{}
+	report := func(key, title, msg string, args ...interface{}) {
+		if !reported[key] {
+			reported[key] = true
+			assert.Failf(h.t, title, msg, args...)
+		}
+	}
+
+	for _, dp := range dps {
+		info, declared := h.metadata.Metrics[dp.Metric]
+		switch {
+		case !declared && !h.metadata.SendAll:
+			report("metric:"+dp.Metric, "Undeclared metric", "Metric %s is not declared in %s", dp.Metric, metadataFile)
+		case declared && info.Type != dp.MetricType:
+			report("type:"+dp.Metric, "Wrong metric type",
+				"Metric %s was sent as a %s but is declared as a %s", dp.Metric, dp.MetricType, info.Type)
+		}
+
+		for dim := range dp.Dimensions {
+			if !h.dimensions[dim] && !allowedDims[dim] {
+				report("dim:"+dim, "Undeclared dimension",
+					"Dimension %s on metric %s is not declared in %s", dim, dp.Metric, metadataFile)
+			}
+		}
+	}
+}
+
+// Sets the named field on the monitor, if it has one of a compatible type
+func injectField(instance interface{}, name string, value interface{}) {
+	typ := reflect.TypeOf(value)
+
+	field := utils.FindFieldWithEmbeddedStructs(instance, name, typ)
+	if !field.IsValid() {
+		for _, iface := range []reflect.Type{
+			reflect.TypeOf((*types.Output)(nil)).Elem(),
+			reflect.TypeOf((*types.FilteringOutput)(nil)).Elem(),
+		} {
+			if typ.Implements(iface) {
+				if field = utils.FindFieldWithEmbeddedStructs(instance, name, iface); field.IsValid() {
+					break
+				}
+			}
+		}
+	}
+
+	if field.IsValid() {
+		field.Set(reflect.ValueOf(value))
+	}
+}
+
+// contractOutput records everything the monitor sends and passes the
+// datapoints that survive the agent's filtering on to the TestOutput.
+type contractOutput struct {
+	*neotest.TestOutput
+	filter *monitors.MetricFilter
+
+	lock sync.Mutex
+	sent []*datapoint.Datapoint
+}
+
+var _ types.FilteringOutput = &contractOutput{}
+
+func (co *contractOutput) Copy() types.Output {
+	return co
+}
+
+func (co *contractOutput) SendDatapoints(dps ...*datapoint.Datapoint) {
+	co.lock.Lock()
+	co.sent = append(co.sent, dps...)
+	co.lock.Unlock()
+
+	var emitted []*datapoint.Datapoint
+	for _, dp := range dps {
+		if !co.filter.Excludes(dp) {
+			emitted = append(emitted, dp)
+		}
+	}
+	if len(emitted) > 0 {
+		co.TestOutput.SendDatapoints(emitted...)
+	}
+}
+
+func (co *contractOutput) sentDatapoints() []*datapoint.Datapoint {
+	co.lock.Lock()
+	defer co.lock.Unlock()
+	return append([]*datapoint.Datapoint(nil), co.sent...)
+}
+
+func (co *contractOutput) AddDatapointExclusionFilter(f dpfilters.DatapointFilter) {
+	co.filter.AddDatapointExclusionFilter(f)
+}
+
+func (co *contractOutput) EnabledMetrics() []string {
+	return co.filter.EnabledMetrics()
+}
+
+func (co *contractOutput) HasEnabledMetricInGroup(group string) bool {
+	return co.filter.HasEnabledMetricInGroup(group)
+}
+
+func (co *contractOutput) HasAnyExtraMetrics() bool {
+	return co.filter.HasAnyExtraMetrics()
+}
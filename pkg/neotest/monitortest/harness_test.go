@@ -0,0 +1,236 @@
+package monitortest
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/signalfx/golib/v3/datapoint"
+	"github.com/signalfx/signalfx-agent/pkg/core/common/httpclient"
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/core/recording"
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+	"github.com/signalfx/signalfx-agent/pkg/monitors/types"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeMonitorType = "monitortest-fake"
+
+var fakeMetadata = monitors.Metadata{
+	MonitorType: fakeMonitorType,
+	DefaultMetrics: map[string]bool{
+		"requests": true,
+		"latency":  true,
+	},
+	Metrics: map[string]monitors.MetricInfo{
+		"requests":      {Type: datapoint.Counter},
+		"latency":       {Type: datapoint.Gauge},
+		"queue.size":    {Type: datapoint.Gauge, Group: "queue"},
+		"queue.dropped": {Type: datapoint.Counter, Group: "queue"},
+		"cache.hits":    {Type: datapoint.Counter, Group: "cache"},
+	},
+	Groups: map[string]bool{"queue": true, "cache": true},
+	GroupMetricsMap: map[string][]string{
+		"queue": {"queue.size", "queue.dropped"},
+		"cache": {"cache.hits"},
+	},
+}
+
+func init() {
+	monitors.Register(&fakeMetadata, func() interface{} { return &fakeMonitor{} }, &fakeConfig{})
+}
+
+type fakeConfig struct {
+	config.MonitorConfig  `yaml:",inline"`
+	httpclient.HTTPConfig `yaml:",inline"`
+	// If set, latency is read from this URL instead of being fixed
+	LatencyURL string `yaml:"latencyURL"`
+	// Makes the monitor break its contract
+	Misbehave bool `yaml:"misbehave"`
+}
+
+// A monitor that polls a fake backend, only collecting the queue and cache
+// metrics if they are enabled
+type fakeMonitor struct {
+	Output types.FilteringOutput
+	cancel context.CancelFunc
+}
+
+func (m *fakeMonitor) Configure(conf *fakeConfig) error {
+	var ctx context.Context
+	ctx, m.cancel = context.WithCancel(context.Background())
+
+	client, err := conf.HTTPConfig.Build()
+	if err != nil {
+		return err
+	}
+
+	utils.RunOnInterval(ctx, func() {
+		latency := 1.5
+		if conf.LatencyURL != "" {
+			resp, err := client.Get(conf.LatencyURL)
+			if err != nil {
+				return
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if latency, err = strconv.ParseFloat(string(body), 64); err != nil {
+				return
+			}
+		}
+
+		dims := map[string]string{"host": "fake"}
+		dps := []*datapoint.Datapoint{
+			datapoint.New("requests", dims, datapoint.NewIntValue(10), datapoint.Counter, time.Time{}),
+			datapoint.New("latency", dims, datapoint.NewFloatValue(latency), datapoint.Gauge, time.Time{}),
+		}
+
+		if m.Output.HasEnabledMetricInGroup("queue") {
+			queueDims := map[string]string{"host": "fake", "queue": "a"}
+			dps = append(dps,
+				datapoint.New("queue.size", queueDims, datapoint.NewIntValue(3), datapoint.Gauge, time.Time{}),
+				datapoint.New("queue.dropped", queueDims, datapoint.NewIntValue(0), datapoint.Counter, time.Time{}))
+
+			if conf.Misbehave {
+				// Not in the group, or declared at all, so it isn't filtered
+				dps = append(dps, datapoint.New("queue.internal", queueDims, datapoint.NewIntValue(1), datapoint.Gauge, time.Time{}))
+			}
+		}
+		if m.Output.HasEnabledMetricInGroup("cache") && !conf.Misbehave {
+			dps = append(dps, datapoint.New("cache.hits", dims, datapoint.NewIntValue(1), datapoint.Counter, time.Time{}))
+		}
+
+		if conf.Misbehave {
+			dps = append(dps,
+				datapoint.New("unknown", dims, datapoint.NewIntValue(1), datapoint.Gauge, time.Time{}),
+				datapoint.New("latency", map[string]string{"region": "x"}, datapoint.NewIntValue(1), datapoint.Counter, time.Time{}))
+		}
+
+		m.Output.SendDatapoints(dps...)
+	}, time.Duration(conf.IntervalSeconds)*time.Second)
+
+	return nil
+}
+
+func (m *fakeMonitor) Shutdown() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// Records failures instead of failing the test
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingT) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func (r *recordingT) Helper() {}
+
+func inTestdata(t *testing.T) func() {
+	wd, err := os.Getwd()
+	require.Nil(t, err)
+	require.Nil(t, os.Chdir("testdata"))
+	return func() { _ = os.Chdir(wd) }
+}
+
+func TestCheckContract(t *testing.T) {
+	defer inTestdata(t)()
+
+	h := New(t, fakeMonitorType)
+	h.CheckContract(func() config.MonitorCustomConfig {
+		return &fakeConfig{MonitorConfig: config.MonitorConfig{IntervalSeconds: 1}}
+	})
+}
+
+func TestRunFiltersLikeTheAgent(t *testing.T) {
+	defer inTestdata(t)()
+
+	h := New(t, fakeMonitorType)
+	res := h.Run(&fakeConfig{MonitorConfig: config.MonitorConfig{
+		IntervalSeconds: 1,
+		ExtraMetrics:    []string{"queue.size"},
+	}})
+
+	require.Equal(t, map[string]bool{"requests": true, "latency": true, "queue.size": true}, res.EmittedMetrics())
+	// queue.dropped is sent since the queue group has an enabled metric, but
+	// is filtered out.
+	require.True(t, len(res.Sent) > len(res.Emitted))
+}
+
+func TestCheckContractFailures(t *testing.T) {
+	defer inTestdata(t)()
+
+	rt := &recordingT{}
+	h := New(rt, fakeMonitorType)
+	h.Timeout = 3 * time.Second
+	h.CheckContract(func() config.MonitorCustomConfig {
+		return &fakeConfig{
+			MonitorConfig: config.MonitorConfig{IntervalSeconds: 1},
+			Misbehave:     true,
+		}
+	})
+
+	joined := fmt.Sprint(rt.errors)
+	require.Contains(t, joined, "Metric unknown is not declared")
+	require.Contains(t, joined, "Metric latency was sent as a cumulative counter but is declared as a gauge")
+	require.Contains(t, joined, "Dimension region on metric latency is not declared")
+	require.Contains(t, joined, "Metric cache.hits was not emitted with extraGroups: [cache]")
+	require.Contains(t, joined, "Enabling group queue caused metric queue.internal to be emitted")
+}
+
+func TestReplay(t *testing.T) {
+	defer inTestdata(t)()
+
+	dir, err := ioutil.TempDir("", "monitortest")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	bundlePath := filepath.Join(dir, "bundle.json")
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte("7.25"))
+	}))
+
+	bundle, err := recording.Create(bundlePath)
+	require.Nil(t, err)
+	client := &http.Client{Transport: bundle.Monitor(fakeMonitorType + "/1").WrapRoundTripper(http.DefaultTransport)}
+	resp, err := client.Get(server.URL + "/latency")
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Nil(t, bundle.Close())
+	server.Close()
+
+	h := New(t, fakeMonitorType)
+	res := h.Replay(&fakeConfig{
+		MonitorConfig: config.MonitorConfig{IntervalSeconds: 1},
+		LatencyURL:    "http://192.0.2.1/latency",
+	}, bundlePath)
+
+	var latencies []float64
+	for _, dp := range res.Emitted {
+		if dp.Metric == "latency" {
+			latencies = append(latencies, dp.Value.(datapoint.FloatValue).Float())
+		}
+	}
+	require.Equal(t, []float64{7.25}, latencies)
+}
+
+func TestNewWithoutMetadata(t *testing.T) {
+	rt := &recordingT{}
+	require.Nil(t, New(rt, fakeMonitorType))
+	require.Len(t, rt.errors, 1)
+}
@@ -8,6 +8,8 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+var logger = log.WithFields(log.Fields{"component": "observerManager"})
+
 // ObserverWrapper represents an active observer
 type ObserverWrapper struct {
 	instance interface{}
@@ -30,6 +32,8 @@ func (ow *ObserverWrapper) Shutdown() {
 type ObserverManager struct {
 	observers []*ObserverWrapper
 	lock      sync.Mutex
+	// The errors from the last Configure, keyed by observer type
+	configureErrors map[string]string
 	// Where to send observer notifications to
 	CallbackTargets *ServiceCallbacks
 }
@@ -37,7 +41,7 @@ type ObserverManager struct {
 func (om *ObserverManager) makeWrappedObserver(config *config.ObserverConfig) *ObserverWrapper {
 	factory, ok := observerFactories[config.Type]
 	if !ok {
-		log.WithFields(log.Fields{
+		logger.WithFields(log.Fields{
 			"observerType": config.Type,
 		}).Error("Observer type not recognized")
 		return nil
@@ -46,7 +50,7 @@ func (om *ObserverManager) makeWrappedObserver(config *config.ObserverConfig) *O
 	if om.CallbackTargets == nil ||
 		om.CallbackTargets.Added == nil ||
 		om.CallbackTargets.Removed == nil {
-		log.Fatal("om.CallbackTargets is not configured correctly, no point in observing")
+		logger.Fatal("om.CallbackTargets is not configured correctly, no point in observing")
 	}
 
 	return &ObserverWrapper{
@@ -69,6 +73,7 @@ func (om *ObserverManager) Configure(obsConfig []config.ObserverConfig) {
 	}
 
 	om.markAllAsDoomed()
+	om.configureErrors = make(map[string]string)
 
 OUTER:
 	for i := range obsConfig {
@@ -83,11 +88,12 @@ OUTER:
 				if !configEqual {
 					err := configureObserver(obs.instance, cfg)
 					if err != nil {
-						log.WithFields(log.Fields{
+						logger.WithFields(log.Fields{
 							"error":        err,
 							"observerType": cfg.Type,
 							"config":       cfg,
 						}).Error("Could not configure observer")
+						om.configureErrors[cfg.Type] = err.Error()
 
 						// Remains doomed if it misconfigures and isn't retried
 						// successfully by another config of the same type
@@ -102,15 +108,17 @@ OUTER:
 		// Couldn't reuse an existing observer so make a new one
 		observer := om.makeWrappedObserver(cfg)
 		if observer == nil {
+			om.configureErrors[cfg.Type] = "observer type not recognized"
 			continue
 		}
 
 		if err := configureObserver(observer.instance, cfg); err != nil {
-			log.WithFields(log.Fields{
+			logger.WithFields(log.Fields{
 				"error":        err,
 				"observerType": cfg.Type,
 				"config":       cfg,
 			}).Error("Could not configure observer")
+			om.configureErrors[cfg.Type] = err.Error()
 		}
 
 		om.observers = append(om.observers, observer)
@@ -138,6 +146,19 @@ func (om *ObserverManager) deleteDoomed() {
 	om.observers = savedObservers
 }
 
+// ConfigureErrors returns the errors from configuring observers the last
+// time Configure was called, keyed by observer type
+func (om *ObserverManager) ConfigureErrors() map[string]string {
+	om.lock.Lock()
+	defer om.lock.Unlock()
+
+	out := make(map[string]string, len(om.configureErrors))
+	for k, v := range om.configureErrors {
+		out[k] = v
+	}
+	return out
+}
+
 // Shutdown all of the managed observers
 func (om *ObserverManager) Shutdown() {
 	for i := range om.observers {
@@ -0,0 +1,390 @@
+package selfdescribe
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/signalfx/signalfx-agent/pkg/core/config"
+	"github.com/signalfx/signalfx-agent/pkg/monitors"
+	"github.com/signalfx/signalfx-agent/pkg/observers"
+	"github.com/signalfx/signalfx-agent/pkg/utils/timeutil"
+)
+
+const (
+	jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+	configSourceRefDef = "configSourceRef"
+	monitorDef         = "monitor"
+	observerDef        = "observer"
+)
+
+// JSONSchema writes a JSON Schema (draft 7) of the agent config file to the
+// writer.  Monitor and observer configs are validated based on the value of
+// their `type` field.  Like JSON(), this must be run from the root of the
+// agent repo since it pulls descriptions from the source code.
+func JSONSchema(writer io.Writer) {
+	monitorTypes := map[string]reflect.Type{}
+	for monType, conf := range monitors.ConfigTemplates {
+		monitorTypes[monType] = reflect.TypeOf(conf).Elem()
+	}
+	monitorDocs := map[string]string{}
+	for _, md := range monitorsStructMetadata() {
+		monitorDocs[md.MonitorType] = md.Doc
+	}
+
+	osm, err := observersStructMetadata()
+	if err != nil {
+		panic(err)
+	}
+	observerTypes := map[string]reflect.Type{}
+	for obsType, conf := range observers.ConfigTemplates {
+		observerTypes[obsType] = reflect.TypeOf(conf).Elem()
+	}
+	observerDocs := map[string]string{}
+	for _, om := range osm {
+		observerDocs[om.ObserverType] = om.Doc
+	}
+
+	sb := newSchemaBuilder()
+	sb.definitions[monitorDef] = sb.discriminatedSchema("monitor", monitorTypes, monitorDocs)
+	sb.definitions[observerDef] = sb.discriminatedSchema("observer", observerTypes, observerDocs)
+
+	schema := sb.structSchema(reflect.TypeOf(config.Config{}), map[string]jsonSchema{
+		"monitors":  orConfigSourceRef(jsonSchema{"type": "array", "items": orConfigSourceRef(schemaRef(monitorDef))}),
+		"observers": orConfigSourceRef(jsonSchema{"type": "array", "items": orConfigSourceRef(schemaRef(observerDef))}),
+	})
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = "SignalFx Smart Agent config"
+	schema["definitions"] = sb.definitions
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	_, _ = writer.Write(out)
+}
+
+type jsonSchema map[string]interface{}
+
+func schemaRef(name string) jsonSchema {
+	escaped := strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+	return jsonSchema{"$ref": "#/definitions/" + escaped}
+}
+
+// orConfigSourceRef returns a schema that also accepts a reference to a
+// config source in place of a value of the schema.
+func orConfigSourceRef(schema jsonSchema) jsonSchema {
+	return jsonSchema{
+		"anyOf": []jsonSchema{schema, schemaRef(configSourceRefDef)},
+	}
+}
+
+type schemaBuilder struct {
+	definitions map[string]jsonSchema
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		definitions: map[string]jsonSchema{
+			// Any value in the config can be pulled in from a config source
+			// instead of being specified literally.
+			configSourceRefDef: {
+				"type":     "object",
+				"required": []string{"#from"},
+				"properties": map[string]jsonSchema{
+					"#from":    {"type": "string", "description": "The path to the value, optionally prefixed by the config source name (e.g. `zk:/path`)"},
+					"flatten":  {"type": "boolean"},
+					"optional": {"type": "boolean"},
+					"raw":      {"type": "boolean"},
+					"template": {"type": "boolean"},
+					"default":  {},
+				},
+				"additionalProperties": false,
+			},
+		},
+	}
+}
+
+// discriminatedSchema returns a schema for a monitor or observer config that
+// applies the schema of the specific component based on the `type` field.
+func (sb *schemaBuilder) discriminatedSchema(kind string, configTypes map[string]reflect.Type, docs map[string]string) jsonSchema {
+	types := []string{}
+	for typ := range configTypes {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	var conditions []jsonSchema
+	for _, typ := range types {
+		defName := kind + ":" + typ
+
+		schema := sb.structSchema(configTypes[typ], map[string]jsonSchema{
+			"type": {"const": typ},
+		})
+		if docs[typ] != "" {
+			schema["description"] = docs[typ]
+		}
+		req, _ := schema["required"].([]string)
+		if acceptsEndpoints(configTypes[typ]) && len(req) > 0 {
+			// The required options like host and port can come from the
+			// discovered endpoint instead
+			delete(schema, "required")
+			schema["anyOf"] = []jsonSchema{
+				{"required": []string{"discoveryRule"}},
+				{"required": req},
+			}
+			req = nil
+		}
+		if !containsString(req, "type") {
+			schema["required"] = append(req, "type")
+		}
+		sb.definitions[defName] = schema
+
+		conditions = append(conditions, jsonSchema{
+			"if": jsonSchema{
+				"properties": map[string]jsonSchema{"type": {"const": typ}},
+				"required":   []string{"type"},
+			},
+			"then": schemaRef(defName),
+		})
+	}
+
+	return jsonSchema{
+		"type":     "object",
+		"required": []string{"type"},
+		"properties": map[string]jsonSchema{
+			"type": {"type": "string", "enum": types},
+		},
+		"allOf": conditions,
+	}
+}
+
+func acceptsEndpoints(typ reflect.Type) bool {
+	f, ok := typ.FieldByName("MonitorConfig")
+	return ok && f.Tag.Get("acceptsEndpoints") == strconv.FormatBool(true)
+}
+
+// structSchema returns an object schema for the struct type.  Properties in
+// overrides replace whatever would have been generated from the struct field
+// of the same YAML name.
+func (sb *schemaBuilder) structSchema(typ reflect.Type, overrides map[string]jsonSchema) jsonSchema {
+	properties := map[string]jsonSchema{}
+	var required []string
+
+	sb.addStructFields(typ, properties, &required)
+
+	for name, prop := range overrides {
+		if existing, ok := properties[name]; ok && existing["description"] != nil {
+			prop["description"] = existing["description"]
+		}
+		properties[name] = prop
+	}
+
+	schema := jsonSchema{
+		"type":       "object",
+		"properties": properties,
+		// Keys starting with `_` are flattened into the containing object
+		// by config sources.
+		"patternProperties":    map[string]jsonSchema{"^_": {}},
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	if doc := structSchemaDoc(typ); doc != "" {
+		schema["description"] = doc
+	}
+	return schema
+}
+
+func (sb *schemaBuilder) addStructFields(typ reflect.Type, properties map[string]jsonSchema, required *[]string) {
+	packageDir := packageDirOfType(typ)
+	var fieldDocs map[string]string
+	if packageDir != "" && typ.Name() != "" {
+		fieldDocs = structFieldDocs(packageDir, typ.Name())
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if (f.Anonymous || isInlinedYAML(f)) && indirectKind(f.Type) == reflect.Struct {
+			sb.addStructFields(indirectType(f.Type), properties, required)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		// Generic catch-all maps like OtherConfig are what the specific
+		// monitor/observer configs are decoded from.
+		if isInlinedYAML(f) {
+			continue
+		}
+
+		yamlName := getYAMLName(f)
+		if yamlName == "" {
+			// The default name the yaml package uses
+			yamlName = strings.ToLower(f.Name)
+		}
+		if yamlName == "-" || strings.HasPrefix(yamlName, "_") {
+			continue
+		}
+
+		prop := sb.fieldSchema(f)
+		if doc := fieldDocs[f.Name]; doc != "" {
+			prop["description"] = doc
+		}
+		properties[yamlName] = prop
+
+		if getRequired(f) {
+			*required = append(*required, yamlName)
+		}
+	}
+}
+
+// fieldSchema returns the schema of a single struct field, which can also be
+// a reference to a config source.
+func (sb *schemaBuilder) fieldSchema(f reflect.StructField) jsonSchema {
+	valueSchema := sb.typeSchema(f.Type)
+	if enum := oneOfValues(f); enum != nil {
+		valueSchema["enum"] = enum
+	}
+
+	prop := orConfigSourceRef(valueSchema)
+	if def, ok := schemaDefault(f); ok {
+		prop["default"] = def
+	}
+	return prop
+}
+
+// nolint: gochecknoglobals
+var (
+	durationType         = reflect.TypeOf(timeutil.Duration(0))
+	standardDurationType = reflect.TypeOf(time.Duration(0))
+)
+
+func (sb *schemaBuilder) typeSchema(typ reflect.Type) jsonSchema {
+	typ = indirectType(typ)
+
+	switch typ {
+	case durationType:
+		// Integers are interpreted as seconds
+		return jsonSchema{"type": []string{"string", "integer"}}
+	case standardDurationType:
+		return jsonSchema{"type": []string{"string", "integer"}}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonSchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return jsonSchema{"type": "number"}
+	case reflect.String:
+		return jsonSchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		// Items can be config sources too, which are flattened into the
+		// array if they resolve to arrays themselves
+		return jsonSchema{"type": "array", "items": orConfigSourceRef(sb.typeSchema(typ.Elem()))}
+	case reflect.Map:
+		return jsonSchema{"type": "object", "additionalProperties": sb.typeSchema(typ.Elem())}
+	case reflect.Struct:
+		return sb.structRef(typ)
+	}
+	// Interfaces and anything else that we can't say much about
+	return jsonSchema{}
+}
+
+// structRef adds a definition for the struct type if it hasn't been seen
+// before and returns a reference to it.
+func (sb *schemaBuilder) structRef(typ reflect.Type) jsonSchema {
+	if typ.Name() == "" {
+		return sb.structSchema(typ, nil)
+	}
+
+	name := strings.Replace(packageDirOfType(typ), "/", ".", -1) + "." + typ.Name()
+	if _, ok := sb.definitions[name]; !ok {
+		// Reserve the name first in case the struct refers to itself
+		sb.definitions[name] = jsonSchema{}
+		sb.definitions[name] = sb.structSchema(typ, nil)
+	}
+	return schemaRef(name)
+}
+
+func structSchemaDoc(typ reflect.Type) string {
+	packageDir := packageDirOfType(typ)
+	if packageDir == "" || typ.Name() == "" {
+		return ""
+	}
+	return structDoc(packageDir, typ.Name())
+}
+
+// oneOfValues returns the allowed values from a `validate:"oneof=..."` tag,
+// or nil if there is no such tag.
+func oneOfValues(f reflect.StructField) []interface{} {
+	for _, v := range strings.Split(f.Tag.Get("validate"), ",") {
+		if !strings.HasPrefix(v, "oneof=") {
+			continue
+		}
+
+		var out []interface{}
+		for _, s := range strings.Fields(strings.TrimPrefix(v, "oneof=")) {
+			out = append(out, convertToKind(s, indirectKind(f.Type)))
+		}
+		return out
+	}
+	return nil
+}
+
+// schemaDefault returns the default value of the field, converted to the
+// field's type, and whether there is any meaningful default at all.
+func schemaDefault(f reflect.StructField) (interface{}, bool) {
+	def := getDefault(f)
+	if def == nil || def == "" {
+		return nil, false
+	}
+
+	v := reflect.ValueOf(def)
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.Len() == 0 {
+		return nil, false
+	}
+
+	if s, ok := def.(string); ok && indirectType(f.Type) != durationType && indirectType(f.Type) != standardDurationType {
+		return convertToKind(s, indirectKind(f.Type)), true
+	}
+	return def, true
+}
+
+func convertToKind(s string, kind reflect.Kind) interface{} {
+	switch kind {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+func containsString(list []string, s string) bool {
+	for i := range list {
+		if list[i] == s {
+			return true
+		}
+	}
+	return false
+}
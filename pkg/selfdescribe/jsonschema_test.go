@@ -0,0 +1,194 @@
+package selfdescribe
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	// Registers all of the monitors and observers, like in the agent
+	_ "github.com/signalfx/signalfx-agent/pkg/core"
+	"github.com/signalfx/signalfx-agent/pkg/utils"
+	"github.com/signalfx/signalfx-agent/pkg/utils/timeutil"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// testSchemaConfig is used to test schema generation
+type testSchemaConfig struct {
+	// The host to connect to
+	Host    string             `yaml:"host" validate:"required"`
+	Port    uint16             `yaml:"port" default:"8080"`
+	Mode    string             `yaml:"mode" validate:"oneof=server client" default:"client"`
+	Enabled *bool              `yaml:"enabled" default:"true"`
+	Timeout timeutil.Duration  `yaml:"timeout" default:"5s"`
+	Headers map[string]string  `yaml:"headers"`
+	Nested  []testSchemaNested `yaml:"nested"`
+	Ignored string             `yaml:"-"`
+}
+
+// testSchemaNested is a nested struct
+type testSchemaNested struct {
+	Name string `yaml:"name"`
+}
+
+func TestStructSchema(t *testing.T) {
+	// The source docs are parsed relative to the repo root
+	wd, err := os.Getwd()
+	require.Nil(t, err)
+	require.Nil(t, os.Chdir("../.."))
+	defer func() { _ = os.Chdir(wd) }()
+
+	sb := newSchemaBuilder()
+	schema := sb.structSchema(reflect.TypeOf(testSchemaConfig{}), map[string]jsonSchema{
+		"type": {"const": "test"},
+	})
+
+	require.Equal(t, "object", schema["type"])
+	require.Equal(t, false, schema["additionalProperties"])
+	require.Equal(t, []string{"host"}, schema["required"])
+	require.Equal(t, "testSchemaConfig is used to test schema generation", schema["description"])
+
+	props := schema["properties"].(map[string]jsonSchema)
+	require.Len(t, props, 8)
+	require.NotContains(t, props, "-")
+	require.Equal(t, jsonSchema{"const": "test"}, props["type"])
+
+	valueSchema := func(name string) jsonSchema {
+		return props[name]["anyOf"].([]jsonSchema)[0]
+	}
+
+	require.Equal(t, "The host to connect to", props["host"]["description"])
+	require.NotContains(t, props["host"], "default")
+
+	require.Equal(t, jsonSchema{"type": "integer"}, valueSchema("port"))
+	require.Equal(t, 8080, props["port"]["default"])
+
+	require.Equal(t, []interface{}{"server", "client"}, valueSchema("mode")["enum"])
+	require.Equal(t, "client", props["mode"]["default"])
+
+	require.Equal(t, jsonSchema{"type": "boolean"}, valueSchema("enabled"))
+	require.Equal(t, true, props["enabled"]["default"])
+
+	require.Equal(t, []string{"string", "integer"}, valueSchema("timeout")["type"])
+	require.Equal(t, "5s", props["timeout"]["default"])
+
+	require.Equal(t, jsonSchema{"type": "string"}, valueSchema("headers")["additionalProperties"])
+	require.NotContains(t, props["headers"], "default")
+
+	require.Equal(t, orConfigSourceRef(schemaRef("pkg.selfdescribe.testSchemaNested")), valueSchema("nested")["items"])
+	require.Contains(t, sb.definitions, "pkg.selfdescribe.testSchemaNested")
+	require.Contains(t, sb.definitions, configSourceRefDef)
+}
+
+func TestSchemaRefEscaping(t *testing.T) {
+	require.Equal(t, jsonSchema{"$ref": "#/definitions/monitor:collectd~1redis"}, schemaRef("monitor:collectd/redis"))
+}
+
+// Converts YAML maps to maps with string keys, which is what JSON has
+func yamlToJSONValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		out := map[string]interface{}{}
+		for k := range val {
+			out[fmt.Sprint(k)] = yamlToJSONValue(val[k])
+		}
+		return out
+	case []interface{}:
+		for i := range val {
+			val[i] = yamlToJSONValue(val[i])
+		}
+	}
+	return v
+}
+
+func TestJSONSchemaValidatesConfigSourceExamples(t *testing.T) {
+	wd, err := os.Getwd()
+	require.Nil(t, err)
+	require.Nil(t, os.Chdir("../.."))
+	defer func() { _ = os.Chdir(wd) }()
+
+	var buf bytes.Buffer
+	JSONSchema(&buf)
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(buf.Bytes()))
+	require.Nil(t, err)
+
+	validate := func(t *testing.T, content string) *gojsonschema.Result {
+		var conf interface{}
+		require.Nil(t, yaml.Unmarshal([]byte(utils.StripIndent(content)), &conf))
+
+		result, err := schema.Validate(gojsonschema.NewGoLoader(yamlToJSONValue(conf)))
+		require.Nil(t, err)
+		return result
+	}
+
+	// These are the examples in docs/remote-config.md and docs/faq.md
+	for name, content := range map[string]string{
+		"Optional monitors from a glob": `
+			signalFxAccessToken: abcd
+			monitors:
+			 - {"#from": "/etc/signalfx/conf2/*.yaml", flatten: true, optional: true}
+			 - type: collectd/cpu
+			 - type: collectd/cpufreq
+			 - type: collectd/df
+		`,
+		"Flattened config items": `
+			monitors:
+			 - type: collectd/mysql
+			   host: 127.0.0.1
+			   port: 3306
+			   username: signalfx
+			   databases:
+			    - name: admin
+			    - {"#from": "zk:/signalfx-agent/mysql/databases/*", flatten: true}
+		`,
+		"Raw collectd templates": `
+			monitors:
+			  - type: collectd/custom
+			    templates:
+			    - {"#from": "/etc/collectd/managed_config/*.conf", flatten: true, raw: true}
+		`,
+		"All monitors from a config source": `
+			signalFxAccessToken: {"#from": "/etc/signalfx/token"}
+			monitors: {"#from": "/etc/signalfx/monitors.yaml"}
+			observers: {"#from": "/etc/signalfx/observers.yaml"}
+		`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := validate(t, content)
+			require.True(t, result.Valid(), "%v", result.Errors())
+		})
+	}
+
+	t.Run("Does not require options that come from discovered endpoints", func(t *testing.T) {
+		result := validate(t, `
+			monitors:
+			 - type: collectd/mysql
+			   discoveryRule: container_image =~ "mysql"
+			   username: signalfx
+			   databases: [{name: admin}]
+		`)
+		require.True(t, result.Valid(), "%v", result.Errors())
+
+		require.False(t, validate(t, `
+			monitors:
+			 - type: collectd/mysql
+			   username: signalfx
+			   databases: [{name: admin}]
+		`).Valid())
+	})
+
+	t.Run("Still validates monitors", func(t *testing.T) {
+		require.False(t, validate(t, `
+			monitors:
+			 - type: collectd/cpu
+			   notAnOption: true
+		`).Valid())
+		require.False(t, validate(t, `
+			monitors:
+			 - {"#from": "/etc/signalfx/conf2/*.yaml", notAnOption: true}
+		`).Valid())
+	})
+}
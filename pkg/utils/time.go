@@ -61,6 +61,30 @@ func RunOnInterval(ctx context.Context, fn func(), interval time.Duration) {
 	}()
 }
 
+// RunOnIntervalOrDemand runs fn like RunOnInterval, and also returns a
+// function that runs fn once right away, e.g. to collect on demand, and
+// returns its error.  Runs never overlap, and the returned function does
+// nothing but return the context error once ctx is done.
+func RunOnIntervalOrDemand(ctx context.Context, fn func() error, interval time.Duration) func() error {
+	var lock sync.Mutex
+	runNow := func() error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fn()
+	}
+
+	RunOnInterval(ctx, func() {
+		// The error is for on demand runs, fn should report it otherwise
+		_ = runNow()
+	}, interval)
+
+	return runNow
+}
+
 // RepeatPolicy repeat behavior for RunOnIntervals Function
 type RepeatPolicy int
 
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"runtime"
 	"sync/atomic"
 	"testing"
@@ -118,3 +119,33 @@ func TestRunOnArrayOfIntervals(t *testing.T) {
 		})
 	}
 }
+
+func TestRunOnIntervalOrDemand(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	monitor := &testMonitor{}
+	errFailed := errors.New("failed")
+
+	runNow := RunOnIntervalOrDemand(ctx, func() error {
+		monitor.Execute()
+		return errFailed
+	}, time.Hour)
+
+	if err := runNow(); err != errFailed {
+		t.Errorf("RunOnIntervalOrDemand() run error = %v, want %v", err, errFailed)
+	}
+	// The first run on the interval happens in the background right away
+	for i := 0; i < 100 && monitor.Count() < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := monitor.Count(); got != 2 {
+		t.Errorf("RunOnIntervalOrDemand() executions = %d, want 2", got)
+	}
+
+	cancel()
+	if err := runNow(); err != context.Canceled {
+		t.Errorf("RunOnIntervalOrDemand() run error after cancel = %v, want %v", err, context.Canceled)
+	}
+	if got := monitor.Count(); got != 2 {
+		t.Errorf("RunOnIntervalOrDemand() executions after cancel = %d, want 2", got)
+	}
+}